@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/mod"
+)
+
+// runExport implements the export subcommand, which converts the POI layers of
+// a mod zip to KML or KMZ for viewing in Google Earth
+func runExport(ctx context.Context, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.Usage = printExportUsage
+
+	var outputPath string
+	fs.StringVar(&outputPath, "o", "", "Output .kml or .kmz file path (default: the mod name with .kmz)")
+	fs.StringVar(&outputPath, "output", "", "Output .kml or .kmz file path (default: the mod name with .kmz)")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if fs.NArg() != 1 {
+		printExportUsage()
+		return errors.New("expected one mod zip file")
+	}
+	modPath := fs.Arg(0)
+
+	layers, err := mod.ReadZip(modPath)
+	if err != nil {
+		return fmt.Errorf("failed to read mod %s: %w", modPath, err)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(modPath), filepath.Ext(modPath))
+	if outputPath == "" {
+		outputPath = name + ".kmz"
+	}
+	if err := mod.WriteKML(outputPath, name, layers); err != nil {
+		return fmt.Errorf("failed to write %s: %w", outputPath, err)
+	}
+
+	poiCount := 0
+	for _, layer := range layers {
+		poiCount += len(layer.POIs)
+	}
+	logger.InfoContext(ctx, "Successfully exported mod to KML", "path", outputPath, "layer_count", len(layers), "poi_count", poiCount)
+	return nil
+}
+
+func printExportUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s export [-o <path>] <mod.zip>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "\nConverts the POI layers of a mod to KML, with a folder per layer and the\n")
+	fmt.Fprintf(os.Stderr, "POI colors as icon styles, for checking in Google Earth.\n")
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
+	fmt.Fprintf(os.Stderr, "  -o, --output <path>          Output .kml or .kmz file path (default: <mod name>.kmz)\n")
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  %s export berlin_railways.zip\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s export -o stations.kml stations_mod.zip\n", os.Args[0])
+}
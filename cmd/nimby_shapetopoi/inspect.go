@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/geometry"
+)
+
+// inspectGeometryTypes is the order geometry counts are listed in
+var inspectGeometryTypes = []geometry.GeometryType{geometry.PointGeometry, geometry.LineStringGeometry, geometry.PolygonGeometry}
+
+// runInspect implements the inspect subcommand, which lists the folders of
+// input files with their feature counts, as a guide for --include-folder and
+// --exclude-folder
+func runInspect(ctx context.Context, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	fs.Usage = printInspectUsage
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if fs.NArg() == 0 {
+		printInspectUsage()
+		return errors.New("expected at least one input file")
+	}
+
+	for _, inputFile := range fs.Args() {
+		reader, err := geometry.GetReaderWithOptions(inputFile, geometry.Options{})
+		if err != nil {
+			return err
+		}
+		featureReader, ok := reader.(geometry.FeatureReader)
+		if !ok {
+			return fmt.Errorf("%s: inspecting this format is not supported", inputFile)
+		}
+
+		logger.InfoContext(ctx, "Reading file", "path", inputFile)
+		if isPBF(inputFile) {
+			logger.WarnContext(ctx, "Inspecting a PBF extract keeps every tagged element in memory", "path", inputFile)
+		}
+		features, err := featureReader.ReadFeatures(inputFile)
+		if err != nil {
+			return fmt.Errorf("%s: %w", inputFile, err)
+		}
+		if err := printFolderSummary(os.Stdout, inputFile, geometry.SummarizeFolders(features)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printFolderSummary writes a line per folder, with its path as matched by
+// --include-folder, its own feature count and geometry types, and the count
+// including its subfolders where that differs
+func printFolderSummary(w io.Writer, fileName string, root *geometry.FolderSummary) error {
+	fmt.Fprintf(w, "%s: %s\n", fileName, countFeatures(root.TotalFeatures()))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if root.Features > 0 {
+		fmt.Fprintf(tw, "  (no folder)\t%s\n", describeFolder(root))
+	}
+	var walk func(folders []*geometry.FolderSummary)
+	walk = func(folders []*geometry.FolderSummary) {
+		for _, folder := range folders {
+			fmt.Fprintf(tw, "  %s\t%s\n", folder.Path, describeFolder(folder))
+			walk(folder.Folders)
+		}
+	}
+	walk(root.Folders)
+	return tw.Flush()
+}
+
+func describeFolder(folder *geometry.FolderSummary) string {
+	description := countFeatures(folder.Features)
+
+	var types []string
+	for _, geometryType := range inspectGeometryTypes {
+		if count := folder.Geometries[geometryType]; count > 0 {
+			types = append(types, fmt.Sprintf("%d %s", count, geometryType))
+		}
+	}
+	if len(types) > 0 {
+		description += " (" + strings.Join(types, ", ") + ")"
+	}
+
+	if total := folder.TotalFeatures(); total != folder.Features {
+		description += fmt.Sprintf(", %d with subfolders", total)
+	}
+	return description
+}
+
+func countFeatures(count int) string {
+	if count == 1 {
+		return "1 feature"
+	}
+	return fmt.Sprintf("%d features", count)
+}
+
+func printInspectUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s inspect <input-files...>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "\nLists the folders of each file with the number of features and geometries\n")
+	fmt.Fprintf(os.Stderr, "in them. The folder paths can be given to --include-folder and --exclude-folder.\n")
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  %s inspect hannover.kmz\n", os.Args[0])
+}
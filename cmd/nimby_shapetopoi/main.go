@@ -9,12 +9,17 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
 
 	"github.com/supermanifolds/nimby_shapetopoi/internal/geometry"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/gis"
 	"github.com/supermanifolds/nimby_shapetopoi/internal/mod"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/openrailway"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/osm"
 	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/rules"
 	"github.com/supermanifolds/nimby_shapetopoi/internal/server"
 )
 
@@ -22,19 +27,85 @@ func main() {
 	ctx := context.Background()
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
 
+	// Subcommands parse their own flags
+	if len(os.Args) > 1 {
+		var run func(context.Context, *slog.Logger, []string) error
+		switch os.Args[1] {
+		case "railway":
+			run = runRailway
+		case "export":
+			run = runExport
+		case "inspect":
+			run = runInspect
+		}
+		if run != nil {
+			if err := run(ctx, logger, os.Args[2:]); err != nil {
+				logger.ErrorContext(ctx, "Fatal error", "error", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	var outputPath string
 	var modFilePath string
+	var exportKMLPath string
 	var serverMode bool
 	var serverPort string
+	var overpassURL string
 	var interpolateDistance float64
+	var resampleDistance float64
+	var resampleKeepVertices bool
+	var simplifyTolerance float64
+	var simplifyMethod string
+	var polygonMode string
+	var fillSpacing float64
+	var fillMethod string
+	var labelField string
+	var sourceCRS string
+	var lonColumn string
+	var latColumn string
+	var wktColumn string
+	var osmFilter string
+	var includeFolders stringsFlag
+	var excludeFolders stringsFlag
+	var where string
+	var clipBBox string
+	var clipFile string
+	var poiColor string
+	var preferFileStyles bool
+	var rulesPath string
 
 	flag.StringVar(&outputPath, "o", "", "Output mod zip file path (default: auto-generated)")
 	flag.StringVar(&outputPath, "output", "", "Output mod zip file path (default: auto-generated)")
 	flag.StringVar(&modFilePath, "m", "", "Custom mod.txt file to use")
 	flag.StringVar(&modFilePath, "mod", "", "Custom mod.txt file to use")
+	flag.StringVar(&exportKMLPath, "export-kml", "", "Also write the POIs to this .kml or .kmz file")
 	flag.BoolVar(&serverMode, "server", false, "Run as web server")
 	flag.StringVar(&serverPort, "port", "", "Web server port (default: 8080, or PORT env var)")
+	flag.StringVar(&overpassURL, "overpass-url", "", "Overpass API endpoint for railway imports in server mode (default: "+openrailway.DefaultOverpassEndpoint+")")
 	flag.Float64Var(&interpolateDistance, "interpolate-distance", 0, "Add extra points along lines if segments are longer than this distance (meters)")
+	flag.Float64Var(&resampleDistance, "resample", 0, "Place POIs exactly this far apart along lines (meters), replacing their vertices")
+	flag.BoolVar(&resampleKeepVertices, "resample-keep-vertices", false, "Keep the original vertices when resampling")
+	flag.Float64Var(&simplifyTolerance, "simplify", 0, "Drop vertices that change lines by less than this distance (meters)")
+	flag.StringVar(&simplifyMethod, "simplify-method", "", "Simplification algorithm: douglas-peucker or visvalingam (default: douglas-peucker)")
+	flag.StringVar(&polygonMode, "polygon-mode", "", "Polygon output: outline, point (one labelled POI inside) or both (default: outline)")
+	flag.Float64Var(&fillSpacing, "fill-spacing", 0, "Fill polygons with POIs this far apart (meters), leaving out holes")
+	flag.StringVar(&fillMethod, "fill-method", "", "Polygon fill pattern: grid or hatch (default: grid)")
+	flag.StringVar(&labelField, "label-field", "", "Attribute to use as POI text (default: Label, then name; \"none\" disables labels)")
+	flag.StringVar(&sourceCRS, "source-crs", "", "Coordinate system of shapefile input, e.g. EPSG:2180 (default: read from .prj)")
+	flag.StringVar(&lonColumn, "lon-col", "", "CSV/TSV longitude column (default: detected from lon, lng, longitude or x)")
+	flag.StringVar(&latColumn, "lat-col", "", "CSV/TSV latitude column (default: detected from lat, latitude or y)")
+	flag.StringVar(&wktColumn, "wkt-col", "", "CSV/TSV column with WKT geometries (default: detected from wkt or geometry)")
+	flag.StringVar(&osmFilter, "osm-filter", "", "Tag filter selecting OpenStreetMap elements, e.g. \"railway=rail and usage=main\"")
+	flag.Var(&includeFolders, "include-folder", "Only convert KML folders matching this path or glob, e.g. \"Lines/S-Bahn/*\" (repeatable)")
+	flag.Var(&excludeFolders, "exclude-folder", "Skip KML folders matching this path or glob (repeatable)")
+	flag.StringVar(&clipBBox, "clip-bbox", "", "Only keep POIs inside this box, as minlon,minlat,maxlon,maxlat, cutting lines at its edges; polygons are masked, not cut")
+	flag.StringVar(&clipFile, "clip-file", "", "Only keep POIs inside the polygons of this file, cutting lines at their boundary; polygons are masked, not cut")
+	flag.StringVar(&where, "where", "", "Only convert features matching this expression, e.g. \"type = 'station' and name ~ '^S'\"")
+	flag.StringVar(&poiColor, "color", "", "POI color as hex, e.g. #ff0000 (default: #0000ff)")
+	flag.BoolVar(&preferFileStyles, "prefer-file-styles", false, "Use colors from KML styles and GTFS route colors, falling back to --color for unstyled features")
+	flag.StringVar(&rulesPath, "rules", "", "JSON rules file that styles POIs from feature attributes")
 	flag.Parse()
 
 	// If server mode, start the web server
@@ -47,7 +118,7 @@ func main() {
 				serverPort = "8080"
 			}
 		}
-		startWebServer(ctx, logger, serverPort)
+		startWebServer(ctx, logger, serverPort, overpassURL)
 		return
 	}
 
@@ -57,65 +128,242 @@ func main() {
 		os.Exit(1)
 	}
 
-	if outputPath == "" {
-		outputPath = generateOutputPath(inputFiles)
+	// Fail early on a bad override rather than once per input file
+	if sourceCRS != "" {
+		if _, err := gis.ParseCRS(sourceCRS); err != nil {
+			logger.ErrorContext(ctx, "Invalid --source-crs", "error", err)
+			os.Exit(1)
+		}
 	}
 
-	// Ensure output has .zip extension
-	if !strings.HasSuffix(outputPath, ".zip") {
-		outputPath = strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".zip"
+	simplify, err := geometry.ParseSimplifyMethod(simplifyMethod)
+	if err != nil {
+		logger.ErrorContext(ctx, "Invalid --simplify-method", "error", err)
+		os.Exit(1)
 	}
 
-	// Generate TSV filename based on the output zip name
-	tsvFileName := strings.TrimSuffix(filepath.Base(outputPath), ".zip") + ".tsv"
+	polygons, err := geometry.ParsePolygonMode(polygonMode)
+	if err != nil {
+		logger.ErrorContext(ctx, "Invalid --polygon-mode", "error", err)
+		os.Exit(1)
+	}
 
-	// Process all input files (with interpolation if requested)
-	poiList, err := processInputFiles(ctx, logger, inputFiles, interpolateDistance)
+	fill, err := geometry.ParseFillMethod(fillMethod)
 	if err != nil {
-		logger.ErrorContext(ctx, "Fatal error", "error", err)
+		logger.ErrorContext(ctx, "Invalid --fill-method", "error", err)
 		os.Exit(1)
 	}
 
-	// Prepare mod content
-	modContent, err := prepareModContent(modFilePath, outputPath, tsvFileName)
+	var filter *osm.Filter
+	if osmFilter != "" {
+		if filter, err = osm.ParseFilter(osmFilter); err != nil {
+			logger.ErrorContext(ctx, "Invalid --osm-filter", "error", err)
+			os.Exit(1)
+		}
+	}
+	// Without a filter every tagged element of a PBF extract is kept in
+	// memory, which country-sized extracts do not fit in
+	if filter == nil && slices.ContainsFunc(inputFiles, isPBF) {
+		logger.ErrorContext(ctx, "--osm-filter is required for .osm.pbf input, e.g. --osm-filter \"railway=rail\"")
+		os.Exit(1)
+	}
+
+	folders, err := geometry.NewFolderFilter(includeFolders, excludeFolders)
 	if err != nil {
-		logger.ErrorContext(ctx, "Fatal error", "error", err)
+		logger.ErrorContext(ctx, "Invalid --include-folder or --exclude-folder", "error", err)
 		os.Exit(1)
 	}
 
-	// Create the mod zip
-	config := mod.Config{
-		OutputPath:  outputPath,
-		TSVFileName: tsvFileName,
+	var featureFilter *geometry.FeatureFilter
+	if where != "" {
+		if featureFilter, err = geometry.ParseFeatureFilter(where); err != nil {
+			logger.ErrorContext(ctx, "Invalid --where", "error", err)
+			os.Exit(1)
+		}
 	}
 
-	err = mod.CreateZip(config, *poiList, modContent)
+	var clip *geometry.ClipArea
+	switch {
+	case clipBBox != "" && clipFile != "":
+		logger.ErrorContext(ctx, "Only one of --clip-bbox and --clip-file can be used")
+		os.Exit(1)
+	case clipBBox != "":
+		bbox, err := openrailway.ParseBoundingBox(clipBBox)
+		if err != nil {
+			logger.ErrorContext(ctx, "Invalid --clip-bbox", "error", err)
+			os.Exit(1)
+		}
+		clip = geometry.NewClipBox(bbox.MinLon, bbox.MinLat, bbox.MaxLon, bbox.MaxLat)
+	case clipFile != "":
+		clipOpts := geometry.Options{SourceCRS: sourceCRS, LonColumn: lonColumn, LatColumn: latColumn, WKTColumn: wktColumn}
+		if clip, err = geometry.ReadClipArea(clipFile, clipOpts); err != nil {
+			logger.ErrorContext(ctx, "Invalid --clip-file", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	var ruleSet *rules.RuleSet
+	if rulesPath != "" {
+		if ruleSet, err = rules.Load(rulesPath); err != nil {
+			logger.ErrorContext(ctx, "Invalid --rules file", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if outputPath == "" {
+		outputPath = generateOutputPath(inputFiles)
+	}
+
+	// Process all input files (with interpolation if requested)
+	opts := geometry.Options{
+		InterpolateDistance:  interpolateDistance,
+		ResampleDistance:     resampleDistance,
+		ResampleKeepVertices: resampleKeepVertices,
+		SimplifyTolerance:    simplifyTolerance,
+		Simplify:             simplify,
+		PolygonMode:          polygons,
+		FillSpacing:          fillSpacing,
+		Fill:                 fill,
+		LabelField:           labelField,
+		SourceCRS:            sourceCRS,
+		LonColumn:            lonColumn,
+		LatColumn:            latColumn,
+		WKTColumn:            wktColumn,
+		OSMFilter:            filter,
+		Folders:              folders,
+		Where:                featureFilter,
+		Clip:                 clip,
+		PreferFileStyles:     preferFileStyles,
+		Rules:                ruleSet,
+	}
+	poiList, err := processInputFiles(ctx, logger, inputFiles, opts, poiColor)
+	if err != nil {
+		logger.ErrorContext(ctx, "Fatal error", "error", err)
+		os.Exit(1)
+	}
+
+	outputPath, err = writeMod(outputPath, modFilePath, poiList)
 	if err != nil {
 		logger.ErrorContext(ctx, "Failed to create mod zip", "error", err)
 		os.Exit(1)
 	}
 
 	logger.InfoContext(ctx, "Successfully created mod file", "path", outputPath, "poi_count", len(*poiList))
+
+	if exportKMLPath != "" {
+		name := strings.TrimSuffix(filepath.Base(outputPath), ".zip")
+		if err := mod.WriteKML(exportKMLPath, name, []mod.Layer{{Name: name + " POIs", POIs: *poiList}}); err != nil {
+			logger.ErrorContext(ctx, "Failed to export KML", "error", err)
+			os.Exit(1)
+		}
+		logger.InfoContext(ctx, "Successfully exported KML", "path", exportKMLPath)
+	}
+}
+
+// writeMod creates the mod zip, adding the .zip extension to outputPath if it
+// is missing, and returns the path written
+func writeMod(outputPath, modFilePath string, poiList *poi.List) (string, error) {
+	// Ensure output has .zip extension
+	if !strings.HasSuffix(outputPath, ".zip") {
+		outputPath = strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".zip"
+	}
+
+	// Generate TSV filename based on the output zip name
+	tsvFileName := strings.TrimSuffix(filepath.Base(outputPath), ".zip") + ".tsv"
+
+	modContent, err := prepareModContent(modFilePath, outputPath, tsvFileName)
+	if err != nil {
+		return "", err
+	}
+
+	config := mod.Config{
+		OutputPath:  outputPath,
+		TSVFileName: tsvFileName,
+	}
+	if err := mod.CreateZip(config, *poiList, modContent); err != nil {
+		return "", err
+	}
+	return outputPath, nil
 }
 
 func printUsage() {
 	fmt.Fprintf(os.Stderr, "Usage: %s [options] <input-files...>\n", os.Args[0])
-	fmt.Fprintf(os.Stderr, "       %s --server [--port <port>]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s railway --bbox <minlon,minlat,maxlon,maxlat> [options]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s inspect <input-files...>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s export [-o <path>] <mod.zip>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s --server [--port <port>] [--overpass-url <url>]\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "\nOptions:\n")
 	fmt.Fprintf(os.Stderr, "  -o, --output <path>          Output mod zip file path\n")
 	fmt.Fprintf(os.Stderr, "  -m, --mod <path>             Custom mod.txt file to use\n")
+	fmt.Fprintf(os.Stderr, "  --export-kml <path>          Also write the POIs to a .kml or .kmz file for Google Earth\n")
 	fmt.Fprintf(os.Stderr, "  --interpolate-distance <m>   Add extra points along lines if segments exceed this distance (meters)\n")
+	fmt.Fprintf(os.Stderr, "  --resample <m>               Place POIs exactly this far apart along lines (meters)\n")
+	fmt.Fprintf(os.Stderr, "  --resample-keep-vertices     Keep the original vertices when resampling\n")
+	fmt.Fprintf(os.Stderr, "  --simplify <m>               Drop vertices that change lines by less than this distance (meters)\n")
+	fmt.Fprintf(os.Stderr, "  --simplify-method <method>   douglas-peucker or visvalingam (default: douglas-peucker)\n")
+	fmt.Fprintf(os.Stderr, "  --polygon-mode <mode>        outline, point (one labelled POI inside) or both (default: outline)\n")
+	fmt.Fprintf(os.Stderr, "  --fill-spacing <m>           Fill polygons with POIs this far apart (meters), leaving out holes\n")
+	fmt.Fprintf(os.Stderr, "  --fill-method <method>       grid or hatch (default: grid)\n")
+	fmt.Fprintf(os.Stderr, "  --label-field <name>         Attribute to use as POI text (default: Label, then name; \"none\" disables labels)\n")
+	fmt.Fprintf(os.Stderr, "  --source-crs <crs>           Coordinate system of shapefile input, e.g. EPSG:2180 (default: read from .prj)\n")
+	fmt.Fprintf(os.Stderr, "  --lon-col <name>             CSV/TSV longitude column (default: lon, lng, longitude or x)\n")
+	fmt.Fprintf(os.Stderr, "  --lat-col <name>             CSV/TSV latitude column (default: lat, latitude or y)\n")
+	fmt.Fprintf(os.Stderr, "  --wkt-col <name>             CSV/TSV column with WKT geometries (default: wkt or geometry)\n")
+	fmt.Fprintf(os.Stderr, "  --osm-filter <expr>          Tag filter for .osm and .osm.pbf input (required for .osm.pbf), e.g. \"railway=rail and usage=main\"\n")
+	fmt.Fprintf(os.Stderr, "  --include-folder <glob>      Only convert KML folders matching this path or glob (repeatable)\n")
+	fmt.Fprintf(os.Stderr, "  --exclude-folder <glob>      Skip KML folders matching this path or glob (repeatable)\n")
+	fmt.Fprintf(os.Stderr, "  --clip-bbox <bbox>           Only keep POIs inside minlon,minlat,maxlon,maxlat, cutting lines at its edges\n")
+	fmt.Fprintf(os.Stderr, "                               and masking polygons without cutting them\n")
+	fmt.Fprintf(os.Stderr, "  --clip-file <path>           Only keep POIs inside the polygons of this file, cutting lines at their boundary\n")
+	fmt.Fprintf(os.Stderr, "                               and masking polygons without cutting them\n")
+	fmt.Fprintf(os.Stderr, "  --where <expr>               Only convert features matching this expression, e.g. \"type = 'station'\"\n")
+	fmt.Fprintf(os.Stderr, "  --color <hex>                POI color, e.g. #ff0000 (default: #0000ff)\n")
+	fmt.Fprintf(os.Stderr, "  --prefer-file-styles         Use colors from KML styles and GTFS routes, falling back to --color\n")
+	fmt.Fprintf(os.Stderr, "  --rules <path>               JSON rules file that styles POIs from feature attributes\n")
 	fmt.Fprintf(os.Stderr, "  --server                     Run as web server\n")
 	fmt.Fprintf(os.Stderr, "  --port <port>                Web server port (default: 8080)\n")
-	fmt.Fprintf(os.Stderr, "\nSupported formats: .shp, .kml, .kmz\n")
+	fmt.Fprintf(os.Stderr, "  --overpass-url <url>         Overpass API endpoint for railway imports in server mode\n")
+	fmt.Fprintf(os.Stderr, "\nSupported formats: .shp, .kml, .kmz, .geojson, .json, .gpx, .csv, .tsv, .osm, .osm.pbf, .zip (GTFS)\n")
 	fmt.Fprintf(os.Stderr, "\nExamples:\n")
 	fmt.Fprintf(os.Stderr, "  %s file.shp\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s -o mymod.zip file1.kml file2.kmz\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s --interpolate-distance 500 --output dense.zip railway.kml\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --simplify 5 --interpolate-distance 200 gps_trace.geojson\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --resample 100 --output dotted.zip railway.kml\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --fill-spacing 50 --fill-method hatch lakes.kml\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --polygon-mode point --label-field NAME districts.shp\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --label-field NAME stations.shp\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --source-crs EPSG:27700 tracks.shp\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --lon-col Easting --lat-col Northing --source-crs EPSG:27700 stations.csv\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --osm-filter \"railway=rail and usage=main\" berlin.osm\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --osm-filter \"public_transport=station\" germany-latest.osm.pbf\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --prefer-file-styles --color #808080 mymaps_export.kmz\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s inspect network.kmz\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --include-folder \"Lines/S-Bahn/*\" --exclude-folder \"*/Closed\" network.kmz\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --where \"type = 'station' and name ~ '^S'\" stations.geojson\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --clip-bbox 13.08,52.33,13.77,52.68 germany-railways.osm.pbf\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --clip-file bavaria.geojson germany-tracks.shp\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --prefer-file-styles --resample 100 gtfs.zip\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --rules railway_rules.json stations.shp platforms.shp\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s --mod custom_mod.txt --output combined.zip *.shp *.kml\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s railway --bbox 13.30,52.49,13.45,52.56 --output berlin_railways.zip\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --export-kml preview.kmz --resample 100 railway.kml\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s export berlin_railways.zip\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s --server --port 3000\n", os.Args[0])
 }
 
+// stringsFlag collects the values of a flag that may be given several times
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ", ")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
 func generateOutputPath(inputFiles []string) string {
 	if len(inputFiles) == 1 {
 		base := filepath.Base(inputFiles[0])
@@ -126,20 +374,30 @@ func generateOutputPath(inputFiles []string) string {
 	return "combined_mod.zip"
 }
 
-func processInputFiles(ctx context.Context, logger *slog.Logger, inputFiles []string, interpolateDistance float64) (*poi.List, error) {
+// isPBF reports whether an input file is an OSM PBF extract
+func isPBF(inputFile string) bool {
+	return strings.EqualFold(filepath.Ext(inputFile), ".pbf")
+}
+
+func processInputFiles(ctx context.Context, logger *slog.Logger, inputFiles []string, opts geometry.Options, poiColor string) (*poi.List, error) {
 	combinedPOIList := make(poi.List, 0)
+	color := geometry.HexToNimbyColor(poiColor)
 
 	for _, inputFile := range inputFiles {
 		logger.InfoContext(ctx, "Processing file", "path", inputFile)
 
-		// Create reader with interpolation distance
-		reader, err := geometry.GetReaderWithInterpolation(inputFile, interpolateDistance)
+		// Create reader with interpolation and label options
+		reader, err := geometry.GetReaderWithOptions(inputFile, opts)
 		if err != nil {
 			logger.ErrorContext(ctx, "Error getting reader for file", "path", inputFile, "error", err)
 			continue
 		}
 
-		poiList, err := reader.ParseFile(inputFile)
+		poiList, err := reader.ParseFileWithFullConfig(inputFile, geometry.DefaultMaxLod, color)
+		if errors.Is(err, geometry.ErrTooManyFillPoints) {
+			// Leaving the file out would silently drop it from the mod
+			return nil, fmt.Errorf("%s: %w", inputFile, err)
+		}
 		if err != nil {
 			logger.ErrorContext(ctx, "Error parsing file", "path", inputFile, "error", err)
 			continue
@@ -172,13 +430,14 @@ func prepareModContent(modFilePath, outputPath, tsvFileName string) (string, err
 	return mod.GenerateDefaultContent(modName, tsvFileName), nil
 }
 
-func startWebServer(ctx context.Context, logger *slog.Logger, port string) {
+func startWebServer(ctx context.Context, logger *slog.Logger, port, overpassURL string) {
 	// Create context that cancels on interrupt signals
 	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
 
 	srv := server.New(server.Config{
-		Port:   port,
-		Logger: logger,
+		Port:        port,
+		Logger:      logger,
+		OverpassURL: overpassURL,
 	})
 
 	err := srv.Start(ctx)
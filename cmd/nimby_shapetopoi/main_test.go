@@ -2,11 +2,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/geometry"
 )
 
 func TestGenerateOutputPath(t *testing.T) {
@@ -73,7 +76,7 @@ func TestProcessInputFiles(t *testing.T) {
 	// Test processing
 	ctx := context.Background()
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-	poiList, err := processInputFiles(ctx, logger, []string{kmlFile}, 0)
+	poiList, err := processInputFiles(ctx, logger, []string{kmlFile}, geometry.Options{}, "")
 	if err != nil {
 		t.Fatalf("processInputFiles returned error: %v", err)
 	}
@@ -91,8 +94,8 @@ func TestProcessInputFiles(t *testing.T) {
 		if poi.Lon != 10.0 || poi.Lat != 53.0 {
 			t.Errorf("Expected coordinates (10.0, 53.0), got (%f, %f)", poi.Lon, poi.Lat)
 		}
-		if poi.Text != "" {
-			t.Errorf("Expected empty text, got '%s'", poi.Text)
+		if poi.Text != "Test Point" {
+			t.Errorf("Expected text 'Test Point', got '%s'", poi.Text)
 		}
 	}
 }
@@ -100,7 +103,7 @@ func TestProcessInputFiles(t *testing.T) {
 func TestProcessInputFiles_NonExistentFile(t *testing.T) {
 	ctx := context.Background()
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-	_, err := processInputFiles(ctx, logger, []string{"nonexistent.kml"}, 0)
+	_, err := processInputFiles(ctx, logger, []string{"nonexistent.kml"}, geometry.Options{}, "")
 
 	// Should return error when no POIs are extracted
 	if err == nil {
@@ -122,7 +125,7 @@ func TestProcessInputFiles_UnsupportedFormat(t *testing.T) {
 
 	ctx := context.Background()
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-	_, err = processInputFiles(ctx, logger, []string{txtFile}, 0)
+	_, err = processInputFiles(ctx, logger, []string{txtFile}, geometry.Options{}, "")
 
 	// Should return error when no POIs are extracted
 	if err == nil {
@@ -134,10 +137,48 @@ func TestProcessInputFiles_UnsupportedFormat(t *testing.T) {
 	}
 }
 
+func TestProcessInputFiles_TooManyFillPoints(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// A square of roughly 11 km by 11 km, which a 1 m spacing would fill with
+	// over a hundred million POIs
+	kmlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+	<Placemark>
+		<name>Area</name>
+		<Polygon><outerBoundaryIs><LinearRing>
+			<coordinates>10.0,53.0,0 10.1,53.0,0 10.1,53.1,0 10.0,53.1,0 10.0,53.0,0</coordinates>
+		</LinearRing></outerBoundaryIs></Polygon>
+	</Placemark>
+</Document>
+</kml>`
+	kmlFile := filepath.Join(tmpDir, "area.kml")
+	if err := os.WriteFile(kmlFile, []byte(kmlContent), 0644); err != nil {
+		t.Fatalf("Failed to create test KML file: %v", err)
+	}
+	pointFile := filepath.Join(tmpDir, "point.kml")
+	pointContent := `<kml><Document><Placemark><name>Station</name><Point><coordinates>10.0,53.0,0</coordinates></Point></Placemark></Document></kml>`
+	if err := os.WriteFile(pointFile, []byte(pointContent), 0644); err != nil {
+		t.Fatalf("Failed to create test KML file: %v", err)
+	}
+
+	// The other file converts fine, but the oversized one must not be left out
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	_, err := processInputFiles(ctx, logger, []string{pointFile, kmlFile}, geometry.Options{FillSpacing: 1}, "")
+	if !errors.Is(err, geometry.ErrTooManyFillPoints) {
+		t.Fatalf("Expected ErrTooManyFillPoints, got %v", err)
+	}
+	if !strings.Contains(err.Error(), kmlFile) {
+		t.Errorf("Expected the error to name %s, got: %v", kmlFile, err)
+	}
+}
+
 func TestProcessInputFiles_NoFiles(t *testing.T) {
 	ctx := context.Background()
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-	_, err := processInputFiles(ctx, logger, []string{}, 0)
+	_, err := processInputFiles(ctx, logger, []string{}, geometry.Options{}, "")
 
 	if err == nil {
 		t.Error("Expected error for empty file list, but got none")
@@ -251,7 +292,7 @@ func TestMainWorkflow_Integration(t *testing.T) {
 	// Process input files
 	ctx := context.Background()
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-	poiList, err := processInputFiles(ctx, logger, []string{inputFile}, 0)
+	poiList, err := processInputFiles(ctx, logger, []string{inputFile}, geometry.Options{}, "")
 	if err != nil {
 		t.Fatalf("processInputFiles failed: %v", err)
 	}
@@ -273,8 +314,8 @@ func TestMainWorkflow_Integration(t *testing.T) {
 
 	if len(*poiList) > 0 {
 		firstPOI := (*poiList)[0]
-		if firstPOI.Text != "" {
-			t.Errorf("Expected empty text, got '%s'", firstPOI.Text)
+		if firstPOI.Text != "Integration Test Point" {
+			t.Errorf("Expected text 'Integration Test Point', got '%s'", firstPOI.Text)
 		}
 		if firstPOI.Lon != 11.123 || firstPOI.Lat != 54.456 {
 			t.Errorf("Expected first POI coordinates (11.123, 54.456), got (%f, %f)",
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/geometry"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/openrailway"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/rules"
+)
+
+// runRailway implements the railway subcommand, which builds a mod from the
+// OpenStreetMap railway infrastructure in a bounding box
+func runRailway(ctx context.Context, logger *slog.Logger, args []string) error {
+	fs := flag.NewFlagSet("railway", flag.ContinueOnError)
+	fs.Usage = printRailwayUsage
+
+	var bbox string
+	var overpassURL string
+	var outputPath string
+	var modFilePath string
+	var interpolateDistance float64
+	var resampleDistance float64
+	var simplifyTolerance float64
+	var labelField string
+	var maxLod int
+	var poiColor string
+	var rulesPath string
+
+	fs.StringVar(&bbox, "bbox", "", "Area to import as minlon,minlat,maxlon,maxlat")
+	fs.StringVar(&overpassURL, "overpass-url", "", "Overpass API endpoint (default: "+openrailway.DefaultOverpassEndpoint+")")
+	fs.StringVar(&outputPath, "o", "railway_mod.zip", "Output mod zip file path")
+	fs.StringVar(&outputPath, "output", "railway_mod.zip", "Output mod zip file path")
+	fs.StringVar(&modFilePath, "m", "", "Custom mod.txt file to use")
+	fs.StringVar(&modFilePath, "mod", "", "Custom mod.txt file to use")
+	fs.Float64Var(&interpolateDistance, "interpolate-distance", 0, "Add extra points along tracks if segments are longer than this distance (meters)")
+	fs.Float64Var(&resampleDistance, "resample", 0, "Place POIs exactly this far apart along tracks (meters)")
+	fs.Float64Var(&simplifyTolerance, "simplify", 0, "Drop vertices that change tracks by less than this distance (meters)")
+	fs.StringVar(&labelField, "label-field", "", "OSM tag to use as POI text (default: name)")
+	fs.IntVar(&maxLod, "max-lod", geometry.DefaultMaxLod, "Zoom level POIs stay visible up to, from 0 (close only) to 10")
+	fs.StringVar(&poiColor, "color", "", "Color of features the railway styles don't cover, e.g. #ff0000")
+	fs.StringVar(&rulesPath, "rules", "", "JSON rules file replacing the built-in railway styles")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if bbox == "" {
+		printRailwayUsage()
+		return errors.New("--bbox is required")
+	}
+	if maxLod < 0 || maxLod > 10 {
+		return fmt.Errorf("invalid --max-lod %d, expected 0 to 10", maxLod)
+	}
+	lod := int32(maxLod)
+
+	var ruleSet *rules.RuleSet
+	if rulesPath != "" {
+		var err error
+		if ruleSet, err = rules.Load(rulesPath); err != nil {
+			return fmt.Errorf("invalid --rules file: %w", err)
+		}
+	}
+
+	cfg := openrailway.Config{
+		Endpoint: overpassURL,
+		Options: geometry.Options{
+			InterpolateDistance: interpolateDistance,
+			ResampleDistance:    resampleDistance,
+			SimplifyTolerance:   simplifyTolerance,
+			LabelField:          labelField,
+			Rules:               ruleSet,
+		},
+		MaxLod: &lod,
+		Color:  poiColor,
+	}
+
+	logger.InfoContext(ctx, "Querying railway infrastructure", "bbox", bbox)
+	poiList, err := openrailway.GeneratePOIs(ctx, bbox, cfg)
+	if err != nil {
+		return err
+	}
+	if len(*poiList) == 0 {
+		return errors.New("no railway infrastructure found in the bounding box")
+	}
+
+	outputPath, err = writeMod(outputPath, modFilePath, poiList)
+	if err != nil {
+		return fmt.Errorf("failed to create mod zip: %w", err)
+	}
+
+	logger.InfoContext(ctx, "Successfully created mod file", "path", outputPath, "poi_count", len(*poiList))
+	return nil
+}
+
+func printRailwayUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s railway --bbox <minlon,minlat,maxlon,maxlat> [options]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "\nBuilds a mod from the tracks, stations, platforms and signals in an area,\n")
+	fmt.Fprintf(os.Stderr, "queried from OpenStreetMap through the Overpass API.\n")
+	fmt.Fprintf(os.Stderr, "\nOptions:\n")
+	fmt.Fprintf(os.Stderr, "  --bbox <bbox>                Area to import, at most 4 square degrees\n")
+	fmt.Fprintf(os.Stderr, "  --overpass-url <url>         Overpass API endpoint (default: %s)\n", openrailway.DefaultOverpassEndpoint)
+	fmt.Fprintf(os.Stderr, "  -o, --output <path>          Output mod zip file path (default: railway_mod.zip)\n")
+	fmt.Fprintf(os.Stderr, "  -m, --mod <path>             Custom mod.txt file to use\n")
+	fmt.Fprintf(os.Stderr, "  --interpolate-distance <m>   Add extra points along tracks if segments exceed this distance (meters)\n")
+	fmt.Fprintf(os.Stderr, "  --resample <m>               Place POIs exactly this far apart along tracks (meters)\n")
+	fmt.Fprintf(os.Stderr, "  --simplify <m>               Drop vertices that change tracks by less than this distance (meters)\n")
+	fmt.Fprintf(os.Stderr, "  --label-field <tag>          OSM tag to use as POI text (default: name)\n")
+	fmt.Fprintf(os.Stderr, "  --max-lod <lod>              Zoom level POIs stay visible up to, 0 to 10 (default: %d)\n", geometry.DefaultMaxLod)
+	fmt.Fprintf(os.Stderr, "  --color <hex>                Color of features the railway styles don't cover\n")
+	fmt.Fprintf(os.Stderr, "  --rules <path>               JSON rules file replacing the built-in railway styles\n")
+	fmt.Fprintf(os.Stderr, "\nExamples:\n")
+	fmt.Fprintf(os.Stderr, "  %s railway --bbox 13.30,52.49,13.45,52.56 --output berlin_railways.zip\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s railway --bbox 10.70,59.88,10.82,59.95 --resample 100 --overpass-url http://localhost:12345/api/interpreter\n", os.Args[0])
+}
@@ -4,4 +4,4 @@ go 1.23.4
 
 require github.com/jonas-p/go-shp v0.1.1
 
-require github.com/a-h/templ v0.3.924 // indirect
+require github.com/a-h/templ v0.3.924
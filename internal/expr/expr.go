@@ -0,0 +1,394 @@
+// Package expr parses the filter expressions shared by --where and
+// --osm-filter. Expressions combine tests with "and", "or", "not" (or "!")
+// and parentheses, "and" binding tighter than "or":
+//
+//	railway                      the attribute is set
+//	railway=rail|light_rail      the attribute is one of the values, with "*", "?" and "[...]" wildcards
+//	ref="S*"                     the attribute is exactly the quoted value
+//	usage!=industrial            the attribute is missing or none of the values
+//	tracks>=2                    numeric comparison when both sides are numbers (<, <=, >, >=)
+//	name~"Hbf$"                  the attribute matches a regular expression
+//	service!~siding|yard         the attribute is missing or does not match
+//
+// Bare values compare as numbers when both sides are numbers, so "tracks=2"
+// also matches "2.0". "<>" is accepted for "!=". Values are bare words or
+// strings in single or double quotes, which values containing spaces or
+// parentheses need. Alternatives and wildcards only apply to bare words: a
+// quoted value after "=" or "!=" matches literally, so name="A|B" matches the
+// name "A|B". A Language adds fields such as $geometry and functions such as
+// bbox(...) to the grammar.
+package expr
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Expr is a parsed expression over records of type T
+type Expr[T any] interface {
+	Match(record T) bool
+}
+
+// Language adapts the grammar to a kind of record
+type Language[T any] struct {
+	// Attribute returns the named attribute of a record, false when it is
+	// missing or blank
+	Attribute func(record T, name string) (string, bool)
+	// Field parses a test of a field starting with "$", such as $geometry.
+	// "!=", "<>" and "!~" reach it as "=" and "~", and the test it returns is
+	// negated. A nil Field rejects such tests.
+	Field func(name, op, value string) (Expr[T], error)
+	// Functions parses the tests written as calls with numeric arguments,
+	// such as bbox(13.0, 52.3, 13.8, 52.7), by their lower-case name
+	Functions map[string]func(args []float64) (Expr[T], error)
+}
+
+// Parse parses an expression in a language
+func Parse[T any](text string, lang *Language[T]) (Expr[T], error) {
+	p := &parser[T]{text: text, lang: lang}
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos < len(p.text) {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.text[p.pos:], p.pos)
+	}
+	return expr, nil
+}
+
+type andExpr[T any] []Expr[T]
+
+func (e andExpr[T]) Match(record T) bool {
+	for _, expr := range e {
+		if !expr.Match(record) {
+			return false
+		}
+	}
+	return true
+}
+
+type orExpr[T any] []Expr[T]
+
+func (e orExpr[T]) Match(record T) bool {
+	for _, expr := range e {
+		if expr.Match(record) {
+			return true
+		}
+	}
+	return false
+}
+
+type notExpr[T any] struct {
+	expr Expr[T]
+}
+
+func (e notExpr[T]) Match(record T) bool {
+	return !e.expr.Match(record)
+}
+
+// attributeExpr tests a single attribute. With no operator it only requires
+// the attribute to be set.
+type attributeExpr[T any] struct {
+	attribute func(record T, name string) (string, bool)
+	name      string
+	op        string
+	// values holds the alternatives of "=", or the single value compared to
+	values []string
+	// exact is set for quoted values, which "=" compares literally
+	exact   bool
+	pattern *regexp.Regexp
+}
+
+func (e attributeExpr[T]) Match(record T) bool {
+	value, ok := e.attribute(record, e.name)
+	if !ok {
+		return false
+	}
+	switch e.op {
+	case "":
+		return true
+	case "~":
+		return e.pattern.MatchString(value)
+	case "=":
+		if e.exact {
+			return value == e.values[0]
+		}
+		for _, alternative := range e.values {
+			if compare(value, alternative) == 0 {
+				return true
+			}
+			if matched, _ := path.Match(alternative, value); matched {
+				return true
+			}
+		}
+		return false
+	}
+
+	order := compare(value, e.values[0])
+	switch e.op {
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	default:
+		return order >= 0
+	}
+}
+
+// compare orders two values as numbers when both are, and as strings otherwise
+func compare(a, b string) int {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		return cmp.Compare(x, y)
+	}
+	return strings.Compare(a, b)
+}
+
+type parser[T any] struct {
+	text string
+	pos  int
+	lang *Language[T]
+}
+
+func (p *parser[T]) or() (Expr[T], error) {
+	expr, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	exprs := orExpr[T]{expr}
+	for p.keyword("or") {
+		if expr, err = p.and(); err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return exprs, nil
+}
+
+func (p *parser[T]) and() (Expr[T], error) {
+	expr, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	exprs := andExpr[T]{expr}
+	for p.keyword("and") {
+		if expr, err = p.unary(); err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return exprs, nil
+}
+
+func (p *parser[T]) unary() (Expr[T], error) {
+	if p.keyword("not") || p.symbol("!") {
+		expr, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr[T]{expr}, nil
+	}
+	if p.symbol("(") {
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.symbol(")") {
+			return nil, fmt.Errorf("expected ')' at offset %d", p.pos)
+		}
+		return expr, nil
+	}
+
+	// An attribute may share a function's name, so a call needs its parenthesis
+	for name, function := range p.lang.Functions {
+		start := p.pos
+		if p.keyword(name) && p.symbol("(") {
+			return p.call(name, function)
+		}
+		p.pos = start
+	}
+	return p.test()
+}
+
+func (p *parser[T]) test() (Expr[T], error) {
+	name, _, err := p.word(false)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return nil, fmt.Errorf("expected an attribute at offset %d", p.pos)
+	}
+
+	var op string
+	for _, candidate := range []string{"!~", "~", "!=", "<>", "<=", ">=", "=", "<", ">"} {
+		if p.symbol(candidate) {
+			op = candidate
+			break
+		}
+	}
+	negate := op == "!~" || op == "!=" || op == "<>"
+	switch op {
+	case "!~":
+		op = "~"
+	case "!=", "<>":
+		op = "="
+	}
+
+	var expr Expr[T]
+	switch {
+	case strings.HasPrefix(name, "$"):
+		expr, err = p.field(name, op)
+	case op == "":
+		expr = attributeExpr[T]{attribute: p.lang.Attribute, name: name}
+	default:
+		expr, err = p.attribute(name, op)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if negate {
+		return notExpr[T]{expr}, nil
+	}
+	return expr, nil
+}
+
+func (p *parser[T]) attribute(name, op string) (Expr[T], error) {
+	value, quoted, err := p.word(true)
+	if err != nil {
+		return nil, err
+	}
+	expr := attributeExpr[T]{attribute: p.lang.Attribute, name: name, op: op, values: []string{value}, exact: quoted}
+
+	switch op {
+	case "~":
+		if expr.pattern, err = regexp.Compile(value); err != nil {
+			return nil, fmt.Errorf("invalid regular expression for %s: %w", name, err)
+		}
+	case "=":
+		if quoted {
+			break
+		}
+		expr.values = strings.Split(value, "|")
+		for _, v := range expr.values {
+			if _, err := path.Match(v, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q for %s", v, name)
+			}
+		}
+	}
+	return expr, nil
+}
+
+func (p *parser[T]) field(name, op string) (Expr[T], error) {
+	if p.lang.Field == nil {
+		return nil, fmt.Errorf("unknown field %q", name)
+	}
+	if op == "" {
+		return nil, fmt.Errorf("expected an operator after %s at offset %d", name, p.pos)
+	}
+	value, _, err := p.word(true)
+	if err != nil {
+		return nil, err
+	}
+	return p.lang.Field(name, op, value)
+}
+
+// call parses the numeric arguments of a function following its "("
+func (p *parser[T]) call(name string, function func(args []float64) (Expr[T], error)) (Expr[T], error) {
+	var args []float64
+	for !p.symbol(")") {
+		if len(args) > 0 && !p.symbol(",") {
+			return nil, fmt.Errorf("expected ',' or ')' at offset %d", p.pos)
+		}
+		p.skipSpace()
+		start := p.pos
+		for p.pos < len(p.text) && strings.IndexByte(" \t\r\n,)", p.text[p.pos]) < 0 {
+			p.pos++
+		}
+		arg, err := strconv.ParseFloat(p.text[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected a number at offset %d", start)
+		}
+		args = append(args, arg)
+	}
+	expr, err := function(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return expr, nil
+}
+
+// word reads a quoted string or a bare word, reporting whether it was
+// quoted. Attribute names end at an operator, values only at whitespace or a
+// closing parenthesis.
+func (p *parser[T]) word(isValue bool) (string, bool, error) {
+	p.skipSpace()
+	if p.pos < len(p.text) && (p.text[p.pos] == '"' || p.text[p.pos] == '\'') {
+		quote := p.text[p.pos]
+		end := strings.IndexByte(p.text[p.pos+1:], quote)
+		if end < 0 {
+			return "", false, fmt.Errorf("unterminated string at offset %d", p.pos)
+		}
+		value := p.text[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return value, true, nil
+	}
+
+	stop := " \t\r\n()=!<>~,\"'"
+	if isValue {
+		stop = " \t\r\n)"
+	}
+	start := p.pos
+	for p.pos < len(p.text) && strings.IndexByte(stop, p.text[p.pos]) < 0 {
+		p.pos++
+	}
+	if isValue && start == p.pos {
+		return "", false, fmt.Errorf("expected a value at offset %d", p.pos)
+	}
+	return p.text[start:p.pos], false, nil
+}
+
+// keyword consumes a case-insensitive word such as "and" if it comes next
+func (p *parser[T]) keyword(word string) bool {
+	p.skipSpace()
+	end := p.pos + len(word)
+	if end > len(p.text) || !strings.EqualFold(p.text[p.pos:end], word) {
+		return false
+	}
+	if end < len(p.text) && strings.IndexByte(" \t\r\n(", p.text[end]) < 0 {
+		return false
+	}
+	p.pos = end
+	return true
+}
+
+func (p *parser[T]) symbol(s string) bool {
+	p.skipSpace()
+	if !strings.HasPrefix(p.text[p.pos:], s) {
+		return false
+	}
+	// "!" on its own negates; "!=" and "!~" are operators
+	if s == "!" && p.pos+1 < len(p.text) && strings.IndexByte("=~", p.text[p.pos+1]) >= 0 {
+		return false
+	}
+	p.pos += len(s)
+	return true
+}
+
+func (p *parser[T]) skipSpace() {
+	for p.pos < len(p.text) && strings.IndexByte(" \t\r\n", p.text[p.pos]) >= 0 {
+		p.pos++
+	}
+}
@@ -0,0 +1,123 @@
+package expr
+
+import (
+	"errors"
+	"testing"
+)
+
+// testLanguage evaluates expressions over a map of attributes, with a $len
+// field comparing the number of attributes and a count(n) function
+var testLanguage = &Language[map[string]string]{
+	Attribute: func(record map[string]string, name string) (string, bool) {
+		value := record[name]
+		return value, value != ""
+	},
+	Field: func(name, op, value string) (Expr[map[string]string], error) {
+		if name != "$len" || op != "=" {
+			return nil, errors.New("unsupported")
+		}
+		return lenExpr(value), nil
+	},
+	Functions: map[string]func(args []float64) (Expr[map[string]string], error){
+		"count": func(args []float64) (Expr[map[string]string], error) {
+			if len(args) != 1 {
+				return nil, errors.New("expected one argument")
+			}
+			return countExpr(args[0]), nil
+		},
+	},
+}
+
+type lenExpr string
+
+func (e lenExpr) Match(record map[string]string) bool {
+	return len(record) == len(e)
+}
+
+type countExpr float64
+
+func (e countExpr) Match(record map[string]string) bool {
+	return float64(len(record)) == float64(e)
+}
+
+func TestParse_Match(t *testing.T) {
+	a := map[string]string{"kind": "rail", "speed": "160", "ref": "S*"}
+	b := map[string]string{"kind": "tram", "speed": "60", "name": "M10", "ref": "A|B"}
+
+	tests := []struct {
+		expr     string
+		expected []bool // a, b
+	}{
+		{expr: "kind", expected: []bool{true, true}},
+		{expr: "name", expected: []bool{false, true}},
+		{expr: "kind=rail|light_rail", expected: []bool{true, false}},
+		{expr: "kind = tr*", expected: []bool{false, true}},
+		{expr: "kind = 'tr*'", expected: []bool{false, false}},
+		{expr: "kind = 'rail|tram'", expected: []bool{false, false}},
+		{expr: "kind != \"rail|tram\"", expected: []bool{true, true}},
+		{expr: "ref = \"S*\"", expected: []bool{true, false}},
+		{expr: "ref = 'A|B'", expected: []bool{false, true}},
+		{expr: "ref = 'x[1'", expected: []bool{false, false}},
+		{expr: "speed = '160.0'", expected: []bool{false, false}},
+		{expr: "kind=?ail", expected: []bool{true, false}},
+		{expr: "kind!=rail", expected: []bool{false, true}},
+		{expr: "kind <> rail", expected: []bool{false, true}},
+		{expr: "speed>=100", expected: []bool{true, false}},
+		{expr: "speed < 100", expected: []bool{false, true}},
+		{expr: "speed = 160.0", expected: []bool{true, false}},
+		{expr: "name~\"^M\"", expected: []bool{false, true}},
+		{expr: "name!~'^M'", expected: []bool{true, false}},
+		{expr: "not name and kind", expected: []bool{true, false}},
+		{expr: "!(kind=rail or name)", expected: []bool{false, false}},
+		{expr: "kind=tram OR kind=rail AND name", expected: []bool{false, true}},
+		{expr: "$len = xxx", expected: []bool{true, false}},
+		{expr: "$len != xxx", expected: []bool{false, true}},
+		{expr: "COUNT(4)", expected: []bool{false, true}},
+		{expr: "count", expected: []bool{false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := Parse(tt.expr, testLanguage)
+			if err != nil {
+				t.Fatalf("Parse returned error: %v", err)
+			}
+			for i, record := range []map[string]string{a, b} {
+				if got := e.Match(record); got != tt.expected[i] {
+					t.Errorf("Match(%v) = %v, expected %v", record, got, tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []string{
+		"",
+		"kind =",
+		"kind = rail and",
+		"(kind = rail",
+		"kind = rail)",
+		"name ~ '['",
+		"kind = [",
+		"kind = rail|[",
+		"name = 'Berlin",
+		"$len",
+		"$len < 2",
+		"count(1, 2)",
+		"count(x)",
+		"count(1",
+	}
+
+	for _, text := range tests {
+		t.Run(text, func(t *testing.T) {
+			if _, err := Parse(text, testLanguage); err == nil {
+				t.Errorf("Expected error for %q", text)
+			}
+		})
+	}
+
+	if _, err := Parse("$len = xx", &Language[map[string]string]{}); err == nil {
+		t.Error("Expected error for a field in a language without fields")
+	}
+}
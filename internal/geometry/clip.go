@@ -0,0 +1,435 @@
+package geometry
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
+)
+
+// clipTolerance is how far outside the boundary a point may lie and still
+// count as inside (degrees), so that the points where lines are cut survive
+// rounding
+const clipTolerance = 1e-9
+
+// ClipArea restricts the output to an area, such as the region a map covers.
+// Lines crossing the boundary are cut where they cross it and points outside
+// it are dropped. Polygons are only masked, not clipped: their rings are never
+// cut into new shapes, they are only filled within the area's bounds, the POIs
+// of their outlines and fills outside the area are dropped, and their labels
+// are moved to the part inside it. The boundary is treated as straight in
+// longitude and latitude between its vertices.
+type ClipArea struct {
+	// polygons holds the rings of each polygon of the area, the outer boundary
+	// first followed by its holes
+	polygons [][][]Coordinate
+	// low and high are the south-west and north-east corners of the area's bounds
+	low, high Coordinate
+	// bands indexes the boundary edges by latitude: band i holds the edges
+	// reaching into the i-th of len(bands) equal slices of the area's
+	// latitude range, in polygon order, so that a point only needs to be
+	// tested against the edges at its latitude
+	bands [][]clipEdge
+}
+
+// clipEdge is an edge of the boundary of one of a ClipArea's polygons
+type clipEdge struct {
+	p, q    Coordinate
+	polygon int
+}
+
+// edgesPerBand is the average number of edges a latitude band is sized for
+const edgesPerBand = 8
+
+// NewClipBox returns the area inside a longitude/latitude bounding box
+func NewClipBox(minLon, minLat, maxLon, maxLat float64) *ClipArea {
+	return newClipArea([][][]Coordinate{{{
+		{Lon: minLon, Lat: minLat},
+		{Lon: maxLon, Lat: minLat},
+		{Lon: maxLon, Lat: maxLat},
+		{Lon: minLon, Lat: maxLat},
+	}}})
+}
+
+// NewClipArea returns the area covered by the polygons of the features. Any
+// points and lines among them are ignored.
+func NewClipArea(features []Feature) (*ClipArea, error) {
+	var polygons [][][]Coordinate
+	for i := range features {
+		for _, geometry := range features[i].Geometries {
+			if geometry.Type == PolygonGeometry && len(geometry.Rings) > 0 && len(geometry.Rings[0]) >= 3 {
+				polygons = append(polygons, geometry.Rings)
+			}
+		}
+	}
+	if len(polygons) == 0 {
+		return nil, errors.New("no polygons found to clip to")
+	}
+	return newClipArea(polygons), nil
+}
+
+// ReadClipArea returns the area covered by the polygons of a file in any
+// supported format. opts configures the reader, e.g. its SourceCRS.
+func ReadClipArea(filePath string, opts Options) (*ClipArea, error) {
+	reader, err := GetReaderWithOptions(filePath, opts)
+	if err != nil {
+		return nil, err
+	}
+	featureReader, ok := reader.(FeatureReader)
+	if !ok {
+		return nil, fmt.Errorf("cannot read features from %s", filepath.Base(filePath))
+	}
+	features, err := featureReader.ReadFeatures(filePath)
+	if err != nil {
+		return nil, err
+	}
+	area, err := NewClipArea(features)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(filePath), err)
+	}
+	return area, nil
+}
+
+func newClipArea(polygons [][][]Coordinate) *ClipArea {
+	area := &ClipArea{
+		polygons: polygons,
+		low:      Coordinate{Lon: math.Inf(1), Lat: math.Inf(1)},
+		high:     Coordinate{Lon: math.Inf(-1), Lat: math.Inf(-1)},
+	}
+	edges := 0
+	for _, rings := range polygons {
+		for _, coord := range rings[0] {
+			area.low.Lon, area.low.Lat = min(area.low.Lon, coord.Lon), min(area.low.Lat, coord.Lat)
+			area.high.Lon, area.high.Lat = max(area.high.Lon, coord.Lon), max(area.high.Lat, coord.Lat)
+		}
+		for _, ring := range rings {
+			edges += len(ring)
+		}
+	}
+
+	area.bands = make([][]clipEdge, min(max(edges/edgesPerBand, 1), 1<<14))
+	for i, rings := range polygons {
+		for _, ring := range rings {
+			for j := range ring {
+				edge := clipEdge{p: ring[j], q: ring[(j+1)%len(ring)], polygon: i}
+				first := area.band(min(edge.p.Lat, edge.q.Lat) - clipTolerance)
+				last := area.band(max(edge.p.Lat, edge.q.Lat) + clipTolerance)
+				for band := first; band <= last; band++ {
+					area.bands[band] = append(area.bands[band], edge)
+				}
+			}
+		}
+	}
+	return area
+}
+
+// band returns the index of the latitude band holding a latitude, clamped to
+// the area's bounds
+func (a *ClipArea) band(lat float64) int {
+	height := a.high.Lat - a.low.Lat
+	if height <= 0 {
+		return 0
+	}
+	band := int((lat - a.low.Lat) / height * float64(len(a.bands)))
+	return min(max(band, 0), len(a.bands)-1)
+}
+
+// edgesWithin returns the boundary edges whose bounds overlap the bounds from
+// low to high. An edge spanning several bands may be returned more than once.
+func (a *ClipArea) edgesWithin(low, high Coordinate) []clipEdge {
+	var result []clipEdge
+	for band := a.band(low.Lat); band <= a.band(high.Lat); band++ {
+		for _, edge := range a.bands[band] {
+			if edge.overlaps(low, high) {
+				result = append(result, edge)
+			}
+		}
+	}
+	return result
+}
+
+// overlaps reports whether the bounds of the edge overlap the bounds from low
+// to high, widened by clipTolerance
+func (e clipEdge) overlaps(low, high Coordinate) bool {
+	return max(e.p.Lon, e.q.Lon) >= low.Lon-clipTolerance && min(e.p.Lon, e.q.Lon) <= high.Lon+clipTolerance &&
+		max(e.p.Lat, e.q.Lat) >= low.Lat-clipTolerance && min(e.p.Lat, e.q.Lat) <= high.Lat+clipTolerance
+}
+
+// contains reports whether a point lies inside the area or on its boundary
+func (a *ClipArea) contains(coord Coordinate) bool {
+	if coord.Lon < a.low.Lon-clipTolerance || coord.Lon > a.high.Lon+clipTolerance ||
+		coord.Lat < a.low.Lat-clipTolerance || coord.Lat > a.high.Lat+clipTolerance {
+		return false
+	}
+
+	// Even-odd rule per polygon, so that the point must be outside the holes.
+	// Every edge crossing the point's latitude is in its band.
+	edges := a.bands[a.band(coord.Lat)]
+	inside := false
+	for i, edge := range edges {
+		if i > 0 && edge.polygon != edges[i-1].polygon {
+			if inside {
+				return true
+			}
+			inside = false
+		}
+		p, q := edge.p, edge.q
+		if (p.Lat > coord.Lat) != (q.Lat > coord.Lat) &&
+			coord.Lon < p.Lon+(coord.Lat-p.Lat)/(q.Lat-p.Lat)*(q.Lon-p.Lon) {
+			inside = !inside
+		}
+	}
+	if inside {
+		return true
+	}
+
+	for _, edge := range edges {
+		if edge.overlaps(coord, coord) && planarSegmentDistance(coord, edge.p, edge.q) <= clipTolerance {
+			return true
+		}
+	}
+	return false
+}
+
+// planarSegmentDistance returns the distance from a point to the segment
+// from p to q, treating degrees as plane coordinates
+func planarSegmentDistance(coord, p, q Coordinate) float64 {
+	dx, dy := q.Lon-p.Lon, q.Lat-p.Lat
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = math.Max(0, math.Min(1, ((coord.Lon-p.Lon)*dx+(coord.Lat-p.Lat)*dy)/length))
+	}
+	return math.Hypot(coord.Lon-(p.Lon+t*dx), coord.Lat-(p.Lat+t*dy))
+}
+
+// crossings returns the positions along the segment from p to q, as fractions
+// of its length, where it crosses the boundary, in order
+func (a *ClipArea) crossings(p, q Coordinate) []float64 {
+	if max(p.Lon, q.Lon) < a.low.Lon || min(p.Lon, q.Lon) > a.high.Lon ||
+		max(p.Lat, q.Lat) < a.low.Lat || min(p.Lat, q.Lat) > a.high.Lat {
+		return nil
+	}
+
+	var result []float64
+	dx, dy := q.Lon-p.Lon, q.Lat-p.Lat
+	low := Coordinate{Lon: min(p.Lon, q.Lon), Lat: min(p.Lat, q.Lat)}
+	high := Coordinate{Lon: max(p.Lon, q.Lon), Lat: max(p.Lat, q.Lat)}
+	for _, edge := range a.edgesWithin(low, high) {
+		c, d := edge.p, edge.q
+		ex, ey := d.Lon-c.Lon, d.Lat-c.Lat
+		denominator := dx*ey - dy*ex
+		if denominator == 0 {
+			// Parallel edges don't cut the segment; one running along
+			// it leaves the segment on the boundary, which counts as inside
+			continue
+		}
+		fx, fy := c.Lon-p.Lon, c.Lat-p.Lat
+		t := (fx*ey - fy*ex) / denominator
+		u := (fx*dy - fy*dx) / denominator
+		if t > 0 && t < 1 && u >= 0 && u <= 1 {
+			result = append(result, t)
+		}
+	}
+	// A segment through a vertex of the boundary crosses both its edges
+	// there, and an edge spanning several bands is found in each of them
+	sort.Float64s(result)
+	return slices.Compact(result)
+}
+
+// clipFeature returns a copy of the feature with its points outside the area
+// left out, its lines cut into the pieces inside it and the points where its
+// polygon rings cross the boundary added as vertices. It returns false when
+// nothing of the feature can lie inside the area.
+func (a *ClipArea) clipFeature(feature *Feature) (Feature, bool) {
+	if low, high, ok := featureBounds(feature); !ok ||
+		low.Lon > a.high.Lon || high.Lon < a.low.Lon || low.Lat > a.high.Lat || high.Lat < a.low.Lat {
+		return Feature{}, false
+	}
+
+	clipped := *feature
+	clipped.Geometries = nil
+	for _, geometry := range feature.Geometries {
+		switch geometry.Type {
+		case PointGeometry:
+			var inside []Coordinate
+			for _, coord := range geometry.Coordinates {
+				if a.contains(coord) {
+					inside = append(inside, coord)
+				}
+			}
+			if len(inside) > 0 {
+				clipped.Geometries = append(clipped.Geometries, Geometry{Type: PointGeometry, Coordinates: inside})
+			}
+		case LineStringGeometry:
+			for _, piece := range a.clipLine(geometry.Coordinates) {
+				clipped.Geometries = append(clipped.Geometries, NewLineString(piece))
+			}
+		case PolygonGeometry:
+			// Polygons keep their shape so that they are still filled as a
+			// whole; their POIs outside the area are dropped once converted
+			if low, high, ok := geometryBounds(geometry); !ok ||
+				low.Lon > a.high.Lon || high.Lon < a.low.Lon || low.Lat > a.high.Lat || high.Lat < a.low.Lat {
+				continue
+			}
+			rings := make([][]Coordinate, len(geometry.Rings))
+			for i, ring := range geometry.Rings {
+				rings[i] = a.splitRing(ring)
+			}
+			clipped.Geometries = append(clipped.Geometries, Geometry{Type: PolygonGeometry, Rings: rings})
+		}
+	}
+	return clipped, len(clipped.Geometries) > 0
+}
+
+// clipLine returns the pieces of a line inside the area, each starting and
+// ending where the line crosses the boundary unless the line itself starts or
+// ends inside
+func (a *ClipArea) clipLine(coords []Coordinate) [][]Coordinate {
+	if len(coords) == 1 {
+		if a.contains(coords[0]) {
+			return [][]Coordinate{coords}
+		}
+		return nil
+	}
+
+	var pieces [][]Coordinate
+	var piece []Coordinate
+	for i := 0; i+1 < len(coords); i++ {
+		p, q := coords[i], coords[i+1]
+		cuts := append(append([]float64{0}, a.crossings(p, q)...), 1)
+		for j := 0; j+1 < len(cuts); j++ {
+			// Between two cuts the segment is entirely inside or outside
+			if !a.contains(pointAlong(p, q, (cuts[j]+cuts[j+1])/2)) {
+				if len(piece) >= 2 {
+					pieces = append(pieces, piece)
+				}
+				piece = nil
+				continue
+			}
+			if len(piece) == 0 {
+				piece = append(piece, pointAlong(p, q, cuts[j]))
+			}
+			piece = append(piece, pointAlong(p, q, cuts[j+1]))
+		}
+	}
+	if len(piece) >= 2 {
+		pieces = append(pieces, piece)
+	}
+	return pieces
+}
+
+// splitRing returns a ring with the points where it crosses the boundary
+// added as vertices, starting from its first vertex inside the area so that
+// the label on the first vertex of an outline is kept
+func (a *ClipArea) splitRing(ring []Coordinate) []Coordinate {
+	result := make([]Coordinate, 0, len(ring))
+	for i := range ring {
+		p, q := ring[i], ring[(i+1)%len(ring)]
+		result = append(result, p)
+		for _, t := range a.crossings(p, q) {
+			result = append(result, pointAlong(p, q, t))
+		}
+	}
+	for i, coord := range result {
+		if a.contains(coord) {
+			return append(result[i:], result[:i]...)
+		}
+	}
+	return result
+}
+
+// clipRingToBounds returns the part of a ring within the area's bounds, cut
+// against each side of the bounds in turn (Sutherland-Hodgman). Concave rings
+// may come out with edges running back and forth along the bounds, which adds
+// nothing to their area.
+func (a *ClipArea) clipRingToBounds(ring []Coordinate) []Coordinate {
+	sides := []struct {
+		inside func(Coordinate) bool
+		cut    func(p, q Coordinate) float64
+	}{
+		{func(c Coordinate) bool { return c.Lon >= a.low.Lon }, func(p, q Coordinate) float64 { return (a.low.Lon - p.Lon) / (q.Lon - p.Lon) }},
+		{func(c Coordinate) bool { return c.Lon <= a.high.Lon }, func(p, q Coordinate) float64 { return (a.high.Lon - p.Lon) / (q.Lon - p.Lon) }},
+		{func(c Coordinate) bool { return c.Lat >= a.low.Lat }, func(p, q Coordinate) float64 { return (a.low.Lat - p.Lat) / (q.Lat - p.Lat) }},
+		{func(c Coordinate) bool { return c.Lat <= a.high.Lat }, func(p, q Coordinate) float64 { return (a.high.Lat - p.Lat) / (q.Lat - p.Lat) }},
+	}
+
+	for _, side := range sides {
+		clipped := make([]Coordinate, 0, len(ring))
+		for i := range ring {
+			p, q := ring[i], ring[(i+1)%len(ring)]
+			if side.inside(p) {
+				clipped = append(clipped, p)
+			}
+			if side.inside(p) != side.inside(q) {
+				clipped = append(clipped, pointAlong(p, q, side.cut(p, q)))
+			}
+		}
+		ring = clipped
+	}
+	return ring
+}
+
+// planeDistance returns the signed distance in meters from a point on the
+// plane of a projection to the area's boundary, negative outside the area. It
+// only measures the distance to the edges within the bounds from low to high,
+// which is exact for points whose nearest edge is within them.
+func (a *ClipArea) planeDistance(projection localProjection, low, high Coordinate) func(x, y float64) float64 {
+	edges := a.edgesWithin(low, high)
+	projected := make([][2]planePoint, len(edges))
+	for i, edge := range edges {
+		px, py := projection.forward(edge.p)
+		qx, qy := projection.forward(edge.q)
+		projected[i] = [2]planePoint{{px, py}, {qx, qy}}
+	}
+
+	return func(x, y float64) float64 {
+		nearest := math.Inf(1)
+		for _, edge := range projected {
+			nearest = min(nearest, segmentDistance(x, y, edge[0], edge[1]))
+		}
+		if a.contains(projection.inverse(x, y)) {
+			return nearest
+		}
+		return -nearest
+	}
+}
+
+// pointAlong returns the point at a fraction of the way from p to q, exactly
+// p or q at either end
+func pointAlong(p, q Coordinate, t float64) Coordinate {
+	switch t {
+	case 0:
+		return p
+	case 1:
+		return q
+	}
+	return Coordinate{Lon: p.Lon + t*(q.Lon-p.Lon), Lat: p.Lat + t*(q.Lat-p.Lat)}
+}
+
+// dropOutside removes the POIs converted from a feature, from start on, that
+// lie outside the area. Only polygons need this, since their outlines, fills
+// and label points are all placed by the converter; a nil area keeps every POI.
+func (a *ClipArea) dropOutside(poiList *poi.List, start int, feature *Feature) {
+	if a == nil {
+		return
+	}
+	hasPolygon := false
+	for _, geometry := range feature.Geometries {
+		hasPolygon = hasPolygon || geometry.Type == PolygonGeometry
+	}
+	if !hasPolygon {
+		return
+	}
+
+	kept := (*poiList)[:start]
+	for _, p := range (*poiList)[start:] {
+		if a.contains(Coordinate{Lon: p.Lon, Lat: p.Lat}) {
+			kept = append(kept, p)
+		}
+	}
+	*poiList = kept
+}
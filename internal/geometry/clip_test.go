@@ -0,0 +1,283 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
+)
+
+// convertFeatures converts features with the default max LOD and color
+func convertFeatures(t *testing.T, opts Options, features []Feature) poi.List {
+	t.Helper()
+	poiList, err := opts.ConvertFeatures(features, DefaultMaxLod, defaultColor)
+	if err != nil {
+		t.Fatalf("ConvertFeatures returned error: %v", err)
+	}
+	return *poiList
+}
+
+func coordinatesEqual(a, b Coordinate) bool {
+	return math.Abs(a.Lon-b.Lon) < 1e-9 && math.Abs(a.Lat-b.Lat) < 1e-9
+}
+
+func TestClipArea_Contains(t *testing.T) {
+	box := NewClipBox(10, 50, 11, 51)
+	// A square with a square hole, given as a polygon
+	frame, err := NewClipArea([]Feature{{Geometries: []Geometry{NewPolygon(
+		[]Coordinate{{Lon: 0, Lat: 0}, {Lon: 4, Lat: 0}, {Lon: 4, Lat: 4}, {Lon: 0, Lat: 4}, {Lon: 0, Lat: 0}},
+		[]Coordinate{{Lon: 1, Lat: 1}, {Lon: 3, Lat: 1}, {Lon: 3, Lat: 3}, {Lon: 1, Lat: 3}},
+	)}}})
+	if err != nil {
+		t.Fatalf("NewClipArea returned error: %v", err)
+	}
+	// Two squares side by side, whose edges share latitude bands
+	pair := newClipArea([][][]Coordinate{
+		{{{Lon: 0, Lat: 0}, {Lon: 1, Lat: 0}, {Lon: 1, Lat: 1}, {Lon: 0, Lat: 1}}},
+		{{{Lon: 2, Lat: 0}, {Lon: 3, Lat: 0}, {Lon: 3, Lat: 1}, {Lon: 2, Lat: 1}}},
+	})
+
+	tests := []struct {
+		name     string
+		area     *ClipArea
+		coord    Coordinate
+		expected bool
+	}{
+		{name: "inside the box", area: box, coord: Coordinate{Lon: 10.5, Lat: 50.5}, expected: true},
+		{name: "outside the box", area: box, coord: Coordinate{Lon: 11.5, Lat: 50.5}, expected: false},
+		{name: "on the edge of the box", area: box, coord: Coordinate{Lon: 11, Lat: 50.5}, expected: true},
+		{name: "on a corner of the box", area: box, coord: Coordinate{Lon: 10, Lat: 50}, expected: true},
+		{name: "inside the polygon", area: frame, coord: Coordinate{Lon: 0.5, Lat: 2}, expected: true},
+		{name: "inside the hole", area: frame, coord: Coordinate{Lon: 2, Lat: 2}, expected: false},
+		{name: "on the edge of the hole", area: frame, coord: Coordinate{Lon: 1, Lat: 2}, expected: true},
+		{name: "outside the polygon", area: frame, coord: Coordinate{Lon: 5, Lat: 2}, expected: false},
+		{name: "inside the first of two polygons", area: pair, coord: Coordinate{Lon: 0.5, Lat: 0.5}, expected: true},
+		{name: "inside the second of two polygons", area: pair, coord: Coordinate{Lon: 2.5, Lat: 0.5}, expected: true},
+		{name: "between two polygons", area: pair, coord: Coordinate{Lon: 1.5, Lat: 0.5}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.area.contains(tt.coord); got != tt.expected {
+				t.Errorf("contains(%v) = %v, expected %v", tt.coord, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewClipArea_NoPolygons(t *testing.T) {
+	features := []Feature{{Geometries: []Geometry{NewPoint(10, 50), NewLineString([]Coordinate{{Lon: 10, Lat: 50}, {Lon: 11, Lat: 51}})}}}
+	if _, err := NewClipArea(features); err == nil {
+		t.Error("Expected an error for features without polygons")
+	}
+}
+
+func TestClipArea_ClipLine(t *testing.T) {
+	box := NewClipBox(0, 0, 2, 2)
+
+	tests := []struct {
+		name     string
+		line     []Coordinate
+		expected [][]Coordinate
+	}{
+		{
+			name:     "inside",
+			line:     []Coordinate{{Lon: 0.5, Lat: 0.5}, {Lon: 1.5, Lat: 1.5}},
+			expected: [][]Coordinate{{{Lon: 0.5, Lat: 0.5}, {Lon: 1.5, Lat: 1.5}}},
+		},
+		{
+			name:     "outside",
+			line:     []Coordinate{{Lon: 3, Lat: 0}, {Lon: 3, Lat: 2}},
+			expected: nil,
+		},
+		{
+			name:     "leaving the box is cut at the edge",
+			line:     []Coordinate{{Lon: 1, Lat: 1}, {Lon: 3, Lat: 1}},
+			expected: [][]Coordinate{{{Lon: 1, Lat: 1}, {Lon: 2, Lat: 1}}},
+		},
+		{
+			name:     "crossing the box without a vertex inside",
+			line:     []Coordinate{{Lon: -1, Lat: 1}, {Lon: 3, Lat: 1}},
+			expected: [][]Coordinate{{{Lon: 0, Lat: 1}, {Lon: 2, Lat: 1}}},
+		},
+		{
+			name: "leaving and coming back splits the line",
+			line: []Coordinate{{Lon: 1, Lat: 0.5}, {Lon: 3, Lat: 0.5}, {Lon: 3, Lat: 1.5}, {Lon: 1, Lat: 1.5}},
+			expected: [][]Coordinate{
+				{{Lon: 1, Lat: 0.5}, {Lon: 2, Lat: 0.5}},
+				{{Lon: 2, Lat: 1.5}, {Lon: 1, Lat: 1.5}},
+			},
+		},
+		{
+			name:     "through a corner",
+			line:     []Coordinate{{Lon: -1, Lat: -1}, {Lon: 1, Lat: 1}},
+			expected: [][]Coordinate{{{Lon: 0, Lat: 0}, {Lon: 1, Lat: 1}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pieces := box.clipLine(tt.line)
+			if len(pieces) != len(tt.expected) {
+				t.Fatalf("Expected %d pieces, got %d: %v", len(tt.expected), len(pieces), pieces)
+			}
+			for i, piece := range pieces {
+				if len(piece) != len(tt.expected[i]) {
+					t.Fatalf("Expected piece %d to be %v, got %v", i, tt.expected[i], piece)
+				}
+				for j := range piece {
+					if !coordinatesEqual(piece[j], tt.expected[i][j]) {
+						t.Errorf("Expected piece %d to be %v, got %v", i, tt.expected[i], piece)
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestOptions_Clip(t *testing.T) {
+	features := []Feature{
+		{Geometries: []Geometry{NewPoint(0.5, 0.5)}, Name: "Inside"},
+		{Geometries: []Geometry{NewPoint(5, 5)}, Name: "Outside"},
+		{Geometries: []Geometry{NewLineString([]Coordinate{{Lon: -1, Lat: 1}, {Lon: 1, Lat: 1}})}, Name: "Line"},
+		{Geometries: []Geometry{NewPolygon([]Coordinate{{Lon: 1, Lat: 1}, {Lon: 3, Lat: 1}, {Lon: 3, Lat: 3}, {Lon: 1, Lat: 3}})}, Name: "Polygon"},
+	}
+	opts := Options{Clip: NewClipBox(0, 0, 2, 2)}
+
+	poiList := convertFeatures(t, opts, features)
+
+	// The point inside, the line from the edge at 0,1 to its end at 1,1, and
+	// the polygon's vertex at 1,1 with the two points where it leaves the box
+	expected := []Coordinate{
+		{Lon: 0.5, Lat: 0.5},
+		{Lon: 0, Lat: 1}, {Lon: 1, Lat: 1},
+		{Lon: 1, Lat: 1}, {Lon: 2, Lat: 1}, {Lon: 1, Lat: 2},
+	}
+	if len(poiList) != len(expected) {
+		t.Fatalf("Expected %d POIs, got %d: %+v", len(expected), len(poiList), poiList)
+	}
+	for i, p := range poiList {
+		if !coordinatesEqual(Coordinate{Lon: p.Lon, Lat: p.Lat}, expected[i]) {
+			t.Errorf("Expected POI %d at %v, got %v,%v", i, expected[i], p.Lon, p.Lat)
+		}
+	}
+	if poiList[1].Text != "Line" {
+		t.Errorf("Expected the clipped line to be labelled at the edge, got '%s'", poiList[1].Text)
+	}
+	if len(features[2].Geometries[0].Coordinates) != 2 || features[2].Geometries[0].Coordinates[0].Lon != -1 {
+		t.Errorf("Expected the features to be left unchanged, got %v", features[2].Geometries[0].Coordinates)
+	}
+}
+
+func TestOptions_ClipFill(t *testing.T) {
+	// A polygon around the whole box still fills it, though its outline is outside
+	features := []Feature{{Geometries: []Geometry{NewPolygon([]Coordinate{{Lon: -1, Lat: -1}, {Lon: 1, Lat: -1}, {Lon: 1, Lat: 1}, {Lon: -1, Lat: 1}})}}}
+	box := NewClipBox(-0.01, -0.01, 0.01, 0.01)
+
+	unclipped := Options{FillSpacing: 500}
+	clipped := Options{FillSpacing: 500, Clip: box}
+	all := convertFeatures(t, unclipped, features)
+	inside := convertFeatures(t, clipped, features)
+
+	if len(inside) == 0 || len(inside) >= len(all) {
+		t.Fatalf("Expected some but not all of the %d POIs to be kept, got %d", len(all), len(inside))
+	}
+	for _, p := range inside {
+		if !box.contains(Coordinate{Lon: p.Lon, Lat: p.Lat}) {
+			t.Errorf("Expected POIs inside the box, got %v,%v", p.Lon, p.Lat)
+		}
+	}
+}
+
+func TestOptions_ClipLargeFill(t *testing.T) {
+	// Filling the whole polygon at this spacing would place over 100 million
+	// POIs, but only the part inside the box is filled
+	features := []Feature{{Geometries: []Geometry{NewPolygon([]Coordinate{{Lon: 5, Lat: 45}, {Lon: 15, Lat: 45}, {Lon: 15, Lat: 55}, {Lon: 5, Lat: 55}})}}}
+	box := NewClipBox(10, 50, 10.02, 50.01)
+
+	for _, method := range []FillMethod{FillGrid, FillHatch} {
+		t.Run(string(method), func(t *testing.T) {
+			poiList := convertFeatures(t, Options{Fill: method, FillSpacing: 100, Clip: box}, features)
+
+			// The box is about 1.4 km by 1.1 km
+			perPoint := 100.0 * 100.0
+			if method == FillHatch {
+				perPoint /= hatchDotsPerSpacing
+			}
+			expected := ringArea(box.polygons[0][0]) / perPoint
+			if n := float64(len(poiList)); n < expected*0.8 || n > expected*1.2 {
+				t.Errorf("Expected about %.0f POIs, got %d", expected, len(poiList))
+			}
+			for _, p := range poiList {
+				if !box.contains(Coordinate{Lon: p.Lon, Lat: p.Lat}) {
+					t.Errorf("Expected POIs inside the box, got %v,%v", p.Lon, p.Lat)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestOptions_ClipPolygonLabel(t *testing.T) {
+	// The middle of the polygon, where its label would go, and its first
+	// vertex are both outside the box
+	features := []Feature{{
+		Geometries: []Geometry{NewPolygon([]Coordinate{{Lon: 0, Lat: 0}, {Lon: 1, Lat: 0}, {Lon: 1, Lat: 1}, {Lon: 0, Lat: 1}})},
+		Name:       "District",
+	}}
+	box := NewClipBox(0.8, 0.2, 1.5, 0.8)
+
+	for _, mode := range []PolygonMode{PolygonOutline, PolygonLabelPoint, PolygonOutlineAndPoint} {
+		t.Run(string(mode), func(t *testing.T) {
+			opts := Options{PolygonMode: mode, Clip: box}
+			poiList := convertFeatures(t, opts, features)
+
+			var labels []Coordinate
+			for _, p := range poiList {
+				if !box.contains(Coordinate{Lon: p.Lon, Lat: p.Lat}) {
+					t.Errorf("Expected POIs inside the box, got %v,%v", p.Lon, p.Lat)
+				}
+				if p.Text == "District" {
+					labels = append(labels, Coordinate{Lon: p.Lon, Lat: p.Lat})
+				}
+			}
+			if len(labels) != 1 {
+				t.Fatalf("Expected one label inside the box, got %d in %+v", len(labels), poiList)
+			}
+			// The label point is on the middle line of the strip inside the box
+			if mode != PolygonOutline && math.Abs(labels[0].Lon-0.9) > 0.01 {
+				t.Errorf("Expected the label at longitude 0.9, got %v", labels[0])
+			}
+		})
+	}
+}
+
+func TestReadClipArea(t *testing.T) {
+	geojsonContent := `{
+		"type": "FeatureCollection",
+		"features": [
+			{"type": "Feature", "properties": {"name": "Region"}, "geometry": {"type": "Polygon", "coordinates": [[[10, 50], [12, 50], [11, 52], [10, 50]]]}},
+			{"type": "Feature", "properties": {"name": "Capital"}, "geometry": {"type": "Point", "coordinates": [11, 51]}}
+		]
+	}`
+	filePath := createTempFile(t, "region.geojson", geojsonContent)
+
+	area, err := ReadClipArea(filePath, Options{})
+	if err != nil {
+		t.Fatalf("ReadClipArea returned error: %v", err)
+	}
+	if !area.contains(Coordinate{Lon: 11, Lat: 51}) {
+		t.Error("Expected the middle of the triangle to be inside")
+	}
+	if area.contains(Coordinate{Lon: 10.1, Lat: 51.9}) {
+		t.Error("Expected a point beside the triangle's tip to be outside")
+	}
+
+	pointsPath := createTempFile(t, "stations.geojson", `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "properties": {}, "geometry": {"type": "Point", "coordinates": [11, 51]}}
+	]}`)
+	if _, err := ReadClipArea(pointsPath, Options{}); err == nil {
+		t.Error("Expected an error for a file without polygons")
+	}
+}
@@ -0,0 +1,181 @@
+package geometry
+
+import (
+	"fmt"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
+)
+
+// Converter turns features into POIs. Set Options.Converter to replace the
+// default POIConverter, for example to emit a single POI per feature.
+type Converter interface {
+	// Convert appends the POIs for a feature to the list. Base carries the
+	// configured color and max LOD that every POI starts from.
+	Convert(feature *Feature, base poi.POI, poiList *poi.List)
+}
+
+// POIConverter is the default Converter. Points become one POI each, while
+// lines and polygon rings become a POI per vertex, labelled on the first vertex
+// only and simplified, resampled or interpolated if configured. Depending on
+// the PolygonMode, a feature's polygons also or instead get a single labelled
+// POI inside, and they are filled with unlabelled POIs if a fill spacing is set.
+type POIConverter struct {
+	Options
+}
+
+func (c *POIConverter) Convert(feature *Feature, base poi.POI, poiList *poi.List) {
+	if c.PreferFileStyles && feature.Color != "" {
+		base.Color = feature.Color
+	}
+	base.Text = resolveLabel(c.LabelField, feature.Properties, feature.Text, feature.Name)
+	feature.Style.Apply(&base)
+	c.Rules.Apply(&base, feature.Attributes())
+
+	for i := range feature.Geometries {
+		geometry := &feature.Geometries[i]
+		switch geometry.Type {
+		case PointGeometry:
+			c.convertPoints(geometry.Coordinates, poiList, base)
+		case LineStringGeometry:
+			c.convertLine(simplifyLine(geometry.Coordinates, c.Simplify, c.SimplifyTolerance, 2), poiList, base)
+		case PolygonGeometry:
+			c.convertPolygon(geometry.Rings, poiList, base)
+		}
+	}
+
+	if c.PolygonMode == PolygonLabelPoint || c.PolygonMode == PolygonOutlineAndPoint {
+		if point, ok := labelPoint(feature.Geometries, c.Clip); ok {
+			c.convertPoints([]Coordinate{point}, poiList, base)
+		}
+	}
+}
+
+func (c *POIConverter) convertPolygon(rings [][]Coordinate, poiList *poi.List, base poi.POI) {
+	unlabelled := base
+	unlabelled.Text = ""
+
+	switch c.PolygonMode {
+	case PolygonLabelPoint:
+		// Only the point inside is drawn
+	case PolygonOutlineAndPoint:
+		// The label goes on the point inside instead
+		for _, ring := range rings {
+			c.convertLine(simplifyLine(ring, c.Simplify, c.SimplifyTolerance, 3), poiList, unlabelled)
+		}
+	default:
+		// Every ring is drawn, outer boundaries and holes alike
+		for _, ring := range rings {
+			c.convertLine(simplifyLine(ring, c.Simplify, c.SimplifyTolerance, 3), poiList, base)
+		}
+	}
+
+	if c.FillSpacing > 0 {
+		c.convertPoints(fillPolygon(rings, c.Fill, c.FillSpacing, c.Clip), poiList, unlabelled)
+	}
+}
+
+func (c *POIConverter) convertPoints(coords []Coordinate, poiList *poi.List, base poi.POI) {
+	for _, coord := range coords {
+		p := base
+		p.Lon = coord.Lon
+		p.Lat = coord.Lat
+		poiList.Add(p)
+	}
+}
+
+func (c *POIConverter) convertLine(coords []Coordinate, poiList *poi.List, base poi.POI) {
+	// Create temporary list for this line
+	tempList := make(poi.List, 0, len(coords))
+	for i, coord := range coords {
+		p := base
+		p.Lon = coord.Lon
+		p.Lat = coord.Lat
+		// Only the first vertex carries the label so lines aren't covered in text
+		if i > 0 {
+			p.Text = ""
+		}
+		tempList = append(tempList, p)
+	}
+
+	// Resample or interpolate this line if configured
+	if c.ResampleDistance > 0 {
+		resampled := tempList.ResampleByDistance(c.ResampleDistance, c.ResampleKeepVertices)
+		tempList = *resampled
+	} else if c.InterpolateDistance > 0 {
+		interpolated := tempList.InterpolateByDistance(c.InterpolateDistance)
+		tempList = *interpolated
+	}
+
+	// Add all points (resampled, interpolated or not) to the main list
+	for _, p := range tempList {
+		poiList.Add(p)
+	}
+}
+
+// ConvertFeatures turns features that were not read from a file, such as OSM
+// data fetched from an API, into POIs in the same way a Reader would
+func (o *Options) ConvertFeatures(features []Feature, maxLod int32, color string) (*poi.List, error) {
+	return o.convert(features, maxLod, color)
+}
+
+// convert turns features into POIs using the configured Converter. It fails
+// before filling polygons with more than maxFillPoints POIs in total.
+func (o *Options) convert(features []Feature, maxLod int32, color string) (*poi.List, error) {
+	c := o.newConversion(maxLod, color)
+	for i := range features {
+		if err := c.add(&features[i], c.base); err != nil {
+			return nil, err
+		}
+	}
+	return &c.poiList, nil
+}
+
+// conversion turns features into POIs one at a time, so that readers can
+// convert features as they read them instead of collecting them first
+type conversion struct {
+	options   *Options
+	converter Converter
+	// base is the POI every vertex is copied from, with the configured color and max LOD
+	base       poi.POI
+	fillPoints float64
+	poiList    poi.List
+}
+
+func (o *Options) newConversion(maxLod int32, color string) *conversion {
+	converter := o.Converter
+	if converter == nil {
+		converter = &POIConverter{Options: *o}
+	}
+	return &conversion{
+		options:   o,
+		converter: converter,
+		base:      newPOITemplate(maxLod, color),
+		poiList:   make(poi.List, 0),
+	}
+}
+
+// add filters, clips and converts a feature, starting its POIs from base
+func (c *conversion) add(feature *Feature, base poi.POI) error {
+	o := c.options
+	if !o.Folders.Match(feature) || !o.Where.Match(feature) {
+		return nil
+	}
+	if o.Clip != nil {
+		clipped, ok := o.Clip.clipFeature(feature)
+		if !ok {
+			return nil
+		}
+		feature = &clipped
+	}
+	if o.FillSpacing > 0 {
+		c.fillPoints += estimateFillPoints(feature.Geometries, o.Fill, o.FillSpacing, o.Clip)
+		if c.fillPoints > maxFillPoints {
+			return fmt.Errorf("%w: a fill spacing of %g m would place over %d POIs, use a larger spacing", ErrTooManyFillPoints, o.FillSpacing, maxFillPoints)
+		}
+	}
+
+	start := len(c.poiList)
+	c.converter.Convert(feature, base, &c.poiList)
+	o.Clip.dropOutside(&c.poiList, start, feature)
+	return nil
+}
@@ -0,0 +1,360 @@
+package geometry
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/gis"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/rules"
+)
+
+// Column names detected when no coordinate columns are configured, matched case-insensitively
+var (
+	lonColumnNames = []string{"lon", "lng", "long", "longitude", "x"}
+	latColumnNames = []string{"lat", "latitude", "y"}
+	wktColumnNames = []string{"wkt", "geometry", "geom", "the_geom"}
+)
+
+// DelimitedTextReader reads CSV and TSV files with a row per feature, located
+// either by longitude/latitude columns or by a WKT geometry column. Columns named
+// like the POI fields of the NIMBY Rails TSV (text, color, font_size, max_lod,
+// transparent, demand and population) set those fields directly.
+type DelimitedTextReader struct {
+	Options
+}
+
+// delimitedColumns holds the indexes of the columns used for geometry, -1 when absent
+type delimitedColumns struct {
+	lon, lat, wkt int
+}
+
+func (d *DelimitedTextReader) ParseFile(filePath string) (*poi.List, error) {
+	return d.ParseFileWithConfig(filePath, DefaultMaxLod)
+}
+
+func (d *DelimitedTextReader) ParseFileWithConfig(filePath string, maxLod int32) (*poi.List, error) {
+	return d.ParseFileWithFullConfig(filePath, maxLod, defaultColor)
+}
+
+func (d *DelimitedTextReader) ParseFileWithFullConfig(filePath string, maxLod int32, color string) (*poi.List, error) {
+	features, err := d.ReadFeatures(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return d.convert(features, maxLod, color)
+}
+
+// ReadFeatures returns a feature per row. Rows without a usable location are
+// skipped and logged.
+func (d *DelimitedTextReader) ReadFeatures(filePath string) ([]Feature, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	// Spreadsheet exports often start with a byte order mark
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	crs := gis.WGS84
+	if d.SourceCRS != "" {
+		if crs, err = gis.ParseCRS(d.SourceCRS); err != nil {
+			return nil, fmt.Errorf("invalid source CRS: %w", err)
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = detectDelimiter(filePath, data)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("file is empty")
+		}
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+
+	columns, err := d.findColumns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	features := make([]Feature, 0)
+	fileName := filepath.Base(filePath)
+	// Rows with invalid POI columns are counted so that they are logged once
+	invalidRows := 0
+	var firstInvalid error
+
+	// Row numbers count the header as row 1, like a spreadsheet
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+
+		properties := make(map[string]string, len(header))
+		for i, name := range header {
+			if i < len(record) && i != columns.lon && i != columns.lat && i != columns.wkt {
+				properties[name] = strings.TrimSpace(record[i])
+			}
+		}
+
+		geometries, err := d.rowGeometries(record, columns, crs)
+		if err != nil {
+			log.Printf("Skipped row %d of %s: %v", row, fileName, err)
+			continue
+		}
+
+		style, errs := columnStyle(properties)
+		if len(errs) > 0 {
+			if invalidRows == 0 {
+				firstInvalid = fmt.Errorf("row %d: %w", row, errors.Join(errs...))
+			}
+			invalidRows++
+		}
+
+		text := rules.LookupAttribute(properties, "text")
+		features = append(features, Feature{
+			Geometries: geometries,
+			Properties: properties,
+			Name:       text,
+			Text:       text,
+			Style:      style,
+			Source:     Source{File: fileName, Index: len(features)},
+		})
+	}
+	if invalidRows > 0 {
+		log.Printf("Ignored invalid POI columns in %d rows of %s, first in %v", invalidRows, fileName, strings.ReplaceAll(firstInvalid.Error(), "\n", "; "))
+	}
+
+	return features, nil
+}
+
+// findColumns locates the configured coordinate columns, or detects them by name
+func (d *DelimitedTextReader) findColumns(header []string) (delimitedColumns, error) {
+	columns := delimitedColumns{lon: -1, lat: -1, wkt: -1}
+
+	find := func(configured string, candidates []string) (int, error) {
+		if configured != "" {
+			if index := columnIndex(header, configured); index >= 0 {
+				return index, nil
+			}
+			return -1, fmt.Errorf("column %q not found", configured)
+		}
+		for _, candidate := range candidates {
+			if index := columnIndex(header, candidate); index >= 0 {
+				return index, nil
+			}
+		}
+		return -1, nil
+	}
+
+	var err error
+	if d.WKTColumn != "" || (d.LonColumn == "" && d.LatColumn == "") {
+		if columns.wkt, err = find(d.WKTColumn, wktColumnNames); err != nil {
+			return columns, err
+		}
+	}
+	if columns.wkt < 0 {
+		if columns.lon, err = find(d.LonColumn, lonColumnNames); err != nil {
+			return columns, err
+		}
+		if columns.lat, err = find(d.LatColumn, latColumnNames); err != nil {
+			return columns, err
+		}
+		if columns.lon < 0 || columns.lat < 0 {
+			return columns, errors.New("no coordinate columns found; name them lon/lat or x/y, or set the longitude, latitude or WKT column")
+		}
+	}
+
+	return columns, nil
+}
+
+func (d *DelimitedTextReader) rowGeometries(record []string, columns delimitedColumns, crs *gis.CRS) ([]Geometry, error) {
+	value := func(index int) string {
+		if index < len(record) {
+			return strings.TrimSpace(record[index])
+		}
+		return ""
+	}
+
+	if columns.wkt >= 0 {
+		geometries, err := ParseWKT(value(columns.wkt))
+		if err != nil {
+			return nil, err
+		}
+		if !crs.IsWGS84() {
+			for i := range geometries {
+				reprojectGeometry(crs, &geometries[i])
+			}
+		}
+		return geometries, nil
+	}
+
+	x, err := parseDecimal(value(columns.lon))
+	if err != nil {
+		return nil, fmt.Errorf("invalid longitude: %w", err)
+	}
+	y, err := parseDecimal(value(columns.lat))
+	if err != nil {
+		return nil, fmt.Errorf("invalid latitude: %w", err)
+	}
+	if !crs.IsWGS84() {
+		x, y = crs.ToWGS84(x, y)
+	}
+	return []Geometry{NewPoint(x, y)}, nil
+}
+
+// reprojectGeometry converts a geometry's coordinates to WGS84 in place
+func reprojectGeometry(crs *gis.CRS, geometry *Geometry) {
+	convert := func(coords []Coordinate) {
+		for i := range coords {
+			coords[i].Lon, coords[i].Lat = crs.ToWGS84(coords[i].Lon, coords[i].Lat)
+		}
+	}
+	convert(geometry.Coordinates)
+	for _, ring := range geometry.Rings {
+		convert(ring)
+	}
+}
+
+// columnStyle reads the POI fields set by columns named like the NIMBY Rails
+// TSV header. Invalid values are left out of the style and returned as errors.
+func columnStyle(properties map[string]string) (rules.Style, []error) {
+	var style rules.Style
+	var errs []error
+
+	// valid reports whether a style holding a single field is valid
+	valid := func(field *rules.Style, err error) bool {
+		if err == nil {
+			err = field.Validate()
+		}
+		if err != nil {
+			errs = append(errs, err)
+			return false
+		}
+		return true
+	}
+
+	if value := firstAttribute(properties, "color", "colour"); value != "" {
+		if field := (rules.Style{Color: &value}); valid(&field, nil) {
+			style.Color = field.Color
+		}
+	}
+	if value := rules.LookupAttribute(properties, "font_size"); value != "" {
+		size, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			err = fmt.Errorf("invalid font_size %q", value)
+		}
+		fontSize := int32(size)
+		if field := (rules.Style{FontSize: &fontSize}); valid(&field, err) {
+			style.FontSize = field.FontSize
+		}
+	}
+	if value := rules.LookupAttribute(properties, "max_lod"); value != "" {
+		lod, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			err = fmt.Errorf("invalid max_lod %q", value)
+		}
+		maxLod := int32(lod)
+		if field := (rules.Style{MaxLod: &maxLod}); valid(&field, err) {
+			style.MaxLod = field.MaxLod
+		}
+	}
+	if value := rules.LookupAttribute(properties, "transparent"); value != "" {
+		transparent, err := parseBool(value)
+		if field := (rules.Style{Transparent: &transparent}); valid(&field, err) {
+			style.Transparent = field.Transparent
+		}
+	}
+	if value := rules.LookupAttribute(properties, "demand"); value != "" {
+		style.Demand = &value
+	}
+	if value := rules.LookupAttribute(properties, "population"); value != "" {
+		population, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			err = fmt.Errorf("invalid population %q", value)
+		}
+		if field := (rules.Style{Population: &population}); valid(&field, err) {
+			style.Population = field.Population
+		}
+	}
+
+	return style, errs
+}
+
+func firstAttribute(attributes map[string]string, names ...string) string {
+	for _, name := range names {
+		if value := rules.LookupAttribute(attributes, name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "y", "1":
+		return true, nil
+	case "false", "no", "n", "0":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid transparent %q", value)
+	}
+}
+
+// parseDecimal parses a number, accepting a decimal comma as written by
+// spreadsheets in many European locales
+func parseDecimal(value string) (float64, error) {
+	if value == "" {
+		return 0, errors.New("empty value")
+	}
+	if strings.Contains(value, ",") && !strings.Contains(value, ".") {
+		value = strings.Replace(value, ",", ".", 1)
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+func columnIndex(header []string, name string) int {
+	for i, column := range header {
+		if strings.EqualFold(column, strings.TrimSpace(name)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// detectDelimiter uses a tab for .tsv files. Otherwise it picks whichever of
+// comma, semicolon and tab appears most often in the header line, since
+// spreadsheets in locales with a decimal comma export semicolon-separated CSV.
+func detectDelimiter(filePath string, data []byte) rune {
+	if strings.EqualFold(filepath.Ext(filePath), ".tsv") {
+		return '\t'
+	}
+
+	header, _ := bufio.NewReader(bytes.NewReader(data)).ReadString('\n')
+	delimiter, count := ',', strings.Count(header, ",")
+	for _, candidate := range []rune{';', '\t'} {
+		if n := strings.Count(header, string(candidate)); n > count {
+			delimiter, count = candidate, n
+		}
+	}
+	return delimiter
+}
@@ -0,0 +1,255 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDelimitedTextReader_ReadFeatures(t *testing.T) {
+	tests := []struct {
+		name        string
+		fileName    string
+		content     string
+		reader      DelimitedTextReader
+		expectError bool
+		expected    []Coordinate
+	}{
+		{
+			name:     "lon/lat columns",
+			fileName: "stations.csv",
+			content:  "name,lat,lon\nBerlin Hbf,52.5251,13.3694\nHamburg Hbf,53.5530,10.0069\n",
+			expected: []Coordinate{{Lon: 13.3694, Lat: 52.5251}, {Lon: 10.0069, Lat: 53.5530}},
+		},
+		{
+			name:     "x/y columns in any case",
+			fileName: "stations.csv",
+			content:  "\ufeffName,X,Y\nBerlin Hbf,13.3694,52.5251\n",
+			expected: []Coordinate{{Lon: 13.3694, Lat: 52.5251}},
+		},
+		{
+			name:     "semicolons and decimal commas",
+			fileName: "stations.csv",
+			content:  "name;longitude;latitude\nBerlin Hbf;13,3694;52,5251\n",
+			expected: []Coordinate{{Lon: 13.3694, Lat: 52.5251}},
+		},
+		{
+			name:     "tab separated",
+			fileName: "stations.tsv",
+			content:  "name\tlng\tlat\nBerlin, Hbf\t13.3694\t52.5251\n",
+			expected: []Coordinate{{Lon: 13.3694, Lat: 52.5251}},
+		},
+		{
+			name:     "configured columns",
+			fileName: "stations.csv",
+			content:  "name,east,north\nBerlin Hbf,13.3694,52.5251\n",
+			reader:   DelimitedTextReader{Options: Options{LonColumn: "east", LatColumn: "north"}},
+			expected: []Coordinate{{Lon: 13.3694, Lat: 52.5251}},
+		},
+		{
+			name:        "configured column missing",
+			fileName:    "stations.csv",
+			content:     "name,lon,lat\nBerlin Hbf,13.3694,52.5251\n",
+			reader:      DelimitedTextReader{Options: Options{LonColumn: "east"}},
+			expectError: true,
+		},
+		{
+			name:        "no coordinate columns",
+			fileName:    "stations.csv",
+			content:     "name,city\nBerlin Hbf,Berlin\n",
+			expectError: true,
+		},
+		{
+			name:     "rows without coordinates are skipped",
+			fileName: "stations.csv",
+			content:  "name,lon,lat\nUnknown,,\nBerlin Hbf,13.3694,52.5251\nBad,abc,52\n",
+			expected: []Coordinate{{Lon: 13.3694, Lat: 52.5251}},
+		},
+		{
+			name:     "projected coordinates",
+			fileName: "stations.csv",
+			content:  "name,x,y\nBerlin Hbf,389871.5,5820951.2\n",
+			reader:   DelimitedTextReader{Options: Options{SourceCRS: "EPSG:25833"}},
+			expected: []Coordinate{{Lon: 13.3766, Lat: 52.5275}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := createTempFile(t, tt.fileName, tt.content)
+
+			features, err := tt.reader.ReadFeatures(filePath)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error, but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadFeatures returned error: %v", err)
+			}
+
+			if len(features) != len(tt.expected) {
+				t.Fatalf("Expected %d features, got %d", len(tt.expected), len(features))
+			}
+			for i, feature := range features {
+				coord := feature.Geometries[0].Coordinates[0]
+				if math.Abs(coord.Lon-tt.expected[i].Lon) > 1e-3 || math.Abs(coord.Lat-tt.expected[i].Lat) > 1e-3 {
+					t.Errorf("Feature %d: Expected %+v, got %+v", i, tt.expected[i], coord)
+				}
+				if feature.Source.Index != i {
+					t.Errorf("Feature %d: Expected source index %d, got %d", i, i, feature.Source.Index)
+				}
+			}
+		})
+	}
+}
+
+func TestDelimitedTextReader_WKT(t *testing.T) {
+	content := "id,wkt,line\n" +
+		"1,\"LINESTRING (13.3694 52.5251, 13.3880 52.5200)\",S5\n" +
+		"2,\"MULTIPOINT ((13.3694 52.5251), (13.3880 52.5200))\",S7\n" +
+		"3,POLYGON EMPTY,S9\n" +
+		"4,LINESTRING (13.3694,S3\n"
+	filePath := createTempFile(t, "lines.csv", content)
+
+	reader := &DelimitedTextReader{}
+	features, err := reader.ReadFeatures(filePath)
+	if err != nil {
+		t.Fatalf("ReadFeatures returned error: %v", err)
+	}
+
+	if len(features) != 3 {
+		t.Fatalf("Expected 3 features (invalid WKT skipped), got %d", len(features))
+	}
+	if len(features[0].Geometries) != 1 || features[0].Geometries[0].Type != LineStringGeometry {
+		t.Errorf("Expected a single line, got %+v", features[0].Geometries)
+	}
+	if len(features[1].Geometries) != 2 {
+		t.Errorf("Expected 2 points, got %d geometries", len(features[1].Geometries))
+	}
+	if len(features[2].Geometries) != 0 {
+		t.Errorf("Expected no geometries for EMPTY, got %d", len(features[2].Geometries))
+	}
+	if _, ok := features[0].Properties["wkt"]; ok {
+		t.Error("Expected the WKT column not to be a property")
+	}
+	if features[0].Properties["line"] != "S5" {
+		t.Errorf("Expected property line 'S5', got %q", features[0].Properties["line"])
+	}
+}
+
+func TestGetReaderWithOptions_DelimitedColumns(t *testing.T) {
+	// None of these columns would be detected on their own
+	content := "station;ost;nord;form\n" +
+		"Hbf;13.3694;52.5251;LINESTRING (13.3694 52.5251, 13.3880 52.5200)\n"
+	filePath := createTempFile(t, "stations.csv", content)
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected int
+	}{
+		{name: "coordinate columns", opts: Options{LonColumn: "ost", LatColumn: "nord", LabelField: "station"}, expected: 1},
+		{name: "WKT column", opts: Options{WKTColumn: "form", LabelField: "station"}, expected: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := GetReaderWithOptions(filePath, tt.opts)
+			if err != nil {
+				t.Fatalf("GetReaderWithOptions returned error: %v", err)
+			}
+			poiList, err := reader.ParseFile(filePath)
+			if err != nil {
+				t.Fatalf("ParseFile returned error: %v", err)
+			}
+
+			if len(*poiList) != tt.expected {
+				t.Fatalf("Expected %d POIs, got %d", tt.expected, len(*poiList))
+			}
+			if p := (*poiList)[0]; p.Lon != 13.3694 || p.Lat != 52.5251 || p.Text != "Hbf" {
+				t.Errorf("Expected Hbf at 13.3694,52.5251, got %+v", p)
+			}
+		})
+	}
+}
+
+func TestDelimitedTextReader_POIColumns(t *testing.T) {
+	content := "lon,lat,text,color,font_size,max_lod,transparent,population,demand\n" +
+		"13.3694,52.5251,Berlin Hbf,#ff0000,16,5,yes,3600000,city\n" +
+		"10.0069,53.5530,Hamburg Hbf,red,16,5,no,1800000,city\n" +
+		"11.5580,48.1402,München Hbf,,,,,,\n"
+	filePath := createTempFile(t, "stations.csv", content)
+
+	reader := &DelimitedTextReader{}
+	poiList, err := reader.ParseFileWithFullConfig(filePath, DefaultMaxLod, defaultColor)
+	if err != nil {
+		t.Fatalf("ParseFileWithFullConfig returned error: %v", err)
+	}
+
+	if len(*poiList) != 3 {
+		t.Fatalf("Expected 3 POIs, got %d", len(*poiList))
+	}
+
+	berlin := (*poiList)[0]
+	if berlin.Text != "Berlin Hbf" {
+		t.Errorf("Expected text 'Berlin Hbf', got %q", berlin.Text)
+	}
+	if berlin.Color != "ff0000" || berlin.FontSize != 16 || berlin.MaxLod != 5 || !berlin.Transparent {
+		t.Errorf("Expected the POI columns to be applied, got %+v", berlin)
+	}
+	if berlin.Population != 3600000 || berlin.Demand != "city" {
+		t.Errorf("Expected population and demand to be applied, got %+v", berlin)
+	}
+
+	// An invalid color is left out, the row's other POI columns still apply
+	hamburg := (*poiList)[1]
+	if hamburg.Text != "Hamburg Hbf" {
+		t.Errorf("Expected text 'Hamburg Hbf', got %q", hamburg.Text)
+	}
+	if hamburg.Color != defaultColor {
+		t.Errorf("Expected the default color for an invalid color column, got %q", hamburg.Color)
+	}
+	if hamburg.FontSize != 16 || hamburg.MaxLod != 5 || hamburg.Transparent || hamburg.Population != 1800000 {
+		t.Errorf("Expected the valid POI columns to be applied, got %+v", hamburg)
+	}
+
+	munich := (*poiList)[2]
+	if munich.Color != defaultColor || munich.FontSize != defaultFontSize || munich.Transparent {
+		t.Errorf("Expected defaults for empty POI columns, got %+v", munich)
+	}
+}
+
+func TestDelimitedTextReader_TextColumn(t *testing.T) {
+	content := "lon,lat,name,label,text\n" +
+		"13.3694,52.5251,Berlin Hauptbahnhof,Hbf,Berlin Hbf\n" +
+		"10.0069,53.5530,Hamburg Hauptbahnhof,,\n"
+	filePath := createTempFile(t, "stations.csv", content)
+
+	tests := []struct {
+		name       string
+		labelField string
+		expected   []string
+	}{
+		{name: "text wins over label and name", expected: []string{"Berlin Hbf", "Hamburg Hauptbahnhof"}},
+		{name: "label field wins over text", labelField: "name", expected: []string{"Berlin Hauptbahnhof", "Hamburg Hauptbahnhof"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &DelimitedTextReader{Options: Options{LabelField: tt.labelField}}
+			poiList, err := reader.ParseFile(filePath)
+			if err != nil {
+				t.Fatalf("ParseFile returned error: %v", err)
+			}
+			if len(*poiList) != len(tt.expected) {
+				t.Fatalf("Expected %d POIs, got %d", len(tt.expected), len(*poiList))
+			}
+			for i, p := range *poiList {
+				if p.Text != tt.expected[i] {
+					t.Errorf("POI %d: Expected text '%s', got '%s'", i, tt.expected[i], p.Text)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package geometry
+
+import (
+	"strings"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/rules"
+)
+
+// GeometryType identifies the kind of a Geometry
+type GeometryType string
+
+const (
+	PointGeometry      GeometryType = "Point"
+	LineStringGeometry GeometryType = "LineString"
+	PolygonGeometry    GeometryType = "Polygon"
+)
+
+// Coordinate is a WGS84 longitude/latitude position in degrees
+type Coordinate struct {
+	Lon float64
+	Lat float64
+}
+
+// Geometry is a single point, line or polygon. Multi-part source geometries
+// such as MultiLineStrings or KML MultiGeometries become one Geometry per part.
+type Geometry struct {
+	Type GeometryType
+	// Coordinates holds the position of a point or the vertices of a line
+	Coordinates []Coordinate
+	// Rings holds the rings of a polygon, the outer boundary first followed by
+	// its holes. Rings are open: the closing vertex is not repeated.
+	Rings [][]Coordinate
+}
+
+// Feature is a geometry read from an input file together with its attributes,
+// before it is converted to POIs
+type Feature struct {
+	Geometries []Geometry
+	// Properties are the feature's own attributes: DBF fields, KML ExtendedData or GeoJSON properties
+	Properties map[string]string
+	// Name is the feature's own name, such as a KML <name>, used when no label attribute is set
+	Name string
+	// Text is POI text the input sets explicitly, such as the text column of a
+	// CSV file. Only the configured label field takes precedence over it.
+	Text string
+	// Color is the feature's color from the input file's styles (rrggbb), empty when it has none
+	Color string
+	// Style holds POI fields the input sets explicitly, such as the color column
+	// of a CSV file. It overrides the configured values; rules override it.
+	Style  rules.Style
+	Source Source
+}
+
+// Source records where in the input a feature was read from
+type Source struct {
+	// File is the base name of the input file
+	File string
+	// Folders is the path of KML folders containing the feature, outermost first
+	Folders []string
+	// Index is the position of the feature in the file
+	Index int
+}
+
+// FeatureReader is implemented by readers that can return the features of a
+// file without converting them to POIs
+type FeatureReader interface {
+	ReadFeatures(filePath string) ([]Feature, error)
+}
+
+// NewPoint returns a point geometry
+func NewPoint(lon, lat float64) Geometry {
+	return Geometry{Type: PointGeometry, Coordinates: []Coordinate{{Lon: lon, Lat: lat}}}
+}
+
+// NewLineString returns a line geometry
+func NewLineString(coords []Coordinate) Geometry {
+	return Geometry{Type: LineStringGeometry, Coordinates: coords}
+}
+
+// NewPolygon returns a polygon geometry, dropping the closing vertex of rings that repeat it
+func NewPolygon(rings ...[]Coordinate) Geometry {
+	open := make([][]Coordinate, 0, len(rings))
+	for _, ring := range rings {
+		if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+			ring = ring[:len(ring)-1]
+		}
+		open = append(open, ring)
+	}
+	return Geometry{Type: PolygonGeometry, Rings: open}
+}
+
+// Attributes returns the feature's properties along with the @file and @folder
+// pseudo-attributes that styling rules can match on
+func (f *Feature) Attributes() map[string]string {
+	attributes := make(map[string]string, len(f.Properties)+2)
+	for key, value := range f.Properties {
+		attributes[key] = value
+	}
+	attributes[rules.FileAttribute] = f.Source.File
+	attributes[rules.FolderAttribute] = strings.Join(f.Source.Folders, "/")
+	return attributes
+}
@@ -0,0 +1,137 @@
+package geometry
+
+import (
+	"testing"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/rules"
+)
+
+func TestNewPolygon(t *testing.T) {
+	closed := []Coordinate{{Lon: 10, Lat: 53}, {Lon: 11, Lat: 53}, {Lon: 11, Lat: 54}, {Lon: 10, Lat: 53}}
+	open := []Coordinate{{Lon: 10.2, Lat: 53.2}, {Lon: 10.4, Lat: 53.2}, {Lon: 10.4, Lat: 53.4}}
+
+	polygon := NewPolygon(closed, open)
+	if polygon.Type != PolygonGeometry {
+		t.Errorf("Expected type %s, got %s", PolygonGeometry, polygon.Type)
+	}
+	if len(polygon.Rings) != 2 {
+		t.Fatalf("Expected 2 rings, got %d", len(polygon.Rings))
+	}
+	if len(polygon.Rings[0]) != 3 {
+		t.Errorf("Expected the closing vertex to be dropped, got %d vertices", len(polygon.Rings[0]))
+	}
+	if len(polygon.Rings[1]) != 3 {
+		t.Errorf("Expected an open ring to be kept as is, got %d vertices", len(polygon.Rings[1]))
+	}
+}
+
+func TestFeature_Attributes(t *testing.T) {
+	feature := Feature{
+		Properties: map[string]string{"railway": "station"},
+		Source:     Source{File: "network.kml", Folders: []string{"Network", "Stations"}},
+	}
+
+	attributes := feature.Attributes()
+	if attributes["railway"] != "station" {
+		t.Errorf("Expected properties to be included, got %v", attributes)
+	}
+	if attributes[rules.FileAttribute] != "network.kml" {
+		t.Errorf("Expected %s to be 'network.kml', got '%s'", rules.FileAttribute, attributes[rules.FileAttribute])
+	}
+	if attributes[rules.FolderAttribute] != "Network/Stations" {
+		t.Errorf("Expected %s to be 'Network/Stations', got '%s'", rules.FolderAttribute, attributes[rules.FolderAttribute])
+	}
+	if _, ok := feature.Properties[rules.FileAttribute]; ok {
+		t.Error("Attributes must not modify the feature's properties")
+	}
+}
+
+func TestPOIConverter_Convert(t *testing.T) {
+	feature := Feature{
+		Geometries: []Geometry{
+			NewPoint(10, 53),
+			NewLineString([]Coordinate{{Lon: 10, Lat: 53}, {Lon: 10.1, Lat: 53}}),
+			NewPolygon([]Coordinate{{Lon: 10, Lat: 53}, {Lon: 10.01, Lat: 53}, {Lon: 10.01, Lat: 53.01}, {Lon: 10, Lat: 53}}),
+		},
+		Properties: map[string]string{"name": "Depot"},
+		Color:      "00ff00",
+	}
+
+	tests := []struct {
+		name          string
+		converter     POIConverter
+		expectedCount int
+		expectedColor string
+	}{
+		{name: "defaults", converter: POIConverter{}, expectedCount: 1 + 2 + 3, expectedColor: "0000ff"},
+		{name: "file styles", converter: POIConverter{Options: Options{PreferFileStyles: true}}, expectedCount: 6, expectedColor: "00ff00"},
+		{name: "interpolation", converter: POIConverter{Options: Options{InterpolateDistance: 5000}}, expectedCount: 1 + 3 + 3, expectedColor: "0000ff"},
+		{name: "resampling replaces interpolation", converter: POIConverter{Options: Options{ResampleDistance: 1000, InterpolateDistance: 5000}}, expectedCount: 1 + 8 + 3, expectedColor: "0000ff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poiList := make(poi.List, 0)
+			tt.converter.Convert(&feature, newPOITemplate(DefaultMaxLod, defaultColor), &poiList)
+
+			if len(poiList) != tt.expectedCount {
+				t.Fatalf("Expected %d POIs, got %d", tt.expectedCount, len(poiList))
+			}
+			if poiList[0].Color != tt.expectedColor {
+				t.Errorf("Expected color '%s', got '%s'", tt.expectedColor, poiList[0].Color)
+			}
+
+			// The point and the first vertex of the line and the ring are labelled
+			labelled := 0
+			for _, p := range poiList {
+				if p.Text == "Depot" {
+					labelled++
+				}
+			}
+			if labelled != 3 {
+				t.Errorf("Expected 3 labelled POIs, got %d", labelled)
+			}
+		})
+	}
+}
+
+// countingConverter emits a single POI per feature at its first coordinate
+type countingConverter struct {
+	features int
+}
+
+func (c *countingConverter) Convert(feature *Feature, base poi.POI, poiList *poi.List) {
+	c.features++
+	base.Lon = feature.Geometries[0].Coordinates[0].Lon
+	base.Lat = feature.Geometries[0].Coordinates[0].Lat
+	poiList.Add(base)
+}
+
+func TestOptions_Converter(t *testing.T) {
+	kmlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+	<Placemark><Point><coordinates>10,53</coordinates></Point></Placemark>
+	<Placemark><LineString><coordinates>10,53 10.5,53.5 11,54</coordinates></LineString></Placemark>
+</Document>
+</kml>`
+	filePath := createTempFile(t, "converter.kml", kmlContent)
+
+	converter := &countingConverter{}
+	reader := &KMLReader{Options: Options{Converter: converter}}
+	poiList, err := reader.ParseFileWithFullConfig(filePath, 5, "ff0000")
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	if converter.features != 2 {
+		t.Errorf("Expected the converter to be called for 2 features, got %d", converter.features)
+	}
+	if len(*poiList) != 2 {
+		t.Fatalf("Expected 2 POIs, got %d", len(*poiList))
+	}
+	if p := (*poiList)[1]; p.Color != "ff0000" || p.MaxLod != 5 {
+		t.Errorf("Expected the converter to receive the configured color and max LOD, got %+v", p)
+	}
+}
@@ -0,0 +1,178 @@
+package geometry
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// FillMethod selects the pattern of POIs placed inside polygons
+type FillMethod string
+
+const (
+	// FillGrid places POIs on a square grid with the fill spacing
+	FillGrid FillMethod = "grid"
+	// FillHatch places POIs along diagonal lines the fill spacing apart, dotted
+	// hatchDotsPerSpacing times as densely, which reads as shading
+	FillHatch FillMethod = "hatch"
+)
+
+const hatchDotsPerSpacing = 4
+
+// maxFillPoints limits the POIs that polygons are filled with in one
+// conversion, well beyond what the game displays smoothly
+const maxFillPoints = 1_000_000
+
+// ErrTooManyFillPoints is returned when the fill spacing is too small for the
+// area of the polygons being filled
+var ErrTooManyFillPoints = errors.New("too many fill points")
+
+// ParseFillMethod accepts a fill method name. An empty name selects the grid.
+func ParseFillMethod(name string) (FillMethod, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", string(FillGrid):
+		return FillGrid, nil
+	case string(FillHatch):
+		return FillHatch, nil
+	default:
+		return "", fmt.Errorf("unknown fill method %q, expected %s or %s", name, FillGrid, FillHatch)
+	}
+}
+
+// fillPolygon returns the points of the fill pattern that lie inside the
+// polygon's outer ring and outside its holes. spacing is in meters. A clip
+// area limits the pattern to the area's bounds, so that only the part of a
+// large polygon near the area is filled; the pattern stays aligned to the
+// whole polygon either way.
+func fillPolygon(rings [][]Coordinate, method FillMethod, spacing float64, clip *ClipArea) []Coordinate {
+	if spacing <= 0 || len(rings) == 0 || len(rings[0]) < 3 {
+		return nil
+	}
+
+	// Work in meters on a plane tangent at the middle of the polygon, rotated
+	// so that the pattern's rows run along the u axis
+	projection := newLocalProjection(rings[0])
+
+	angle, dotSpacing := 0.0, spacing
+	if method == FillHatch {
+		angle, dotSpacing = math.Pi/4, spacing/hatchDotsPerSpacing
+	}
+	sin, cos := math.Sin(angle), math.Cos(angle)
+
+	type point struct{ u, v float64 }
+	project := func(coord Coordinate) point {
+		x, y := projection.forward(coord)
+		return point{u: x*cos + y*sin, v: y*cos - x*sin}
+	}
+	// rowCrossings appends the positions along the row at v where the ring crosses it
+	rowCrossings := func(crossings []float64, ring []point, v float64) []float64 {
+		for j := range ring {
+			a, b := ring[j], ring[(j+1)%len(ring)]
+			// Half-open so that a vertex on the row is counted once
+			if (a.v <= v) != (b.v <= v) {
+				crossings = append(crossings, a.u+(v-a.v)/(b.v-a.v)*(b.u-a.u))
+			}
+		}
+		return crossings
+	}
+
+	projected := make([][]point, len(rings))
+	minU, maxU, minV, maxV := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	for i, ring := range rings {
+		projected[i] = make([]point, len(ring))
+		for j, coord := range ring {
+			p := project(coord)
+			projected[i][j] = p
+			minU, maxU = min(minU, p.u), max(maxU, p.u)
+			minV, maxV = min(minV, p.v), max(maxV, p.v)
+		}
+	}
+
+	var result []Coordinate
+	var crossings, bounds []float64
+	// Rows and dots are centred on the polygon's extent, so that polygons
+	// smaller than the spacing still get a point in their middle
+	firstU := centredStart(minU, maxU, dotSpacing)
+	firstV := centredStart(minV, maxV, spacing)
+	lowU, highU := math.Inf(-1), math.Inf(1)
+
+	// The clip area's bounds are a rectangle on the plane, turned with the
+	// pattern, which rows start, stop and are cut at
+	var window []point
+	if clip != nil {
+		window = []point{
+			project(clip.low),
+			project(Coordinate{Lon: clip.high.Lon, Lat: clip.low.Lat}),
+			project(clip.high),
+			project(Coordinate{Lon: clip.low.Lon, Lat: clip.high.Lat}),
+		}
+		windowMinV, windowMaxV := math.Inf(1), math.Inf(-1)
+		for _, p := range window {
+			windowMinV, windowMaxV = min(windowMinV, p.v), max(windowMaxV, p.v)
+		}
+		if windowMinV > firstV {
+			firstV += math.Ceil((windowMinV-firstV)/spacing) * spacing
+		}
+		maxV = min(maxV, windowMaxV+spacing)
+	}
+
+	for v := firstV; v < maxV; v += spacing {
+		if window != nil {
+			bounds = rowCrossings(bounds[:0], window, v)
+			if len(bounds) < 2 {
+				continue
+			}
+			lowU, highU = slices.Min(bounds), slices.Max(bounds)
+		}
+
+		crossings = crossings[:0]
+		for _, ring := range projected {
+			crossings = rowCrossings(crossings, ring, v)
+		}
+		sort.Float64s(crossings)
+
+		// Crossings pair up into the spans inside the polygon, holes included
+		// by the even-odd rule
+		for k := 0; k+1 < len(crossings); k += 2 {
+			start, end := max(crossings[k], lowU), min(crossings[k+1], highU)
+			first := firstU + math.Ceil((start-firstU)/dotSpacing)*dotSpacing
+			for u := first; u <= end; u += dotSpacing {
+				x, y := u*cos-v*sin, u*sin+v*cos
+				result = append(result, projection.inverse(x, y))
+			}
+		}
+	}
+	return result
+}
+
+// estimateFillPoints returns about how many POIs fillPolygon places in the
+// polygons among the geometries: the area of their outer rings divided by the
+// area each POI covers. With a clip area only the part of the outer rings
+// within the area's bounds is counted.
+func estimateFillPoints(geometries []Geometry, method FillMethod, spacing float64, clip *ClipArea) float64 {
+	perPoint := spacing * spacing
+	if method == FillHatch {
+		perPoint /= hatchDotsPerSpacing
+	}
+	var points float64
+	for _, geometry := range geometries {
+		if geometry.Type == PolygonGeometry && len(geometry.Rings) > 0 {
+			ring := geometry.Rings[0]
+			if clip != nil {
+				ring = clip.clipRingToBounds(ring)
+			}
+			points += ringArea(ring) / perPoint
+		}
+	}
+	return points
+}
+
+// centredStart returns the first of the evenly spaced positions that are
+// centred between low and high
+func centredStart(low, high, spacing float64) float64 {
+	steps := max(math.Round((high-low)/spacing), 1)
+	return low + (high-low-(steps-1)*spacing)/2
+}
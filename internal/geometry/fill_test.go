@@ -0,0 +1,152 @@
+package geometry
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/gis"
+)
+
+// squareRing returns a square ring of the given size (meters) centred on a latitude
+func squareRing(lon, lat, size float64) []Coordinate {
+	metersPerDegree := gis.EarthRadiusKm * 1000 * math.Pi / 180
+	dLat := size / 2 / metersPerDegree
+	dLon := dLat / math.Cos(lat*math.Pi/180)
+	return []Coordinate{
+		{Lon: lon - dLon, Lat: lat - dLat},
+		{Lon: lon + dLon, Lat: lat - dLat},
+		{Lon: lon + dLon, Lat: lat + dLat},
+		{Lon: lon - dLon, Lat: lat + dLat},
+	}
+}
+
+func inRing(coord Coordinate, ring []Coordinate) bool {
+	inside := false
+	for i := range ring {
+		a, b := ring[i], ring[(i+1)%len(ring)]
+		if (a.Lat <= coord.Lat) != (b.Lat <= coord.Lat) &&
+			coord.Lon < a.Lon+(coord.Lat-a.Lat)/(b.Lat-a.Lat)*(b.Lon-a.Lon) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func TestParseFillMethod(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected FillMethod
+		wantErr  bool
+	}{
+		{"", FillGrid, false},
+		{"grid", FillGrid, false},
+		{" Hatch ", FillHatch, false},
+		{"dots", "", true},
+	}
+
+	for _, tt := range tests {
+		method, err := ParseFillMethod(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFillMethod(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+		if method != tt.expected {
+			t.Errorf("ParseFillMethod(%q) = %q, expected %q", tt.input, method, tt.expected)
+		}
+	}
+}
+
+func TestFillPolygon_Grid(t *testing.T) {
+	outer := squareRing(10, 60, 1000)
+	hole := squareRing(10, 60, 400)
+
+	points := fillPolygon([][]Coordinate{outer}, FillGrid, 100, nil)
+	if len(points) != 100 {
+		t.Errorf("Expected a 10x10 grid in a 1 km square, got %d points", len(points))
+	}
+
+	points = fillPolygon([][]Coordinate{outer, hole}, FillGrid, 100, nil)
+	if len(points) != 100-16 {
+		t.Errorf("Expected the 4x4 points inside the hole to be left out, got %d points", len(points))
+	}
+	for _, point := range points {
+		if !inRing(point, outer) || inRing(point, hole) {
+			t.Errorf("Point %v lies outside the filled area", point)
+		}
+	}
+
+	// Neighbouring points are one spacing apart
+	distance := gis.HaversineDistance(points[0].Lat, points[0].Lon, points[1].Lat, points[1].Lon)
+	if math.Abs(distance-100) > 0.5 {
+		t.Errorf("Expected points 100 m apart, got %.2f m", distance)
+	}
+}
+
+func TestFillPolygon_Hatch(t *testing.T) {
+	outer := squareRing(10, 60, 1000)
+
+	points := fillPolygon([][]Coordinate{outer}, FillHatch, 100, nil)
+	// Diagonal lines 100 m apart dotted every 25 m cover the square at 4 times
+	// the density of the grid
+	if len(points) < 380 || len(points) > 420 {
+		t.Errorf("Expected about 400 hatch points, got %d", len(points))
+	}
+	for _, point := range points {
+		if !inRing(point, outer) {
+			t.Errorf("Point %v lies outside the polygon", point)
+		}
+	}
+}
+
+func TestFillPolygon_Small(t *testing.T) {
+	// A polygon narrower than the spacing still gets a point in its middle
+	points := fillPolygon([][]Coordinate{squareRing(10, 60, 50)}, FillGrid, 100, nil)
+	if len(points) != 1 {
+		t.Errorf("Expected 1 point, got %d", len(points))
+	}
+
+	if points := fillPolygon(nil, FillGrid, 100, nil); len(points) != 0 {
+		t.Errorf("Expected no points without rings, got %d", len(points))
+	}
+}
+
+func TestPOIConverter_Fill(t *testing.T) {
+	feature := Feature{
+		Geometries: []Geometry{NewPolygon(squareRing(10, 60, 1000))},
+		Name:       "Lake",
+	}
+
+	options := Options{FillSpacing: 250}
+	poiList, err := options.ConvertFeatures([]Feature{feature}, 5, "00ff00")
+	if err != nil {
+		t.Fatalf("ConvertFeatures returned error: %v", err)
+	}
+
+	// The 4 outline vertices followed by a 4x4 fill grid
+	if len(*poiList) != 4+16 {
+		t.Fatalf("Expected 20 POIs, got %d", len(*poiList))
+	}
+	if (*poiList)[0].Text != "Lake" {
+		t.Errorf("Expected the outline to carry the label, got %q", (*poiList)[0].Text)
+	}
+	for _, p := range (*poiList)[4:] {
+		if p.Text != "" || p.Color != "00ff00" || p.MaxLod != 5 {
+			t.Errorf("Expected unlabelled fill POIs in the feature's style, got %+v", p)
+		}
+	}
+}
+
+func TestPOIConverter_FillLimit(t *testing.T) {
+	// A 100 km square takes 10 billion POIs at a 1 m spacing
+	features := []Feature{{Geometries: []Geometry{NewPolygon(squareRing(10, 60, 100000))}}}
+
+	options := Options{FillSpacing: 1}
+	if _, err := options.ConvertFeatures(features, 5, "00ff00"); !errors.Is(err, ErrTooManyFillPoints) {
+		t.Errorf("Expected ErrTooManyFillPoints, got %v", err)
+	}
+
+	options.FillSpacing = 1000
+	if _, err := options.ConvertFeatures(features, 5, "00ff00"); err != nil {
+		t.Errorf("Expected a 1 km spacing to be accepted, got %v", err)
+	}
+}
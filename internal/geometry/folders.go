@@ -0,0 +1,151 @@
+package geometry
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// FolderFilter selects features by the KML folders holding them. Patterns
+// are case-insensitive globs matched against the path of the folder holding a
+// feature and of every folder above it; the feature's own name is not part of
+// the path. "Lines" selects everything in the Lines folder, and
+// "Lines/S-Bahn/*" everything inside Lines/S-Bahn, the trailing "*" standing
+// for the placemarks directly in it as well as its subfolders. Features of
+// other formats have no folders and are always kept.
+type FolderFilter struct {
+	include []string
+	exclude []string
+}
+
+// NewFolderFilter returns a filter that keeps the features matching one of
+// the include patterns, or every feature when there are none, and drops those
+// matching an exclude pattern. It returns nil when there are no patterns.
+func NewFolderFilter(include, exclude []string) (*FolderFilter, error) {
+	filter := &FolderFilter{}
+	var err error
+	if filter.include, err = folderPatterns(include); err != nil {
+		return nil, err
+	}
+	if filter.exclude, err = folderPatterns(exclude); err != nil {
+		return nil, err
+	}
+	if len(filter.include) == 0 && len(filter.exclude) == 0 {
+		return nil, nil
+	}
+	return filter, nil
+}
+
+func folderPatterns(patterns []string) ([]string, error) {
+	var result []string
+	for _, pattern := range patterns {
+		pattern = strings.Trim(strings.ToLower(strings.TrimSpace(pattern)), "/")
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid folder pattern %q: %w", pattern, err)
+		}
+		result = append(result, pattern)
+	}
+	return result, nil
+}
+
+// Match reports whether the filter keeps a feature. A nil filter keeps every feature.
+func (f *FolderFilter) Match(feature *Feature) bool {
+	if f == nil || !isKMLSource(feature.Source) {
+		return true
+	}
+
+	// The feature itself is an empty last segment, so that "Lines/S-Bahn/*"
+	// selects the placemarks directly in Lines/S-Bahn whatever their names
+	segments := make([]string, 0, len(feature.Source.Folders)+1)
+	for _, folder := range feature.Source.Folders {
+		segments = append(segments, strings.ToLower(folder))
+	}
+	segments = append(segments, "")
+
+	if len(f.include) > 0 && !matchesAnyPrefix(f.include, segments) {
+		return false
+	}
+	return !matchesAnyPrefix(f.exclude, segments)
+}
+
+// isKMLSource reports whether a feature was read from a KML or KMZ file,
+// the only formats with folders
+func isKMLSource(source Source) bool {
+	switch strings.ToLower(path.Ext(source.File)) {
+	case ".kml", ".kmz":
+		return true
+	}
+	return false
+}
+
+// matchesAnyPrefix reports whether a pattern matches the path made of the
+// first n segments for any n
+func matchesAnyPrefix(patterns []string, segments []string) bool {
+	for n := 1; n <= len(segments); n++ {
+		prefix := strings.Join(segments[:n], "/")
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(pattern, prefix); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FolderSummary describes a folder of an input file for the inspect command
+type FolderSummary struct {
+	Name string
+	// Path is the folder's path as matched by a FolderFilter, e.g. "Lines/S-Bahn"
+	Path string
+	// Features counts the features directly in the folder
+	Features int
+	// Geometries counts the geometries of those features by type
+	Geometries map[GeometryType]int
+	// Folders holds the subfolders in the order they first appear
+	Folders []*FolderSummary
+}
+
+// SummarizeFolders returns the folder tree of a file's features. The root
+// holds the features outside any folder.
+func SummarizeFolders(features []Feature) *FolderSummary {
+	root := &FolderSummary{Geometries: make(map[GeometryType]int)}
+	for i := range features {
+		folder := root
+		for _, name := range features[i].Source.Folders {
+			folder = folder.subfolder(name)
+		}
+		folder.Features++
+		for _, geometry := range features[i].Geometries {
+			folder.Geometries[geometry.Type]++
+		}
+	}
+	return root
+}
+
+func (s *FolderSummary) subfolder(name string) *FolderSummary {
+	for _, folder := range s.Folders {
+		if folder.Name == name {
+			return folder
+		}
+	}
+
+	folderPath := name
+	if s.Path != "" {
+		folderPath = s.Path + "/" + name
+	}
+	folder := &FolderSummary{Name: name, Path: folderPath, Geometries: make(map[GeometryType]int)}
+	s.Folders = append(s.Folders, folder)
+	return folder
+}
+
+// TotalFeatures counts the features in the folder and all its subfolders
+func (s *FolderSummary) TotalFeatures() int {
+	total := s.Features
+	for _, folder := range s.Folders {
+		total += folder.TotalFeatures()
+	}
+	return total
+}
@@ -0,0 +1,240 @@
+package geometry
+
+import (
+	"testing"
+)
+
+func folderFeature(name string, folders ...string) Feature {
+	return Feature{
+		Geometries: []Geometry{NewPoint(10, 53)},
+		Name:       name,
+		Source:     Source{File: "lines.kml", Folders: folders},
+	}
+}
+
+func TestNewFolderFilter(t *testing.T) {
+	filter, err := NewFolderFilter([]string{"", "  "}, nil)
+	if err != nil {
+		t.Fatalf("NewFolderFilter returned error: %v", err)
+	}
+	if filter != nil {
+		t.Errorf("Expected no filter for blank patterns, got %+v", filter)
+	}
+
+	if _, err := NewFolderFilter([]string{"Lines/["}, nil); err == nil {
+		t.Error("Expected an error for a malformed include pattern")
+	}
+	if _, err := NewFolderFilter(nil, []string{"[Closed"}); err == nil {
+		t.Error("Expected an error for a malformed exclude pattern")
+	}
+}
+
+func TestFolderFilter_Match(t *testing.T) {
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		feature  Feature
+		expected bool
+	}{
+		{
+			name:     "folder selects everything inside it",
+			include:  []string{"Lines"},
+			feature:  folderFeature("S1", "Lines", "S-Bahn"),
+			expected: true,
+		},
+		{
+			name:     "other folders are left out",
+			include:  []string{"Lines"},
+			feature:  folderFeature("Depot", "Depots"),
+			expected: false,
+		},
+		{
+			name:     "glob selects the contents of a subfolder",
+			include:  []string{"Lines/S-Bahn/*"},
+			feature:  folderFeature("S1", "Lines", "S-Bahn"),
+			expected: true,
+		},
+		{
+			name:     "glob does not select sibling folders",
+			include:  []string{"Lines/S-Bahn/*"},
+			feature:  folderFeature("U1", "Lines", "U-Bahn"),
+			expected: false,
+		},
+		{
+			name:     "placemark name is not part of the path",
+			include:  []string{"lines/s-bahn/s1"},
+			feature:  folderFeature("S1", "Lines", "S-Bahn"),
+			expected: false,
+		},
+		{
+			name:     "root placemark named like an excluded folder is kept",
+			exclude:  []string{"Berlin"},
+			feature:  folderFeature("Berlin"),
+			expected: true,
+		},
+		{
+			name:     "placemark name with a slash does not add folders",
+			include:  []string{"Lines/S-Bahn"},
+			feature:  folderFeature("S-Bahn/S1", "Lines"),
+			expected: false,
+		},
+		{
+			name:     "patterns are case-insensitive and trimmed of slashes",
+			include:  []string{"/LINES/"},
+			feature:  folderFeature("S1", "Lines", "S-Bahn"),
+			expected: true,
+		},
+		{
+			name:     "exclude drops matching folders",
+			exclude:  []string{"*/Closed"},
+			feature:  folderFeature("S9", "Lines", "Closed"),
+			expected: false,
+		},
+		{
+			name:     "exclude keeps other folders",
+			exclude:  []string{"*/Closed"},
+			feature:  folderFeature("S1", "Lines", "S-Bahn"),
+			expected: true,
+		},
+		{
+			name:     "exclude wins over include",
+			include:  []string{"Lines"},
+			exclude:  []string{"Lines/Closed"},
+			feature:  folderFeature("S9", "Lines", "Closed"),
+			expected: false,
+		},
+		{
+			name:     "feature outside any folder",
+			include:  []string{"Lines"},
+			feature:  folderFeature("Hauptbahnhof"),
+			expected: false,
+		},
+		{
+			name:     "glob selects unnamed placemarks in the folder",
+			include:  []string{"Lines/S-Bahn/*"},
+			feature:  folderFeature("", "Lines", "S-Bahn"),
+			expected: true,
+		},
+		{
+			name:     "glob does not select unnamed placemarks in the parent",
+			include:  []string{"Lines/S-Bahn/*"},
+			feature:  folderFeature("", "Lines"),
+			expected: false,
+		},
+		{
+			name:     "exclude glob drops unnamed placemarks in the folder",
+			exclude:  []string{"Lines/Closed/*"},
+			feature:  folderFeature(" ", "Lines", "Closed"),
+			expected: false,
+		},
+		{
+			name:     "features of other formats are kept",
+			include:  []string{"Lines"},
+			feature:  Feature{Name: "Lines", Source: Source{File: "stations.geojson"}},
+			expected: true,
+		},
+		{
+			name:     "features of other formats are not matched by name",
+			exclude:  []string{"Depot*"},
+			feature:  Feature{Name: "Depot", Source: Source{File: "depots.csv"}},
+			expected: true,
+		},
+		{
+			name:     "KMZ placemarks are filtered",
+			include:  []string{"Lines"},
+			feature:  Feature{Name: "Depot", Source: Source{File: "export.KMZ", Folders: []string{"Depots"}}},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewFolderFilter(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatalf("NewFolderFilter returned error: %v", err)
+			}
+			if got := filter.Match(&tt.feature); got != tt.expected {
+				t.Errorf("Expected Match to return %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	var filter *FolderFilter
+	feature := folderFeature("S1", "Lines")
+	if !filter.Match(&feature) {
+		t.Error("Expected a nil filter to keep every feature")
+	}
+}
+
+func TestSummarizeFolders(t *testing.T) {
+	features := []Feature{
+		folderFeature("Hauptbahnhof"),
+		folderFeature("S1", "Lines", "S-Bahn"),
+		folderFeature("S2", "Lines", "S-Bahn"),
+		folderFeature("U1", "Lines", "U-Bahn"),
+		folderFeature("Depot", "Depots"),
+	}
+	features[1].Geometries = append(features[1].Geometries, NewLineString([]Coordinate{{Lon: 10, Lat: 53}, {Lon: 11, Lat: 54}}))
+
+	root := SummarizeFolders(features)
+	if root.Features != 1 || root.TotalFeatures() != 5 {
+		t.Errorf("Expected 1 feature at the root and 5 in total, got %d and %d", root.Features, root.TotalFeatures())
+	}
+	if len(root.Folders) != 2 || root.Folders[0].Name != "Lines" || root.Folders[1].Name != "Depots" {
+		t.Fatalf("Expected the folders Lines and Depots in order, got %+v", root.Folders)
+	}
+
+	lines := root.Folders[0]
+	if lines.Features != 0 || lines.TotalFeatures() != 3 {
+		t.Errorf("Expected Lines to hold 3 features in subfolders, got %d directly and %d in total", lines.Features, lines.TotalFeatures())
+	}
+	sBahn := lines.Folders[0]
+	if sBahn.Path != "Lines/S-Bahn" {
+		t.Errorf("Expected path 'Lines/S-Bahn', got '%s'", sBahn.Path)
+	}
+	if sBahn.Features != 2 || sBahn.Geometries[PointGeometry] != 2 || sBahn.Geometries[LineStringGeometry] != 1 {
+		t.Errorf("Expected 2 features with 2 points and 1 line in S-Bahn, got %d features and %v", sBahn.Features, sBahn.Geometries)
+	}
+}
+
+func TestKMLReader_Folders(t *testing.T) {
+	kmlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+	<Folder>
+		<name>Lines</name>
+		<Folder>
+			<name>S-Bahn</name>
+			<Placemark><name>S1</name><Point><coordinates>10,53</coordinates></Point></Placemark>
+		</Folder>
+		<Folder>
+			<name>Closed</name>
+			<Placemark><name>S9</name><Point><coordinates>10.1,53</coordinates></Point></Placemark>
+		</Folder>
+	</Folder>
+	<Folder>
+		<name>Depots</name>
+		<Placemark><name>Depot</name><Point><coordinates>10.2,53</coordinates></Point></Placemark>
+	</Folder>
+</Document>
+</kml>`
+	filePath := createTempFile(t, "folders.kml", kmlContent)
+
+	folders, err := NewFolderFilter([]string{"Lines"}, []string{"*/Closed"})
+	if err != nil {
+		t.Fatalf("NewFolderFilter returned error: %v", err)
+	}
+	reader := &KMLReader{Options: Options{Folders: folders}}
+	poiList, err := reader.ParseFile(filePath)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	if len(*poiList) != 1 {
+		t.Fatalf("Expected 1 POI, got %d", len(*poiList))
+	}
+	if p := (*poiList)[0]; p.Lon != 10 || p.Text != "S1" {
+		t.Errorf("Expected the S1 placemark, got %+v", p)
+	}
+}
@@ -0,0 +1,218 @@
+package geometry
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
+)
+
+type GeoJSONReader struct {
+	Options
+}
+
+// geoJSONObject covers every GeoJSON object type we care about. Only the
+// members relevant to the object's "type" are populated by the decoder.
+type geoJSONObject struct {
+	Type        string          `json:"type"`
+	Features    []geoJSONObject `json:"features"`
+	Geometry    *geoJSONObject  `json:"geometry"`
+	Geometries  []geoJSONObject `json:"geometries"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Properties  map[string]any  `json:"properties"`
+}
+
+// geoJSONPosition is a single [lon, lat(, alt)] position
+type geoJSONPosition []float64
+
+func (g *GeoJSONReader) ParseFile(filePath string) (*poi.List, error) {
+	return g.ParseFileWithConfig(filePath, DefaultMaxLod)
+}
+
+func (g *GeoJSONReader) ParseFileWithConfig(filePath string, maxLod int32) (*poi.List, error) {
+	return g.ParseFileWithFullConfig(filePath, maxLod, defaultColor)
+}
+
+func (g *GeoJSONReader) ParseFileWithFullConfig(filePath string, maxLod int32, color string) (*poi.List, error) {
+	features, err := g.ReadFeatures(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return g.convert(features, maxLod, color)
+}
+
+// ReadFeatures returns a feature per GeoJSON Feature. Geometries outside a
+// Feature become features without properties.
+func (g *GeoJSONReader) ReadFeatures(filePath string) ([]Feature, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var root geoJSONObject
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid GeoJSON: %w", err)
+	}
+
+	features := make([]Feature, 0)
+
+	if err := g.processObject(&root, filepath.Base(filePath), &features); err != nil {
+		return nil, err
+	}
+
+	return features, nil
+}
+
+func (g *GeoJSONReader) processObject(obj *geoJSONObject, fileName string, features *[]Feature) error {
+	switch obj.Type {
+	case "FeatureCollection":
+		for i := range obj.Features {
+			if err := g.processObject(&obj.Features[i], fileName, features); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "Feature":
+		// Features with a null geometry are valid GeoJSON and simply have no location
+		if obj.Geometry == nil {
+			return nil
+		}
+		return g.addFeature(obj.Geometry, geoJSONProperties(obj.Properties), fileName, features)
+	default:
+		return g.addFeature(obj, map[string]string{}, fileName, features)
+	}
+}
+
+func (g *GeoJSONReader) addFeature(obj *geoJSONObject, properties map[string]string, fileName string, features *[]Feature) error {
+	var geometries []Geometry
+	if err := g.processGeometry(obj, &geometries); err != nil {
+		return err
+	}
+
+	*features = append(*features, Feature{
+		Geometries: geometries,
+		Properties: properties,
+		Source:     Source{File: fileName, Index: len(*features)},
+	})
+	return nil
+}
+
+func (g *GeoJSONReader) processGeometry(obj *geoJSONObject, geometries *[]Geometry) error {
+	switch obj.Type {
+	case "Point":
+		var position geoJSONPosition
+		if err := g.decodeCoordinates(obj, &position); err != nil {
+			return err
+		}
+		g.processPoints([]geoJSONPosition{position}, geometries)
+	case "MultiPoint":
+		var positions []geoJSONPosition
+		if err := g.decodeCoordinates(obj, &positions); err != nil {
+			return err
+		}
+		g.processPoints(positions, geometries)
+	case "LineString":
+		var line []geoJSONPosition
+		if err := g.decodeCoordinates(obj, &line); err != nil {
+			return err
+		}
+		*geometries = append(*geometries, NewLineString(geoJSONCoordinates(line)))
+	case "MultiLineString":
+		var lines [][]geoJSONPosition
+		if err := g.decodeCoordinates(obj, &lines); err != nil {
+			return err
+		}
+		for _, line := range lines {
+			*geometries = append(*geometries, NewLineString(geoJSONCoordinates(line)))
+		}
+	case "Polygon":
+		var rings [][]geoJSONPosition
+		if err := g.decodeCoordinates(obj, &rings); err != nil {
+			return err
+		}
+		g.processPolygon(rings, geometries)
+	case "MultiPolygon":
+		var polygons [][][]geoJSONPosition
+		if err := g.decodeCoordinates(obj, &polygons); err != nil {
+			return err
+		}
+		for _, rings := range polygons {
+			g.processPolygon(rings, geometries)
+		}
+	case "GeometryCollection":
+		for i := range obj.Geometries {
+			if err := g.processGeometry(&obj.Geometries[i], geometries); err != nil {
+				return err
+			}
+		}
+	default:
+		log.Printf("Skipped unsupported GeoJSON type %q", obj.Type)
+	}
+	return nil
+}
+
+func (g *GeoJSONReader) decodeCoordinates(obj *geoJSONObject, target any) error {
+	if len(obj.Coordinates) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(obj.Coordinates, target); err != nil {
+		return fmt.Errorf("invalid %s coordinates: %w", obj.Type, err)
+	}
+	return nil
+}
+
+func (g *GeoJSONReader) processPoints(positions []geoJSONPosition, geometries *[]Geometry) {
+	for _, position := range positions {
+		if len(position) < 2 {
+			continue
+		}
+		*geometries = append(*geometries, NewPoint(position[0], position[1]))
+	}
+}
+
+func (g *GeoJSONReader) processPolygon(rings [][]geoJSONPosition, geometries *[]Geometry) {
+	coords := make([][]Coordinate, 0, len(rings))
+	for _, ring := range rings {
+		coords = append(coords, geoJSONCoordinates(ring))
+	}
+	*geometries = append(*geometries, NewPolygon(coords...))
+}
+
+// geoJSONCoordinates converts positions to coordinates, skipping positions without both lon and lat
+func geoJSONCoordinates(positions []geoJSONPosition) []Coordinate {
+	coords := make([]Coordinate, 0, len(positions))
+	for _, position := range positions {
+		if len(position) < 2 {
+			continue
+		}
+		coords = append(coords, Coordinate{Lon: position[0], Lat: position[1]})
+	}
+	return coords
+}
+
+// geoJSONProperties flattens feature properties to strings so they can be used as attributes
+func geoJSONProperties(properties map[string]any) map[string]string {
+	attributes := make(map[string]string, len(properties))
+	for key, value := range properties {
+		switch v := value.(type) {
+		case nil:
+			continue
+		case string:
+			attributes[key] = v
+		case float64:
+			attributes[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			attributes[key] = strconv.FormatBool(v)
+		default:
+			// Nested objects and arrays keep their JSON representation
+			if encoded, err := json.Marshal(v); err == nil {
+				attributes[key] = string(encoded)
+			}
+		}
+	}
+	return attributes
+}
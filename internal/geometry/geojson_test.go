@@ -0,0 +1,300 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/rules"
+)
+
+func TestGeoJSONReader_ParseFile_FeatureCollection(t *testing.T) {
+	geojsonContent := `{
+	"type": "FeatureCollection",
+	"features": [
+		{
+			"type": "Feature",
+			"properties": {"name": "Station"},
+			"geometry": {"type": "Point", "coordinates": [10.123, 53.456]}
+		},
+		{
+			"type": "Feature",
+			"properties": {},
+			"geometry": {"type": "LineString", "coordinates": [[11.0, 54.0, 0], [12.0, 55.0, 0], [13.0, 56.0, 0]]}
+		},
+		{
+			"type": "Feature",
+			"properties": {},
+			"geometry": null
+		}
+	]
+}`
+
+	tmpFile := createTempFile(t, "test.geojson", geojsonContent)
+
+	reader := &GeoJSONReader{}
+	poiList, err := reader.ParseFile(tmpFile)
+
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	// Should have 1 point + 3 line points
+	if len(*poiList) != 4 {
+		t.Fatalf("Expected 4 POIs, got %d", len(*poiList))
+	}
+
+	pointPOI := (*poiList)[0]
+	if pointPOI.Lon != 10.123 || pointPOI.Lat != 53.456 {
+		t.Errorf("Expected point coordinates (10.123, 53.456), got (%f, %f)", pointPOI.Lon, pointPOI.Lat)
+	}
+	if pointPOI.Color != "0000ff" {
+		t.Errorf("Expected point color '0000ff', got '%s'", pointPOI.Color)
+	}
+	if pointPOI.MaxLod != 10 {
+		t.Errorf("Expected max LOD 10, got %d", pointPOI.MaxLod)
+	}
+}
+
+func TestGeoJSONReader_ParseFile_AllGeometryTypes(t *testing.T) {
+	geojsonContent := `{
+	"type": "FeatureCollection",
+	"features": [
+		{"type": "Feature", "geometry": {"type": "MultiPoint", "coordinates": [[10.0, 53.0], [10.1, 53.1]]}},
+		{"type": "Feature", "geometry": {"type": "MultiLineString", "coordinates": [[[11.0, 54.0], [11.1, 54.1]], [[12.0, 55.0], [12.1, 55.1]]]}},
+		{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": [
+			[[10.0, 53.0], [11.0, 53.0], [11.0, 54.0], [10.0, 54.0], [10.0, 53.0]],
+			[[10.2, 53.2], [10.4, 53.2], [10.4, 53.4], [10.2, 53.2]]
+		]}},
+		{"type": "Feature", "geometry": {"type": "MultiPolygon", "coordinates": [
+			[[[20.0, 50.0], [21.0, 50.0], [21.0, 51.0], [20.0, 50.0]]]
+		]}},
+		{"type": "Feature", "geometry": {"type": "GeometryCollection", "geometries": [
+			{"type": "Point", "coordinates": [5.0, 45.0]},
+			{"type": "LineString", "coordinates": [[6.0, 46.0], [6.1, 46.1]]}
+		]}}
+	]
+}`
+
+	tmpFile := createTempFile(t, "test.geojson", geojsonContent)
+
+	reader := &GeoJSONReader{}
+	poiList, err := reader.ParseFile(tmpFile)
+
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	// MultiPoint: 2, MultiLineString: 4, Polygon: 4 + 3 (closing points removed),
+	// MultiPolygon: 3, GeometryCollection: 1 + 2
+	expectedCount := 2 + 4 + 7 + 3 + 3
+	if len(*poiList) != expectedCount {
+		t.Errorf("Expected %d POIs, got %d", expectedCount, len(*poiList))
+	}
+}
+
+func TestGeoJSONReader_ParseFile_Labels(t *testing.T) {
+	geojsonContent := `{
+	"type": "FeatureCollection",
+	"features": [
+		{"type": "Feature", "properties": {"name": "Altona", "ref": 8002553}, "geometry": {"type": "Point", "coordinates": [9.9, 53.5]}},
+		{"type": "Feature", "properties": {"name": "S1"}, "geometry": {"type": "LineString", "coordinates": [[10.0, 53.0], [10.1, 53.1]]}},
+		{"type": "Feature", "properties": null, "geometry": {"type": "Point", "coordinates": [10.0, 53.0]}}
+	]
+}`
+
+	tmpFile := createTempFile(t, "labels.geojson", geojsonContent)
+
+	tests := []struct {
+		name       string
+		labelField string
+		expected   []string
+	}{
+		{name: "default uses name", labelField: "", expected: []string{"Altona", "S1", "", ""}},
+		{name: "numeric property", labelField: "ref", expected: []string{"8002553", "S1", "", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &GeoJSONReader{Options: Options{LabelField: tt.labelField}}
+			poiList, err := reader.ParseFile(tmpFile)
+			if err != nil {
+				t.Fatalf("ParseFile returned error: %v", err)
+			}
+
+			if len(*poiList) != len(tt.expected) {
+				t.Fatalf("Expected %d POIs, got %d", len(tt.expected), len(*poiList))
+			}
+			for i, p := range *poiList {
+				if p.Text != tt.expected[i] {
+					t.Errorf("POI %d: Expected text '%s', got '%s'", i, tt.expected[i], p.Text)
+				}
+			}
+		})
+	}
+}
+
+func TestGeoJSONReader_ParseFile_BareGeometry(t *testing.T) {
+	tmpFile := createTempFile(t, "point.json", `{"type": "Point", "coordinates": [10.0, 53.0]}`)
+
+	reader := &GeoJSONReader{}
+	poiList, err := reader.ParseFile(tmpFile)
+
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	if len(*poiList) != 1 {
+		t.Errorf("Expected 1 POI, got %d", len(*poiList))
+	}
+}
+
+func TestGeoJSONReader_ParseFile_Interpolation(t *testing.T) {
+	// Two points roughly 11km apart along a meridian
+	geojsonContent := `{"type": "Feature", "geometry": {"type": "MultiLineString", "coordinates": [
+		[[10.0, 53.0], [10.0, 53.1]],
+		[[12.0, 53.0], [12.0, 53.1]]
+	]}}`
+
+	tmpFile := createTempFile(t, "line.geojson", geojsonContent)
+
+	reader := &GeoJSONReader{Options: Options{InterpolateDistance: 5000}}
+	poiList, err := reader.ParseFile(tmpFile)
+
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	// Each line gets 2 intermediate points; no points bridge the gap between lines
+	if len(*poiList) != 8 {
+		t.Fatalf("Expected 8 POIs, got %d", len(*poiList))
+	}
+
+	for i, p := range *poiList {
+		if math.Abs(p.Lon-10.0) > 1e-9 && math.Abs(p.Lon-12.0) > 1e-9 {
+			t.Errorf("POI %d: expected longitude on one of the lines, got %f", i, p.Lon)
+		}
+	}
+}
+
+func TestGeoJSONReader_ParseFile_WithConfig(t *testing.T) {
+	tmpFile := createTempFile(t, "point.geojson", `{"type": "Point", "coordinates": [10.0, 53.0]}`)
+
+	reader := &GeoJSONReader{}
+	poiList, err := reader.ParseFileWithFullConfig(tmpFile, 3, "ff0000")
+
+	if err != nil {
+		t.Fatalf("ParseFileWithFullConfig returned error: %v", err)
+	}
+
+	p := (*poiList)[0]
+	if p.MaxLod != 3 {
+		t.Errorf("Expected max LOD 3, got %d", p.MaxLod)
+	}
+	if p.Color != "ff0000" {
+		t.Errorf("Expected color 'ff0000', got '%s'", p.Color)
+	}
+}
+
+func TestGeoJSONReader_ParseFile_Rules(t *testing.T) {
+	geojsonContent := `{
+	"type": "FeatureCollection",
+	"features": [
+		{"type": "Feature", "properties": {"railway": "station", "name": "Central"}, "geometry": {"type": "Point", "coordinates": [10.0, 53.0]}},
+		{"type": "Feature", "properties": {"railway": "halt"}, "geometry": {"type": "Point", "coordinates": [10.1, 53.1]}}
+	]
+}`
+	tmpFile := createTempFile(t, "railway.geojson", geojsonContent)
+
+	ruleSet, err := rules.Parse([]byte(`{"rules": [
+		{"match": {"railway": ["station", "halt"]}, "style": {"font_size": 14}},
+		{"match": {"railway": "station"}, "style": {"color": "#00ff00"}}
+	]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse rules: %v", err)
+	}
+
+	reader := &GeoJSONReader{Options: Options{Rules: ruleSet}}
+	poiList, err := reader.ParseFile(tmpFile)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	if len(*poiList) != 2 {
+		t.Fatalf("Expected 2 POIs, got %d", len(*poiList))
+	}
+	if p := (*poiList)[0]; p.Color != "00ff00" || p.FontSize != 14 || p.Text != "Central" {
+		t.Errorf("Expected styled station, got %+v", p)
+	}
+	if p := (*poiList)[1]; p.Color != defaultColor || p.FontSize != 14 {
+		t.Errorf("Expected halt with font size 14 and default color, got %+v", p)
+	}
+}
+
+func TestGeoJSONReader_ReadFeatures(t *testing.T) {
+	geoJSON := `{
+		"type": "FeatureCollection",
+		"features": [
+			{"type": "Feature", "properties": {"name": "Hbf", "tracks": 16}, "geometry": {"type": "Point", "coordinates": [10.0, 53.5]}},
+			{"type": "Feature", "properties": {"name": "Nowhere"}, "geometry": null},
+			{"type": "Feature", "properties": {}, "geometry": {"type": "MultiPolygon", "coordinates": [
+				[[[10, 53], [11, 53], [11, 54], [10, 53]], [[10.2, 53.2], [10.4, 53.2], [10.4, 53.4], [10.2, 53.2]]],
+				[[[12, 53], [13, 53], [13, 54], [12, 53]]]
+			]}}
+		]
+	}`
+	filePath := createTempFile(t, "stations.geojson", geoJSON)
+
+	reader := &GeoJSONReader{}
+	features, err := reader.ReadFeatures(filePath)
+	if err != nil {
+		t.Fatalf("ReadFeatures returned error: %v", err)
+	}
+	if len(features) != 2 {
+		t.Fatalf("Expected 2 features with geometries, got %d", len(features))
+	}
+
+	if features[0].Properties["name"] != "Hbf" || features[0].Properties["tracks"] != "16" {
+		t.Errorf("Expected properties as strings, got %v", features[0].Properties)
+	}
+	if features[0].Source.File != "stations.geojson" {
+		t.Errorf("Expected source file 'stations.geojson', got '%s'", features[0].Source.File)
+	}
+
+	polygons := features[1].Geometries
+	if len(polygons) != 2 {
+		t.Fatalf("Expected 2 polygons, got %d", len(polygons))
+	}
+	if len(polygons[0].Rings) != 2 || len(polygons[0].Rings[0]) != 3 {
+		t.Errorf("Expected an open outer ring and a hole, got %v", polygons[0].Rings)
+	}
+}
+
+func TestGeoJSONReader_ParseFile_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "malformed JSON", content: `{"type": "Point", "coordinates": [10.0`},
+		{name: "wrong coordinate nesting", content: `{"type": "LineString", "coordinates": [10.0, 53.0]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpFile := createTempFile(t, "invalid.geojson", tt.content)
+
+			reader := &GeoJSONReader{}
+			if _, err := reader.ParseFile(tmpFile); err == nil {
+				t.Error("Expected error for invalid GeoJSON, but got none")
+			}
+		})
+	}
+}
+
+func TestGeoJSONReader_ParseFile_NonExistentFile(t *testing.T) {
+	reader := &GeoJSONReader{}
+	_, err := reader.ParseFile("nonexistent.geojson")
+
+	if err == nil {
+		t.Error("Expected error for nonexistent file, but got none")
+	}
+}
@@ -0,0 +1,166 @@
+package geometry
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
+)
+
+type GPXReader struct {
+	Options
+}
+
+// gpxFile covers GPX 1.0 and 1.1. Element names are matched regardless of
+// namespace, so both versions decode into the same structure.
+type gpxFile struct {
+	XMLName   xml.Name   `xml:"gpx"`
+	Waypoints []gpxPoint `xml:"wpt"`
+	Routes    []gpxRoute `xml:"rte"`
+	Tracks    []gpxTrack `xml:"trk"`
+}
+
+type gpxPoint struct {
+	Lat         float64 `xml:"lat,attr"`
+	Lon         float64 `xml:"lon,attr"`
+	Elevation   string  `xml:"ele"`
+	Name        string  `xml:"name"`
+	Comment     string  `xml:"cmt"`
+	Description string  `xml:"desc"`
+	Symbol      string  `xml:"sym"`
+	Type        string  `xml:"type"`
+}
+
+type gpxRoute struct {
+	Name        string     `xml:"name"`
+	Comment     string     `xml:"cmt"`
+	Description string     `xml:"desc"`
+	Number      string     `xml:"number"`
+	Type        string     `xml:"type"`
+	Points      []gpxPoint `xml:"rtept"`
+}
+
+type gpxTrack struct {
+	Name        string       `xml:"name"`
+	Comment     string       `xml:"cmt"`
+	Description string       `xml:"desc"`
+	Number      string       `xml:"number"`
+	Type        string       `xml:"type"`
+	Segments    []gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+func (g *GPXReader) ParseFile(filePath string) (*poi.List, error) {
+	return g.ParseFileWithConfig(filePath, DefaultMaxLod)
+}
+
+func (g *GPXReader) ParseFileWithConfig(filePath string, maxLod int32) (*poi.List, error) {
+	return g.ParseFileWithFullConfig(filePath, maxLod, defaultColor)
+}
+
+func (g *GPXReader) ParseFileWithFullConfig(filePath string, maxLod int32, color string) (*poi.List, error) {
+	features, err := g.ReadFeatures(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return g.convert(features, maxLod, color)
+}
+
+// ReadFeatures returns a point feature per waypoint, a line per route and a
+// feature per track with each track segment as its own line
+func (g *GPXReader) ReadFeatures(filePath string) ([]Feature, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var gpx gpxFile
+	if err := xml.Unmarshal(data, &gpx); err != nil {
+		return nil, fmt.Errorf("invalid GPX: %w", err)
+	}
+
+	features := make([]Feature, 0, len(gpx.Waypoints)+len(gpx.Routes)+len(gpx.Tracks))
+	source := func() Source {
+		return Source{File: filepath.Base(filePath), Index: len(features)}
+	}
+
+	for _, waypoint := range gpx.Waypoints {
+		features = append(features, Feature{
+			Geometries: []Geometry{NewPoint(waypoint.Lon, waypoint.Lat)},
+			Properties: gpxProperties(map[string]string{
+				"name": waypoint.Name,
+				"cmt":  waypoint.Comment,
+				"desc": waypoint.Description,
+				"sym":  waypoint.Symbol,
+				"type": waypoint.Type,
+				"ele":  waypoint.Elevation,
+			}),
+			Name:   waypoint.Name,
+			Source: source(),
+		})
+	}
+
+	for _, route := range gpx.Routes {
+		features = append(features, Feature{
+			Geometries: []Geometry{NewLineString(gpxCoordinates(route.Points))},
+			Properties: gpxProperties(map[string]string{
+				"name":   route.Name,
+				"cmt":    route.Comment,
+				"desc":   route.Description,
+				"number": route.Number,
+				"type":   route.Type,
+			}),
+			Name:   route.Name,
+			Source: source(),
+		})
+	}
+
+	for _, track := range gpx.Tracks {
+		// Segments are separate lines so interpolation never bridges a gap in
+		// the recording, such as a tunnel without GPS reception
+		geometries := make([]Geometry, 0, len(track.Segments))
+		for _, segment := range track.Segments {
+			geometries = append(geometries, NewLineString(gpxCoordinates(segment.Points)))
+		}
+
+		features = append(features, Feature{
+			Geometries: geometries,
+			Properties: gpxProperties(map[string]string{
+				"name":   track.Name,
+				"cmt":    track.Comment,
+				"desc":   track.Description,
+				"number": track.Number,
+				"type":   track.Type,
+			}),
+			Name:   track.Name,
+			Source: source(),
+		})
+	}
+
+	return features, nil
+}
+
+func gpxCoordinates(points []gpxPoint) []Coordinate {
+	coords := make([]Coordinate, len(points))
+	for i, point := range points {
+		coords[i] = Coordinate{Lon: point.Lon, Lat: point.Lat}
+	}
+	return coords
+}
+
+// gpxProperties drops empty elements and surrounding whitespace
+func gpxProperties(elements map[string]string) map[string]string {
+	properties := make(map[string]string, len(elements))
+	for name, value := range elements {
+		if value = strings.TrimSpace(value); value != "" {
+			properties[name] = value
+		}
+	}
+	return properties
+}
@@ -0,0 +1,143 @@
+package geometry
+
+import (
+	"testing"
+)
+
+const gpx11 = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="Logger" xmlns="http://www.topografix.com/GPX/1/1">
+	<wpt lat="52.5251" lon="13.3694">
+		<ele>34.5</ele>
+		<name>Berlin Hbf</name>
+		<sym>Train Station</sym>
+	</wpt>
+	<wpt lat="52.5200" lon="13.3880">
+		<name>Friedrichstraße</name>
+	</wpt>
+	<rte>
+		<name>Planned</name>
+		<rtept lat="52.5251" lon="13.3694"><name>Start</name></rtept>
+		<rtept lat="52.5200" lon="13.3880"><name>End</name></rtept>
+	</rte>
+	<trk>
+		<name>S5 ride</name>
+		<type>rail</type>
+		<trkseg>
+			<trkpt lat="52.5251" lon="13.3694"><time>2024-05-01T08:00:00Z</time></trkpt>
+			<trkpt lat="52.5230" lon="13.3780"><time>2024-05-01T08:01:00Z</time></trkpt>
+			<trkpt lat="52.5200" lon="13.3880"><time>2024-05-01T08:02:00Z</time></trkpt>
+		</trkseg>
+		<trkseg>
+			<trkpt lat="52.5100" lon="13.4200"/>
+			<trkpt lat="52.5080" lon="13.4350"/>
+		</trkseg>
+	</trk>
+</gpx>`
+
+func TestGPXReader_ParseFile(t *testing.T) {
+	filePath := createTempFile(t, "ride.gpx", gpx11)
+
+	reader := &GPXReader{}
+	poiList, err := reader.ParseFile(filePath)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	// 2 waypoints + 2 route points + 3 and 2 track points
+	if len(*poiList) != 2+2+3+2 {
+		t.Fatalf("Expected 9 POIs, got %d", len(*poiList))
+	}
+
+	first := (*poiList)[0]
+	if first.Lon != 13.3694 || first.Lat != 52.5251 {
+		t.Errorf("Expected first waypoint at (13.3694, 52.5251), got (%f, %f)", first.Lon, first.Lat)
+	}
+
+	expectedTexts := []string{"Berlin Hbf", "Friedrichstraße", "Planned", "", "S5 ride", "", "", "S5 ride", ""}
+	for i, expected := range expectedTexts {
+		if (*poiList)[i].Text != expected {
+			t.Errorf("POI %d: Expected text '%s', got '%s'", i, expected, (*poiList)[i].Text)
+		}
+	}
+}
+
+func TestGPXReader_ParseFile_GPX10(t *testing.T) {
+	gpx10 := `<?xml version="1.0"?>
+<gpx version="1.0" creator="Old Logger" xmlns="http://www.topografix.com/GPX/1/0">
+	<wpt lat="48.1403" lon="11.5600"><name>München Hbf</name></wpt>
+	<trk><trkseg>
+		<trkpt lat="48.1403" lon="11.5600"/>
+		<trkpt lat="48.1500" lon="11.5800"/>
+	</trkseg></trk>
+</gpx>`
+	filePath := createTempFile(t, "old.gpx", gpx10)
+
+	reader := &GPXReader{}
+	poiList, err := reader.ParseFile(filePath)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	if len(*poiList) != 3 {
+		t.Fatalf("Expected 3 POIs, got %d", len(*poiList))
+	}
+	if (*poiList)[0].Text != "München Hbf" {
+		t.Errorf("Expected waypoint name as text, got '%s'", (*poiList)[0].Text)
+	}
+}
+
+func TestGPXReader_ParseFile_SegmentsInterpolatedSeparately(t *testing.T) {
+	filePath := createTempFile(t, "ride.gpx", gpx11)
+
+	reader := &GPXReader{Options: Options{InterpolateDistance: 200}}
+	poiList, err := reader.ParseFile(filePath)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	// The gap of about 2.5 km between the segments must not be filled in
+	for i := 1; i < len(*poiList); i++ {
+		previous, current := (*poiList)[i-1], (*poiList)[i]
+		if previous.Lon == 13.3880 && previous.Lat == 52.5200 && current.Lon > 13.3880 && current.Lon < 13.4200 {
+			t.Errorf("Expected no POIs between track segments, found (%f, %f)", current.Lon, current.Lat)
+		}
+	}
+}
+
+func TestGPXReader_ReadFeatures(t *testing.T) {
+	filePath := createTempFile(t, "ride.gpx", gpx11)
+
+	reader := &GPXReader{}
+	features, err := reader.ReadFeatures(filePath)
+	if err != nil {
+		t.Fatalf("ReadFeatures returned error: %v", err)
+	}
+	if len(features) != 4 {
+		t.Fatalf("Expected 2 waypoints, a route and a track, got %d features", len(features))
+	}
+
+	waypoint := features[0]
+	if waypoint.Properties["sym"] != "Train Station" || waypoint.Properties["ele"] != "34.5" {
+		t.Errorf("Expected waypoint elements as properties, got %v", waypoint.Properties)
+	}
+	if _, ok := waypoint.Properties["desc"]; ok {
+		t.Error("Expected empty elements to be left out of the properties")
+	}
+
+	track := features[3]
+	if len(track.Geometries) != 2 || track.Properties["type"] != "rail" || track.Source.File != "ride.gpx" {
+		t.Errorf("Expected a track with 2 segments, got %+v", track)
+	}
+}
+
+func TestGPXReader_ParseFile_Invalid(t *testing.T) {
+	filePath := createTempFile(t, "broken.gpx", `<gpx><wpt lat="52" lon="13">`)
+
+	reader := &GPXReader{}
+	if _, err := reader.ParseFile(filePath); err == nil {
+		t.Error("Expected error for invalid GPX")
+	}
+
+	if _, err := reader.ParseFile("nonexistent.gpx"); err == nil {
+		t.Error("Expected error for nonexistent file")
+	}
+}
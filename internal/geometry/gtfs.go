@@ -0,0 +1,324 @@
+package geometry
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
+)
+
+// gtfsStopTypes names the GTFS location_type values, which are kept in the
+// stop_type attribute for labels and styling rules. Generic nodes (3) and
+// boarding areas (4) only describe the inside of stations and are skipped.
+var gtfsStopTypes = map[string]string{
+	"":  "stop",
+	"0": "stop",
+	"1": "station",
+	"2": "entrance",
+}
+
+// gtfsRouteColumns are copied from routes.txt onto the shapes the route's trips follow
+var gtfsRouteColumns = []string{"route_id", "agency_id", "route_short_name", "route_long_name", "route_type", "route_color", "route_text_color"}
+
+// GTFSReader reads GTFS feeds (.zip) as published by transit operators. Stops
+// become points labelled with their stop_name, and shapes become lines named
+// after the route whose trips follow them.
+type GTFSReader struct {
+	Options
+}
+
+// gtfsFeed holds the files of a GTFS archive, keyed by their base name
+type gtfsFeed map[string]*zip.File
+
+// gtfsShapePoint is a row of shapes.txt
+type gtfsShapePoint struct {
+	sequence int
+	coord    Coordinate
+}
+
+func (g *GTFSReader) ParseFile(filePath string) (*poi.List, error) {
+	return g.ParseFileWithConfig(filePath, DefaultMaxLod)
+}
+
+func (g *GTFSReader) ParseFileWithConfig(filePath string, maxLod int32) (*poi.List, error) {
+	return g.ParseFileWithFullConfig(filePath, maxLod, defaultColor)
+}
+
+func (g *GTFSReader) ParseFileWithFullConfig(filePath string, maxLod int32, color string) (*poi.List, error) {
+	features, err := g.ReadFeatures(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return g.convert(features, maxLod, color)
+}
+
+// ReadFeatures returns the stops of stops.txt followed by a line per shape of
+// shapes.txt. A platform (location_type 0 with a parent_station) has the
+// stop_type "platform", so rules can tell them apart from stations. Shapes
+// carry the routes.txt columns of the first trip using them, and their
+// route_color as file style.
+func (g *GTFSReader) ReadFeatures(filePath string) ([]Feature, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GTFS feed: %w", err)
+	}
+	defer archive.Close()
+
+	feed := newGTFSFeed(archive.File)
+	if !feed.hasGeometry() {
+		return nil, errors.New("not a GTFS feed: no stops.txt or shapes.txt found")
+	}
+
+	fileName := filepath.Base(filePath)
+	features := make([]Feature, 0)
+
+	stops, err := feed.readStops(fileName)
+	if err != nil {
+		return nil, err
+	}
+	features = append(features, stops...)
+
+	shapes, err := feed.readShapes(fileName)
+	if err != nil {
+		return nil, err
+	}
+	features = append(features, shapes...)
+
+	for i := range features {
+		features[i].Source.Index = i
+	}
+	return features, nil
+}
+
+// isGTFSFeed reports whether the zip archive at filePath is a GTFS feed, so
+// that zipped shapefiles or KML files are not mistaken for one
+func isGTFSFeed(filePath string) (bool, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to open zip archive: %w", err)
+	}
+	defer archive.Close()
+	return newGTFSFeed(archive.File).hasGeometry(), nil
+}
+
+// newGTFSFeed indexes the files of a GTFS archive by their lowercased base name
+func newGTFSFeed(files []*zip.File) gtfsFeed {
+	feed := make(gtfsFeed)
+	for _, file := range files {
+		if file.FileInfo().IsDir() || strings.HasPrefix(file.Name, "__MACOSX/") {
+			continue
+		}
+		// Some feeds are zipped with their containing folder
+		name := strings.ToLower(path.Base(file.Name))
+		if existing, ok := feed[name]; !ok || strings.Count(file.Name, "/") < strings.Count(existing.Name, "/") {
+			feed[name] = file
+		}
+	}
+	return feed
+}
+
+// hasGeometry reports whether the feed has stops or shapes to read
+func (f gtfsFeed) hasGeometry() bool {
+	return f["stops.txt"] != nil || f["shapes.txt"] != nil
+}
+
+func (f gtfsFeed) readStops(fileName string) ([]Feature, error) {
+	features := make([]Feature, 0)
+	err := f.readTable("stops.txt", []string{"stop_lat", "stop_lon"}, func(row map[string]string, line int) {
+		stopType, ok := gtfsStopTypes[row["location_type"]]
+		if !ok {
+			return
+		}
+		if stopType == "stop" && row["parent_station"] != "" {
+			stopType = "platform"
+		}
+
+		lat, latErr := strconv.ParseFloat(row["stop_lat"], 64)
+		lon, lonErr := strconv.ParseFloat(row["stop_lon"], 64)
+		if latErr != nil || lonErr != nil {
+			log.Printf("Skipped stop %q on line %d of stops.txt in %s: invalid coordinates", row["stop_id"], line, fileName)
+			return
+		}
+
+		row["stop_type"] = stopType
+		features = append(features, Feature{
+			Geometries: []Geometry{NewPoint(lon, lat)},
+			Properties: row,
+			Name:       row["stop_name"],
+			Source:     Source{File: fileName},
+		})
+	})
+	return features, err
+}
+
+func (f gtfsFeed) readShapes(fileName string) ([]Feature, error) {
+	var order []string
+	points := make(map[string][]gtfsShapePoint)
+
+	err := f.readTable("shapes.txt", []string{"shape_id", "shape_pt_lat", "shape_pt_lon", "shape_pt_sequence"}, func(row map[string]string, line int) {
+		lat, latErr := strconv.ParseFloat(row["shape_pt_lat"], 64)
+		lon, lonErr := strconv.ParseFloat(row["shape_pt_lon"], 64)
+		sequence, seqErr := strconv.Atoi(row["shape_pt_sequence"])
+		if latErr != nil || lonErr != nil || seqErr != nil {
+			log.Printf("Skipped line %d of shapes.txt in %s: invalid coordinates or sequence", line, fileName)
+			return
+		}
+
+		id := row["shape_id"]
+		if _, ok := points[id]; !ok {
+			order = append(order, id)
+		}
+		points[id] = append(points[id], gtfsShapePoint{sequence: sequence, coord: Coordinate{Lon: lon, Lat: lat}})
+	})
+	if err != nil || len(order) == 0 {
+		return nil, err
+	}
+
+	routes, err := f.shapeRoutes()
+	if err != nil {
+		return nil, err
+	}
+
+	features := make([]Feature, 0, len(order))
+	for _, id := range order {
+		shape := points[id]
+		if len(shape) < 2 {
+			continue
+		}
+		sort.SliceStable(shape, func(i, j int) bool { return shape[i].sequence < shape[j].sequence })
+
+		coords := make([]Coordinate, len(shape))
+		for i, point := range shape {
+			coords[i] = point.coord
+		}
+
+		properties := map[string]string{"shape_id": id}
+		for key, value := range routes[id] {
+			properties[key] = value
+		}
+
+		features = append(features, Feature{
+			Geometries: []Geometry{NewLineString(coords)},
+			Properties: properties,
+			Name:       firstAttribute(properties, "route_short_name", "route_long_name"),
+			Color:      gtfsColor(properties["route_color"]),
+			Source:     Source{File: fileName},
+		})
+	}
+	return features, nil
+}
+
+// shapeRoutes maps shape IDs to the routes.txt columns of the first trip that
+// follows the shape. Feeds without trips.txt or routes.txt have no routes.
+func (f gtfsFeed) shapeRoutes() (map[string]map[string]string, error) {
+	shapeRoutes := make(map[string]string)
+	err := f.readTable("trips.txt", []string{"route_id"}, func(row map[string]string, _ int) {
+		if shapeID := row["shape_id"]; shapeID != "" {
+			if _, ok := shapeRoutes[shapeID]; !ok {
+				shapeRoutes[shapeID] = row["route_id"]
+			}
+		}
+	})
+	if err != nil || len(shapeRoutes) == 0 {
+		return nil, err
+	}
+
+	routes := make(map[string]map[string]string)
+	err = f.readTable("routes.txt", []string{"route_id"}, func(row map[string]string, _ int) {
+		route := make(map[string]string, len(gtfsRouteColumns))
+		for _, column := range gtfsRouteColumns {
+			if value := row[column]; value != "" {
+				route[column] = value
+			}
+		}
+		routes[row["route_id"]] = route
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]map[string]string, len(shapeRoutes))
+	for shapeID, routeID := range shapeRoutes {
+		if route, ok := routes[routeID]; ok {
+			result[shapeID] = route
+		}
+	}
+	return result, nil
+}
+
+// readTable calls handle with every row of a GTFS file, keyed by column name.
+// Missing files are skipped, since most GTFS files are optional; a file that
+// lacks one of the required columns is an error. Lines are numbered from the
+// header as line 1.
+func (f gtfsFeed) readTable(name string, required []string, handle func(row map[string]string, line int)) error {
+	file := f[name]
+	if file == nil {
+		return nil
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer rc.Close()
+
+	reader := csv.NewReader(rc)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return fmt.Errorf("invalid header in %s: %w", name, err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff")))
+	}
+	for _, column := range required {
+		if columnIndex(header, column) < 0 {
+			return fmt.Errorf("%s has no %s column", name, column)
+		}
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s line %d: %w", name, line, err)
+		}
+
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				if value := strings.TrimSpace(record[i]); value != "" {
+					row[column] = value
+				}
+			}
+		}
+		handle(row, line)
+	}
+}
+
+// gtfsColor returns a route_color as rrggbb, or empty when it is not a valid color
+func gtfsColor(value string) string {
+	if len(value) != 6 {
+		return ""
+	}
+	if _, err := strconv.ParseUint(value, 16, 32); err != nil {
+		return ""
+	}
+	return strings.ToLower(value)
+}
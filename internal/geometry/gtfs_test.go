@@ -0,0 +1,174 @@
+package geometry
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testGTFS is a small feed with a station, two of its platforms, an entrance,
+// a boarding area and a standalone stop, and two shapes whose points are out
+// of order. Shape B has no trip.
+var testGTFS = map[string]string{
+	"feed/stops.txt": "\ufeffstop_id,stop_name,stop_lat,stop_lon,location_type,parent_station,platform_code\n" +
+		"S1,Hauptbahnhof,52.5251,13.3694,1,,\n" +
+		"P1,Hauptbahnhof,52.5250,13.3690,0,S1,1\n" +
+		"P2,Hauptbahnhof,52.5252,13.3698,,S1,2\n" +
+		"E1,Hauptbahnhof Nord,52.5260,13.3694,2,S1,\n" +
+		"B1,Boarding area,,,4,P1,\n" +
+		"T1,Dorfplatz,52.6000,13.5000,0,,\n",
+	"feed/shapes.txt": "shape_id,shape_pt_lat,shape_pt_lon,shape_pt_sequence\n" +
+		"A,52.52,13.40,2\n" +
+		"A,52.50,13.30,0\n" +
+		"B,48.10,11.50,1\n" +
+		"A,52.51,13.35,1\n" +
+		"B,48.20,11.60,2\n",
+	"feed/trips.txt": "route_id,service_id,trip_id,shape_id\n" +
+		"R1,daily,T100,A\n" +
+		"R2,daily,T200,A\n",
+	"feed/routes.txt": "route_id,route_short_name,route_long_name,route_type,route_color\n" +
+		"R1,S5,Westkreuz - Strausberg,109,E2001A\n" +
+		"R2,S7,Potsdam - Ahrensfelde,109,7F5EA6\n",
+}
+
+func createTestGTFS(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	filePath := filepath.Join(t.TempDir(), "gtfs.zip")
+	file, err := os.Create(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	writer := zip.NewWriter(file)
+	for name, content := range files {
+		entry, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+func TestGTFSReader_ReadFeatures(t *testing.T) {
+	reader := &GTFSReader{}
+	features, err := reader.ReadFeatures(createTestGTFS(t, testGTFS))
+	if err != nil {
+		t.Fatalf("ReadFeatures returned error: %v", err)
+	}
+
+	// 5 stops without the boarding area, then the 2 shapes
+	if len(features) != 7 {
+		t.Fatalf("Expected 7 features, got %d", len(features))
+	}
+
+	expectedTypes := []string{"station", "platform", "platform", "entrance", "stop"}
+	for i, expected := range expectedTypes {
+		if got := features[i].Properties["stop_type"]; got != expected {
+			t.Errorf("Stop %d: expected stop_type %q, got %q", i, expected, got)
+		}
+	}
+	if features[0].Name != "Hauptbahnhof" || features[1].Properties["platform_code"] != "1" {
+		t.Errorf("Expected stop name and columns, got %+v and %+v", features[0], features[1])
+	}
+
+	shapeA := features[5]
+	expectedCoords := []Coordinate{{Lon: 13.30, Lat: 52.50}, {Lon: 13.35, Lat: 52.51}, {Lon: 13.40, Lat: 52.52}}
+	coords := shapeA.Geometries[0].Coordinates
+	if len(coords) != len(expectedCoords) {
+		t.Fatalf("Expected %d shape points, got %d", len(expectedCoords), len(coords))
+	}
+	for i, expected := range expectedCoords {
+		if coords[i] != expected {
+			t.Errorf("Shape point %d: expected %v, got %v", i, expected, coords[i])
+		}
+	}
+
+	// The first trip following shape A belongs to R1
+	if shapeA.Name != "S5" || shapeA.Properties["route_id"] != "R1" || shapeA.Color != "e2001a" {
+		t.Errorf("Expected shape A to carry route R1, got name %q, properties %v, color %q", shapeA.Name, shapeA.Properties, shapeA.Color)
+	}
+
+	shapeB := features[6]
+	if shapeB.Properties["shape_id"] != "B" || shapeB.Name != "" || shapeB.Color != "" {
+		t.Errorf("Expected shape B without a route, got %+v", shapeB)
+	}
+}
+
+func TestGTFSReader_ParseFile_RouteColors(t *testing.T) {
+	reader := &GTFSReader{Options: Options{PreferFileStyles: true}}
+	poiList, err := reader.ParseFileWithFullConfig(createTestGTFS(t, testGTFS), 5, "808080")
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	// 5 stops, 3 points of shape A and 2 of shape B
+	if len(*poiList) != 10 {
+		t.Fatalf("Expected 10 POIs, got %d", len(*poiList))
+	}
+
+	expectedColors := []string{"808080", "808080", "808080", "808080", "808080", "e2001a", "e2001a", "e2001a", "808080", "808080"}
+	for i, expected := range expectedColors {
+		if (*poiList)[i].Color != expected {
+			t.Errorf("POI %d: expected color %s, got %s", i, expected, (*poiList)[i].Color)
+		}
+	}
+	if (*poiList)[5].Text != "S5" || (*poiList)[6].Text != "" {
+		t.Errorf("Expected only the first shape point to be labelled, got %q and %q", (*poiList)[5].Text, (*poiList)[6].Text)
+	}
+}
+
+func TestGTFSReader_StopsOnly(t *testing.T) {
+	reader := &GTFSReader{}
+	features, err := reader.ReadFeatures(createTestGTFS(t, map[string]string{
+		"stops.txt": "stop_id,stop_name,stop_lat,stop_lon\nA,Alpha,1.5,2.5\nB,Broken,north,2.5\n",
+	}))
+	if err != nil {
+		t.Fatalf("ReadFeatures returned error: %v", err)
+	}
+	if len(features) != 1 || features[0].Name != "Alpha" || features[0].Properties["stop_type"] != "stop" {
+		t.Errorf("Expected only stop Alpha, got %+v", features)
+	}
+}
+
+func TestGTFSReader_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{
+			name:  "not a GTFS feed",
+			files: map[string]string{"readme.txt": "hello"},
+		},
+		{
+			name:  "stops without coordinates",
+			files: map[string]string{"stops.txt": "stop_id,stop_name\nA,Alpha\n"},
+		},
+		{
+			name:  "shapes without sequence",
+			files: map[string]string{"shapes.txt": "shape_id,shape_pt_lat,shape_pt_lon\nA,1,2\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &GTFSReader{}
+			if _, err := reader.ReadFeatures(createTestGTFS(t, tt.files)); err == nil {
+				t.Error("Expected error, got none")
+			}
+		})
+	}
+
+	reader := &GTFSReader{}
+	if _, err := reader.ReadFeatures(createTempFile(t, "broken.zip", "not a zip")); err == nil {
+		t.Error("Expected error for a file that is not a zip archive")
+	}
+}
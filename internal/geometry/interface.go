@@ -6,17 +6,84 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/supermanifolds/nimby_shapetopoi/internal/osm"
 	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/rules"
+	"github.com/supermanifolds/nimby_shapetopoi/pkg/kml"
 )
 
 const (
 	defaultColor      = "0000ff"
 	defaultFontSize   = 12
-	defaultMaxLod     = 10
 	defaultDemand     = ""
 	defaultPopulation = 0
 )
 
+// DefaultMaxLod is the zoom level POIs stay visible up to when none is configured
+const DefaultMaxLod = 10
+
+// Options configures how a Reader turns source geometries into POIs
+type Options struct {
+	// InterpolateDistance adds extra points along lines whose segments are longer than this (meters)
+	InterpolateDistance float64
+	// ResampleDistance places POIs exactly this far apart along lines (meters),
+	// replacing their vertices. Takes precedence over InterpolateDistance.
+	ResampleDistance float64
+	// ResampleKeepVertices keeps the original vertices alongside the resampled POIs
+	ResampleKeepVertices bool
+	// SimplifyTolerance thins out lines before interpolation, dropping vertices
+	// that change the line by less than this distance (meters)
+	SimplifyTolerance float64
+	// Simplify selects the simplification algorithm; empty means Douglas-Peucker
+	Simplify SimplifyMethod
+	// PolygonMode selects whether polygons become their outlines, a single
+	// labelled POI inside them, or both; empty means the outline
+	PolygonMode PolygonMode
+	// FillSpacing covers the inside of polygons with POIs this far apart
+	// (meters), leaving out their holes. Zero draws only the outlines.
+	FillSpacing float64
+	// Fill selects the fill pattern; empty means a grid
+	Fill FillMethod
+	// LabelField names the attribute used as POI text; see resolveLabel for the fallbacks
+	LabelField string
+	// SourceCRS overrides the coordinate system of shapefiles, e.g. "EPSG:2180".
+	// When empty the .prj sidecar is used, or WGS84 if there is none. CSV and
+	// TSV coordinates are also read in this system.
+	SourceCRS string
+	// LonColumn and LatColumn name the CSV/TSV coordinate columns; when empty
+	// they are detected from names such as lon/lat, lng/lat and x/y
+	LonColumn string
+	LatColumn string
+	// WKTColumn names a CSV/TSV column holding WKT geometries, used instead of
+	// coordinate columns. When empty a column named wkt or geometry is used.
+	WKTColumn string
+	// OSMFilter selects the OpenStreetMap elements that become features by their
+	// tags; nil keeps every tagged element
+	OSMFilter *osm.Filter
+	// Folders selects features by the KML folders holding them; nil keeps
+	// every feature
+	Folders *FolderFilter
+	// Where selects features by an expression over their attributes and
+	// geometry; nil keeps every feature
+	Where *FeatureFilter
+	// Clip restricts the POIs to an area, cutting lines where they cross its
+	// boundary; nil keeps everything
+	Clip *ClipArea
+	// KMLLinks limits the files on disk that KML NetworkLinks are followed
+	// to; the zero value follows links anywhere
+	KMLLinks kml.LinkScope
+	// PreferFileStyles colors POIs from the input file's own styles (KML
+	// IconStyle/LineStyle/PolyStyle) instead of the configured color, which is
+	// still used for features without a style
+	PreferFileStyles bool
+	// Rules sets POI fields from feature attributes; matching rules override the
+	// configured color and max LOD as well as file styles
+	Rules *rules.RuleSet
+	// Converter turns the features a reader emits into POIs; nil uses a
+	// POIConverter configured with these options
+	Converter Converter
+}
+
 type Reader interface {
 	ParseFile(filePath string) (*poi.List, error)
 	ParseFileWithConfig(filePath string, maxLod int32) (*poi.List, error)
@@ -47,13 +114,48 @@ func GetReader(filePath string) (Reader, error) {
 }
 
 func GetReaderWithInterpolation(filePath string, interpolateDistance float64) (Reader, error) {
+	return GetReaderWithOptions(filePath, Options{InterpolateDistance: interpolateDistance})
+}
+
+func GetReaderWithOptions(filePath string, opts Options) (Reader, error) {
 	ext := strings.ToLower(filepath.Ext(filePath))
 	switch ext {
 	case ".shp":
-		return &ShapefileReader{InterpolateDistance: interpolateDistance}, nil
+		return &ShapefileReader{Options: opts}, nil
 	case ".kml", ".kmz":
-		return &KMLReader{InterpolateDistance: interpolateDistance}, nil
+		return &KMLReader{Options: opts}, nil
+	case ".geojson", ".json":
+		return &GeoJSONReader{Options: opts}, nil
+	case ".gpx":
+		return &GPXReader{Options: opts}, nil
+	case ".csv", ".tsv":
+		return &DelimitedTextReader{Options: opts}, nil
+	case ".osm", ".pbf":
+		return &OSMReader{Options: opts}, nil
+	case ".zip":
+		// Only GTFS feeds are read from zip archives; zipped shapefiles or
+		// KML files are reported as unsupported rather than as broken feeds
+		ok, err := isGTFSFeed(filePath)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("unsupported file format: %s (not a GTFS feed: no stops.txt or shapes.txt found)", ext)
+		}
+		return &GTFSReader{Options: opts}, nil
 	default:
 		return nil, fmt.Errorf("unsupported file format: %s", ext)
 	}
 }
+
+// newPOITemplate returns the POI that every vertex of a feature is copied from
+func newPOITemplate(maxLod int32, color string) poi.POI {
+	return poi.POI{
+		Color:       color,
+		FontSize:    defaultFontSize,
+		MaxLod:      maxLod,
+		Transparent: false,
+		Demand:      defaultDemand,
+		Population:  defaultPopulation,
+	}
+}
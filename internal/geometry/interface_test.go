@@ -1,6 +1,7 @@
 package geometry
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -41,6 +42,48 @@ func TestGetReader(t *testing.T) {
 			expectedType: "*geometry.ShapefileReader",
 			expectError:  false,
 		},
+		{
+			name:         "GeoJSON file",
+			filePath:     "test.geojson",
+			expectedType: "*geometry.GeoJSONReader",
+			expectError:  false,
+		},
+		{
+			name:         "JSON file",
+			filePath:     "test.json",
+			expectedType: "*geometry.GeoJSONReader",
+			expectError:  false,
+		},
+		{
+			name:         "GPX file",
+			filePath:     "ride.gpx",
+			expectedType: "*geometry.GPXReader",
+			expectError:  false,
+		},
+		{
+			name:         "CSV file",
+			filePath:     "stations.csv",
+			expectedType: "*geometry.DelimitedTextReader",
+			expectError:  false,
+		},
+		{
+			name:         "TSV file",
+			filePath:     "stations.tsv",
+			expectedType: "*geometry.DelimitedTextReader",
+			expectError:  false,
+		},
+		{
+			name:         "OSM file",
+			filePath:     "extract.osm",
+			expectedType: "*geometry.OSMReader",
+			expectError:  false,
+		},
+		{
+			name:         "OSM PBF file",
+			filePath:     "germany-latest.osm.pbf",
+			expectedType: "*geometry.OSMReader",
+			expectError:  false,
+		},
 		{
 			name:        "unsupported extension",
 			filePath:    "test.txt",
@@ -98,12 +141,45 @@ func TestGetReader(t *testing.T) {
 	}
 }
 
+func TestGetReader_Zip(t *testing.T) {
+	reader, err := GetReader(createTestGTFS(t, testGTFS))
+	if err != nil {
+		t.Fatalf("Unexpected error for GTFS feed: %v", err)
+	}
+	if getTypeName(reader) != "*geometry.GTFSReader" {
+		t.Errorf("Expected *geometry.GTFSReader, got %s", getTypeName(reader))
+	}
+
+	// Zipped shapefiles are not GTFS feeds
+	reader, err = GetReader(createTestGTFS(t, map[string]string{"rail.shp": "", "rail.dbf": ""}))
+	if err == nil || !strings.Contains(err.Error(), "unsupported file format") {
+		t.Errorf("Expected unsupported format error for zipped shapefile, got %v", err)
+	}
+	if reader != nil {
+		t.Errorf("Expected nil reader for zipped shapefile, got %T", reader)
+	}
+
+	if _, err := GetReader(createTempFile(t, "broken.zip", "not a zip")); err == nil {
+		t.Error("Expected error for a file that is not a zip archive")
+	}
+}
+
 func getTypeName(reader Reader) string {
 	switch reader.(type) {
 	case *ShapefileReader:
 		return "*geometry.ShapefileReader"
 	case *KMLReader:
 		return "*geometry.KMLReader"
+	case *GeoJSONReader:
+		return "*geometry.GeoJSONReader"
+	case *GPXReader:
+		return "*geometry.GPXReader"
+	case *DelimitedTextReader:
+		return "*geometry.DelimitedTextReader"
+	case *OSMReader:
+		return "*geometry.OSMReader"
+	case *GTFSReader:
+		return "*geometry.GTFSReader"
 	default:
 		return "unknown"
 	}
@@ -113,4 +189,9 @@ func TestReader_Interface(_ *testing.T) {
 	// Test that our readers implement the Reader interface
 	var _ Reader = &ShapefileReader{}
 	var _ Reader = &KMLReader{}
+	var _ Reader = &GeoJSONReader{}
+	var _ Reader = &GPXReader{}
+	var _ Reader = &DelimitedTextReader{}
+	var _ Reader = &OSMReader{}
+	var _ Reader = &GTFSReader{}
 }
@@ -1,177 +1,313 @@
 package geometry
 
 import (
+	"path/filepath"
+
 	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
 	"github.com/supermanifolds/nimby_shapetopoi/pkg/kml"
 )
 
 type KMLReader struct {
-	InterpolateDistance float64
+	Options
 }
 
 func (k *KMLReader) ParseFile(filePath string) (*poi.List, error) {
-	return k.ParseFileWithConfig(filePath, defaultMaxLod)
+	return k.ParseFileWithConfig(filePath, DefaultMaxLod)
 }
 
 func (k *KMLReader) ParseFileWithConfig(filePath string, maxLod int32) (*poi.List, error) {
 	return k.ParseFileWithFullConfig(filePath, maxLod, defaultColor)
 }
 
+// ParseFileWithFullConfig converts each placemark as it is read, so that only
+// the POIs are held in memory rather than the features of the whole file.
 func (k *KMLReader) ParseFileWithFullConfig(filePath string, maxLod int32, color string) (*poi.List, error) {
-	kmlData, err := kml.ParseFile(filePath)
+	fileName := filepath.Base(filePath)
+	c := k.newConversion(maxLod, color)
+
+	// Shared styles may come after the placemarks using them. The POIs of
+	// placemarks whose color is not known yet are left without one, and the
+	// range of the list they take up is kept along with their style references
+	// until the whole file has been read.
+	type pendingStyle struct {
+		placemark  kml.Placemark
+		styles     *kml.StyleSheet
+		start, end int
+	}
+	var pending []pendingStyle
+	index := 0
+	err := kml.StreamFileWithLinks(filePath, k.KMLLinks, func(placemark *kml.Placemark, folders []string, styles *kml.StyleSheet) error {
+		feature := k.placemarkFeature(placemark, folders, fileName, index)
+		index++
+
+		feature.Color = placemarkColor(styles, placemark)
+		base := c.base
+		unresolved := k.PreferFileStyles && feature.Color == "" && placemark.StyleURL != ""
+		if unresolved {
+			base.Color = ""
+		}
+		start := len(c.poiList)
+		if err := c.add(&feature, base); err != nil {
+			return err
+		}
+		if unresolved && len(c.poiList) > start {
+			pending = append(pending, pendingStyle{
+				placemark: styleReference(placemark),
+				styles:    styles,
+				start:     start,
+				end:       len(c.poiList),
+			})
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	poiList := make(poi.List, 0)
-
-	if kmlData.Document != nil {
-		placemarks := kmlData.Document.AllPlacemarks()
-		for _, placemark := range placemarks {
-			k.processPlacemark(&placemark, &poiList, maxLod, color)
+	for _, p := range pending {
+		resolved := placemarkColor(p.styles, &p.placemark)
+		if resolved == "" {
+			resolved = color
+		}
+		for i := p.start; i < p.end; i++ {
+			// Styling rules may have set the color in the meantime
+			if c.poiList[i].Color == "" {
+				c.poiList[i].Color = resolved
+			}
 		}
 	}
+	return &c.poiList, nil
+}
+
+// ReadFeatures returns a feature per placemark, with its folder path, ExtendedData and style color.
+// The placemarks of linked KML files follow NetworkLinks. Unlike ParseFileWithFullConfig it holds
+// every feature of the file in memory, which inspect and clip boundaries need.
+func (k *KMLReader) ReadFeatures(filePath string) ([]Feature, error) {
+	fileName := filepath.Base(filePath)
+	features := make([]Feature, 0)
 
-	return &poiList, nil
+	// Shared styles may come after the placemarks using them, so colors are
+	// resolved once the whole file has been read. Only the style references
+	// of each placemark are kept until then.
+	var refs []kml.Placemark
+	var sheets []*kml.StyleSheet
+	err := kml.StreamFileWithLinks(filePath, k.KMLLinks, func(placemark *kml.Placemark, folders []string, styles *kml.StyleSheet) error {
+		features = append(features, k.placemarkFeature(placemark, folders, fileName, len(features)))
+		refs = append(refs, styleReference(placemark))
+		sheets = append(sheets, styles)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range features {
+		features[i].Color = placemarkColor(sheets[i], &refs[i])
+	}
+	return features, nil
 }
 
-func (k *KMLReader) processPlacemark(placemark *kml.Placemark, poiList *poi.List, maxLod int32, color string) {
+// placemarkFeature returns the feature of a placemark, without its color
+func (k *KMLReader) placemarkFeature(placemark *kml.Placemark, folders []string, fileName string, index int) Feature {
+	return Feature{
+		Geometries: k.placemarkGeometries(placemark),
+		Properties: placemark.Attributes(),
+		Name:       placemark.Name,
+		Source: Source{
+			File:    fileName,
+			Folders: append([]string(nil), folders...),
+			Index:   index,
+		},
+	}
+}
+
+// styleReference returns the parts of a placemark that placemarkColor needs:
+// its styles and whether it is a point
+func styleReference(placemark *kml.Placemark) kml.Placemark {
+	ref := kml.Placemark{StyleURL: placemark.StyleURL, Style: placemark.Style}
 	if placemark.Point != nil {
-		k.processPoint(placemark.Point, poiList, maxLod, color)
+		ref.Point = &kml.Point{}
+	}
+	return ref
+}
+
+func (k *KMLReader) placemarkGeometries(placemark *kml.Placemark) []Geometry {
+	var geometries []Geometry
+
+	if placemark.Point != nil {
+		geometries = append(geometries, k.processPoint(placemark.Point)...)
 	}
 	if placemark.LineString != nil {
-		k.processLineString(placemark.LineString, poiList, maxLod, color)
+		geometries = append(geometries, k.processLineString(placemark.LineString)...)
 	}
 	if placemark.LinearRing != nil {
-		k.processLinearRing(placemark.LinearRing, poiList, maxLod, color)
+		geometries = append(geometries, k.processLinearRing(placemark.LinearRing)...)
 	}
 	if placemark.Polygon != nil {
-		k.processPolygon(placemark.Polygon, poiList, maxLod, color)
+		geometries = append(geometries, k.processPolygon(placemark.Polygon)...)
 	}
 	if placemark.MultiGeometry != nil {
-		k.processMultiGeometry(placemark.MultiGeometry, poiList, maxLod, color)
+		geometries = append(geometries, k.processMultiGeometry(placemark.MultiGeometry)...)
+	}
+	if placemark.Track != nil {
+		geometries = append(geometries, k.processTrack(placemark.Track)...)
 	}
+	if placemark.MultiTrack != nil {
+		geometries = append(geometries, k.processMultiTrack(placemark.MultiTrack)...)
+	}
+
+	return geometries
 }
 
-func (k *KMLReader) processPoint(point *kml.Point, poiList *poi.List, maxLod int32, color string) {
-	coords, err := kml.ParseCoordinates(point.Coordinates)
-	if err != nil {
-		return
+// placemarkColor returns the color of the placemark's KML style, or "" when it
+// has none. Points use the icon color first; lines and polygons use the line
+// color, since their outlines become POIs.
+func placemarkColor(styles *kml.StyleSheet, placemark *kml.Placemark) string {
+	style := styles.PlacemarkStyle(placemark)
+	if style == nil {
+		return ""
 	}
 
-	for _, coord := range coords {
-		p := poi.POI{
-			Lon:         coord.Lon,
-			Lat:         coord.Lat,
-			Color:       color,
-			Text:        "",
-			FontSize:    defaultFontSize,
-			MaxLod:      maxLod,
-			Transparent: false,
-			Demand:      defaultDemand,
-			Population:  defaultPopulation,
+	var iconColor, lineColor, polyColor string
+	if style.IconStyle != nil {
+		iconColor = style.IconStyle.Color
+	}
+	if style.LineStyle != nil {
+		lineColor = style.LineStyle.Color
+	}
+	if style.PolyStyle != nil {
+		polyColor = style.PolyStyle.Color
+	}
+
+	candidates := []string{lineColor, polyColor, iconColor}
+	if placemark.Point != nil {
+		candidates = []string{iconColor, lineColor, polyColor}
+	}
+	for _, candidate := range candidates {
+		if rgb, ok := kml.ColorToRGB(candidate); ok {
+			return rgb
 		}
-		poiList.Add(p)
 	}
+	return ""
 }
 
-func (k *KMLReader) processLineString(lineString *kml.LineString, poiList *poi.List, maxLod int32, color string) {
-	coords, err := kml.ParseCoordinates(lineString.Coordinates)
+func (k *KMLReader) processPoint(point *kml.Point) []Geometry {
+	coords, err := kml.ParseCoordinates(point.Coordinates)
 	if err != nil {
-		return
+		return nil
 	}
 
-	// Create temporary list for this line string
-	tempList := make(poi.List, 0, len(coords))
+	geometries := make([]Geometry, 0, len(coords))
 	for _, coord := range coords {
-		p := poi.POI{
-			Lon:         coord.Lon,
-			Lat:         coord.Lat,
-			Color:       color,
-			Text:        "",
-			FontSize:    defaultFontSize,
-			MaxLod:      maxLod,
-			Transparent: false,
-			Demand:      defaultDemand,
-			Population:  defaultPopulation,
-		}
-		tempList = append(tempList, p)
+		geometries = append(geometries, NewPoint(coord.Lon, coord.Lat))
 	}
+	return geometries
+}
 
-	// Interpolate this line string if configured
-	if k.InterpolateDistance > 0 {
-		interpolated := tempList.InterpolateByDistance(k.InterpolateDistance)
-		tempList = *interpolated
+func (k *KMLReader) processLineString(lineString *kml.LineString) []Geometry {
+	coords, err := kml.ParseCoordinates(lineString.Coordinates)
+	if err != nil {
+		return nil
 	}
 
-	// Add all points (interpolated or not) to the main list
-	for _, p := range tempList {
-		poiList.Add(p)
-	}
+	return []Geometry{NewLineString(kmlCoordinates(coords))}
 }
 
-func (k *KMLReader) processLinearRing(linearRing *kml.LinearRing, poiList *poi.List, maxLod int32, color string) {
+// processLinearRing returns a standalone LinearRing as a polygon without holes
+func (k *KMLReader) processLinearRing(linearRing *kml.LinearRing) []Geometry {
 	coords, err := kml.ParseCoordinates(linearRing.Coordinates)
 	if err != nil {
-		return
+		return nil
 	}
 
-	// LinearRing is a closed geometry - remove the duplicate closing point to avoid
-	// interpolation creating unwanted lines back to the start
-	if len(coords) > 1 && coords[0].Lat == coords[len(coords)-1].Lat && coords[0].Lon == coords[len(coords)-1].Lon {
-		coords = coords[:len(coords)-1]
-	}
+	return []Geometry{NewPolygon(kmlCoordinates(coords))}
+}
 
-	// Create temporary list for this linear ring
-	tempList := make(poi.List, 0, len(coords))
-	for _, coord := range coords {
-		p := poi.POI{
-			Lon:         coord.Lon,
-			Lat:         coord.Lat,
-			Color:       color,
-			Text:        "",
-			FontSize:    defaultFontSize,
-			MaxLod:      maxLod,
-			Transparent: false,
-			Demand:      defaultDemand,
-			Population:  defaultPopulation,
-		}
-		tempList = append(tempList, p)
+// processPolygon returns a polygon with its innerBoundaryIs rings as holes.
+// Holes that cannot be parsed are left out.
+func (k *KMLReader) processPolygon(polygon *kml.Polygon) []Geometry {
+	if polygon.OuterBoundaryIs == nil || polygon.OuterBoundaryIs.LinearRing == nil {
+		return nil
 	}
-
-	// Interpolate this linear ring if configured
-	if k.InterpolateDistance > 0 {
-		interpolated := tempList.InterpolateByDistance(k.InterpolateDistance)
-		tempList = *interpolated
+	outer, err := kml.ParseCoordinates(polygon.OuterBoundaryIs.LinearRing.Coordinates)
+	if err != nil {
+		return nil
 	}
 
-	// Add all points (interpolated or not) to the main list
-	for _, p := range tempList {
-		poiList.Add(p)
+	rings := [][]Coordinate{kmlCoordinates(outer)}
+	for _, inner := range polygon.InnerBoundaryIs {
+		if inner.LinearRing == nil {
+			continue
+		}
+		if coords, err := kml.ParseCoordinates(inner.LinearRing.Coordinates); err == nil && len(coords) > 0 {
+			rings = append(rings, kmlCoordinates(coords))
+		}
 	}
+	return []Geometry{NewPolygon(rings...)}
 }
 
-func (k *KMLReader) processPolygon(polygon *kml.Polygon, poiList *poi.List, maxLod int32, color string) {
-	if polygon.OuterBoundaryIs != nil && polygon.OuterBoundaryIs.LinearRing != nil {
-		k.processLinearRing(polygon.OuterBoundaryIs.LinearRing, poiList, maxLod, color)
-	}
-}
+func (k *KMLReader) processMultiGeometry(multiGeometry *kml.MultiGeometry) []Geometry {
+	var geometries []Geometry
 
-func (k *KMLReader) processMultiGeometry(multiGeometry *kml.MultiGeometry, poiList *poi.List, maxLod int32, color string) {
 	for _, point := range multiGeometry.Points {
-		k.processPoint(&point, poiList, maxLod, color)
+		geometries = append(geometries, k.processPoint(&point)...)
 	}
 	for _, lineString := range multiGeometry.LineStrings {
-		k.processLineString(&lineString, poiList, maxLod, color)
+		geometries = append(geometries, k.processLineString(&lineString)...)
 	}
 	for _, linearRing := range multiGeometry.LinearRings {
-		k.processLinearRing(&linearRing, poiList, maxLod, color)
+		geometries = append(geometries, k.processLinearRing(&linearRing)...)
 	}
 	for _, polygon := range multiGeometry.Polygons {
-		k.processPolygon(&polygon, poiList, maxLod, color)
+		geometries = append(geometries, k.processPolygon(&polygon)...)
 	}
 	// Handle nested MultiGeometry
 	for _, nestedMultiGeometry := range multiGeometry.MultiGeometries {
-		k.processMultiGeometry(&nestedMultiGeometry, poiList, maxLod, color)
+		geometries = append(geometries, k.processMultiGeometry(&nestedMultiGeometry)...)
+	}
+	for _, track := range multiGeometry.Tracks {
+		geometries = append(geometries, k.processTrack(&track)...)
+	}
+	for _, multiTrack := range multiGeometry.MultiTracks {
+		geometries = append(geometries, k.processMultiTrack(&multiTrack)...)
+	}
+
+	return geometries
+}
+
+// processTrack returns a gx:Track as a line, like a LineString
+func (k *KMLReader) processTrack(track *kml.Track) []Geometry {
+	coords, err := track.Coordinates()
+	if err != nil || len(coords) == 0 {
+		return nil
+	}
+
+	return []Geometry{NewLineString(kmlCoordinates(coords))}
+}
+
+// processMultiTrack returns a line per track, or a single line when
+// gx:interpolate joins the tracks into one path
+func (k *KMLReader) processMultiTrack(multiTrack *kml.MultiTrack) []Geometry {
+	var geometries []Geometry
+	for _, track := range multiTrack.Tracks {
+		geometries = append(geometries, k.processTrack(&track)...)
+	}
+
+	if multiTrack.Interpolate != 1 || len(geometries) < 2 {
+		return geometries
+	}
+	var joined []Coordinate
+	for _, geometry := range geometries {
+		joined = append(joined, geometry.Coordinates...)
+	}
+	return []Geometry{NewLineString(joined)}
+}
+
+// kmlCoordinates drops the altitude of parsed KML coordinates
+func kmlCoordinates(coords []kml.Coordinate) []Coordinate {
+	result := make([]Coordinate, len(coords))
+	for i, coord := range coords {
+		result[i] = Coordinate{Lon: coord.Lon, Lat: coord.Lat}
 	}
+	return result
 }
@@ -3,7 +3,12 @@ package geometry
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/rules"
+	"github.com/supermanifolds/nimby_shapetopoi/pkg/kml"
 )
 
 func TestKMLReader_ParseFile_SimpleKML(t *testing.T) {
@@ -52,8 +57,8 @@ func TestKMLReader_ParseFile_SimpleKML(t *testing.T) {
 	if pointPOI.Lon != 10.123 || pointPOI.Lat != 53.456 {
 		t.Errorf("Expected point coordinates (10.123, 53.456), got (%f, %f)", pointPOI.Lon, pointPOI.Lat)
 	}
-	if pointPOI.Text != "" {
-		t.Errorf("Expected empty text, got '%s'", pointPOI.Text)
+	if pointPOI.Text != "Test Point" {
+		t.Errorf("Expected text 'Test Point', got '%s'", pointPOI.Text)
 	}
 	if pointPOI.Color != "0000ff" {
 		t.Errorf("Expected point color '0000ff', got '%s'", pointPOI.Color)
@@ -64,8 +69,15 @@ func TestKMLReader_ParseFile_SimpleKML(t *testing.T) {
 	if linePointPOI.Color != "0000ff" {
 		t.Errorf("Expected line point color '0000ff', got '%s'", linePointPOI.Color)
 	}
-	if linePointPOI.Text != "" {
-		t.Errorf("Expected empty text, got '%s'", linePointPOI.Text)
+	if linePointPOI.Text != "Test Line" {
+		t.Errorf("Expected text 'Test Line', got '%s'", linePointPOI.Text)
+	}
+
+	// Only the first vertex of a line is labelled
+	for i, p := range (*poiList)[2:] {
+		if p.Text != "" {
+			t.Errorf("Line POI %d: Expected empty text, got '%s'", i+1, p.Text)
+		}
 	}
 }
 
@@ -140,10 +152,11 @@ func TestKMLReader_ParseFile_MultiGeometry(t *testing.T) {
 		t.Errorf("Expected 3 POIs, got %d", len(*poiList))
 	}
 
-	// All should have the same text from placemark
+	// The point and the start of the line carry the placemark name
+	expectedTexts := []string{"Multi Test", "Multi Test", ""}
 	for i, p := range *poiList {
-		if p.Text != "" {
-			t.Errorf("POI %d: Expected empty text, got '%s'", i, p.Text)
+		if p.Text != expectedTexts[i] {
+			t.Errorf("POI %d: Expected text '%s', got '%s'", i, expectedTexts[i], p.Text)
 		}
 	}
 }
@@ -218,8 +231,64 @@ func TestKMLReader_ParseFile_ExtendedData(t *testing.T) {
 
 	// Should use the Label from ExtendedData instead of name
 	p := (*poiList)[0]
-	if p.Text != "" {
-		t.Errorf("Expected empty text, got '%s'", p.Text)
+	if p.Text != "Custom Label" {
+		t.Errorf("Expected text 'Custom Label', got '%s'", p.Text)
+	}
+}
+
+func TestKMLReader_ParseFile_LabelField(t *testing.T) {
+	kmlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+	<Placemark>
+		<name>Hamburg Hbf</name>
+		<ExtendedData>
+			<SchemaData schemaUrl="#stations">
+				<SimpleData name="ref">HH</SimpleData>
+			</SchemaData>
+		</ExtendedData>
+		<Point>
+			<coordinates>10.0,53.0,0</coordinates>
+		</Point>
+	</Placemark>
+	<Placemark>
+		<name>Altona</name>
+		<Point>
+			<coordinates>9.9,53.5,0</coordinates>
+		</Point>
+	</Placemark>
+</Document>
+</kml>`
+
+	tmpFile := createTempFile(t, "labels.kml", kmlContent)
+
+	tests := []struct {
+		name       string
+		labelField string
+		expected   []string
+	}{
+		{name: "default uses name", labelField: "", expected: []string{"Hamburg Hbf", "Altona"}},
+		{name: "configured field falls back to name", labelField: "REF", expected: []string{"HH", "Altona"}},
+		{name: "none disables labels", labelField: "none", expected: []string{"", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &KMLReader{Options: Options{LabelField: tt.labelField}}
+			poiList, err := reader.ParseFile(tmpFile)
+			if err != nil {
+				t.Fatalf("ParseFile returned error: %v", err)
+			}
+
+			if len(*poiList) != len(tt.expected) {
+				t.Fatalf("Expected %d POIs, got %d", len(tt.expected), len(*poiList))
+			}
+			for i, p := range *poiList {
+				if p.Text != tt.expected[i] {
+					t.Errorf("POI %d: Expected text '%s', got '%s'", i, tt.expected[i], p.Text)
+				}
+			}
+		})
 	}
 }
 
@@ -261,6 +330,528 @@ func TestKMLReader_ParseFile_Polygon(t *testing.T) {
 	}
 }
 
+func TestKMLReader_ParseFile_PolygonHoles(t *testing.T) {
+	kmlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+	<Placemark>
+		<name>Yard</name>
+		<Polygon>
+			<outerBoundaryIs>
+				<LinearRing>
+					<coordinates>10.0,53.0 11.0,53.0 11.0,54.0 10.0,54.0 10.0,53.0</coordinates>
+				</LinearRing>
+			</outerBoundaryIs>
+			<innerBoundaryIs>
+				<LinearRing>
+					<coordinates>10.2,53.2 10.4,53.2 10.4,53.4 10.2,53.2</coordinates>
+				</LinearRing>
+			</innerBoundaryIs>
+			<innerBoundaryIs>
+				<LinearRing>
+					<coordinates>10.6,53.6 10.8,53.6 10.8,53.8 10.6,53.8 10.6,53.6</coordinates>
+				</LinearRing>
+			</innerBoundaryIs>
+		</Polygon>
+	</Placemark>
+</Document>
+</kml>`
+
+	reader := &KMLReader{}
+	features, err := reader.ReadFeatures(createTempFile(t, "holes.kml", kmlContent))
+	if err != nil {
+		t.Fatalf("ReadFeatures returned error: %v", err)
+	}
+	if len(features) != 1 || len(features[0].Geometries) != 1 {
+		t.Fatalf("Expected a single polygon, got %+v", features)
+	}
+
+	rings := features[0].Geometries[0].Rings
+	if len(rings) != 3 {
+		t.Fatalf("Expected the outer ring and 2 holes, got %d rings", len(rings))
+	}
+	expectedLengths := []int{4, 3, 4}
+	for i, expected := range expectedLengths {
+		if len(rings[i]) != expected {
+			t.Errorf("Ring %d: expected %d vertices, got %d", i, expected, len(rings[i]))
+		}
+	}
+
+	poiList, err := reader.ParseFile(createTempFile(t, "holes.kml", kmlContent))
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	if len(*poiList) != 11 {
+		t.Errorf("Expected 11 POIs for the outline and holes, got %d", len(*poiList))
+	}
+}
+
+func TestKMLReader_ParseFile_Tracks(t *testing.T) {
+	kmlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2" xmlns:gx="http://www.google.com/kml/ext/2.2">
+<Document>
+	<Placemark>
+		<name>Ride</name>
+		<gx:Track>
+			<when>2024-05-01T08:00:00Z</when>
+			<when>2024-05-01T08:01:00Z</when>
+			<when>2024-05-01T08:02:00Z</when>
+			<gx:coord>13.3694 52.5251 34</gx:coord>
+			<gx:coord>13.3780 52.5230 35</gx:coord>
+			<gx:coord>13.3880 52.5200 36</gx:coord>
+		</gx:Track>
+	</Placemark>
+	<Placemark>
+		<name>Legs</name>
+		<gx:MultiTrack>
+			<gx:Track>
+				<gx:coord>10.0 53.0 0</gx:coord>
+				<gx:coord>10.1 53.1 0</gx:coord>
+			</gx:Track>
+			<gx:Track>
+				<gx:coord>10.2 53.2 0</gx:coord>
+				<gx:coord>10.3 53.3 0</gx:coord>
+			</gx:Track>
+		</gx:MultiTrack>
+	</Placemark>
+	<Placemark>
+		<name>Trip</name>
+		<gx:MultiTrack>
+			<gx:interpolate>1</gx:interpolate>
+			<gx:Track>
+				<gx:coord>11.0 54.0 0</gx:coord>
+				<gx:coord>11.1 54.1 0</gx:coord>
+			</gx:Track>
+			<gx:Track>
+				<gx:coord>11.2 54.2 0</gx:coord>
+			</gx:Track>
+		</gx:MultiTrack>
+	</Placemark>
+</Document>
+</kml>`
+
+	reader := &KMLReader{}
+	features, err := reader.ReadFeatures(createTempFile(t, "tracks.kml", kmlContent))
+	if err != nil {
+		t.Fatalf("ReadFeatures returned error: %v", err)
+	}
+	if len(features) != 3 {
+		t.Fatalf("Expected 3 features, got %d", len(features))
+	}
+
+	// Separate legs stay separate lines, interpolated ones are joined
+	expectedLines := [][]int{{3}, {2, 2}, {3}}
+	for i, expected := range expectedLines {
+		geometries := features[i].Geometries
+		if len(geometries) != len(expected) {
+			t.Errorf("Feature %d: expected %d lines, got %d", i, len(expected), len(geometries))
+			continue
+		}
+		for j, geometry := range geometries {
+			if geometry.Type != LineStringGeometry || len(geometry.Coordinates) != expected[j] {
+				t.Errorf("Feature %d line %d: expected a line of %d vertices, got %+v", i, j, expected[j], geometry)
+			}
+		}
+	}
+
+	poiList, err := reader.ParseFile(createTempFile(t, "tracks.kml", kmlContent))
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	if len(*poiList) != 3+4+3 {
+		t.Errorf("Expected 10 POIs, got %d", len(*poiList))
+	}
+	if (*poiList)[0].Text != "Ride" || (*poiList)[0].Lon != 13.3694 {
+		t.Errorf("Expected the track to start at its first gx:coord, got %+v", (*poiList)[0])
+	}
+}
+
+func TestKMLReader_ParseFile_FileStyles(t *testing.T) {
+	kmlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+	<Style id="icon-red">
+		<IconStyle><color>ff0000ff</color></IconStyle>
+	</Style>
+	<Style id="line-green-normal">
+		<IconStyle><color>ffffffff</color></IconStyle>
+		<LineStyle><color>ff00ff00</color></LineStyle>
+	</Style>
+	<StyleMap id="line-green">
+		<Pair><key>normal</key><styleUrl>#line-green-normal</styleUrl></Pair>
+		<Pair><key>highlight</key><styleUrl>#icon-red</styleUrl></Pair>
+	</StyleMap>
+	<Placemark>
+		<name>Station</name>
+		<styleUrl>#icon-red</styleUrl>
+		<Point><coordinates>10.0,53.0,0</coordinates></Point>
+	</Placemark>
+	<Placemark>
+		<name>Route</name>
+		<styleUrl>#line-green</styleUrl>
+		<LineString><coordinates>10.0,53.0,0 10.1,53.1,0</coordinates></LineString>
+	</Placemark>
+	<Placemark>
+		<name>Inline</name>
+		<Style><PolyStyle><color>80ff0000</color></PolyStyle></Style>
+		<Polygon><outerBoundaryIs><LinearRing>
+			<coordinates>10.0,53.0,0 10.1,53.0,0 10.1,53.1,0 10.0,53.0,0</coordinates>
+		</LinearRing></outerBoundaryIs></Polygon>
+	</Placemark>
+	<Placemark>
+		<name>Unstyled</name>
+		<Point><coordinates>11.0,54.0,0</coordinates></Point>
+	</Placemark>
+</Document>
+</kml>`
+
+	tmpFile := createTempFile(t, "styled.kml", kmlContent)
+
+	tests := []struct {
+		name             string
+		preferFileStyles bool
+		expected         []string
+	}{
+		{
+			name:     "configured color by default",
+			expected: []string{"abcdef", "abcdef", "abcdef", "abcdef", "abcdef", "abcdef", "abcdef"},
+		},
+		{
+			name:             "file styles preferred",
+			preferFileStyles: true,
+			expected:         []string{"ff0000", "00ff00", "00ff00", "0000ff", "0000ff", "0000ff", "abcdef"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &KMLReader{Options: Options{PreferFileStyles: tt.preferFileStyles}}
+			poiList, err := reader.ParseFileWithFullConfig(tmpFile, DefaultMaxLod, "abcdef")
+			if err != nil {
+				t.Fatalf("ParseFile returned error: %v", err)
+			}
+
+			if len(*poiList) != len(tt.expected) {
+				t.Fatalf("Expected %d POIs, got %d", len(tt.expected), len(*poiList))
+			}
+			for i, p := range *poiList {
+				if p.Color != tt.expected[i] {
+					t.Errorf("POI %d (%s): Expected color %s, got %s", i, p.Text, tt.expected[i], p.Color)
+				}
+			}
+		})
+	}
+}
+
+func TestKMLReader_ReadFeatures_TrailingStyles(t *testing.T) {
+	kmlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+	<Folder>
+		<name>Stations</name>
+		<Placemark>
+			<name>Station</name>
+			<styleUrl>#icon-red</styleUrl>
+			<Point><coordinates>10.0,53.0,0</coordinates></Point>
+		</Placemark>
+	</Folder>
+	<Style id="icon-red"><IconStyle><color>ff0000ff</color></IconStyle></Style>
+</Document>
+</kml>`
+	tmpFile := createTempFile(t, "trailing.kml", kmlContent)
+
+	reader := &KMLReader{}
+	features, err := reader.ReadFeatures(tmpFile)
+	if err != nil {
+		t.Fatalf("ReadFeatures returned error: %v", err)
+	}
+	if len(features) != 1 || features[0].Color != "ff0000" {
+		t.Errorf("Expected the style declared after the placemark to apply, got %+v", features)
+	}
+}
+
+func TestKMLReader_ParseFile_TrailingStyles(t *testing.T) {
+	kmlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+	<Placemark>
+		<name>Station</name>
+		<styleUrl>#icon-red</styleUrl>
+		<Point><coordinates>10.0,53.0,0</coordinates></Point>
+	</Placemark>
+	<Placemark>
+		<name>Depot</name>
+		<styleUrl>#icon-red</styleUrl>
+		<ExtendedData><Data name="usage"><value>depot</value></Data></ExtendedData>
+		<Point><coordinates>10.1,53.1,0</coordinates></Point>
+	</Placemark>
+	<Placemark>
+		<name>Missing</name>
+		<styleUrl>#undefined</styleUrl>
+		<LineString><coordinates>10.0,53.0,0 10.1,53.1,0</coordinates></LineString>
+	</Placemark>
+	<Style id="icon-red"><IconStyle><color>ff0000ff</color></IconStyle></Style>
+</Document>
+</kml>`
+	tmpFile := createTempFile(t, "trailing.kml", kmlContent)
+
+	ruleSet, err := rules.Parse([]byte(`{"rules": [{"match": {"usage": "depot"}, "style": {"color": "#00ff00"}}]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse rules: %v", err)
+	}
+
+	// Placemarks are converted before the style is read, rules still win over
+	// the file's colors and undefined styles leave the configured color
+	reader := &KMLReader{Options: Options{Rules: ruleSet, PreferFileStyles: true}}
+	poiList, err := reader.ParseFileWithFullConfig(tmpFile, DefaultMaxLod, "abcdef")
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	expected := []string{"ff0000", "00ff00", "abcdef", "abcdef"}
+	if len(*poiList) != len(expected) {
+		t.Fatalf("Expected %d POIs, got %d", len(expected), len(*poiList))
+	}
+	for i, p := range *poiList {
+		if p.Color != expected[i] {
+			t.Errorf("POI %d (%s): Expected color %s, got %s", i, p.Text, expected[i], p.Color)
+		}
+	}
+}
+
+// recoloringConverter emits a POI per feature, colored by the converter
+// itself for features named "Fixed"
+type recoloringConverter struct{}
+
+func (recoloringConverter) Convert(feature *Feature, base poi.POI, poiList *poi.List) {
+	if feature.Name == "Fixed" {
+		base.Color = "123456"
+	}
+	base.Lon = feature.Geometries[0].Coordinates[0].Lon
+	base.Lat = feature.Geometries[0].Coordinates[0].Lat
+	poiList.Add(base)
+}
+
+func TestKMLReader_ParseFile_TrailingStylesConverter(t *testing.T) {
+	kmlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+	<Placemark><name>Fixed</name><styleUrl>#red</styleUrl><Point><coordinates>10,53</coordinates></Point></Placemark>
+	<Placemark><name>Styled</name><styleUrl>#red</styleUrl><Point><coordinates>10.1,53.1</coordinates></Point></Placemark>
+	<Style id="red"><IconStyle><color>ff0000ff</color></IconStyle></Style>
+</Document>
+</kml>`
+	tmpFile := createTempFile(t, "trailing.kml", kmlContent)
+
+	// Colors a custom converter sets are kept, the others come from the style
+	reader := &KMLReader{Options: Options{Converter: recoloringConverter{}, PreferFileStyles: true}}
+	poiList, err := reader.ParseFileWithFullConfig(tmpFile, DefaultMaxLod, "abcdef")
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	expected := []string{"123456", "ff0000"}
+	if len(*poiList) != len(expected) {
+		t.Fatalf("Expected %d POIs, got %d", len(expected), len(*poiList))
+	}
+	for i, p := range *poiList {
+		if p.Color != expected[i] {
+			t.Errorf("POI %d: Expected color %s, got %s", i, expected[i], p.Color)
+		}
+	}
+}
+
+func TestKMLReader_ReadFeatures_NetworkLinks(t *testing.T) {
+	dir := t.TempDir()
+	linked := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+	<Style id="blue"><IconStyle><color>ffff0000</color></IconStyle></Style>
+	<Placemark>
+		<name>Depot</name>
+		<styleUrl>#blue</styleUrl>
+		<Point><coordinates>10.0,53.0,0</coordinates></Point>
+	</Placemark>
+</Document>
+</kml>`
+	if err := os.WriteFile(filepath.Join(dir, "depots.kml"), []byte(linked), 0644); err != nil {
+		t.Fatal(err)
+	}
+	main := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+	<Folder>
+		<name>Network</name>
+		<NetworkLink><name>Depots</name><Link><href>depots.kml</href></Link></NetworkLink>
+	</Folder>
+</Document>
+</kml>`
+	mainPath := filepath.Join(dir, "main.kml")
+	if err := os.WriteFile(mainPath, []byte(main), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := &KMLReader{}
+	features, err := reader.ReadFeatures(mainPath)
+	if err != nil {
+		t.Fatalf("ReadFeatures returned error: %v", err)
+	}
+	if len(features) != 1 {
+		t.Fatalf("Expected the linked placemark, got %d features", len(features))
+	}
+	depot := features[0]
+	if depot.Name != "Depot" || depot.Color != "0000ff" || strings.Join(depot.Source.Folders, "/") != "Network/Depots" {
+		t.Errorf("Expected Depot in Network/Depots with its own style, got %+v", depot)
+	}
+}
+
+func TestKMLReader_ReadFeatures_LinksInDirectory(t *testing.T) {
+	// Another user's result sits next to the upload directory
+	dir := t.TempDir()
+	other := `<kml><Document><Placemark><name>Other</name><Point><coordinates>10.0,53.0,0</coordinates></Point></Placemark></Document></kml>`
+	otherPath := filepath.Join(dir, "other-result.kml")
+	if err := os.WriteFile(otherPath, []byte(other), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	upload := filepath.Join(dir, "upload")
+	if err := os.MkdirAll(upload, 0755); err != nil {
+		t.Fatal(err)
+	}
+	main := `<kml><Document>
+	<Placemark><name>Main</name><Point><coordinates>13.0,52.0,0</coordinates></Point></Placemark>
+	<NetworkLink><Link><href>` + otherPath + `</href></Link></NetworkLink>
+	<NetworkLink><Link><href>file://` + filepath.ToSlash(otherPath) + `</href></Link></NetworkLink>
+</Document></kml>`
+	mainPath := filepath.Join(upload, "main.kml")
+	if err := os.WriteFile(mainPath, []byte(main), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := &KMLReader{Options: Options{KMLLinks: kml.LinksInDirectory}}
+	features, err := reader.ReadFeatures(mainPath)
+	if err != nil {
+		t.Fatalf("ReadFeatures returned error: %v", err)
+	}
+	if len(features) != 1 || features[0].Name != "Main" {
+		t.Errorf("Expected only the uploaded placemark, got %+v", features)
+	}
+}
+
+func TestKMLReader_ParseFile_Rules(t *testing.T) {
+	kmlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+	<Placemark>
+		<name>Loose</name>
+		<Point><coordinates>10.0,53.0,0</coordinates></Point>
+	</Placemark>
+	<Folder>
+		<name>Network</name>
+		<Folder>
+			<name>Stations</name>
+			<Placemark>
+				<name>Central</name>
+				<ExtendedData><Data name="usage"><value>tourism</value></Data></ExtendedData>
+				<Point><coordinates>10.1,53.1,0</coordinates></Point>
+			</Placemark>
+		</Folder>
+	</Folder>
+</Document>
+</kml>`
+
+	tmpFile := createTempFile(t, "network.kml", kmlContent)
+
+	ruleSet, err := rules.Parse([]byte(`{"rules": [
+		{"match": {"@folder": "Stations"}, "style": {"color": "#ff0000", "max_lod": 8}},
+		{"match": {"usage": "tourism"}, "style": {"demand": "tourist", "population": 250, "transparent": true}},
+		{"match": {"@folder": ""}, "style": {"font_size": 8}}
+	]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse rules: %v", err)
+	}
+
+	reader := &KMLReader{Options: Options{Rules: ruleSet, PreferFileStyles: true}}
+	poiList, err := reader.ParseFile(tmpFile)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	if len(*poiList) != 2 {
+		t.Fatalf("Expected 2 POIs, got %d", len(*poiList))
+	}
+
+	loose, central := (*poiList)[0], (*poiList)[1]
+	if loose.FontSize != 8 || loose.Color != defaultColor {
+		t.Errorf("Expected top-level placemark to get font size 8 and the default color, got %+v", loose)
+	}
+	if central.Color != "ff0000" || central.MaxLod != 8 || central.Demand != "tourist" ||
+		central.Population != 250 || !central.Transparent || central.FontSize != defaultFontSize {
+		t.Errorf("Expected folder and ExtendedData rules to style the station, got %+v", central)
+	}
+	if central.Text != "Central" {
+		t.Errorf("Expected label 'Central', got '%s'", central.Text)
+	}
+}
+
+func TestKMLReader_ReadFeatures(t *testing.T) {
+	kmlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+	<Style id="red"><LineStyle><color>ff0000ff</color></LineStyle></Style>
+	<Folder>
+		<name>Network</name>
+		<Folder>
+			<name>Lines</name>
+			<Placemark>
+				<name>S1</name>
+				<styleUrl>#red</styleUrl>
+				<ExtendedData><Data name="operator"><value>DB</value></Data></ExtendedData>
+				<MultiGeometry>
+					<LineString><coordinates>10,53 11,54</coordinates></LineString>
+					<Point><coordinates>10,53</coordinates></Point>
+				</MultiGeometry>
+			</Placemark>
+		</Folder>
+	</Folder>
+	<Placemark>
+		<name>Yard</name>
+		<Polygon><outerBoundaryIs><LinearRing><coordinates>10,53 11,53 11,54 10,53</coordinates></LinearRing></outerBoundaryIs></Polygon>
+	</Placemark>
+</Document>
+</kml>`
+	filePath := createTempFile(t, "network.kml", kmlContent)
+
+	reader := &KMLReader{}
+	features, err := reader.ReadFeatures(filePath)
+	if err != nil {
+		t.Fatalf("ReadFeatures returned error: %v", err)
+	}
+	if len(features) != 2 {
+		t.Fatalf("Expected 2 features, got %d", len(features))
+	}
+
+	// Placemarks come in document order, wherever their folders are
+	line := features[0]
+	if line.Name != "S1" || line.Properties["operator"] != "DB" || line.Color != "ff0000" {
+		t.Errorf("Expected name, ExtendedData and style color, got %+v", line)
+	}
+	if strings.Join(line.Source.Folders, "/") != "Network/Lines" || line.Source.File != "network.kml" {
+		t.Errorf("Expected source network.kml in Network/Lines, got %+v", line.Source)
+	}
+	if len(line.Geometries) != 2 || line.Geometries[0].Type != PointGeometry || line.Geometries[1].Type != LineStringGeometry {
+		t.Errorf("Expected a point and a line, got %+v", line.Geometries)
+	}
+
+	yard := features[1]
+	if len(yard.Source.Folders) != 0 || yard.Color != "" {
+		t.Errorf("Expected a top-level feature without a style, got %+v", yard)
+	}
+	if len(yard.Geometries) != 1 || yard.Geometries[0].Type != PolygonGeometry || len(yard.Geometries[0].Rings[0]) != 3 {
+		t.Errorf("Expected a polygon with an open ring, got %+v", yard.Geometries)
+	}
+}
+
 func TestKMLReader_ParseFile_NonExistentFile(t *testing.T) {
 	reader := &KMLReader{}
 	_, err := reader.ParseFile("nonexistent.kml")
@@ -0,0 +1,41 @@
+package geometry
+
+import (
+	"strings"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/rules"
+)
+
+// LabelNone disables POI labels when used as the label field
+const LabelNone = "none"
+
+// defaultLabelFields are the attribute names tried, in order, when no label
+// field is configured or the configured field is missing on a feature
+var defaultLabelFields = []string{"label", "name"}
+
+// resolveLabel picks the POI text for a feature. The configured label field is
+// tried first, then the explicit text, then the "label" and "name" attributes,
+// and finally the feature's own name (e.g. a KML <name>). Attribute names are
+// matched case-insensitively.
+func resolveLabel(labelField string, attributes map[string]string, text, name string) string {
+	if strings.EqualFold(labelField, LabelNone) {
+		return ""
+	}
+
+	if labelField != "" {
+		if value := rules.LookupAttribute(attributes, labelField); value != "" {
+			return value
+		}
+	}
+	if text = strings.TrimSpace(text); text != "" {
+		return text
+	}
+
+	for _, field := range defaultLabelFields {
+		if value := rules.LookupAttribute(attributes, field); value != "" {
+			return value
+		}
+	}
+
+	return strings.TrimSpace(name)
+}
@@ -0,0 +1,70 @@
+package geometry
+
+import (
+	"testing"
+)
+
+func TestResolveLabel(t *testing.T) {
+	tests := []struct {
+		name       string
+		labelField string
+		attributes map[string]string
+		fallback   string
+		expected   string
+	}{
+		{
+			name:       "configured field",
+			labelField: "NAME_DE",
+			attributes: map[string]string{"NAME_DE": "Hauptbahnhof", "Label": "Hbf"},
+			expected:   "Hauptbahnhof",
+		},
+		{
+			name:       "configured field matched case-insensitively",
+			labelField: "name_de",
+			attributes: map[string]string{"NAME_DE": "Hauptbahnhof"},
+			expected:   "Hauptbahnhof",
+		},
+		{
+			name:       "missing configured field falls back to Label",
+			labelField: "NAME_DE",
+			attributes: map[string]string{"Label": "Hbf"},
+			expected:   "Hbf",
+		},
+		{
+			name:       "Label preferred over name",
+			attributes: map[string]string{"name": "Hamburg Hauptbahnhof", "LABEL": "Hbf"},
+			expected:   "Hbf",
+		},
+		{
+			name:       "name attribute",
+			attributes: map[string]string{"NAME": "Altona"},
+			expected:   "Altona",
+		},
+		{
+			name:       "blank attributes fall back to feature name",
+			attributes: map[string]string{"Label": "  "},
+			fallback:   "Placemark Name",
+			expected:   "Placemark Name",
+		},
+		{
+			name:       "none disables labels",
+			labelField: "none",
+			attributes: map[string]string{"Label": "Hbf"},
+			fallback:   "Placemark Name",
+			expected:   "",
+		},
+		{
+			name:     "nothing available",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := resolveLabel(tt.labelField, tt.attributes, "", tt.fallback)
+			if result != tt.expected {
+				t.Errorf("resolveLabel() = '%s', expected '%s'", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package geometry
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/osm"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
+)
+
+// OSMReader reads OpenStreetMap XML (.osm) and PBF (.osm.pbf) extracts.
+// Elements whose tags match Options.OSMFilter become features, or every tagged
+// element without a filter.
+type OSMReader struct {
+	Options
+}
+
+func (o *OSMReader) ParseFile(filePath string) (*poi.List, error) {
+	return o.ParseFileWithConfig(filePath, DefaultMaxLod)
+}
+
+func (o *OSMReader) ParseFileWithConfig(filePath string, maxLod int32) (*poi.List, error) {
+	return o.ParseFileWithFullConfig(filePath, maxLod, defaultColor)
+}
+
+func (o *OSMReader) ParseFileWithFullConfig(filePath string, maxLod int32, color string) (*poi.List, error) {
+	features, err := o.ReadFeatures(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return o.convert(features, maxLod, color)
+}
+
+// ReadFeatures returns matching nodes as points, ways as lines or, for closed
+// ways tagged as areas, polygons, and relations as polygons (multipolygons and
+// boundaries) or the combined geometry of their members (routes and others).
+// Tags become the feature's properties.
+func (o *OSMReader) ReadFeatures(filePath string) ([]Feature, error) {
+	var data *osm.Data
+	var err error
+	if strings.EqualFold(filepath.Ext(filePath), ".pbf") {
+		// The filter is applied while decoding so only the selected elements are kept
+		data, err = osm.ReadPBFFile(filePath, o.OSMFilter)
+	} else {
+		data, err = osm.ReadXMLFile(filePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return OSMFeatures(data, o.OSMFilter, filepath.Base(filePath)), nil
+}
+
+// OSMFeatures turns the elements of OSM data that match filter into features,
+// as ReadFeatures does for files. fileName is recorded as their source.
+func OSMFeatures(data *osm.Data, filter *osm.Filter, fileName string) []Feature {
+	features := make([]Feature, 0)
+	add := func(tags map[string]string, geometries []Geometry) {
+		if len(geometries) == 0 {
+			return
+		}
+		features = append(features, Feature{
+			Geometries: geometries,
+			Properties: osmProperties(tags),
+			Name:       tags["name"],
+			Source:     Source{File: fileName, Index: len(features)},
+		})
+	}
+
+	for i := range data.Nodes {
+		if node := &data.Nodes[i]; filter.Match(node.Tags) {
+			add(node.Tags, []Geometry{NewPoint(node.Lon, node.Lat)})
+		}
+	}
+
+	for i := range data.Ways {
+		if way := &data.Ways[i]; filter.Match(way.Tags) {
+			add(way.Tags, osmWayGeometries(data, way))
+		}
+	}
+
+	for i := range data.Relations {
+		if relation := &data.Relations[i]; filter.Match(relation.Tags) {
+			add(relation.Tags, osmRelationGeometries(data, relation, map[int64]bool{}))
+		}
+	}
+
+	return features
+}
+
+func osmWayGeometries(data *osm.Data, way *osm.Way) []Geometry {
+	nodes, _ := data.WayNodes(way)
+	if len(nodes) == 0 {
+		return nil
+	}
+	if way.IsArea() {
+		return []Geometry{NewPolygon(osmCoordinates(nodes))}
+	}
+	if len(nodes) == 1 {
+		return []Geometry{NewPoint(nodes[0].Lon, nodes[0].Lat)}
+	}
+	return []Geometry{NewLineString(osmCoordinates(nodes))}
+}
+
+// osmRelationGeometries resolves a relation's members, following nested
+// relations such as the routes of a route_master once each
+func osmRelationGeometries(data *osm.Data, relation *osm.Relation, visited map[int64]bool) []Geometry {
+	if visited[relation.ID] {
+		return nil
+	}
+	visited[relation.ID] = true
+
+	if relation.IsMultipolygon() {
+		polygons := data.Multipolygons(relation)
+		geometries := make([]Geometry, 0, len(polygons))
+		for _, polygon := range polygons {
+			rings := [][]Coordinate{osmCoordinates(polygon.Outer)}
+			for _, inner := range polygon.Inner {
+				rings = append(rings, osmCoordinates(inner))
+			}
+			geometries = append(geometries, NewPolygon(rings...))
+		}
+		return geometries
+	}
+
+	var geometries []Geometry
+	for _, member := range relation.Members {
+		switch member.Type {
+		case osm.NodeType:
+			if node, ok := data.Node(member.Ref); ok {
+				geometries = append(geometries, NewPoint(node.Lon, node.Lat))
+			}
+		case osm.WayType:
+			if way, ok := data.Way(member.Ref); ok {
+				geometries = append(geometries, osmWayGeometries(data, way)...)
+			}
+		case osm.RelationType:
+			if nested, ok := data.Relation(member.Ref); ok {
+				geometries = append(geometries, osmRelationGeometries(data, nested, visited)...)
+			}
+		}
+	}
+	return geometries
+}
+
+func osmCoordinates(nodes []*osm.Node) []Coordinate {
+	coords := make([]Coordinate, len(nodes))
+	for i, node := range nodes {
+		coords[i] = Coordinate{Lon: node.Lon, Lat: node.Lat}
+	}
+	return coords
+}
+
+// osmProperties copies tags so that features never share a map with the OSM data
+func osmProperties(tags map[string]string) map[string]string {
+	properties := make(map[string]string, len(tags))
+	for key, value := range tags {
+		properties[key] = value
+	}
+	return properties
+}
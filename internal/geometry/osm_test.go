@@ -0,0 +1,166 @@
+package geometry
+
+import (
+	"testing"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/osm"
+)
+
+const railwayOSM = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6" generator="osmium/1.16.0">
+	<node id="1" lat="52.5251" lon="13.3694">
+		<tag k="public_transport" v="station"/>
+		<tag k="railway" v="station"/>
+		<tag k="name" v="Berlin Hbf"/>
+	</node>
+	<node id="2" lat="52.5300" lon="13.3000"/>
+	<node id="3" lat="52.5400" lon="13.2000"/>
+	<node id="4" lat="52.5200" lon="13.3100"/>
+	<node id="5" lat="52.5210" lon="13.3100"/>
+	<node id="6" lat="52.5210" lon="13.3120"/>
+	<node id="7" lat="52.5200" lon="13.3120">
+		<tag k="railway" v="buffer_stop"/>
+	</node>
+	<way id="10">
+		<nd ref="1"/>
+		<nd ref="2"/>
+		<nd ref="3"/>
+		<tag k="railway" v="rail"/>
+		<tag k="usage" v="main"/>
+		<tag k="name" v="Hamburger Bahn"/>
+	</way>
+	<way id="11">
+		<nd ref="2"/>
+		<nd ref="4"/>
+		<tag k="railway" v="rail"/>
+		<tag k="service" v="siding"/>
+	</way>
+	<way id="12">
+		<nd ref="4"/>
+		<nd ref="5"/>
+		<nd ref="6"/>
+		<nd ref="7"/>
+		<nd ref="4"/>
+		<tag k="landuse" v="railway"/>
+	</way>
+	<relation id="20">
+		<member type="way" ref="10" role=""/>
+		<member type="node" ref="1" role="stop"/>
+		<tag k="type" v="route"/>
+		<tag k="route" v="train"/>
+		<tag k="name" v="RE 1"/>
+	</relation>
+	<relation id="21">
+		<member type="relation" ref="20" role=""/>
+		<member type="relation" ref="21" role=""/>
+		<tag k="type" v="route_master"/>
+		<tag k="route_master" v="train"/>
+	</relation>
+</osm>`
+
+func TestOSMReader_ReadFeatures(t *testing.T) {
+	filePath := createTempFile(t, "berlin.osm", railwayOSM)
+
+	tests := []struct {
+		name          string
+		filter        string
+		expectedNames []string
+		expectedTypes [][]GeometryType
+	}{
+		{
+			name:          "no filter keeps tagged elements",
+			expectedNames: []string{"Berlin Hbf", "", "Hamburger Bahn", "", "", "RE 1", ""},
+			expectedTypes: [][]GeometryType{
+				{PointGeometry},
+				{PointGeometry},
+				{LineStringGeometry},
+				{LineStringGeometry},
+				{PolygonGeometry},
+				{LineStringGeometry, PointGeometry},
+				{LineStringGeometry, PointGeometry},
+			},
+		},
+		{
+			name:          "main lines",
+			filter:        "railway=rail and usage=main",
+			expectedNames: []string{"Hamburger Bahn"},
+			expectedTypes: [][]GeometryType{{LineStringGeometry}},
+		},
+		{
+			name:          "stations",
+			filter:        "public_transport=station",
+			expectedNames: []string{"Berlin Hbf"},
+			expectedTypes: [][]GeometryType{{PointGeometry}},
+		},
+		{
+			name:          "train routes",
+			filter:        "type=route and route=train",
+			expectedNames: []string{"RE 1"},
+			expectedTypes: [][]GeometryType{{LineStringGeometry, PointGeometry}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &OSMReader{}
+			if tt.filter != "" {
+				filter, err := osm.ParseFilter(tt.filter)
+				if err != nil {
+					t.Fatalf("ParseFilter returned error: %v", err)
+				}
+				reader.OSMFilter = filter
+			}
+
+			features, err := reader.ReadFeatures(filePath)
+			if err != nil {
+				t.Fatalf("ReadFeatures returned error: %v", err)
+			}
+
+			if len(features) != len(tt.expectedNames) {
+				t.Fatalf("Expected %d features, got %d", len(tt.expectedNames), len(features))
+			}
+			for i, feature := range features {
+				if feature.Name != tt.expectedNames[i] {
+					t.Errorf("Feature %d: Expected name %q, got %q", i, tt.expectedNames[i], feature.Name)
+				}
+				if len(feature.Geometries) != len(tt.expectedTypes[i]) {
+					t.Errorf("Feature %d: Expected %d geometries, got %d", i, len(tt.expectedTypes[i]), len(feature.Geometries))
+					continue
+				}
+				for j, geometry := range feature.Geometries {
+					if geometry.Type != tt.expectedTypes[i][j] {
+						t.Errorf("Feature %d geometry %d: Expected %s, got %s", i, j, tt.expectedTypes[i][j], geometry.Type)
+					}
+				}
+				if feature.Source.File != "berlin.osm" || feature.Source.Index != i {
+					t.Errorf("Feature %d: Unexpected source %+v", i, feature.Source)
+				}
+			}
+		})
+	}
+}
+
+func TestOSMReader_ParseFile(t *testing.T) {
+	filePath := createTempFile(t, "berlin.osm", railwayOSM)
+
+	filter, err := osm.ParseFilter("railway=rail and usage=main")
+	if err != nil {
+		t.Fatalf("ParseFilter returned error: %v", err)
+	}
+	reader := &OSMReader{Options: Options{OSMFilter: filter, LabelField: "usage"}}
+
+	poiList, err := reader.ParseFile(filePath)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	if len(*poiList) != 3 {
+		t.Fatalf("Expected 3 POIs, got %d", len(*poiList))
+	}
+	if (*poiList)[0].Text != "main" {
+		t.Errorf("Expected the first POI to be labelled from the usage tag, got %q", (*poiList)[0].Text)
+	}
+	if (*poiList)[0].Lat != 52.5251 || (*poiList)[0].Lon != 13.3694 {
+		t.Errorf("Expected the first POI at Berlin Hbf, got %f, %f", (*poiList)[0].Lat, (*poiList)[0].Lon)
+	}
+}
@@ -0,0 +1,263 @@
+package geometry
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/gis"
+)
+
+// PolygonMode selects how polygons are turned into POIs
+type PolygonMode string
+
+const (
+	// PolygonOutline draws every ring, labelled on the first vertex of each
+	PolygonOutline PolygonMode = "outline"
+	// PolygonLabelPoint replaces the outline with a single labelled POI inside
+	// the polygon, such as a town name from a boundary dataset
+	PolygonLabelPoint PolygonMode = "point"
+	// PolygonOutlineAndPoint draws the outline without labels and adds the
+	// labelled POI inside
+	PolygonOutlineAndPoint PolygonMode = "both"
+)
+
+// ParsePolygonMode accepts a polygon mode name. An empty name selects the outline.
+func ParsePolygonMode(name string) (PolygonMode, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", string(PolygonOutline):
+		return PolygonOutline, nil
+	case string(PolygonLabelPoint):
+		return PolygonLabelPoint, nil
+	case string(PolygonOutlineAndPoint):
+		return PolygonOutlineAndPoint, nil
+	default:
+		return "", fmt.Errorf("unknown polygon mode %q, expected %s, %s or %s", name, PolygonOutline, PolygonLabelPoint, PolygonOutlineAndPoint)
+	}
+}
+
+// localProjection maps coordinates to meters on a plane tangent to the middle
+// of a ring. It is accurate enough for placing points inside areas up to a few
+// hundred kilometers across.
+type localProjection struct {
+	lon0, lat0 float64
+	kx, ky     float64
+}
+
+func newLocalProjection(ring []Coordinate) localProjection {
+	minLat, maxLat := ring[0].Lat, ring[0].Lat
+	minLon, maxLon := ring[0].Lon, ring[0].Lon
+	for _, coord := range ring {
+		minLat, maxLat = min(minLat, coord.Lat), max(maxLat, coord.Lat)
+		minLon, maxLon = min(minLon, coord.Lon), max(maxLon, coord.Lon)
+	}
+	lat0 := (minLat + maxLat) / 2
+	metersPerDegree := gis.EarthRadiusKm * 1000 * math.Pi / 180
+	return localProjection{
+		lon0: (minLon + maxLon) / 2,
+		lat0: lat0,
+		kx:   metersPerDegree * math.Cos(lat0*math.Pi/180),
+		ky:   metersPerDegree,
+	}
+}
+
+func (p localProjection) forward(coord Coordinate) (float64, float64) {
+	return (coord.Lon - p.lon0) * p.kx, (coord.Lat - p.lat0) * p.ky
+}
+
+func (p localProjection) inverse(x, y float64) Coordinate {
+	return Coordinate{Lon: p.lon0 + x/p.kx, Lat: p.lat0 + y/p.ky}
+}
+
+type planePoint struct{ x, y float64 }
+
+// ringArea returns the area of a ring in square meters
+func ringArea(ring []Coordinate) float64 {
+	if len(ring) < 3 {
+		return 0
+	}
+	projection := newLocalProjection(ring)
+	var area float64
+	for i := range ring {
+		x1, y1 := projection.forward(ring[i])
+		x2, y2 := projection.forward(ring[(i+1)%len(ring)])
+		area += x1*y2 - x2*y1
+	}
+	return math.Abs(area) / 2
+}
+
+// labelPoint returns the point of a feature's polygons where a single label
+// goes: the pole of inaccessibility of its largest polygon, the interior point
+// furthest from any edge or hole. Unlike the centroid it always lies inside,
+// even for crescent-shaped or holed areas. When the point is outside the clip
+// area, the pole of the part of the polygon inside the area is used instead.
+func labelPoint(geometries []Geometry, clip *ClipArea) (Coordinate, bool) {
+	var largest *Geometry
+	var largestArea float64
+	for i := range geometries {
+		geometry := &geometries[i]
+		if geometry.Type != PolygonGeometry || len(geometry.Rings) == 0 || len(geometry.Rings[0]) == 0 {
+			continue
+		}
+		if area := ringArea(geometry.Rings[0]); largest == nil || area > largestArea {
+			largest, largestArea = geometry, area
+		}
+	}
+	if largest == nil {
+		return Coordinate{}, false
+	}
+	point := poleOfInaccessibility(largest.Rings, nil)
+	if clip != nil && !clip.contains(point) {
+		point = poleOfInaccessibility(largest.Rings, clip)
+	}
+	return point, true
+}
+
+// poleOfInaccessibility finds the interior point furthest from the polygon's
+// edges to within a meter or a thousandth of its size, by recursively
+// subdividing the cells that might still contain a better point (the
+// "polylabel" algorithm). A clip area limits the search to the part of the
+// polygon inside it, whose edges then count as edges of the polygon.
+func poleOfInaccessibility(rings [][]Coordinate, clip *ClipArea) Coordinate {
+	projection := newLocalProjection(rings[0])
+	projected := make([][]planePoint, len(rings))
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for i, ring := range rings {
+		projected[i] = make([]planePoint, len(ring))
+		for j, coord := range ring {
+			x, y := projection.forward(coord)
+			projected[i][j] = planePoint{x, y}
+			if i == 0 {
+				minX, maxX = min(minX, x), max(maxX, x)
+				minY, maxY = min(minY, y), max(maxY, y)
+			}
+		}
+	}
+
+	cellSize := min(maxX-minX, maxY-minY)
+	if cellSize <= 0 || len(rings[0]) < 3 {
+		return rings[0][0]
+	}
+	precision := max(1, cellSize/1000)
+
+	var clipDistance func(x, y float64) float64
+	if clip != nil {
+		low, high, _ := geometryBounds(Geometry{Rings: rings[:1]})
+		clipDistance = clip.planeDistance(projection, low, high)
+	}
+
+	newCell := func(x, y, half float64) *polygonCell {
+		distance := signedDistance(x, y, projected)
+		if clipDistance != nil {
+			distance = min(distance, clipDistance(x, y))
+		}
+		return &polygonCell{x: x, y: y, half: half, distance: distance, potential: distance + half*math.Sqrt2}
+	}
+
+	best := newCell(ringCentroid(projected[0]))
+	if center := newCell((minX+maxX)/2, (minY+maxY)/2, 0); center.distance > best.distance {
+		best = center
+	}
+
+	queue := make(cellQueue, 0)
+	half := cellSize / 2
+	for x := minX; x < maxX; x += cellSize {
+		for y := minY; y < maxY; y += cellSize {
+			heap.Push(&queue, newCell(x+half, y+half, half))
+		}
+	}
+
+	for queue.Len() > 0 {
+		cell := heap.Pop(&queue).(*polygonCell)
+		if cell.distance > best.distance {
+			best = cell
+		}
+		// Stop splitting cells that cannot beat the best point by the precision
+		if cell.potential-best.distance <= precision {
+			continue
+		}
+		quarter := cell.half / 2
+		heap.Push(&queue, newCell(cell.x-quarter, cell.y-quarter, quarter))
+		heap.Push(&queue, newCell(cell.x+quarter, cell.y-quarter, quarter))
+		heap.Push(&queue, newCell(cell.x-quarter, cell.y+quarter, quarter))
+		heap.Push(&queue, newCell(cell.x+quarter, cell.y+quarter, quarter))
+	}
+
+	return projection.inverse(best.x, best.y)
+}
+
+// ringCentroid returns the area centroid of a ring, or its first vertex when
+// the ring has no area; the cell at that point starts the search
+func ringCentroid(ring []planePoint) (float64, float64, float64) {
+	var area, x, y float64
+	for i := range ring {
+		a, b := ring[i], ring[(i+1)%len(ring)]
+		f := a.x*b.y - b.x*a.y
+		x += (a.x + b.x) * f
+		y += (a.y + b.y) * f
+		area += f * 3
+	}
+	if area == 0 {
+		return ring[0].x, ring[0].y, 0
+	}
+	return x / area, y / area, 0
+}
+
+// signedDistance returns the distance from a point to the nearest edge of the
+// polygon, negative when the point lies outside it or in a hole
+func signedDistance(x, y float64, rings [][]planePoint) float64 {
+	inside := false
+	nearest := math.Inf(1)
+	for _, ring := range rings {
+		for i := range ring {
+			a, b := ring[i], ring[(i+1)%len(ring)]
+			if (a.y > y) != (b.y > y) && x < (b.x-a.x)*(y-a.y)/(b.y-a.y)+a.x {
+				inside = !inside
+			}
+			nearest = min(nearest, segmentDistance(x, y, a, b))
+		}
+	}
+	if inside {
+		return nearest
+	}
+	return -nearest
+}
+
+func segmentDistance(x, y float64, a, b planePoint) float64 {
+	dx, dy := b.x-a.x, b.y-a.y
+	px, py := a.x, a.y
+	if dx != 0 || dy != 0 {
+		t := ((x-a.x)*dx + (y-a.y)*dy) / (dx*dx + dy*dy)
+		t = max(0, min(1, t))
+		px, py = a.x+t*dx, a.y+t*dy
+	}
+	return math.Hypot(x-px, y-py)
+}
+
+// polygonCell is a square search cell centred on x, y
+type polygonCell struct {
+	x, y, half float64
+	// distance is the signed distance from the centre to the polygon
+	distance float64
+	// potential is the largest distance any point in the cell can have
+	potential float64
+}
+
+// cellQueue is a max-heap of cells ordered by potential
+type cellQueue []*polygonCell
+
+func (q cellQueue) Len() int           { return len(q) }
+func (q cellQueue) Less(i, j int) bool { return q[i].potential > q[j].potential }
+func (q cellQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+func (q *cellQueue) Push(x any) {
+	*q = append(*q, x.(*polygonCell))
+}
+
+func (q *cellQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,120 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParsePolygonMode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected PolygonMode
+		wantErr  bool
+	}{
+		{"", PolygonOutline, false},
+		{"outline", PolygonOutline, false},
+		{"Point", PolygonLabelPoint, false},
+		{"both", PolygonOutlineAndPoint, false},
+		{"centroid", "", true},
+	}
+
+	for _, tt := range tests {
+		mode, err := ParsePolygonMode(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePolygonMode(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+		if mode != tt.expected {
+			t.Errorf("ParsePolygonMode(%q) = %q, expected %q", tt.input, mode, tt.expected)
+		}
+	}
+}
+
+func TestPoleOfInaccessibility(t *testing.T) {
+	square := squareRing(10, 60, 1000)
+
+	t.Run("square", func(t *testing.T) {
+		point := poleOfInaccessibility([][]Coordinate{square}, nil)
+		if math.Abs(point.Lon-10) > 1e-4 || math.Abs(point.Lat-60) > 1e-4 {
+			t.Errorf("Expected the centre of the square, got %v", point)
+		}
+	})
+
+	t.Run("hole in the middle", func(t *testing.T) {
+		// The centroid lies in the hole, so the label must move beside it
+		hole := squareRing(10, 60, 600)
+		point := poleOfInaccessibility([][]Coordinate{square, hole}, nil)
+		if !inRing(point, square) || inRing(point, hole) {
+			t.Errorf("Expected a point between the outline and the hole, got %v", point)
+		}
+	})
+
+	t.Run("U shape", func(t *testing.T) {
+		// The centroid of a U lies in its gap
+		u := []Coordinate{
+			{Lon: 0, Lat: 0}, {Lon: 0.03, Lat: 0}, {Lon: 0.03, Lat: 0.03}, {Lon: 0.02, Lat: 0.03},
+			{Lon: 0.02, Lat: 0.01}, {Lon: 0.01, Lat: 0.01}, {Lon: 0.01, Lat: 0.03}, {Lon: 0, Lat: 0.03},
+		}
+		point := poleOfInaccessibility([][]Coordinate{u}, nil)
+		if !inRing(point, u) {
+			t.Errorf("Expected a point inside the U, got %v", point)
+		}
+	})
+
+	t.Run("degenerate", func(t *testing.T) {
+		line := []Coordinate{{Lon: 1, Lat: 2}, {Lon: 1, Lat: 3}, {Lon: 1, Lat: 4}}
+		if point := poleOfInaccessibility([][]Coordinate{line}, nil); point != line[0] {
+			t.Errorf("Expected the first vertex for a ring without area, got %v", point)
+		}
+	})
+}
+
+func TestPOIConverter_PolygonMode(t *testing.T) {
+	features := []Feature{{
+		Geometries: []Geometry{
+			NewPolygon(squareRing(11, 60, 100)),
+			NewPolygon(squareRing(10, 60, 1000)),
+		},
+		Name: "Skogby",
+	}}
+
+	tests := []struct {
+		mode          PolygonMode
+		expectedCount int
+		expectedTexts int
+	}{
+		{PolygonOutline, 8, 2},
+		{PolygonLabelPoint, 1, 1},
+		{PolygonOutlineAndPoint, 9, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			options := Options{PolygonMode: tt.mode}
+			poiList, err := options.ConvertFeatures(features, 5, "00ff00")
+			if err != nil {
+				t.Fatalf("ConvertFeatures returned error: %v", err)
+			}
+			if len(*poiList) != tt.expectedCount {
+				t.Fatalf("Expected %d POIs, got %d", tt.expectedCount, len(*poiList))
+			}
+
+			texts := 0
+			for _, p := range *poiList {
+				if p.Text != "" {
+					texts++
+				}
+			}
+			if texts != tt.expectedTexts {
+				t.Errorf("Expected %d labelled POIs, got %d", tt.expectedTexts, texts)
+			}
+
+			// The label point goes in the larger polygon
+			if tt.mode != PolygonOutline {
+				last := (*poiList)[len(*poiList)-1]
+				if last.Text != "Skogby" || math.Abs(last.Lon-10) > 1e-4 || math.Abs(last.Lat-60) > 1e-4 {
+					t.Errorf("Expected the label at the centre of the larger polygon, got %+v", last)
+				}
+			}
+		})
+	}
+}
@@ -1,18 +1,27 @@
 package geometry
 
 import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/jonas-p/go-shp"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/gis"
 	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
 )
 
 type ShapefileReader struct {
-	InterpolateDistance float64
+	Options
 }
 
 func (sr *ShapefileReader) ParseFile(filePath string) (*poi.List, error) {
-	return sr.ParseFileWithConfig(filePath, defaultMaxLod)
+	return sr.ParseFileWithConfig(filePath, DefaultMaxLod)
 }
 
 func (sr *ShapefileReader) ParseFileWithConfig(filePath string, maxLod int32) (*poi.List, error) {
@@ -20,65 +29,362 @@ func (sr *ShapefileReader) ParseFileWithConfig(filePath string, maxLod int32) (*
 }
 
 func (sr *ShapefileReader) ParseFileWithFullConfig(filePath string, maxLod int32, color string) (*poi.List, error) {
-	shapefile, err := shp.Open(filePath)
+	features, err := sr.ReadFeatures(filePath)
 	if err != nil {
 		return nil, err
 	}
+	return sr.convert(features, maxLod, color)
+}
+
+// ReadFeatures returns a feature per shape, reprojected to WGS84 and carrying its DBF attributes
+func (sr *ShapefileReader) ReadFeatures(filePath string) ([]Feature, error) {
+	fileName := filepath.Base(filePath)
+	shapefile, err := openShapefile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
 	defer shapefile.Close()
 
-	poiList := make(poi.List, 0)
+	crs, err := sr.sourceCRS(filePath)
+	if err != nil {
+		return nil, err
+	}
 
-	for shapeIndex := 0; shapefile.Next(); shapeIndex++ {
-		_, shape := shapefile.Shape()
+	features := make([]Feature, 0)
+	shapeIndex := 0
+	for ; shapefile.shapes.Next(); shapeIndex++ {
+		_, shape := shapefile.shapes.Shape()
+		// Records pair up with shapes in order, including those of null shapes
+		attributes := shapefile.table.next()
 
+		var geometries []Geometry
 		switch s := shape.(type) {
 		case *shp.Point:
-			p := poi.POI{
-				Lon:         s.X,
-				Lat:         s.Y,
-				Color:       color,
-				Text:        "",
-				FontSize:    defaultFontSize,
-				MaxLod:      maxLod,
-				Transparent: false,
-				Demand:      defaultDemand,
-				Population:  defaultPopulation,
-			}
-			poiList.Add(p)
-
+			geometries = shapePoints(reproject(crs, []shp.Point{*s}))
+		case *shp.PointZ:
+			geometries = shapePoints(reproject(crs, []shp.Point{{X: s.X, Y: s.Y}}))
+		case *shp.PointM:
+			geometries = shapePoints(reproject(crs, []shp.Point{{X: s.X, Y: s.Y}}))
+		case *shp.MultiPoint:
+			geometries = shapePoints(reproject(crs, s.Points))
+		case *shp.MultiPointZ:
+			geometries = shapePoints(reproject(crs, s.Points))
+		case *shp.MultiPointM:
+			geometries = shapePoints(reproject(crs, s.Points))
 		case *shp.PolyLine:
-			// Create temporary list for this polyline
-			tempList := make(poi.List, 0, len(s.Points))
-			for _, point := range s.Points {
-				p := poi.POI{
-					Lon:         point.X,
-					Lat:         point.Y,
-					Color:       color,
-					Text:        "",
-					FontSize:    12,
-					MaxLod:      maxLod,
-					Transparent: false,
-					Demand:      defaultDemand,
-					Population:  0,
-				}
-				tempList = append(tempList, p)
-			}
-
-			// Interpolate this polyline if configured
-			if sr.InterpolateDistance > 0 {
-				interpolated := tempList.InterpolateByDistance(sr.InterpolateDistance)
-				tempList = *interpolated
-			}
-
-			// Add all points (interpolated or not) to the main list
-			for _, p := range tempList {
-				poiList.Add(p)
-			}
-
+			geometries = shapePolyLine(s.Parts, reproject(crs, s.Points))
+		case *shp.PolyLineZ:
+			geometries = shapePolyLine(s.Parts, reproject(crs, s.Points))
+		case *shp.PolyLineM:
+			geometries = shapePolyLine(s.Parts, reproject(crs, s.Points))
+		case *shp.Polygon:
+			geometries = shapePolygon(s.Parts, reproject(crs, s.Points))
+		case *shp.PolygonZ:
+			geometries = shapePolygon(s.Parts, reproject(crs, s.Points))
+		case *shp.PolygonM:
+			geometries = shapePolygon(s.Parts, reproject(crs, s.Points))
+		case *shp.Null:
+			// Null shapes are placeholders for records without geometry
+			continue
 		default:
 			log.Printf("Skipped unsupported shape type at index %d", shapeIndex)
+			continue
+		}
+
+		features = append(features, Feature{
+			Geometries: geometries,
+			Properties: attributes,
+			Source:     Source{File: fileName, Index: shapeIndex},
+		})
+	}
+	if err := shapefile.shapes.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+	if table := shapefile.table; table != nil && table.read < shapeIndex {
+		log.Printf("%s: the .dbf only has attributes for %d of %d shapes", fileName, table.read, shapeIndex)
+	}
+
+	return features, nil
+}
+
+// shapefile is an open .shp file along with its optional .dbf sidecar
+type shapefile struct {
+	shapes *shp.Reader
+	// table is nil when there is no .dbf
+	table *dbfTable
+	// linkDir is the temporary directory of the link the shapes are read
+	// through, if any
+	linkDir string
+}
+
+// openShapefile opens a shapefile along with its .dbf sidecar, which is
+// optional; without it shapes have no attributes. go-shp replaces the last
+// three characters of the path with "shp", so a file named .SHP on a
+// case-sensitive file system is opened through a link with a lower-case
+// extension. The .dbf is read here rather than by go-shp, which only looks
+// for it with a lower-case extension.
+func openShapefile(filePath string) (*shapefile, error) {
+	s := &shapefile{}
+	shapePath := filePath
+	if _, err := os.Stat(filePath[:len(filePath)-3] + "shp"); err != nil {
+		if _, err := os.Stat(filePath); err != nil {
+			return nil, err
+		}
+		absPath, err := filepath.Abs(filePath)
+		if err != nil {
+			return nil, err
+		}
+		if s.linkDir, err = os.MkdirTemp("", "shapefile"); err != nil {
+			return nil, err
+		}
+		shapePath = filepath.Join(s.linkDir, "shapes.shp")
+		if err := os.Symlink(absPath, shapePath); err != nil {
+			s.Close()
+			return nil, err
 		}
 	}
 
-	return &poiList, nil
+	var err error
+	if s.shapes, err = shp.Open(shapePath); err != nil {
+		s.Close()
+		return nil, err
+	}
+	if dbfPath, ok := sidecarPath(filePath, "dbf"); ok {
+		if s.table, err = openDBF(dbfPath); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *shapefile) Close() {
+	if s.shapes != nil {
+		s.shapes.Close()
+	}
+	if s.table != nil {
+		s.table.file.Close()
+	}
+	if s.linkDir != "" {
+		os.RemoveAll(s.linkDir)
+	}
+}
+
+// dbfTable reads the records of a dBASE table one after another
+type dbfTable struct {
+	file    *os.File
+	reader  *bufio.Reader
+	fields  []shp.Field
+	records int
+	// read counts the records read so far
+	read int
+	row  []byte
+}
+
+// dbfHeader is the fixed part of a dBASE table header, which is followed by
+// a 32 byte descriptor per field and a terminator
+type dbfHeader struct {
+	Version      byte
+	Updated      [3]byte
+	Records      int32
+	HeaderLength int16
+	RecordLength int16
+	_            [20]byte
+}
+
+func openDBF(dbfPath string) (*dbfTable, error) {
+	file, err := os.Open(dbfPath)
+	if err != nil {
+		return nil, err
+	}
+	table, err := readDBFHeader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("invalid .dbf: %w", err)
+	}
+	return table, nil
+}
+
+func readDBFHeader(file *os.File) (*dbfTable, error) {
+	reader := bufio.NewReader(file)
+	var header dbfHeader
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	headerSize := binary.Size(header)
+	if int(header.HeaderLength) < headerSize+1 || header.Records < 0 {
+		return nil, errors.New("malformed header")
+	}
+
+	fields := make([]shp.Field, (int(header.HeaderLength)-headerSize-1)/binary.Size(shp.Field{}))
+	if err := binary.Read(reader, binary.LittleEndian, fields); err != nil {
+		return nil, err
+	}
+	recordLength := 1
+	for _, field := range fields {
+		recordLength += int(field.Size)
+	}
+	if int(header.RecordLength) < recordLength {
+		return nil, fmt.Errorf("records of %d bytes cannot hold fields of %d", header.RecordLength, recordLength-1)
+	}
+
+	// Skip the terminator and any padding up to the first record
+	skip := int(header.HeaderLength) - headerSize - len(fields)*binary.Size(shp.Field{})
+	if _, err := reader.Discard(skip); err != nil {
+		return nil, err
+	}
+	return &dbfTable{
+		file:    file,
+		reader:  reader,
+		fields:  fields,
+		records: int(header.Records),
+		row:     make([]byte, header.RecordLength),
+	}, nil
+}
+
+// next returns the next record as a field name/value map. Once the table has
+// run out of records, or when there is none, the map is empty.
+func (t *dbfTable) next() map[string]string {
+	if t == nil || t.read >= t.records {
+		return map[string]string{}
+	}
+	if _, err := io.ReadFull(t.reader, t.row); err != nil {
+		// The table is shorter than its header says
+		t.records = t.read
+		return map[string]string{}
+	}
+	t.read++
+
+	attributes := make(map[string]string, len(t.fields))
+	offset := 1 // after the deletion flag
+	for _, field := range t.fields {
+		// Some writers pad values with NUL bytes instead of spaces
+		attributes[field.String()] = strings.Trim(string(t.row[offset:offset+int(field.Size)]), "\x00 ")
+		offset += int(field.Size)
+	}
+	return attributes
+}
+
+func shapePoints(points []shp.Point) []Geometry {
+	geometries := make([]Geometry, 0, len(points))
+	for _, point := range points {
+		geometries = append(geometries, NewPoint(point.X, point.Y))
+	}
+	return geometries
+}
+
+// shapePolyLine returns each part of a polyline as its own line so that
+// interpolation never bridges the gap between disconnected parts
+func shapePolyLine(parts []int32, points []shp.Point) []Geometry {
+	var geometries []Geometry
+	for _, part := range splitParts(parts, points) {
+		geometries = append(geometries, NewLineString(shapeCoordinates(part)))
+	}
+	return geometries
+}
+
+// shapePolygon groups the rings of a polygon shape into polygons. Shapefiles
+// store outer rings clockwise and holes counter-clockwise, each hole following
+// the outer ring it belongs to.
+func shapePolygon(parts []int32, points []shp.Point) []Geometry {
+	var geometries []Geometry
+	for _, part := range splitParts(parts, points) {
+		ring := shapeCoordinates(part)
+		if len(geometries) > 0 && !isClockwise(ring) {
+			polygon := &geometries[len(geometries)-1]
+			*polygon = NewPolygon(append(polygon.Rings, ring)...)
+			continue
+		}
+		geometries = append(geometries, NewPolygon(ring))
+	}
+	return geometries
+}
+
+func shapeCoordinates(points []shp.Point) []Coordinate {
+	coords := make([]Coordinate, len(points))
+	for i, point := range points {
+		coords[i] = Coordinate{Lon: point.X, Lat: point.Y}
+	}
+	return coords
+}
+
+// isClockwise reports whether a ring winds clockwise, using the sign of its shoelace area
+func isClockwise(ring []Coordinate) bool {
+	var area float64
+	for i := range ring {
+		next := ring[(i+1)%len(ring)]
+		area += (next.Lon - ring[i].Lon) * (next.Lat + ring[i].Lat)
+	}
+	return area > 0
+}
+
+// splitParts slices a shape's points into its parts. Parts holds the index of
+// the first point of each part; malformed offsets are clamped to the point range.
+func splitParts(parts []int32, points []shp.Point) [][]shp.Point {
+	if len(parts) == 0 {
+		return [][]shp.Point{points}
+	}
+
+	result := make([][]shp.Point, 0, len(parts))
+	for i, start := range parts {
+		end := int32(len(points))
+		if i+1 < len(parts) {
+			end = parts[i+1]
+		}
+		start = max(0, min(start, int32(len(points))))
+		end = max(start, min(end, int32(len(points))))
+		if end > start {
+			result = append(result, points[start:end])
+		}
+	}
+	return result
+}
+
+// sourceCRS returns the coordinate system of the shapefile: the SourceCRS option
+// when set, otherwise the .prj sidecar, otherwise WGS84
+func (sr *ShapefileReader) sourceCRS(filePath string) (*gis.CRS, error) {
+	if sr.SourceCRS != "" {
+		crs, err := gis.ParseCRS(sr.SourceCRS)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source CRS: %w", err)
+		}
+		return crs, nil
+	}
+	if prjPath, ok := sidecarPath(filePath, "prj"); ok {
+		return gis.ReadPRJ(prjPath)
+	}
+	return gis.WGS84, nil
+}
+
+// reproject converts points to WGS84 longitude/latitude in place
+func reproject(crs *gis.CRS, points []shp.Point) []shp.Point {
+	if crs.IsWGS84() {
+		return points
+	}
+	for i := range points {
+		points[i].X, points[i].Y = crs.ToWGS84(points[i].X, points[i].Y)
+	}
+	return points
+}
+
+// sidecarPath returns the path of the shapefile's companion file with the
+// given extension. Exports such as RAIL.SHP come with RAIL.DBF and RAIL.PRJ,
+// so the name and extension are matched case-insensitively.
+func sidecarPath(filePath, ext string) (string, bool) {
+	dir := filepath.Dir(filePath)
+	name := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath)) + "." + ext
+	if info, err := os.Stat(filepath.Join(dir, name)); err == nil && !info.IsDir() {
+		return filepath.Join(dir, name), true
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.EqualFold(entry.Name(), name) {
+			return filepath.Join(dir, entry.Name()), true
+		}
+	}
+	return "", false
 }
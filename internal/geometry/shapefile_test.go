@@ -1,8 +1,16 @@
 package geometry
 
 import (
+	"encoding/binary"
+	"math"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/jonas-p/go-shp"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/rules"
 )
 
 func TestShapefileReader_ParseFile_NonExistentFile(t *testing.T) {
@@ -73,6 +81,550 @@ func TestShapefileReader_ParseFile_ActualTestFile(t *testing.T) {
 	t.Logf("Successfully parsed %d POIs from shapefile", len(*poiList))
 }
 
+func TestShapefileReader_ParseFile_Labels(t *testing.T) {
+	filePath := createTestShapefile(t, "stations.shp", shp.POINT,
+		[]shp.Field{shp.StringField("NAME", 25), shp.StringField("Label", 25)},
+		[]testShape{
+			{shape: &shp.Point{X: 10.0, Y: 53.0}, attributes: []string{"Hamburg Hauptbahnhof", "Hbf"}},
+			{shape: &shp.Point{X: 9.9, Y: 53.5}, attributes: []string{"Altona", ""}},
+		})
+
+	tests := []struct {
+		name       string
+		labelField string
+		expected   []string
+	}{
+		{name: "default prefers Label", labelField: "", expected: []string{"Hbf", "Altona"}},
+		{name: "configured field", labelField: "NAME", expected: []string{"Hamburg Hauptbahnhof", "Altona"}},
+		{name: "none disables labels", labelField: "none", expected: []string{"", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &ShapefileReader{Options: Options{LabelField: tt.labelField}}
+			poiList, err := reader.ParseFile(filePath)
+			if err != nil {
+				t.Fatalf("ParseFile returned error: %v", err)
+			}
+
+			if len(*poiList) != len(tt.expected) {
+				t.Fatalf("Expected %d POIs, got %d", len(tt.expected), len(*poiList))
+			}
+			for i, p := range *poiList {
+				if p.Text != tt.expected[i] {
+					t.Errorf("POI %d: Expected text '%s', got '%s'", i, tt.expected[i], p.Text)
+				}
+			}
+		})
+	}
+}
+
+func TestShapefileReader_ParseFile_Rules(t *testing.T) {
+	filePath := createTestShapefile(t, "railway.shp", shp.POINT,
+		[]shp.Field{shp.StringField("railway", 20)},
+		[]testShape{
+			{shape: &shp.Point{X: 10.0, Y: 53.0}, attributes: []string{"station"}},
+			{shape: &shp.Point{X: 10.1, Y: 53.1}, attributes: []string{"platform"}},
+			{shape: &shp.Point{X: 10.2, Y: 53.2}, attributes: []string{"signal"}},
+		})
+
+	ruleSet, err := rules.Parse([]byte(`{"rules": [
+		{"match": {"railway": "station"}, "style": {"color": "#ff0000", "font_size": 16, "max_lod": 8}},
+		{"match": {"railway": "platform", "@file": "railway.shp"}, "style": {"color": "#808080", "font_size": 8}}
+	]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse rules: %v", err)
+	}
+
+	reader := &ShapefileReader{Options: Options{Rules: ruleSet}}
+	poiList, err := reader.ParseFile(filePath)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	expected := []poi.POI{
+		{Color: "ff0000", FontSize: 16, MaxLod: 8},
+		{Color: "808080", FontSize: 8, MaxLod: DefaultMaxLod},
+		{Color: defaultColor, FontSize: defaultFontSize, MaxLod: DefaultMaxLod},
+	}
+	if len(*poiList) != len(expected) {
+		t.Fatalf("Expected %d POIs, got %d", len(expected), len(*poiList))
+	}
+	for i, p := range *poiList {
+		if p.Color != expected[i].Color || p.FontSize != expected[i].FontSize || p.MaxLod != expected[i].MaxLod {
+			t.Errorf("POI %d: Expected %s/%d/%d, got %s/%d/%d", i,
+				expected[i].Color, expected[i].FontSize, expected[i].MaxLod, p.Color, p.FontSize, p.MaxLod)
+		}
+	}
+}
+
+func TestShapefileReader_ParseFile_WithoutDBF(t *testing.T) {
+	filePath := createTestShapefile(t, "points.shp", shp.POINT, nil,
+		[]testShape{{shape: &shp.Point{X: 10.0, Y: 53.0}}})
+
+	reader := &ShapefileReader{}
+	poiList, err := reader.ParseFile(filePath)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	if len(*poiList) != 1 || (*poiList)[0].Text != "" {
+		t.Errorf("Expected 1 unlabelled POI, got %+v", *poiList)
+	}
+}
+
+func TestShapefileReader_ReadFeatures_ShortDBF(t *testing.T) {
+	filePath := createTestShapefile(t, "stations.shp", shp.POINT,
+		[]shp.Field{shp.StringField("NAME", 25)},
+		[]testShape{
+			{shape: &shp.Point{X: 10.0, Y: 53.0}, attributes: []string{"Hamburg Hbf"}},
+			{shape: &shp.Point{X: 9.9, Y: 53.5}, attributes: []string{"Altona"}},
+			{shape: &shp.Point{X: 10.1, Y: 53.6}, attributes: []string{"Barmbek"}},
+		})
+
+	// Cut the table off after its first record, while its header still
+	// counts three
+	dbfPath := strings.TrimSuffix(filePath, ".shp") + ".dbf"
+	data, err := os.ReadFile(dbfPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headerLength := int(binary.LittleEndian.Uint16(data[8:10]))
+	recordLength := int(binary.LittleEndian.Uint16(data[10:12]))
+	if err := os.WriteFile(dbfPath, data[:headerLength+recordLength], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader := &ShapefileReader{}
+	features, err := reader.ReadFeatures(filePath)
+	if err != nil {
+		t.Fatalf("ReadFeatures returned error: %v", err)
+	}
+	if len(features) != 3 {
+		t.Fatalf("Expected every shape to be read, got %d features", len(features))
+	}
+	if name := features[0].Properties["NAME"]; name != "Hamburg Hbf" {
+		t.Errorf("Expected the first shape to keep its attributes, got '%s'", name)
+	}
+	for _, feature := range features[1:] {
+		if len(feature.Properties) != 0 {
+			t.Errorf("Expected shapes without a record to have no attributes, got %v", feature.Properties)
+		}
+	}
+}
+
+func TestShapefileReader_ParseFile_UpperCaseSidecars(t *testing.T) {
+	filePath := createTestShapefile(t, "rail.shp", shp.POINT,
+		[]shp.Field{shp.StringField("NAME", 25)},
+		[]testShape{{shape: &shp.Point{X: 500000, Y: 0}, attributes: []string{"Origin"}}})
+	writePrj(t, filePath, utm33NPrj)
+
+	// Exports from some tools name every file in upper case
+	base := strings.TrimSuffix(filePath, filepath.Ext(filePath))
+	upper := filepath.Join(filepath.Dir(filePath), "RAIL")
+	for _, ext := range []string{"shp", "dbf", "prj"} {
+		if err := os.Rename(base+"."+ext, upper+"."+strings.ToUpper(ext)); err != nil {
+			t.Fatalf("Failed to rename %s: %v", ext, err)
+		}
+	}
+
+	reader := &ShapefileReader{}
+	poiList, err := reader.ParseFile(upper + ".SHP")
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	if len(*poiList) != 1 {
+		t.Fatalf("Expected 1 POI, got %d", len(*poiList))
+	}
+	if p := (*poiList)[0]; p.Text != "Origin" || math.Abs(p.Lon-15) > 1e-9 || math.Abs(p.Lat) > 1e-9 {
+		t.Errorf("Expected the labelled UTM origin at (15, 0), got %+v", p)
+	}
+}
+
+func TestShapefileReader_ParseFile_MultiPartPolyLine(t *testing.T) {
+	// Two parts roughly 11km long each, about 130km apart
+	first := []shp.Point{{X: 10.0, Y: 53.0}, {X: 10.0, Y: 53.1}}
+	second := []shp.Point{{X: 12.0, Y: 53.0}, {X: 12.0, Y: 53.1}}
+	polyLine := shp.NewPolyLine([][]shp.Point{first, second})
+
+	filePath := createTestShapefile(t, "tracks.shp", shp.POLYLINE,
+		[]shp.Field{shp.StringField("Label", 25)},
+		[]testShape{{shape: polyLine, attributes: []string{"S1"}}})
+
+	reader := &ShapefileReader{Options: Options{InterpolateDistance: 5000}}
+	poiList, err := reader.ParseFile(filePath)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	// Each part gets 2 intermediate points; nothing is added across the gap
+	if len(*poiList) != 8 {
+		t.Fatalf("Expected 8 POIs, got %d", len(*poiList))
+	}
+	for i, p := range *poiList {
+		if math.Abs(p.Lon-10.0) > 1e-9 && math.Abs(p.Lon-12.0) > 1e-9 {
+			t.Errorf("POI %d: expected longitude on one of the parts, got %f", i, p.Lon)
+		}
+	}
+
+	// Each part starts with a labelled vertex
+	if (*poiList)[0].Text != "S1" || (*poiList)[4].Text != "S1" {
+		t.Errorf("Expected both parts to be labelled, got '%s' and '%s'", (*poiList)[0].Text, (*poiList)[4].Text)
+	}
+}
+
+func TestShapefileReader_ParseFile_Polygon(t *testing.T) {
+	outer := []shp.Point{{X: 10.0, Y: 53.0}, {X: 11.0, Y: 53.0}, {X: 11.0, Y: 54.0}, {X: 10.0, Y: 54.0}, {X: 10.0, Y: 53.0}}
+	hole := []shp.Point{{X: 10.2, Y: 53.2}, {X: 10.4, Y: 53.2}, {X: 10.4, Y: 53.4}, {X: 10.2, Y: 53.2}}
+	polygon := shp.Polygon(*shp.NewPolyLine([][]shp.Point{outer, hole}))
+
+	filePath := createTestShapefile(t, "footprints.shp", shp.POLYGON,
+		[]shp.Field{shp.StringField("Label", 25)},
+		[]testShape{{shape: &polygon, attributes: []string{"Depot"}}})
+
+	reader := &ShapefileReader{}
+	poiList, err := reader.ParseFile(filePath)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	// Both rings are emitted with their closing points removed
+	if len(*poiList) != 4+3 {
+		t.Fatalf("Expected 7 POIs, got %d", len(*poiList))
+	}
+
+	// Each ring is labelled on its first vertex
+	for i, p := range *poiList {
+		expected := ""
+		if i == 0 || i == 4 {
+			expected = "Depot"
+		}
+		if p.Text != expected {
+			t.Errorf("POI %d: Expected text '%s', got '%s'", i, expected, p.Text)
+		}
+	}
+	if (*poiList)[4].Lon != 10.2 || (*poiList)[4].Lat != 53.2 {
+		t.Errorf("Expected hole to start at (10.2, 53.2), got (%f, %f)", (*poiList)[4].Lon, (*poiList)[4].Lat)
+	}
+}
+
+func TestShapefileReader_ReadFeatures_PolygonRings(t *testing.T) {
+	// Outer rings are clockwise and holes counter-clockwise
+	first := []shp.Point{{X: 10.0, Y: 53.0}, {X: 10.0, Y: 54.0}, {X: 11.0, Y: 54.0}, {X: 11.0, Y: 53.0}, {X: 10.0, Y: 53.0}}
+	hole := []shp.Point{{X: 10.2, Y: 53.2}, {X: 10.4, Y: 53.2}, {X: 10.4, Y: 53.4}, {X: 10.2, Y: 53.2}}
+	second := []shp.Point{{X: 12.0, Y: 53.0}, {X: 12.0, Y: 54.0}, {X: 13.0, Y: 54.0}, {X: 12.0, Y: 53.0}}
+	polygon := shp.Polygon(*shp.NewPolyLine([][]shp.Point{first, hole, second}))
+
+	filePath := createTestShapefile(t, "islands.shp", shp.POLYGON,
+		[]shp.Field{shp.StringField("name", 25)},
+		[]testShape{{shape: &polygon, attributes: []string{"Islands"}}})
+
+	reader := &ShapefileReader{}
+	features, err := reader.ReadFeatures(filePath)
+	if err != nil {
+		t.Fatalf("ReadFeatures returned error: %v", err)
+	}
+
+	if len(features) != 1 {
+		t.Fatalf("Expected 1 feature, got %d", len(features))
+	}
+	feature := features[0]
+	if feature.Properties["name"] != "Islands" || feature.Source.File != "islands.shp" {
+		t.Errorf("Expected attributes and source to be kept, got %+v and %+v", feature.Properties, feature.Source)
+	}
+
+	if len(feature.Geometries) != 2 {
+		t.Fatalf("Expected 2 polygons, got %d", len(feature.Geometries))
+	}
+	if rings := feature.Geometries[0].Rings; len(rings) != 2 || len(rings[0]) != 4 || len(rings[1]) != 3 {
+		t.Errorf("Expected the first polygon to have an outer ring and a hole, got %v", rings)
+	}
+	if rings := feature.Geometries[1].Rings; len(rings) != 1 {
+		t.Errorf("Expected the second polygon to have a single ring, got %v", rings)
+	}
+}
+
+func TestShapefileReader_ParseFile_MultiPoint(t *testing.T) {
+	points := []shp.Point{{X: 10.0, Y: 53.0}, {X: 10.1, Y: 53.1}, {X: 10.2, Y: 53.2}}
+	multiPoint := &shp.MultiPoint{Box: shp.BBoxFromPoints(points), NumPoints: int32(len(points)), Points: points}
+
+	filePath := createTestShapefile(t, "signals.shp", shp.MULTIPOINT, nil, []testShape{{shape: multiPoint}})
+
+	reader := &ShapefileReader{}
+	poiList, err := reader.ParseFile(filePath)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	if len(*poiList) != 3 {
+		t.Fatalf("Expected 3 POIs, got %d", len(*poiList))
+	}
+	for i, p := range *poiList {
+		if p.Lon != points[i].X || p.Lat != points[i].Y {
+			t.Errorf("POI %d: Expected (%f, %f), got (%f, %f)", i, points[i].X, points[i].Y, p.Lon, p.Lat)
+		}
+	}
+}
+
+func TestShapefileReader_ParseFile_ZAndMShapes(t *testing.T) {
+	line := []shp.Point{{X: 10.0, Y: 53.0}, {X: 10.1, Y: 53.1}}
+	ring := []shp.Point{{X: 10.0, Y: 53.0}, {X: 10.1, Y: 53.0}, {X: 10.1, Y: 53.1}, {X: 10.0, Y: 53.0}}
+
+	tests := []struct {
+		name      string
+		shapeType shp.ShapeType
+		shape     shp.Shape
+		expected  int
+	}{
+		{
+			name:      "PointZ",
+			shapeType: shp.POINTZ,
+			shape:     &shp.PointZ{X: 10.0, Y: 53.0, Z: 12.5},
+			expected:  1,
+		},
+		{
+			name:      "PointM",
+			shapeType: shp.POINTM,
+			shape:     &shp.PointM{X: 10.0, Y: 53.0, M: 4},
+			expected:  1,
+		},
+		{
+			name:      "PolyLineZ",
+			shapeType: shp.POLYLINEZ,
+			shape: &shp.PolyLineZ{
+				Box: shp.BBoxFromPoints(line), NumParts: 1, NumPoints: 2, Parts: []int32{0}, Points: line,
+				ZArray: make([]float64, 2), MArray: make([]float64, 2),
+			},
+			expected: 2,
+		},
+		{
+			name:      "PolyLineM",
+			shapeType: shp.POLYLINEM,
+			shape: &shp.PolyLineM{
+				Box: shp.BBoxFromPoints(line), NumParts: 1, NumPoints: 2, Parts: []int32{0}, Points: line,
+				MArray: make([]float64, 2),
+			},
+			expected: 2,
+		},
+		{
+			name:      "PolygonZ",
+			shapeType: shp.POLYGONZ,
+			shape: &shp.PolygonZ{
+				Box: shp.BBoxFromPoints(ring), NumParts: 1, NumPoints: 4, Parts: []int32{0}, Points: ring,
+				ZArray: make([]float64, 4), MArray: make([]float64, 4),
+			},
+			expected: 3,
+		},
+		{
+			name:      "PolygonM",
+			shapeType: shp.POLYGONM,
+			shape: &shp.PolygonM{
+				Box: shp.BBoxFromPoints(ring), NumParts: 1, NumPoints: 4, Parts: []int32{0}, Points: ring,
+				MArray: make([]float64, 4),
+			},
+			expected: 3,
+		},
+		{
+			name:      "MultiPointZ",
+			shapeType: shp.MULTIPOINTZ,
+			shape: &shp.MultiPointZ{
+				Box: shp.BBoxFromPoints(line), NumPoints: 2, Points: line,
+				ZArray: make([]float64, 2), MArray: make([]float64, 2),
+			},
+			expected: 2,
+		},
+		{
+			name:      "MultiPointM",
+			shapeType: shp.MULTIPOINTM,
+			shape: &shp.MultiPointM{
+				Box: shp.BBoxFromPoints(line), NumPoints: 2, Points: line,
+				MArray: make([]float64, 2),
+			},
+			expected: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := createTestShapefile(t, "shape.shp", tt.shapeType, nil, []testShape{{shape: tt.shape}})
+
+			reader := &ShapefileReader{}
+			poiList, err := reader.ParseFile(filePath)
+			if err != nil {
+				t.Fatalf("ParseFile returned error: %v", err)
+			}
+
+			if len(*poiList) != tt.expected {
+				t.Fatalf("Expected %d POIs, got %d", tt.expected, len(*poiList))
+			}
+			if (*poiList)[0].Lon != 10.0 || (*poiList)[0].Lat != 53.0 {
+				t.Errorf("Expected first POI at (10.0, 53.0), got (%f, %f)", (*poiList)[0].Lon, (*poiList)[0].Lat)
+			}
+		})
+	}
+}
+
+const utm33NPrj = `PROJCS["WGS_1984_UTM_Zone_33N",GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",` +
+	`SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],` +
+	`PROJECTION["Transverse_Mercator"],PARAMETER["False_Easting",500000.0],PARAMETER["False_Northing",0.0],` +
+	`PARAMETER["Central_Meridian",15.0],PARAMETER["Scale_Factor",0.9996],PARAMETER["Latitude_Of_Origin",0.0],` +
+	`UNIT["Meter",1.0]]`
+
+// writePrj adds a .prj sidecar next to a test shapefile
+func writePrj(t *testing.T, filePath, wkt string) {
+	t.Helper()
+	prjPath := strings.TrimSuffix(filePath, filepath.Ext(filePath)) + ".prj"
+	if err := os.WriteFile(prjPath, []byte(wkt), 0o644); err != nil {
+		t.Fatalf("Failed to write prj: %v", err)
+	}
+}
+
+func TestShapefileReader_ParseFile_Reprojection(t *testing.T) {
+	// The UTM zone 33N origin on the central meridian, and a point 1km north of it
+	line := &shp.PolyLine{
+		NumParts:  1,
+		NumPoints: 2,
+		Parts:     []int32{0},
+		Points:    []shp.Point{{X: 500000, Y: 0}, {X: 500000, Y: 1000}},
+	}
+
+	tests := []struct {
+		name      string
+		prj       string
+		sourceCRS string
+	}{
+		{name: "prj sidecar", prj: utm33NPrj},
+		{name: "source CRS without prj", sourceCRS: "EPSG:32633"},
+		{name: "source CRS overrides prj", prj: `GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",` +
+			`SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`,
+			sourceCRS: "EPSG:32633"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := createTestShapefile(t, "utm.shp", shp.POLYLINE, nil, []testShape{{shape: line}})
+			if tt.prj != "" {
+				writePrj(t, filePath, tt.prj)
+			}
+
+			reader := &ShapefileReader{Options: Options{SourceCRS: tt.sourceCRS}}
+			poiList, err := reader.ParseFile(filePath)
+			if err != nil {
+				t.Fatalf("ParseFile returned error: %v", err)
+			}
+			if len(*poiList) != 2 {
+				t.Fatalf("Expected 2 POIs, got %d", len(*poiList))
+			}
+
+			first, second := (*poiList)[0], (*poiList)[1]
+			if math.Abs(first.Lon-15) > 1e-9 || math.Abs(first.Lat) > 1e-9 {
+				t.Errorf("Expected origin at (15, 0), got (%v, %v)", first.Lon, first.Lat)
+			}
+			// 1km of northing is roughly 0.009 degrees of latitude
+			if math.Abs(second.Lon-15) > 1e-9 || math.Abs(second.Lat-0.00904) > 1e-4 {
+				t.Errorf("Expected second point near (15, 0.009), got (%v, %v)", second.Lon, second.Lat)
+			}
+		})
+	}
+}
+
+func TestShapefileReader_ParseFile_ReprojectionErrors(t *testing.T) {
+	t.Run("invalid source CRS", func(t *testing.T) {
+		filePath := createTestShapefile(t, "points.shp", shp.POINT, nil,
+			[]testShape{{shape: &shp.Point{X: 500000, Y: 0}}})
+
+		reader := &ShapefileReader{Options: Options{SourceCRS: "EPSG:1"}}
+		if _, err := reader.ParseFile(filePath); err == nil {
+			t.Error("Expected error for unknown EPSG code")
+		}
+	})
+
+	t.Run("unreadable prj", func(t *testing.T) {
+		filePath := createTestShapefile(t, "points.shp", shp.POINT, nil,
+			[]testShape{{shape: &shp.Point{X: 500000, Y: 0}}})
+		writePrj(t, filePath, "not a coordinate system")
+
+		reader := &ShapefileReader{}
+		if _, err := reader.ParseFile(filePath); err == nil {
+			t.Error("Expected error for invalid .prj")
+		}
+	})
+}
+
+func TestSplitParts(t *testing.T) {
+	points := []shp.Point{{X: 0}, {X: 1}, {X: 2}, {X: 3}, {X: 4}}
+
+	tests := []struct {
+		name     string
+		parts    []int32
+		expected []int
+	}{
+		{name: "no parts", parts: nil, expected: []int{5}},
+		{name: "single part", parts: []int32{0}, expected: []int{5}},
+		{name: "two parts", parts: []int32{0, 2}, expected: []int{2, 3}},
+		{name: "out of range offset", parts: []int32{0, 3, 9}, expected: []int{3, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := splitParts(tt.parts, points)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Expected %d parts, got %d", len(tt.expected), len(result))
+			}
+			for i, part := range result {
+				if len(part) != tt.expected[i] {
+					t.Errorf("Part %d: Expected %d points, got %d", i, tt.expected[i], len(part))
+				}
+			}
+		})
+	}
+}
+
+type testShape struct {
+	shape      shp.Shape
+	attributes []string
+}
+
+// createTestShapefile writes a shapefile into a temp directory. A .dbf is only
+// written when fields are given.
+func createTestShapefile(t *testing.T, name string, shapeType shp.ShapeType, fields []shp.Field, shapes []testShape) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, name)
+
+	writer, err := shp.Create(filePath, shapeType)
+	if err != nil {
+		t.Fatalf("Failed to create shapefile: %v", err)
+	}
+	if len(fields) > 0 {
+		if err := writer.SetFields(fields); err != nil {
+			t.Fatalf("Failed to set fields: %v", err)
+		}
+	}
+	for _, s := range shapes {
+		row := writer.Write(s.shape)
+		for i, value := range s.attributes {
+			if err := writer.WriteAttribute(int(row), i, value); err != nil {
+				t.Fatalf("Failed to write attribute: %v", err)
+			}
+		}
+	}
+	writer.Close()
+
+	// go-shp writes the DBF without a dot before the extension
+	base := strings.TrimSuffix(filePath, filepath.Ext(filePath))
+	if len(fields) > 0 {
+		if err := os.Rename(base+"dbf", base+".dbf"); err != nil {
+			t.Fatalf("Failed to rename dbf: %v", err)
+		}
+	} else {
+		os.Remove(base + "dbf")
+	}
+
+	return filePath
+}
+
 func TestShapefileReader_Interface(_ *testing.T) {
 	// Ensure ShapefileReader implements the Reader interface
 	var _ Reader = &ShapefileReader{}
@@ -0,0 +1,180 @@
+package geometry
+
+import (
+	"container/heap"
+	"fmt"
+	"strings"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/gis"
+)
+
+// SimplifyMethod selects the algorithm used to thin out dense lines
+type SimplifyMethod string
+
+const (
+	// SimplifyDouglasPeucker keeps the vertices that deviate from the simplified
+	// line by more than the tolerance. It preserves sharp corners well.
+	SimplifyDouglasPeucker SimplifyMethod = "douglas-peucker"
+	// SimplifyVisvalingam repeatedly drops the vertex forming the smallest
+	// triangle with its neighbours until every triangle is at least
+	// tolerance × tolerance. It gives smoother curves than Douglas-Peucker.
+	SimplifyVisvalingam SimplifyMethod = "visvalingam"
+)
+
+// ParseSimplifyMethod accepts a method name or its abbreviation ("dp", "vw").
+// An empty name selects Douglas-Peucker.
+func ParseSimplifyMethod(name string) (SimplifyMethod, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "dp", string(SimplifyDouglasPeucker):
+		return SimplifyDouglasPeucker, nil
+	case "vw", string(SimplifyVisvalingam):
+		return SimplifyVisvalingam, nil
+	default:
+		return "", fmt.Errorf("unknown simplification method %q, expected %s or %s", name, SimplifyDouglasPeucker, SimplifyVisvalingam)
+	}
+}
+
+// simplifyLine thins out a line with the given method and tolerance in meters,
+// always keeping its first and last vertex and at least minPoints vertices
+func simplifyLine(coords []Coordinate, method SimplifyMethod, tolerance float64, minPoints int) []Coordinate {
+	if tolerance <= 0 || len(coords) <= max(minPoints, 2) {
+		return coords
+	}
+
+	var keep []bool
+	if method == SimplifyVisvalingam {
+		keep = visvalingam(coords, tolerance*tolerance, minPoints)
+	} else {
+		keep = douglasPeucker(coords, tolerance)
+	}
+
+	simplified := make([]Coordinate, 0, len(coords))
+	for i, coord := range coords {
+		if keep[i] {
+			simplified = append(simplified, coord)
+		}
+	}
+
+	// Douglas-Peucker can collapse a ring to its two end vertices
+	if len(simplified) < minPoints {
+		return coords
+	}
+	return simplified
+}
+
+// douglasPeucker marks the vertices to keep. It uses an explicit stack so that
+// lines with hundreds of thousands of vertices cannot exhaust the call stack.
+func douglasPeucker(coords []Coordinate, tolerance float64) []bool {
+	keep := make([]bool, len(coords))
+	keep[0] = true
+	keep[len(coords)-1] = true
+
+	type span struct{ first, last int }
+	stack := []span{{0, len(coords) - 1}}
+
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		first, last := coords[s.first], coords[s.last]
+		farthest, farthestDistance := -1, tolerance
+		for i := s.first + 1; i < s.last; i++ {
+			distance := gis.SegmentDistance(coords[i].Lat, coords[i].Lon, first.Lat, first.Lon, last.Lat, last.Lon)
+			if distance > farthestDistance {
+				farthest, farthestDistance = i, distance
+			}
+		}
+
+		if farthest >= 0 {
+			keep[farthest] = true
+			stack = append(stack, span{s.first, farthest}, span{farthest, s.last})
+		}
+	}
+
+	return keep
+}
+
+// visvalingam marks the vertices to keep, removing the vertex with the smallest
+// effective area until every remaining area reaches minArea (square meters)
+func visvalingam(coords []Coordinate, minArea float64, minPoints int) []bool {
+	n := len(coords)
+	keep := make([]bool, n)
+	prev := make([]int, n)
+	next := make([]int, n)
+	for i := range coords {
+		keep[i] = true
+		prev[i] = i - 1
+		next[i] = i + 1
+	}
+
+	area := func(i int) float64 {
+		a, b, c := coords[prev[i]], coords[i], coords[next[i]]
+		return gis.TriangleArea(a.Lat, a.Lon, b.Lat, b.Lon, c.Lat, c.Lon)
+	}
+
+	queue := make(vertexQueue, 0, n-2)
+	items := make([]*vertexArea, n)
+	for i := 1; i < n-1; i++ {
+		items[i] = &vertexArea{vertex: i, area: area(i), index: len(queue)}
+		queue = append(queue, items[i])
+	}
+	heap.Init(&queue)
+
+	remaining := n
+	for queue.Len() > 0 && remaining > max(minPoints, 2) {
+		smallest := heap.Pop(&queue).(*vertexArea)
+		if smallest.area >= minArea {
+			break
+		}
+
+		i := smallest.vertex
+		items[i] = nil
+		keep[i] = false
+		remaining--
+		next[prev[i]] = next[i]
+		prev[next[i]] = prev[i]
+
+		// A neighbour's area never drops below that of the vertex just removed,
+		// so vertices are removed in order of their effective area
+		for _, neighbour := range []int{prev[i], next[i]} {
+			if item := items[neighbour]; item != nil {
+				item.area = max(area(neighbour), smallest.area)
+				heap.Fix(&queue, item.index)
+			}
+		}
+	}
+
+	return keep
+}
+
+type vertexArea struct {
+	vertex int
+	area   float64
+	index  int
+}
+
+// vertexQueue is a min-heap of vertices ordered by effective area
+type vertexQueue []*vertexArea
+
+func (q vertexQueue) Len() int           { return len(q) }
+func (q vertexQueue) Less(i, j int) bool { return q[i].area < q[j].area }
+
+func (q vertexQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *vertexQueue) Push(x any) {
+	item := x.(*vertexArea)
+	item.index = len(*q)
+	*q = append(*q, item)
+}
+
+func (q *vertexQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	item.index = -1
+	return item
+}
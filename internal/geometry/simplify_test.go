@@ -0,0 +1,142 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
+)
+
+// wigglyLine runs north along the 10°E meridian, a great circle, with vertices
+// about 111 m apart and every other vertex offset east by about 1.3 m
+func wigglyLine(n int) []Coordinate {
+	coords := make([]Coordinate, n)
+	for i := range coords {
+		coords[i] = Coordinate{Lon: 10, Lat: 53 + float64(i)*0.001}
+		if i%2 == 1 {
+			coords[i].Lon += 0.00002
+		}
+	}
+	return coords
+}
+
+func TestParseSimplifyMethod(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected SimplifyMethod
+		wantErr  bool
+	}{
+		{input: "", expected: SimplifyDouglasPeucker},
+		{input: "dp", expected: SimplifyDouglasPeucker},
+		{input: "Douglas-Peucker", expected: SimplifyDouglasPeucker},
+		{input: "vw", expected: SimplifyVisvalingam},
+		{input: "visvalingam", expected: SimplifyVisvalingam},
+		{input: "chaikin", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			method, err := ParseSimplifyMethod(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tt.input)
+				}
+				return
+			}
+			if err != nil || method != tt.expected {
+				t.Errorf("ParseSimplifyMethod(%q) = (%q, %v), want %q", tt.input, method, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSimplifyLine_DouglasPeucker(t *testing.T) {
+	line := wigglyLine(101)
+
+	simplified := simplifyLine(line, SimplifyDouglasPeucker, 5, 2)
+	if len(simplified) != 2 {
+		t.Fatalf("Expected offsets below the tolerance to be removed, got %d vertices", len(simplified))
+	}
+	if simplified[0] != line[0] || simplified[1] != line[len(line)-1] {
+		t.Errorf("Expected the end vertices to be kept, got %v", simplified)
+	}
+
+	if kept := simplifyLine(line, SimplifyDouglasPeucker, 0.5, 2); len(kept) != len(line) {
+		t.Errorf("Expected offsets above the tolerance to be kept, got %d of %d vertices", len(kept), len(line))
+	}
+}
+
+func TestSimplifyLine_Visvalingam(t *testing.T) {
+	line := wigglyLine(101)
+
+	// Each offset vertex forms a triangle of about 150 m² with its neighbours.
+	// Removing vertices grows the remaining triangles, so the line is thinned
+	// out rather than reduced to its ends.
+	simplified := simplifyLine(line, SimplifyVisvalingam, 20, 2)
+	if len(simplified) >= len(line)/2 {
+		t.Errorf("Expected at least half of the vertices to be removed, got %d of %d", len(simplified), len(line))
+	}
+	if simplified[0] != line[0] || simplified[len(simplified)-1] != line[len(line)-1] {
+		t.Error("Expected the end vertices to be kept")
+	}
+
+	if kept := simplifyLine(line, SimplifyVisvalingam, 10, 2); len(kept) != len(line) {
+		t.Errorf("Expected triangles above the tolerance to be kept, got %d of %d vertices", len(kept), len(line))
+	}
+}
+
+func TestSimplifyLine_KeepsCorners(t *testing.T) {
+	// An L-shape with dense vertices along both legs
+	var line []Coordinate
+	for i := 0; i <= 50; i++ {
+		line = append(line, Coordinate{Lon: 10 + float64(i)*0.0001, Lat: 53})
+	}
+	for i := 1; i <= 50; i++ {
+		line = append(line, Coordinate{Lon: 10.005, Lat: 53 + float64(i)*0.0001})
+	}
+
+	for _, method := range []SimplifyMethod{SimplifyDouglasPeucker, SimplifyVisvalingam} {
+		t.Run(string(method), func(t *testing.T) {
+			simplified := simplifyLine(line, method, 10, 2)
+			if len(simplified) != 3 {
+				t.Fatalf("Expected the start, corner and end, got %d vertices", len(simplified))
+			}
+			corner := simplified[1]
+			if math.Abs(corner.Lon-10.005) > 1e-9 || math.Abs(corner.Lat-53) > 1e-9 {
+				t.Errorf("Expected the corner to be kept, got %v", corner)
+			}
+		})
+	}
+}
+
+func TestSimplifyLine_Rings(t *testing.T) {
+	// A small open ring that would collapse entirely at this tolerance
+	ring := []Coordinate{{Lon: 10, Lat: 53}, {Lon: 10.0001, Lat: 53}, {Lon: 10.0001, Lat: 53.0001}, {Lon: 10, Lat: 53.0001}}
+
+	for _, method := range []SimplifyMethod{SimplifyDouglasPeucker, SimplifyVisvalingam} {
+		t.Run(string(method), func(t *testing.T) {
+			if simplified := simplifyLine(ring, method, 1000, 3); len(simplified) < 3 {
+				t.Errorf("Expected a ring to keep at least 3 vertices, got %d", len(simplified))
+			}
+		})
+	}
+}
+
+func TestPOIConverter_Simplify(t *testing.T) {
+	feature := Feature{
+		Geometries: []Geometry{NewLineString(wigglyLine(1001))},
+		Properties: map[string]string{"name": "Trace"},
+	}
+
+	converter := POIConverter{Options: Options{SimplifyTolerance: 5, InterpolateDistance: 1000}}
+	poiList := make(poi.List, 0)
+	converter.Convert(&feature, newPOITemplate(DefaultMaxLod, defaultColor), &poiList)
+
+	// The 111 km line is reduced to its ends before being interpolated every kilometer
+	if len(poiList) != 113 {
+		t.Errorf("Expected the simplified line to be interpolated to 113 POIs, got %d", len(poiList))
+	}
+	if poiList[0].Text != "Trace" {
+		t.Errorf("Expected the first POI to be labelled, got '%s'", poiList[0].Text)
+	}
+}
@@ -0,0 +1,173 @@
+package geometry
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/expr"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/rules"
+)
+
+// FeatureFilter selects features by an expression over their attributes and
+// geometry, as given to --where. The grammar is that of --osm-filter (see the
+// expr package) with two additions:
+//
+//	type = station               the attribute is one of the values ("|" separated, "*" wildcards allowed)
+//	name = 'S1|S2'               the attribute is exactly the quoted value
+//	type != depot                the attribute is missing or none of the values
+//	tracks >= 2                  numeric comparison when both sides are numbers (=, !=, <, <=, >, >=)
+//	name ~ '^S'                  the attribute matches a regular expression
+//	name !~ 'Hbf$'               the attribute is missing or does not match
+//	electrified                  the attribute is set
+//	$geometry = line             the feature has a geometry of the type: point, line or polygon
+//	bbox(13.0, 52.3, 13.8, 52.7) the feature's bounds overlap the box (lon/lat)
+//
+// Attribute names are matched case-insensitively and include the @file and
+// @folder pseudo-attributes of styling rules; "name" falls back to the
+// feature's own name, such as a KML <name>.
+type FeatureFilter struct {
+	text string
+	expr expr.Expr[*Feature]
+}
+
+// featureLanguage evaluates expressions over features
+var featureLanguage = &expr.Language[*Feature]{
+	Attribute: featureAttribute,
+	Field:     featureField,
+	Functions: map[string]func(args []float64) (expr.Expr[*Feature], error){
+		"bbox": newBBoxExpr,
+	},
+}
+
+// ParseFeatureFilter parses a --where expression
+func ParseFeatureFilter(text string) (*FeatureFilter, error) {
+	e, err := expr.Parse(text, featureLanguage)
+	if err != nil {
+		return nil, fmt.Errorf("invalid where expression: %w", err)
+	}
+	return &FeatureFilter{text: strings.TrimSpace(text), expr: e}, nil
+}
+
+// Match reports whether a feature satisfies the expression. A nil filter keeps every feature.
+func (f *FeatureFilter) Match(feature *Feature) bool {
+	if f == nil {
+		return true
+	}
+	return f.expr.Match(feature)
+}
+
+func (f *FeatureFilter) String() string {
+	return f.text
+}
+
+// featureAttribute returns an attribute of a feature, matching the name
+// case-insensitively. Blank attributes count as missing.
+func featureAttribute(feature *Feature, name string) (string, bool) {
+	var value string
+	switch {
+	case strings.EqualFold(name, rules.FileAttribute):
+		value = feature.Source.File
+	case strings.EqualFold(name, rules.FolderAttribute):
+		value = strings.Join(feature.Source.Folders, "/")
+	default:
+		value = rules.LookupAttribute(feature.Properties, name)
+		if value == "" && strings.EqualFold(name, "name") {
+			value = feature.Name
+		}
+	}
+	value = strings.TrimSpace(value)
+	return value, value != ""
+}
+
+// featureField parses a test of one of the $ fields, of which there is only
+// $geometry so far
+func featureField(name, op, value string) (expr.Expr[*Feature], error) {
+	if !strings.EqualFold(name, "$geometry") {
+		return nil, fmt.Errorf("unknown field %q, expected $geometry", name)
+	}
+	if op != "=" {
+		return nil, fmt.Errorf("expected = or != after %s", name)
+	}
+
+	switch strings.ToLower(value) {
+	case "point":
+		return geometryTypeExpr{PointGeometry}, nil
+	case "line", "linestring":
+		return geometryTypeExpr{LineStringGeometry}, nil
+	case "polygon":
+		return geometryTypeExpr{PolygonGeometry}, nil
+	}
+	return nil, fmt.Errorf("unknown geometry type %q, expected point, line or polygon", value)
+}
+
+type geometryTypeExpr struct {
+	geometryType GeometryType
+}
+
+func (e geometryTypeExpr) Match(feature *Feature) bool {
+	for _, geometry := range feature.Geometries {
+		if geometry.Type == e.geometryType {
+			return true
+		}
+	}
+	return false
+}
+
+// bboxExpr keeps features whose bounding box overlaps the box
+type bboxExpr struct {
+	low, high Coordinate
+}
+
+// newBBoxExpr returns the test for bbox(minlon, minlat, maxlon, maxlat), as in --bbox
+func newBBoxExpr(args []float64) (expr.Expr[*Feature], error) {
+	if len(args) != 4 {
+		return nil, errors.New("expected minlon, minlat, maxlon, maxlat")
+	}
+	e := bboxExpr{
+		low:  Coordinate{Lon: args[0], Lat: args[1]},
+		high: Coordinate{Lon: args[2], Lat: args[3]},
+	}
+	if e.low.Lon > e.high.Lon || e.low.Lat > e.high.Lat {
+		return nil, fmt.Errorf("minimum %g,%g exceeds maximum %g,%g", args[0], args[1], args[2], args[3])
+	}
+	return e, nil
+}
+
+func (e bboxExpr) Match(feature *Feature) bool {
+	low, high, ok := featureBounds(feature)
+	return ok && low.Lon <= e.high.Lon && high.Lon >= e.low.Lon && low.Lat <= e.high.Lat && high.Lat >= e.low.Lat
+}
+
+// featureBounds returns the south-west and north-east corners of the bounding
+// box of all the feature's coordinates, or false when it has none
+func featureBounds(feature *Feature) (Coordinate, Coordinate, bool) {
+	return geometryBounds(feature.Geometries...)
+}
+
+// geometryBounds returns the corners of the bounding box of the geometries
+// as featureBounds does
+func geometryBounds(geometries ...Geometry) (Coordinate, Coordinate, bool) {
+	var low, high Coordinate
+	found := false
+	extend := func(coords []Coordinate) {
+		for _, coord := range coords {
+			if !found {
+				low, high, found = coord, coord, true
+				continue
+			}
+			low.Lon = math.Min(low.Lon, coord.Lon)
+			low.Lat = math.Min(low.Lat, coord.Lat)
+			high.Lon = math.Max(high.Lon, coord.Lon)
+			high.Lat = math.Max(high.Lat, coord.Lat)
+		}
+	}
+	for _, geometry := range geometries {
+		extend(geometry.Coordinates)
+		for _, ring := range geometry.Rings {
+			extend(ring)
+		}
+	}
+	return low, high, found
+}
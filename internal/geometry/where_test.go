@@ -0,0 +1,135 @@
+package geometry
+
+import (
+	"testing"
+)
+
+func TestFeatureFilter_Match(t *testing.T) {
+	station := Feature{
+		Geometries: []Geometry{NewPoint(13.37, 52.52)},
+		Properties: map[string]string{"TYPE": "station", "tracks": "12"},
+		Name:       "Berlin Hbf",
+		Source:     Source{File: "berlin.kml", Folders: []string{"Stations"}},
+	}
+	sBahn := Feature{
+		Geometries: []Geometry{NewLineString([]Coordinate{{Lon: 13.0, Lat: 52.4}, {Lon: 14.0, Lat: 52.6}})},
+		Properties: map[string]string{"type": "line", "name": "S1", "tracks": "2", "electrified": "yes"},
+	}
+	depot := Feature{
+		Geometries: []Geometry{NewPolygon([]Coordinate{{Lon: 9.9, Lat: 53.5}, {Lon: 10.0, Lat: 53.5}, {Lon: 10.0, Lat: 53.6}})},
+		Properties: map[string]string{"type": "depot", "name": " "},
+	}
+
+	tests := []struct {
+		filter   string
+		expected []bool // station, sBahn, depot
+	}{
+		{filter: "type = 'station'", expected: []bool{true, false, false}},
+		{filter: "type = station", expected: []bool{true, false, false}},
+		{filter: "type != 'depot'", expected: []bool{true, true, false}},
+		{filter: "type <> depot", expected: []bool{true, true, false}},
+		{filter: "type = 'station' or name ~ '^S'", expected: []bool{true, true, false}},
+		{filter: "name ~ 'Hbf$'", expected: []bool{true, false, false}},
+		{filter: "name !~ '^S'", expected: []bool{true, false, true}},
+		{filter: "tracks >= 2", expected: []bool{true, true, false}},
+		{filter: "tracks > 2", expected: []bool{true, false, false}},
+		{filter: "tracks < 10", expected: []bool{false, true, false}},
+		{filter: "tracks = 2.0", expected: []bool{false, true, false}},
+		{filter: "electrified", expected: []bool{false, true, false}},
+		{filter: "not electrified", expected: []bool{true, false, true}},
+		{filter: "name", expected: []bool{true, true, false}},
+		{filter: "$geometry = point", expected: []bool{true, false, false}},
+		{filter: "$geometry = LineString or $geometry = polygon", expected: []bool{false, true, true}},
+		{filter: "$geometry != line", expected: []bool{true, false, true}},
+		{filter: "bbox(13.3, 52.5, 13.4, 52.6)", expected: []bool{true, true, false}},
+		{filter: "BBOX(9, 53, 11, 54)", expected: []bool{false, false, true}},
+		{filter: "@folder = Stations and @file ~ '\\.kml$'", expected: []bool{true, false, false}},
+		{filter: "\"Type\" = station", expected: []bool{true, false, false}},
+		{filter: "(type = line or type = depot) and !($geometry = polygon)", expected: []bool{false, true, false}},
+		{filter: "type = line OR type = depot AND bbox(13, 52, 14, 53)", expected: []bool{false, true, false}},
+		{filter: "type=station|depot", expected: []bool{true, false, true}},
+		{filter: "type!=line|depot", expected: []bool{true, false, false}},
+		{filter: "name=S*", expected: []bool{false, true, false}},
+		{filter: "name='S*'", expected: []bool{false, false, false}},
+		{filter: "type='station|depot'", expected: []bool{false, false, false}},
+		{filter: "name~\"Hbf$\"", expected: []bool{true, false, false}},
+		{filter: "railway", expected: []bool{false, false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			filter, err := ParseFeatureFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("ParseFeatureFilter returned error: %v", err)
+			}
+			for i, feature := range []Feature{station, sBahn, depot} {
+				if got := filter.Match(&feature); got != tt.expected[i] {
+					t.Errorf("Match(%v) = %v, expected %v", feature.Properties, got, tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFeatureFilter_Nil(t *testing.T) {
+	var filter *FeatureFilter
+	feature := Feature{Geometries: []Geometry{NewPoint(10, 53)}}
+	if !filter.Match(&feature) {
+		t.Error("Expected a nil filter to keep every feature")
+	}
+}
+
+func TestParseFeatureFilter_Errors(t *testing.T) {
+	tests := []string{
+		"",
+		"type =",
+		"type = station and",
+		"(type = station",
+		"type = station)",
+		"name ~ '['",
+		"name = 'Berlin",
+		"$geometry = circle",
+		"$geometry ~ point",
+		"$area > 5",
+		"bbox(13, 52, 14)",
+		"bbox(14, 52, 13, 53)",
+		"bbox(a, b, c, d)",
+	}
+
+	for _, filter := range tests {
+		t.Run(filter, func(t *testing.T) {
+			if _, err := ParseFeatureFilter(filter); err == nil {
+				t.Errorf("Expected error for %q", filter)
+			}
+		})
+	}
+}
+
+func TestOptions_Where(t *testing.T) {
+	geojsonContent := `{
+		"type": "FeatureCollection",
+		"features": [
+			{"type": "Feature", "properties": {"type": "station", "name": "S Hackescher Markt"}, "geometry": {"type": "Point", "coordinates": [13.40, 52.52]}},
+			{"type": "Feature", "properties": {"type": "station", "name": "Alexanderplatz"}, "geometry": {"type": "Point", "coordinates": [13.41, 52.52]}},
+			{"type": "Feature", "properties": {"type": "halt", "name": "S Savignyplatz"}, "geometry": {"type": "Point", "coordinates": [13.32, 52.50]}}
+		]
+	}`
+	filePath := createTempFile(t, "stations.geojson", geojsonContent)
+
+	where, err := ParseFeatureFilter("type = 'station' and name ~ '^S'")
+	if err != nil {
+		t.Fatalf("ParseFeatureFilter returned error: %v", err)
+	}
+	reader := &GeoJSONReader{Options: Options{Where: where}}
+	poiList, err := reader.ParseFile(filePath)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	if len(*poiList) != 1 {
+		t.Fatalf("Expected 1 POI, got %d", len(*poiList))
+	}
+	if p := (*poiList)[0]; p.Text != "S Hackescher Markt" {
+		t.Errorf("Expected the Hackescher Markt station, got %+v", p)
+	}
+}
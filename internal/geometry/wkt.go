@@ -0,0 +1,241 @@
+package geometry
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// wktTypes are the geometry types ParseWKT understands
+var wktTypes = map[string]bool{
+	"POINT":              true,
+	"LINESTRING":         true,
+	"POLYGON":            true,
+	"MULTIPOINT":         true,
+	"MULTILINESTRING":    true,
+	"MULTIPOLYGON":       true,
+	"GEOMETRYCOLLECTION": true,
+}
+
+// ParseWKT parses a WKT geometry such as "POINT (13.37 52.52)" or
+// "MULTILINESTRING ((...), (...))". Z and M values are ignored and an EWKT
+// "SRID=...;" prefix is skipped. Multi-part geometries and collections return a
+// Geometry per part; EMPTY geometries return none.
+func ParseWKT(text string) ([]Geometry, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(strings.ToUpper(text), "SRID=") {
+		if _, rest, found := strings.Cut(text, ";"); found {
+			text = rest
+		}
+	}
+
+	p := &wktGeometryParser{text: text}
+	geometries, err := p.geometry()
+	if err != nil {
+		return nil, fmt.Errorf("invalid WKT: %w", err)
+	}
+	if p.skipSpace(); p.pos < len(p.text) {
+		return nil, fmt.Errorf("invalid WKT: unexpected %q at offset %d", p.text[p.pos:], p.pos)
+	}
+	return geometries, nil
+}
+
+type wktGeometryParser struct {
+	text string
+	pos  int
+}
+
+func (p *wktGeometryParser) geometry() ([]Geometry, error) {
+	keyword := p.word()
+
+	// Dimension modifiers may be written separately ("POINT Z") or attached ("POINTZ")
+	if trimmed := strings.TrimRight(keyword, "ZM"); trimmed != keyword && wktTypes[trimmed] {
+		keyword = trimmed
+	}
+	if !wktTypes[keyword] {
+		if keyword == "" {
+			return nil, fmt.Errorf("expected a geometry type at offset %d", p.pos)
+		}
+		return nil, fmt.Errorf("unsupported geometry type %q", keyword)
+	}
+
+	switch p.peekWord() {
+	case "Z", "M", "ZM":
+		p.word()
+	}
+	if p.peekWord() == "EMPTY" {
+		p.word()
+		return nil, nil
+	}
+
+	var geometries []Geometry
+	switch keyword {
+	case "POINT":
+		coords, err := wktList(p, p.coordinate)
+		if err != nil {
+			return nil, err
+		}
+		if len(coords) != 1 {
+			return nil, fmt.Errorf("POINT must have exactly one position")
+		}
+		geometries = append(geometries, NewPoint(coords[0].Lon, coords[0].Lat))
+	case "LINESTRING":
+		coords, err := p.coordinateList()
+		if err != nil {
+			return nil, err
+		}
+		geometries = append(geometries, NewLineString(coords))
+	case "POLYGON":
+		rings, err := p.rings()
+		if err != nil {
+			return nil, err
+		}
+		geometries = append(geometries, NewPolygon(rings...))
+	case "MULTIPOINT":
+		// Both "MULTIPOINT (1 2, 3 4)" and "MULTIPOINT ((1 2), (3 4))" are in use
+		coords, err := wktList(p, p.multiPointMember)
+		if err != nil {
+			return nil, err
+		}
+		for _, coord := range coords {
+			geometries = append(geometries, NewPoint(coord.Lon, coord.Lat))
+		}
+	case "MULTILINESTRING":
+		lines, err := wktList(p, p.coordinateList)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			geometries = append(geometries, NewLineString(line))
+		}
+	case "MULTIPOLYGON":
+		polygons, err := wktList(p, p.rings)
+		if err != nil {
+			return nil, err
+		}
+		for _, rings := range polygons {
+			geometries = append(geometries, NewPolygon(rings...))
+		}
+	case "GEOMETRYCOLLECTION":
+		members, err := wktList(p, p.geometry)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			geometries = append(geometries, member...)
+		}
+	}
+	return geometries, nil
+}
+
+func (p *wktGeometryParser) rings() ([][]Coordinate, error) {
+	return wktList(p, p.coordinateList)
+}
+
+func (p *wktGeometryParser) coordinateList() ([]Coordinate, error) {
+	return wktList(p, p.coordinate)
+}
+
+func (p *wktGeometryParser) multiPointMember() (Coordinate, error) {
+	if p.skipSpace(); p.peek() != '(' {
+		return p.coordinate()
+	}
+	coords, err := wktList(p, p.coordinate)
+	if err != nil {
+		return Coordinate{}, err
+	}
+	if len(coords) != 1 {
+		return Coordinate{}, fmt.Errorf("MULTIPOINT members must have exactly one position")
+	}
+	return coords[0], nil
+}
+
+// coordinate reads "x y" followed by optional z and m values
+func (p *wktGeometryParser) coordinate() (Coordinate, error) {
+	var values []float64
+	for {
+		p.skipSpace()
+		start := p.pos
+		for p.pos < len(p.text) && strings.IndexByte("+-.0123456789eE", p.text[p.pos]) >= 0 {
+			p.pos++
+		}
+		if start == p.pos {
+			break
+		}
+		value, err := strconv.ParseFloat(p.text[start:p.pos], 64)
+		if err != nil {
+			return Coordinate{}, fmt.Errorf("invalid number %q", p.text[start:p.pos])
+		}
+		values = append(values, value)
+	}
+	if len(values) < 2 || len(values) > 4 {
+		return Coordinate{}, fmt.Errorf("expected 2 to 4 coordinate values at offset %d, got %d", p.pos, len(values))
+	}
+	return Coordinate{Lon: values[0], Lat: values[1]}, nil
+}
+
+// wktList reads a parenthesized, comma-separated list of items
+func wktList[T any](p *wktGeometryParser, item func() (T, error)) ([]T, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	var items []T
+	for {
+		value, err := item()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, value)
+
+		if p.skipSpace(); p.peek() == ',' {
+			p.pos++
+			continue
+		}
+		if err := p.expect(')'); err != nil {
+			return nil, err
+		}
+		return items, nil
+	}
+}
+
+func (p *wktGeometryParser) expect(c byte) error {
+	if p.skipSpace(); p.peek() != c {
+		return fmt.Errorf("expected %q at offset %d", c, p.pos)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *wktGeometryParser) peek() byte {
+	if p.pos < len(p.text) {
+		return p.text[p.pos]
+	}
+	return 0
+}
+
+// word reads an upper-cased keyword
+func (p *wktGeometryParser) word() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.text) && isWKTLetter(p.text[p.pos]) {
+		p.pos++
+	}
+	return strings.ToUpper(p.text[start:p.pos])
+}
+
+func (p *wktGeometryParser) peekWord() string {
+	pos := p.pos
+	word := p.word()
+	p.pos = pos
+	return word
+}
+
+func (p *wktGeometryParser) skipSpace() {
+	for p.pos < len(p.text) && strings.IndexByte(" \t\r\n", p.text[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func isWKTLetter(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
@@ -0,0 +1,79 @@
+package geometry
+
+import (
+	"testing"
+)
+
+func TestParseWKT(t *testing.T) {
+	tests := []struct {
+		name          string
+		wkt           string
+		expectedTypes []GeometryType
+	}{
+		{name: "point", wkt: "POINT (13.37 52.52)", expectedTypes: []GeometryType{PointGeometry}},
+		{name: "lowercase with z", wkt: "point z (13.37 52.52 34)", expectedTypes: []GeometryType{PointGeometry}},
+		{name: "attached modifier", wkt: "POINTZM(13.37 52.52 34 0)", expectedTypes: []GeometryType{PointGeometry}},
+		{name: "ewkt", wkt: "SRID=4326;POINT(13.37 52.52)", expectedTypes: []GeometryType{PointGeometry}},
+		{name: "linestring", wkt: "LINESTRING (13.37 52.52, 13.38 52.53, 13.39 52.54)", expectedTypes: []GeometryType{LineStringGeometry}},
+		{name: "polygon with hole", wkt: "POLYGON ((10 53, 11 53, 11 54, 10 53), (10.2 53.2, 10.4 53.2, 10.4 53.4, 10.2 53.2))", expectedTypes: []GeometryType{PolygonGeometry}},
+		{name: "multipoint without parentheses", wkt: "MULTIPOINT (10 53, 11 54)", expectedTypes: []GeometryType{PointGeometry, PointGeometry}},
+		{name: "multipoint with parentheses", wkt: "MULTIPOINT ((10 53), (11 54))", expectedTypes: []GeometryType{PointGeometry, PointGeometry}},
+		{name: "multilinestring", wkt: "MULTILINESTRING ((10 53, 11 54), (12 55, 13 56))", expectedTypes: []GeometryType{LineStringGeometry, LineStringGeometry}},
+		{name: "multipolygon", wkt: "MULTIPOLYGON (((10 53, 11 53, 11 54, 10 53)), ((12 53, 13 53, 13 54, 12 53)))", expectedTypes: []GeometryType{PolygonGeometry, PolygonGeometry}},
+		{name: "collection", wkt: "GEOMETRYCOLLECTION (POINT (10 53), LINESTRING (10 53, 11 54))", expectedTypes: []GeometryType{PointGeometry, LineStringGeometry}},
+		{name: "empty", wkt: "LINESTRING EMPTY", expectedTypes: nil},
+		{name: "scientific notation", wkt: "POINT (1.337e1 5.252E+1)", expectedTypes: []GeometryType{PointGeometry}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geometries, err := ParseWKT(tt.wkt)
+			if err != nil {
+				t.Fatalf("ParseWKT returned error: %v", err)
+			}
+			if len(geometries) != len(tt.expectedTypes) {
+				t.Fatalf("Expected %d geometries, got %d", len(tt.expectedTypes), len(geometries))
+			}
+			for i, geometry := range geometries {
+				if geometry.Type != tt.expectedTypes[i] {
+					t.Errorf("Geometry %d: Expected type %s, got %s", i, tt.expectedTypes[i], geometry.Type)
+				}
+			}
+		})
+	}
+}
+
+func TestParseWKT_Coordinates(t *testing.T) {
+	geometries, err := ParseWKT("POLYGON ((10 53, 11 53, 11 54, 10 53), (10.2 53.2, 10.4 53.2, 10.4 53.4, 10.2 53.2))")
+	if err != nil {
+		t.Fatalf("ParseWKT returned error: %v", err)
+	}
+
+	rings := geometries[0].Rings
+	if len(rings) != 2 || len(rings[0]) != 3 || len(rings[1]) != 3 {
+		t.Fatalf("Expected 2 open rings of 3 vertices, got %v", rings)
+	}
+	if rings[1][1] != (Coordinate{Lon: 10.4, Lat: 53.2}) {
+		t.Errorf("Expected x to be longitude and y latitude, got %+v", rings[1][1])
+	}
+}
+
+func TestParseWKT_Errors(t *testing.T) {
+	tests := []string{
+		"",
+		"CIRCLE (10 53, 1)",
+		"POINT (10)",
+		"POINT (10 53",
+		"POINT (10 53, 11 54)",
+		"LINESTRING (10 53, 11 abc)",
+		"POINT (10 53) trailing",
+	}
+
+	for _, wkt := range tests {
+		t.Run(wkt, func(t *testing.T) {
+			if _, err := ParseWKT(wkt); err == nil {
+				t.Errorf("Expected error for %q", wkt)
+			}
+		})
+	}
+}
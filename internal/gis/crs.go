@@ -0,0 +1,256 @@
+package gis
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Linear units in meters
+const (
+	UnitMeter        = 1.0
+	UnitFoot         = 0.3048
+	UnitUSSurveyFoot = 1200.0 / 3937.0
+)
+
+// CRS is a coordinate reference system that can be converted to WGS84
+type CRS struct {
+	Name  string
+	Datum Datum
+	// Projection is nil for geographic (lon/lat) coordinate systems
+	Projection Projection
+	// LinearUnit is the size of one projected unit in meters
+	LinearUnit float64
+	// AngularUnit is the size of one geographic unit in radians
+	AngularUnit float64
+	// PrimeMeridian is the longitude of the prime meridian east of Greenwich, in degrees
+	PrimeMeridian float64
+}
+
+// WGS84 is the coordinate system of GPS and NIMBY Rails; converting from it is a no-op
+var WGS84 = &CRS{Name: "WGS 84", Datum: DatumWGS84, AngularUnit: degToRad}
+
+// IsWGS84 reports whether coordinates in this CRS are already WGS84 lon/lat degrees
+func (c *CRS) IsWGS84() bool {
+	return c == nil || (c.Projection == nil && c.Datum.ToWGS84 == nil &&
+		c.PrimeMeridian == 0 && angularIsDegrees(c.AngularUnit))
+}
+
+// ToWGS84 converts a coordinate pair (easting/northing, or lon/lat for geographic
+// systems) to WGS84 longitude and latitude in degrees
+func (c *CRS) ToWGS84(x, y float64) (float64, float64) {
+	if c.IsWGS84() {
+		return x, y
+	}
+
+	var lat, lon float64
+	if c.Projection == nil {
+		unit := c.AngularUnit
+		if unit == 0 {
+			unit = degToRad
+		}
+		lon, lat = x*unit, y*unit
+	} else {
+		unit := c.LinearUnit
+		if unit == 0 {
+			unit = UnitMeter
+		}
+		lat, lon = c.Projection.Inverse(x*unit, y*unit)
+	}
+
+	lon += c.PrimeMeridian * degToRad
+	lat, lon = c.Datum.ShiftToWGS84(lat, lon)
+	return normalizeLongitude(lon * radToDeg), lat * radToDeg
+}
+
+func angularIsDegrees(unit float64) bool {
+	return unit == 0 || math.Abs(unit-degToRad) < 1e-12
+}
+
+// normalizeLongitude wraps a longitude in degrees to [-180, 180]
+func normalizeLongitude(lon float64) float64 {
+	if lon >= -180 && lon <= 180 {
+		return lon
+	}
+	lon = math.Mod(lon+180, 360)
+	if lon < 0 {
+		lon += 360
+	}
+	return lon - 180
+}
+
+// ParseCRS parses a user supplied coordinate system: an EPSG code such as
+// "EPSG:25832", "25832" or "urn:ogc:def:crs:EPSG::25832", or WKT text
+func ParseCRS(value string) (*CRS, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, fmt.Errorf("empty coordinate system")
+	}
+
+	if strings.Contains(value, "[") {
+		return ParseWKT(value)
+	}
+
+	code := value
+	if idx := strings.LastIndex(code, ":"); idx >= 0 {
+		authority := strings.ToUpper(code[:idx])
+		if !strings.HasSuffix(strings.TrimRight(authority, ":0123456789."), "EPSG") {
+			return nil, fmt.Errorf("unsupported coordinate system authority: %s", value)
+		}
+		code = code[idx+1:]
+	}
+
+	epsg, err := strconv.Atoi(code)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EPSG code: %s", value)
+	}
+	return LookupEPSG(epsg)
+}
+
+// ReadPRJ parses the WKT in a shapefile's .prj sidecar
+func ReadPRJ(path string) (*CRS, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	crs, err := ParseWKT(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return crs, nil
+}
+
+// LookupEPSG returns a built-in definition for an EPSG code
+func LookupEPSG(code int) (*CRS, error) {
+	etrs89, _ := lookupDatum("ETRS_1989")
+
+	switch {
+	case code == 4326:
+		return WGS84, nil
+	case code == 4258:
+		return geographicCRS("ETRS89", etrs89), nil
+	case code == 4269:
+		return geographicCRS("NAD83", knownDatums["northamerican1983"]), nil
+	case code == 4267:
+		return geographicCRS("NAD27", knownDatums["northamerican1927"]), nil
+	case code == 4277:
+		return geographicCRS("OSGB36", knownDatums["osgb1936"]), nil
+	case code == 4314:
+		return geographicCRS("DHDN", knownDatums["deutscheshauptdreiecksnetz"]), nil
+	case code == 4230:
+		return geographicCRS("ED50", knownDatums["europeandatum1950"]), nil
+	case code == 3857 || code == 900913 || code == 3785 || code == 102100:
+		return &CRS{
+			Name:       "WGS 84 / Pseudo-Mercator",
+			Datum:      DatumWGS84,
+			Projection: NewWebMercator(wgs84Semimajor, ProjectionParams{}),
+		}, nil
+	case code >= 32601 && code <= 32660:
+		return utmCRS("WGS 84", DatumWGS84, code-32600, false), nil
+	case code >= 32701 && code <= 32760:
+		return utmCRS("WGS 84", DatumWGS84, code-32700, true), nil
+	case code >= 25828 && code <= 25838:
+		return utmCRS("ETRS89", etrs89, code-25800, false), nil
+	case code >= 26901 && code <= 26923:
+		return utmCRS("NAD83", knownDatums["northamerican1983"], code-26900, false), nil
+	case code >= 23028 && code <= 23038:
+		return utmCRS("ED50", knownDatums["europeandatum1950"], code-23000, false), nil
+	case code == 3067:
+		return tmCRS("ETRS89 / TM35FIN(E,N)", etrs89, 0, 27, 0.9996, 500000, 0), nil
+	case code == 3006:
+		return tmCRS("SWEREF99 TM", knownDatums["swedishreferenceframe1999"], 0, 15, 0.9996, 500000, 0), nil
+	case code == 27700:
+		return tmCRS("OSGB36 / British National Grid", knownDatums["osgb1936"], 49, -2, 0.9996012717, 400000, -100000), nil
+	case code == 2180:
+		return tmCRS("ETRF2000-PL / CS92", knownDatums["etrf2000pl"], 0, 19, 0.9993, 500000, -5300000), nil
+	case code >= 2176 && code <= 2179:
+		// Polish CS2000 zones 5-8 on meridians 15°, 18°, 21° and 24°
+		zone := code - 2171
+		return tmCRS(fmt.Sprintf("ETRF2000-PL / CS2000/%d", zone*3), knownDatums["etrf2000pl"],
+			0, float64(zone*3), 0.999923, float64(zone)*1000000+500000, 0), nil
+	case code >= 31466 && code <= 31469:
+		// German Gauss-Krüger zones 2-5 on meridians 6°, 9°, 12° and 15°
+		zone := code - 31464
+		return tmCRS(fmt.Sprintf("DHDN / 3-degree Gauss-Kruger zone %d", zone), knownDatums["deutscheshauptdreiecksnetz"],
+			0, float64(zone*3), 1, float64(zone)*1000000+500000, 0), nil
+	case code == 3035:
+		return &CRS{
+			Name:  "ETRS89-extended / LAEA Europe",
+			Datum: etrs89,
+			Projection: NewLambertAzimuthalEqualArea(etrs89.Ellipsoid, ProjectionParams{
+				LatitudeOfOrigin: 52 * degToRad,
+				CentralMeridian:  10 * degToRad,
+				FalseEasting:     4321000,
+				FalseNorthing:    3210000,
+			}),
+		}, nil
+	case code == 3034:
+		return lccCRS("ETRS89-extended / LCC Europe", etrs89, 52, 10, 35, 65, 4000000, 2800000), nil
+	case code == 2154:
+		return lccCRS("RGF93 v1 / Lambert-93", knownDatums["reseaugeodesiquefrancais1993"], 46.5, 3, 49, 44, 700000, 6600000), nil
+	case code == 31370:
+		return lccCRS("Belge 1972 / Belgian Lambert 72", knownDatums["belge1972"],
+			90, 4.36748666666667, 51.16666723333333, 49.8333339, 150000.013, 5400088.438), nil
+	case code == 28992:
+		amersfoort := knownDatums["amersfoort"]
+		return &CRS{
+			Name:  "Amersfoort / RD New",
+			Datum: amersfoort,
+			Projection: NewObliqueStereographic(amersfoort.Ellipsoid, ProjectionParams{
+				LatitudeOfOrigin: 52.15616055555555 * degToRad,
+				CentralMeridian:  5.38763888888889 * degToRad,
+				ScaleFactor:      0.9999079,
+				FalseEasting:     155000,
+				FalseNorthing:    463000,
+			}),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported EPSG code: %d", code)
+}
+
+func geographicCRS(name string, datum Datum) *CRS {
+	return &CRS{Name: name, Datum: datum, AngularUnit: degToRad}
+}
+
+func utmCRS(datumName string, datum Datum, zone int, south bool) *CRS {
+	falseNorthing := 0.0
+	hemisphere := "N"
+	if south {
+		falseNorthing = 10000000
+		hemisphere = "S"
+	}
+	return tmCRS(fmt.Sprintf("%s / UTM zone %d%s", datumName, zone, hemisphere), datum,
+		0, float64(zone*6-183), 0.9996, 500000, falseNorthing)
+}
+
+func tmCRS(name string, datum Datum, lat0, lon0, k0, falseEasting, falseNorthing float64) *CRS {
+	return &CRS{
+		Name:  name,
+		Datum: datum,
+		Projection: NewTransverseMercator(datum.Ellipsoid, ProjectionParams{
+			LatitudeOfOrigin: lat0 * degToRad,
+			CentralMeridian:  lon0 * degToRad,
+			ScaleFactor:      k0,
+			FalseEasting:     falseEasting,
+			FalseNorthing:    falseNorthing,
+		}),
+	}
+}
+
+func lccCRS(name string, datum Datum, lat0, lon0, lat1, lat2, falseEasting, falseNorthing float64) *CRS {
+	return &CRS{
+		Name:  name,
+		Datum: datum,
+		Projection: NewLambertConformalConic(datum.Ellipsoid, ProjectionParams{
+			LatitudeOfOrigin:  lat0 * degToRad,
+			CentralMeridian:   lon0 * degToRad,
+			StandardParallel1: lat1 * degToRad,
+			StandardParallel2: lat2 * degToRad,
+			FalseEasting:      falseEasting,
+			FalseNorthing:     falseNorthing,
+		}),
+	}
+}
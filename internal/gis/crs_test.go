@@ -0,0 +1,216 @@
+package gis
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	esriBritishNationalGrid = `PROJCS["British_National_Grid",GEOGCS["GCS_OSGB_1936",DATUM["D_OSGB_1936",` +
+		`SPHEROID["Airy_1830",6377563.396,299.3249646]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],` +
+		`PROJECTION["Transverse_Mercator"],PARAMETER["False_Easting",400000.0],PARAMETER["False_Northing",-100000.0],` +
+		`PARAMETER["Central_Meridian",-2.0],PARAMETER["Scale_Factor",0.9996012717],PARAMETER["Latitude_Of_Origin",49.0],` +
+		`UNIT["Meter",1.0]]`
+
+	esriPolandCS92 = `PROJCS["ETRS_1989_Poland_CS92",GEOGCS["GCS_ETRS_1989",DATUM["D_ETRS_1989",` +
+		`SPHEROID["GRS_1980",6378137.0,298.257222101]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],` +
+		`PROJECTION["Transverse_Mercator"],PARAMETER["False_Easting",500000.0],PARAMETER["False_Northing",-5300000.0],` +
+		`PARAMETER["Central_Meridian",19.0],PARAMETER["Scale_Factor",0.9993],PARAMETER["Latitude_Of_Origin",0.0],` +
+		`UNIT["Meter",1.0]]`
+
+	esriWebMercator = `PROJCS["WGS_1984_Web_Mercator_Auxiliary_Sphere",GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",` +
+		`SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]],` +
+		`PROJECTION["Mercator_Auxiliary_Sphere"],PARAMETER["False_Easting",0.0],PARAMETER["False_Northing",0.0],` +
+		`PARAMETER["Central_Meridian",0.0],PARAMETER["Standard_Parallel_1",0.0],PARAMETER["Auxiliary_Sphere_Type",0.0],` +
+		`UNIT["Meter",1.0]]`
+
+	ogcUTM32N = `PROJCS["ETRS89 / UTM zone 32N",
+    GEOGCS["ETRS89",
+        DATUM["European_Terrestrial_Reference_System_1989",
+            SPHEROID["GRS 1980",6378137,298.257222101,AUTHORITY["EPSG","7019"]],
+            TOWGS84[0,0,0,0,0,0,0],
+            AUTHORITY["EPSG","6258"]],
+        PRIMEM["Greenwich",0,AUTHORITY["EPSG","8901"]],
+        UNIT["degree",0.0174532925199433,AUTHORITY["EPSG","9122"]],
+        AUTHORITY["EPSG","4258"]],
+    PROJECTION["Transverse_Mercator"],
+    PARAMETER["latitude_of_origin",0],
+    PARAMETER["central_meridian",9],
+    PARAMETER["scale_factor",0.9996],
+    PARAMETER["false_easting",500000],
+    PARAMETER["false_northing",0],
+    UNIT["metre",1,AUTHORITY["EPSG","9001"]],
+    AXIS["Easting",EAST],
+    AXIS["Northing",NORTH],
+    AUTHORITY["EPSG","25832"]]`
+
+	ogcLAEAEurope = `PROJCS["ETRS89 / LAEA Europe",GEOGCS["ETRS89",DATUM["European_Terrestrial_Reference_System_1989",` +
+		`SPHEROID["GRS 1980",6378137,298.257222101]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]],` +
+		`PROJECTION["Lambert_Azimuthal_Equal_Area"],PARAMETER["latitude_of_center",52],PARAMETER["longitude_of_center",10],` +
+		`PARAMETER["false_easting",4321000],PARAMETER["false_northing",3210000],UNIT["metre",1]]`
+
+	esriWGS84 = `GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],` +
+		`PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`
+)
+
+func TestParseWKT_BritishNationalGrid(t *testing.T) {
+	crs, err := ParseWKT(esriBritishNationalGrid)
+	if err != nil {
+		t.Fatalf("ParseWKT() error = %v", err)
+	}
+
+	// Caister Water Tower, from the Ordnance Survey's guide to coordinate systems.
+	// A single Helmert shift is good to a few meters, hence the loose tolerance.
+	lon, lat := crs.ToWGS84(651409.903, 313177.270)
+	assertLatLon(t, lat, lon, dms(52, 39, 28.8282), dms(1, 42, 57.8663), 5e-5)
+
+	// Without the datum shift the grid gives the OSGB36 position to sub-millimeter accuracy
+	osgb36 := *crs
+	osgb36.Datum.ToWGS84 = nil
+	lon, lat = osgb36.ToWGS84(651409.903, 313177.270)
+	assertLatLon(t, lat, lon, dms(52, 39, 27.2531), dms(1, 43, 4.5177), 1e-7)
+}
+
+func TestParseWKT_MatchesEPSG(t *testing.T) {
+	tests := []struct {
+		name string
+		wkt  string
+		epsg int
+		x, y float64
+	}{
+		{"Poland CS92", esriPolandCS92, 2180, 637000, 486000},
+		{"Web Mercator", esriWebMercator, 3857, 2226389.8, 6446275.8},
+		{"UTM 32N", ogcUTM32N, 25832, 566000, 5933000},
+		{"LAEA Europe", ogcLAEAEurope, 3035, 3962799.45, 2999718.85},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crs, err := ParseWKT(tt.wkt)
+			if err != nil {
+				t.Fatalf("ParseWKT() error = %v", err)
+			}
+			reference, err := LookupEPSG(tt.epsg)
+			if err != nil {
+				t.Fatalf("LookupEPSG() error = %v", err)
+			}
+
+			gotLon, gotLat := crs.ToWGS84(tt.x, tt.y)
+			wantLon, wantLat := reference.ToWGS84(tt.x, tt.y)
+			assertLatLon(t, gotLat, gotLon, wantLat, wantLon, 1e-9)
+		})
+	}
+}
+
+func TestParseWKT_Geographic(t *testing.T) {
+	crs, err := ParseWKT(esriWGS84)
+	if err != nil {
+		t.Fatalf("ParseWKT() error = %v", err)
+	}
+	if !crs.IsWGS84() {
+		t.Errorf("expected %q to be treated as WGS84", crs.Name)
+	}
+
+	lon, lat := crs.ToWGS84(19.5, 52.25)
+	if lon != 19.5 || lat != 52.25 {
+		t.Errorf("ToWGS84() = (%v, %v), want unchanged", lon, lat)
+	}
+}
+
+func TestParseWKT_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		wkt  string
+	}{
+		{"empty", ""},
+		{"unterminated", `PROJCS["broken",GEOGCS["x"`},
+		{"unknown root", `VERT_CS["height"]`},
+		{"unsupported projection", `PROJCS["Krovak",GEOGCS["GCS_S_JTSK",DATUM["D_S_JTSK",` +
+			`SPHEROID["Bessel_1841",6377397.155,299.1528128]]],PROJECTION["Krovak"],UNIT["Meter",1.0]]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseWKT(tt.wkt); err == nil {
+				t.Error("ParseWKT() expected error")
+			}
+		})
+	}
+}
+
+func TestParseCRS(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "EPSG:4326", want: "WGS 84"},
+		{value: "epsg:27700", want: "OSGB36 / British National Grid"},
+		{value: "2180", want: "ETRF2000-PL / CS92"},
+		{value: "urn:ogc:def:crs:EPSG::32633", want: "WGS 84 / UTM zone 33N"},
+		{value: "EPSG:32733", want: "WGS 84 / UTM zone 33S"},
+		{value: "EPSG:31468", want: "DHDN / 3-degree Gauss-Kruger zone 4"},
+		{value: esriPolandCS92, want: "ETRS_1989_Poland_CS92"},
+		{value: "", wantErr: true},
+		{value: "ESRI:102100", wantErr: true},
+		{value: "EPSG:abc", wantErr: true},
+		{value: "EPSG:5514", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			crs, err := ParseCRS(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseCRS(%q) expected error", tt.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCRS(%q) error = %v", tt.value, err)
+			}
+			if crs.Name != tt.want {
+				t.Errorf("ParseCRS(%q).Name = %q, want %q", tt.value, crs.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupEPSG_UTM(t *testing.T) {
+	north, err := LookupEPSG(32633)
+	if err != nil {
+		t.Fatalf("LookupEPSG() error = %v", err)
+	}
+	lon, lat := north.ToWGS84(500000, 0)
+	assertLatLon(t, lat, lon, 0, 15, 1e-9)
+
+	south, err := LookupEPSG(32733)
+	if err != nil {
+		t.Fatalf("LookupEPSG() error = %v", err)
+	}
+	lon, lat = south.ToWGS84(500000, 10000000)
+	assertLatLon(t, lat, lon, 0, 15, 1e-9)
+}
+
+func TestReadPRJ(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grid.prj")
+	if err := os.WriteFile(path, []byte(esriPolandCS92+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	crs, err := ReadPRJ(path)
+	if err != nil {
+		t.Fatalf("ReadPRJ() error = %v", err)
+	}
+
+	// The central meridian runs through the false easting
+	lon, lat := crs.ToWGS84(500000, 500000)
+	if lon < 18.999999 || lon > 19.000001 || lat < 50 || lat > 55 {
+		t.Errorf("ToWGS84() = (%v, %v), want lon 19 in Poland", lon, lat)
+	}
+
+	if _, err := ReadPRJ(filepath.Join(t.TempDir(), "missing.prj")); err == nil {
+		t.Error("ReadPRJ() expected error for missing file")
+	}
+}
@@ -0,0 +1,210 @@
+package gis
+
+import (
+	"math"
+	"strings"
+)
+
+const (
+	degToRad       = math.Pi / 180.0
+	radToDeg       = 180.0 / math.Pi
+	arcSecToRad    = degToRad / 3600.0
+	wgs84Semimajor = 6378137.0
+)
+
+// Ellipsoid describes the reference ellipsoid of a datum
+type Ellipsoid struct {
+	SemiMajorAxis     float64
+	InverseFlattening float64 // 0 for a sphere
+}
+
+// Common reference ellipsoids
+var (
+	EllipsoidWGS84             = Ellipsoid{SemiMajorAxis: wgs84Semimajor, InverseFlattening: 298.257223563}
+	EllipsoidGRS80             = Ellipsoid{SemiMajorAxis: 6378137.0, InverseFlattening: 298.257222101}
+	EllipsoidAiry1830          = Ellipsoid{SemiMajorAxis: 6377563.396, InverseFlattening: 299.3249646}
+	EllipsoidBessel1841        = Ellipsoid{SemiMajorAxis: 6377397.155, InverseFlattening: 299.1528128}
+	EllipsoidInternational1924 = Ellipsoid{SemiMajorAxis: 6378388.0, InverseFlattening: 297.0}
+	EllipsoidKrassowsky1940    = Ellipsoid{SemiMajorAxis: 6378245.0, InverseFlattening: 298.3}
+	EllipsoidClarke1866        = Ellipsoid{SemiMajorAxis: 6378206.4, InverseFlattening: 294.978698214}
+)
+
+// Flattening returns f, or 0 for a sphere
+func (e Ellipsoid) Flattening() float64 {
+	if e.InverseFlattening == 0 {
+		return 0
+	}
+	return 1 / e.InverseFlattening
+}
+
+// EccentricitySquared returns e²
+func (e Ellipsoid) EccentricitySquared() float64 {
+	f := e.Flattening()
+	return f * (2 - f)
+}
+
+// Eccentricity returns e
+func (e Ellipsoid) Eccentricity() float64 {
+	return math.Sqrt(e.EccentricitySquared())
+}
+
+// Helmert holds the seven parameters of a position vector transformation to
+// WGS84, in the same units as a WKT TOWGS84 clause: meters, arc-seconds and ppm.
+type Helmert struct {
+	Tx, Ty, Tz float64
+	Rx, Ry, Rz float64
+	Scale      float64
+}
+
+// Datum is a geodetic datum and how to shift coordinates from it to WGS84
+type Datum struct {
+	Name      string
+	Ellipsoid Ellipsoid
+	// ToWGS84 is nil when the datum is treated as identical to WGS84
+	ToWGS84 *Helmert
+}
+
+// DatumWGS84 is the datum GPS coordinates and NIMBY Rails use
+var DatumWGS84 = Datum{Name: "WGS_1984", Ellipsoid: EllipsoidWGS84}
+
+// knownDatums holds shifts for datums whose WKT usually lacks a TOWGS84 clause
+// (ESRI .prj files never include one). Keys are normalized datum names.
+var knownDatums = map[string]Datum{
+	"wgs1984":                                DatumWGS84,
+	"etrs1989":                               {Name: "ETRS89", Ellipsoid: EllipsoidGRS80},
+	"europeanterrestrialreferencesystem1989": {Name: "ETRS89", Ellipsoid: EllipsoidGRS80},
+	"northamerican1983":                      {Name: "NAD83", Ellipsoid: EllipsoidGRS80},
+	"reseaugeodesiquefrancais1993":           {Name: "RGF93", Ellipsoid: EllipsoidGRS80},
+	"swedishreferenceframe1999":              {Name: "SWEREF99", Ellipsoid: EllipsoidGRS80},
+	"etrf2000pl":                             {Name: "ETRF2000-PL", Ellipsoid: EllipsoidGRS80},
+	"osgb1936": {
+		Name:      "OSGB36",
+		Ellipsoid: EllipsoidAiry1830,
+		ToWGS84:   &Helmert{Tx: 446.448, Ty: -125.157, Tz: 542.06, Rx: 0.15, Ry: 0.247, Rz: 0.842, Scale: -20.489},
+	},
+	"deutscheshauptdreiecksnetz": {
+		Name:      "DHDN",
+		Ellipsoid: EllipsoidBessel1841,
+		ToWGS84:   &Helmert{Tx: 598.1, Ty: 73.7, Tz: 418.2, Rx: 0.202, Ry: 0.045, Rz: -2.455, Scale: 6.7},
+	},
+	"amersfoort": {
+		Name:      "Amersfoort",
+		Ellipsoid: EllipsoidBessel1841,
+		ToWGS84:   &Helmert{Tx: 565.417, Ty: 50.3319, Tz: 465.552, Rx: -0.398957, Ry: 0.343988, Rz: -1.8774, Scale: 4.0725},
+	},
+	"belge1972": {
+		Name:      "Belge 1972",
+		Ellipsoid: EllipsoidInternational1924,
+		ToWGS84:   &Helmert{Tx: -106.8686, Ty: 52.2978, Tz: -103.7239, Rx: 0.3366, Ry: -0.457, Rz: 1.8422, Scale: -1.2747},
+	},
+	"militargeographischeinstitut": {
+		Name:      "MGI",
+		Ellipsoid: EllipsoidBessel1841,
+		ToWGS84:   &Helmert{Tx: 577.326, Ty: 90.129, Tz: 463.919, Rx: 5.137, Ry: 1.474, Rz: 5.297, Scale: 2.4232},
+	},
+	"europeandatum1950": {
+		Name:      "ED50",
+		Ellipsoid: EllipsoidInternational1924,
+		ToWGS84:   &Helmert{Tx: -87, Ty: -98, Tz: -121},
+	},
+	"pulkovo1942": {
+		Name:      "Pulkovo 1942",
+		Ellipsoid: EllipsoidKrassowsky1940,
+		ToWGS84:   &Helmert{Tx: 23.92, Ty: -141.27, Tz: -80.9, Ry: 0.35, Rz: 0.82, Scale: -0.12},
+	},
+	"northamerican1927": {
+		Name:      "NAD27",
+		Ellipsoid: EllipsoidClarke1866,
+		ToWGS84:   &Helmert{Tx: -8, Ty: 160, Tz: 176},
+	},
+}
+
+// lookupDatum finds a known datum by its WKT name, ignoring ESRI's "D_" prefix,
+// case and punctuation
+func lookupDatum(name string) (Datum, bool) {
+	key := normalizeName(strings.TrimPrefix(name, "D_"))
+	datum, ok := knownDatums[key]
+	return datum, ok
+}
+
+// normalizeName lowercases a WKT name and strips everything but letters and digits
+func normalizeName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// GeodeticToGeocentric converts latitude/longitude (radians) and ellipsoidal
+// height (meters) to earth-centered X/Y/Z coordinates
+func GeodeticToGeocentric(ellipsoid Ellipsoid, lat, lon, height float64) (float64, float64, float64) {
+	e2 := ellipsoid.EccentricitySquared()
+	sinLat := math.Sin(lat)
+	nu := ellipsoid.SemiMajorAxis / math.Sqrt(1-e2*sinLat*sinLat)
+
+	x := (nu + height) * math.Cos(lat) * math.Cos(lon)
+	y := (nu + height) * math.Cos(lat) * math.Sin(lon)
+	z := ((1-e2)*nu + height) * sinLat
+	return x, y, z
+}
+
+// GeocentricToGeodetic converts earth-centered X/Y/Z coordinates to latitude
+// and longitude (radians) and ellipsoidal height (meters)
+func GeocentricToGeodetic(ellipsoid Ellipsoid, x, y, z float64) (float64, float64, float64) {
+	a := ellipsoid.SemiMajorAxis
+	e2 := ellipsoid.EccentricitySquared()
+	p := math.Hypot(x, y)
+	lon := math.Atan2(y, x)
+
+	// Iterate from the spherical approximation; converges to sub-millimeter in a few steps
+	lat := math.Atan2(z, p*(1-e2))
+	var nu float64
+	for range 10 {
+		sinLat := math.Sin(lat)
+		nu = a / math.Sqrt(1-e2*sinLat*sinLat)
+		next := math.Atan2(z+e2*nu*sinLat, p)
+		if math.Abs(next-lat) < 1e-12 {
+			lat = next
+			break
+		}
+		lat = next
+	}
+
+	sinLat := math.Sin(lat)
+	nu = a / math.Sqrt(1-e2*sinLat*sinLat)
+	var height float64
+	if math.Abs(math.Cos(lat)) > 1e-10 {
+		height = p/math.Cos(lat) - nu
+	} else {
+		height = math.Abs(z) - nu*(1-e2)
+	}
+	return lat, lon, height
+}
+
+// Apply transforms geocentric coordinates using the position vector convention
+// (EPSG method 9606), which is also the convention of WKT TOWGS84 clauses
+func (h *Helmert) Apply(x, y, z float64) (float64, float64, float64) {
+	rx := h.Rx * arcSecToRad
+	ry := h.Ry * arcSecToRad
+	rz := h.Rz * arcSecToRad
+	m := 1 + h.Scale*1e-6
+
+	x2 := h.Tx + m*(x-rz*y+ry*z)
+	y2 := h.Ty + m*(rz*x+y-rx*z)
+	z2 := h.Tz + m*(-ry*x+rx*y+z)
+	return x2, y2, z2
+}
+
+// ShiftToWGS84 shifts a geodetic position (radians) on this datum to WGS84 (radians)
+func (d Datum) ShiftToWGS84(lat, lon float64) (float64, float64) {
+	if d.ToWGS84 == nil {
+		return lat, lon
+	}
+	x, y, z := GeodeticToGeocentric(d.Ellipsoid, lat, lon, 0)
+	x, y, z = d.ToWGS84.Apply(x, y, z)
+	lat, lon, _ = GeocentricToGeodetic(EllipsoidWGS84, x, y, z)
+	return lat, lon
+}
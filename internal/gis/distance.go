@@ -70,3 +70,54 @@ func InterpolatePoint(lat1, lon1, lat2, lon2, fraction float64) (float64, float6
 
 	return lat, lon
 }
+
+// SegmentDistance returns the distance in meters from a point to the great
+// circle segment between two others. Points beyond either end of the segment
+// are measured to the nearest end.
+func SegmentDistance(lat, lon, lat1, lon1, lat2, lon2 float64) float64 {
+	d13 := HaversineDistance(lat1, lon1, lat, lon)
+	if lat1 == lat2 && lon1 == lon2 {
+		return d13
+	}
+
+	// Beyond the start or the end when the angle at that end is obtuse
+	bearing12 := bearing(lat1, lon1, lat2, lon2)
+	bearing13 := bearing(lat1, lon1, lat, lon)
+	if math.Cos(bearing13-bearing12) < 0 {
+		return d13
+	}
+	if math.Cos(bearing(lat2, lon2, lat, lon)-bearing(lat2, lon2, lat1, lon1)) < 0 {
+		return HaversineDistance(lat2, lon2, lat, lon)
+	}
+
+	radius := EarthRadiusKm * 1000.0
+	angle := d13 / radius
+	crossTrack := math.Asin(math.Sin(angle) * math.Sin(bearing13-bearing12))
+	return math.Abs(crossTrack) * radius
+}
+
+// TriangleArea returns the area in square meters of the triangle between three
+// nearby points, measured on a plane tangent to the Earth at the second point
+func TriangleArea(lat1, lon1, lat2, lon2, lat3, lon3 float64) float64 {
+	radius := EarthRadiusKm * 1000.0
+	scale := math.Cos(lat2 * math.Pi / 180.0)
+
+	// Local east/north offsets from the second point in meters
+	x1 := (lon1 - lon2) * math.Pi / 180.0 * scale * radius
+	y1 := (lat1 - lat2) * math.Pi / 180.0 * radius
+	x3 := (lon3 - lon2) * math.Pi / 180.0 * scale * radius
+	y3 := (lat3 - lat2) * math.Pi / 180.0 * radius
+
+	return math.Abs(x1*y3-x3*y1) / 2
+}
+
+// bearing returns the initial great circle bearing from the first point to the second in radians
+func bearing(lat1, lon1, lat2, lon2 float64) float64 {
+	lat1Rad := lat1 * math.Pi / 180.0
+	lat2Rad := lat2 * math.Pi / 180.0
+	deltaLon := (lon2 - lon1) * math.Pi / 180.0
+
+	y := math.Sin(deltaLon) * math.Cos(lat2Rad)
+	x := math.Cos(lat1Rad)*math.Sin(lat2Rad) - math.Sin(lat1Rad)*math.Cos(lat2Rad)*math.Cos(deltaLon)
+	return math.Atan2(y, x)
+}
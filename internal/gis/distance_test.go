@@ -0,0 +1,51 @@
+package gis
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSegmentDistance(t *testing.T) {
+	// One degree of latitude is about 111.2 km on the sphere
+	const degree = EarthRadiusKm * 1000.0 * math.Pi / 180.0
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		expected float64
+	}{
+		{name: "beside the segment", lat: 0.001, lon: 0.5, expected: 0.001 * degree},
+		{name: "on the segment", lat: 0, lon: 0.25, expected: 0},
+		{name: "before the start", lat: 0, lon: -0.01, expected: 0.01 * degree},
+		{name: "past the end", lat: 0.003, lon: 1.004, expected: 0.005 * degree},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			distance := SegmentDistance(tt.lat, tt.lon, 0, 0, 0, 1)
+			if math.Abs(distance-tt.expected) > 0.01 {
+				t.Errorf("SegmentDistance() = %.3f m, want %.3f m", distance, tt.expected)
+			}
+		})
+	}
+
+	if distance := SegmentDistance(0.001, 0, 0, 0, 0, 0); math.Abs(distance-0.001*degree) > 0.01 {
+		t.Errorf("Expected the distance to a degenerate segment to be the point distance, got %.3f m", distance)
+	}
+}
+
+func TestTriangleArea(t *testing.T) {
+	// A right triangle with 100 m legs at 60°N, where a degree of longitude is half as long
+	const metersPerDegree = EarthRadiusKm * 1000.0 * math.Pi / 180.0
+	dLat := 100 / metersPerDegree
+	dLon := 100 / (metersPerDegree * 0.5)
+
+	area := TriangleArea(60+dLat, 10, 60, 10, 60, 10+dLon)
+	if math.Abs(area-5000) > 1 {
+		t.Errorf("TriangleArea() = %.2f m², want 5000 m²", area)
+	}
+
+	if area := TriangleArea(60, 10, 60, 10.001, 60, 10.002); area > 1e-6 {
+		t.Errorf("Expected collinear points to have no area, got %f m²", area)
+	}
+}
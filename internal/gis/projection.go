@@ -0,0 +1,370 @@
+package gis
+
+import (
+	"math"
+)
+
+// Projection converts projected easting/northing (meters) back to geodetic
+// latitude/longitude (radians) on the projection's ellipsoid
+type Projection interface {
+	Inverse(easting, northing float64) (lat, lon float64)
+}
+
+// ProjectionParams are the defining parameters shared by the supported
+// projections. Angles are in radians, offsets in meters.
+type ProjectionParams struct {
+	LatitudeOfOrigin  float64
+	CentralMeridian   float64
+	ScaleFactor       float64
+	StandardParallel1 float64
+	StandardParallel2 float64
+	FalseEasting      float64
+	FalseNorthing     float64
+}
+
+// TransverseMercator implements the JHS formulas from EPSG Guidance Note 7-2,
+// which stay accurate to the millimeter well beyond a 6° zone
+type TransverseMercator struct {
+	params ProjectionParams
+	e      float64
+	b      float64
+	m0     float64
+	h      [4]float64
+}
+
+// NewTransverseMercator creates a Transverse Mercator projection (also used for
+// UTM and Gauss-Krüger grids)
+func NewTransverseMercator(ellipsoid Ellipsoid, params ProjectionParams) *TransverseMercator {
+	if params.ScaleFactor == 0 {
+		params.ScaleFactor = 1
+	}
+	n := ellipsoid.Flattening() / (2 - ellipsoid.Flattening())
+	n2, n3, n4 := n*n, n*n*n, n*n*n*n
+
+	tm := &TransverseMercator{
+		params: params,
+		e:      ellipsoid.Eccentricity(),
+		b:      ellipsoid.SemiMajorAxis / (1 + n) * (1 + n2/4 + n4/64),
+		h: [4]float64{
+			n/2 - 2.0/3.0*n2 + 37.0/96.0*n3 - 1.0/360.0*n4,
+			1.0/48.0*n2 + 1.0/15.0*n3 - 437.0/1440.0*n4,
+			17.0/480.0*n3 - 37.0/840.0*n4,
+			4397.0 / 161280.0 * n4,
+		},
+	}
+
+	// Meridional arc to the latitude of origin, using the forward series
+	if params.LatitudeOfOrigin != 0 {
+		forward := [4]float64{
+			n/2 - 2.0/3.0*n2 + 5.0/16.0*n3 + 41.0/180.0*n4,
+			13.0/48.0*n2 - 3.0/5.0*n3 + 557.0/1440.0*n4,
+			61.0/240.0*n3 - 103.0/140.0*n4,
+			49561.0 / 161280.0 * n4,
+		}
+		q := math.Asinh(math.Tan(params.LatitudeOfOrigin)) - tm.e*math.Atanh(tm.e*math.Sin(params.LatitudeOfOrigin))
+		xi := math.Atan(math.Sinh(q))
+		m := xi
+		for i, h := range forward {
+			m += h * math.Sin(2*float64(i+1)*xi)
+		}
+		tm.m0 = tm.b * m
+	}
+
+	return tm
+}
+
+func (tm *TransverseMercator) Inverse(easting, northing float64) (float64, float64) {
+	k0 := tm.params.ScaleFactor
+	eta := (easting - tm.params.FalseEasting) / (tm.b * k0)
+	xi := ((northing - tm.params.FalseNorthing) + k0*tm.m0) / (tm.b * k0)
+
+	xi0, eta0 := xi, eta
+	for i, h := range tm.h {
+		j := 2 * float64(i+1)
+		xi0 -= h * math.Sin(j*xi) * math.Cosh(j*eta)
+		eta0 -= h * math.Cos(j*xi) * math.Sinh(j*eta)
+	}
+
+	beta := math.Asin(math.Sin(xi0) / math.Cosh(eta0))
+	q := math.Asinh(math.Tan(beta))
+	qi := q + tm.e*math.Atanh(tm.e*math.Tanh(q))
+	for range 20 {
+		next := q + tm.e*math.Atanh(tm.e*math.Tanh(qi))
+		if math.Abs(next-qi) < 1e-14 {
+			qi = next
+			break
+		}
+		qi = next
+	}
+
+	lat := math.Atan(math.Sinh(qi))
+	lon := tm.params.CentralMeridian + math.Asin(math.Tanh(eta0)/math.Cos(beta))
+	return lat, lon
+}
+
+// WebMercator is the spherical "Pseudo-Mercator" used by web maps (EPSG:3857).
+// Its coordinates are on the WGS84 datum despite the spherical formulas.
+type WebMercator struct {
+	radius float64
+	params ProjectionParams
+}
+
+// NewWebMercator creates a Web Mercator projection on a sphere of the given radius
+func NewWebMercator(radius float64, params ProjectionParams) *WebMercator {
+	return &WebMercator{radius: radius, params: params}
+}
+
+func (wm *WebMercator) Inverse(easting, northing float64) (float64, float64) {
+	lat := math.Pi/2 - 2*math.Atan(math.Exp(-(northing-wm.params.FalseNorthing)/wm.radius))
+	lon := (easting-wm.params.FalseEasting)/wm.radius + wm.params.CentralMeridian
+	return lat, lon
+}
+
+// Mercator is the ellipsoidal normal Mercator projection (EPSG methods 9804 and 9805)
+type Mercator struct {
+	a      float64
+	e      float64
+	k0     float64
+	params ProjectionParams
+}
+
+// NewMercator creates a Mercator projection. When a standard parallel is given
+// (the 2SP variant) the scale factor is derived from it.
+func NewMercator(ellipsoid Ellipsoid, params ProjectionParams) *Mercator {
+	e2 := ellipsoid.EccentricitySquared()
+	k0 := params.ScaleFactor
+	if params.StandardParallel1 != 0 || k0 == 0 {
+		sin1 := math.Sin(params.StandardParallel1)
+		k0 = math.Cos(params.StandardParallel1) / math.Sqrt(1-e2*sin1*sin1)
+	}
+	return &Mercator{a: ellipsoid.SemiMajorAxis, e: math.Sqrt(e2), k0: k0, params: params}
+}
+
+func (m *Mercator) Inverse(easting, northing float64) (float64, float64) {
+	t := math.Exp(-(northing - m.params.FalseNorthing) / (m.a * m.k0))
+	lat := isometricInverse(t, m.e)
+	lon := (easting-m.params.FalseEasting)/(m.a*m.k0) + m.params.CentralMeridian
+	return lat, lon
+}
+
+// LambertConformalConic implements the 1SP and 2SP variants (EPSG methods 9801 and 9802)
+type LambertConformalConic struct {
+	a      float64
+	e      float64
+	n      float64
+	f      float64
+	r0     float64
+	params ProjectionParams
+}
+
+// NewLambertConformalConic creates a Lambert Conformal Conic projection. With two
+// distinct standard parallels the 2SP variant is used, in which case the false
+// easting/northing are given at the false origin (latitude of origin, central meridian).
+func NewLambertConformalConic(ellipsoid Ellipsoid, params ProjectionParams) *LambertConformalConic {
+	e := ellipsoid.Eccentricity()
+	a := ellipsoid.SemiMajorAxis
+	lcc := &LambertConformalConic{a: a, e: e, params: params}
+
+	twoParallels := params.StandardParallel1 != 0 || params.StandardParallel2 != 0
+	if !twoParallels {
+		// 1SP: the natural origin doubles as the single standard parallel
+		k0 := params.ScaleFactor
+		if k0 == 0 {
+			k0 = 1
+		}
+		lat0 := params.LatitudeOfOrigin
+		lcc.n = math.Sin(lat0)
+		lcc.f = lccM(lat0, e) / (lcc.n * math.Pow(lccT(lat0, e), lcc.n))
+		lcc.r0 = a * lcc.f * math.Pow(lccT(lat0, e), lcc.n) * k0
+		lcc.f *= k0
+		return lcc
+	}
+
+	lat1, lat2 := params.StandardParallel1, params.StandardParallel2
+	if params.StandardParallel2 == 0 {
+		lat2 = lat1
+	}
+	m1, m2 := lccM(lat1, e), lccM(lat2, e)
+	t1, t2 := lccT(lat1, e), lccT(lat2, e)
+	if math.Abs(lat1-lat2) < 1e-12 {
+		lcc.n = math.Sin(lat1)
+	} else {
+		lcc.n = (math.Log(m1) - math.Log(m2)) / (math.Log(t1) - math.Log(t2))
+	}
+	lcc.f = m1 / (lcc.n * math.Pow(t1, lcc.n))
+	lcc.r0 = a * lcc.f * math.Pow(lccT(params.LatitudeOfOrigin, e), lcc.n)
+	return lcc
+}
+
+func (lcc *LambertConformalConic) Inverse(easting, northing float64) (float64, float64) {
+	dx := easting - lcc.params.FalseEasting
+	dy := lcc.r0 - (northing - lcc.params.FalseNorthing)
+
+	sign := 1.0
+	if lcc.n < 0 {
+		sign = -1.0
+	}
+	r := sign * math.Hypot(dx, dy)
+	theta := math.Atan2(sign*dx, sign*dy)
+
+	if r == 0 {
+		return sign * math.Pi / 2, lcc.params.CentralMeridian
+	}
+
+	t := math.Pow(r/(lcc.a*lcc.f), 1/lcc.n)
+	lat := isometricInverse(t, lcc.e)
+	lon := theta/lcc.n + lcc.params.CentralMeridian
+	return lat, lon
+}
+
+func lccM(lat, e float64) float64 {
+	sinLat := math.Sin(lat)
+	return math.Cos(lat) / math.Sqrt(1-e*e*sinLat*sinLat)
+}
+
+func lccT(lat, e float64) float64 {
+	sinLat := math.Sin(lat)
+	return math.Tan(math.Pi/4-lat/2) / math.Pow((1-e*sinLat)/(1+e*sinLat), e/2)
+}
+
+// isometricInverse solves t = tan(π/4 - φ/2) / ((1 - e sinφ)/(1 + e sinφ))^(e/2) for φ
+func isometricInverse(t, e float64) float64 {
+	lat := math.Pi/2 - 2*math.Atan(t)
+	for range 20 {
+		sinLat := math.Sin(lat)
+		next := math.Pi/2 - 2*math.Atan(t*math.Pow((1-e*sinLat)/(1+e*sinLat), e/2))
+		if math.Abs(next-lat) < 1e-14 {
+			return next
+		}
+		lat = next
+	}
+	return lat
+}
+
+// LambertAzimuthalEqualArea implements the ellipsoidal oblique aspect (EPSG
+// method 9820), used by the pan-European ETRS89-LAEA grid
+type LambertAzimuthalEqualArea struct {
+	e      float64
+	rq     float64
+	d      float64
+	beta0  float64
+	params ProjectionParams
+}
+
+// NewLambertAzimuthalEqualArea creates a Lambert Azimuthal Equal Area projection
+func NewLambertAzimuthalEqualArea(ellipsoid Ellipsoid, params ProjectionParams) *LambertAzimuthalEqualArea {
+	a := ellipsoid.SemiMajorAxis
+	e := ellipsoid.Eccentricity()
+	qp := laeaQ(math.Pi/2, e)
+	q0 := laeaQ(params.LatitudeOfOrigin, e)
+	beta0 := math.Asin(q0 / qp)
+	rq := a * math.Sqrt(qp/2)
+	d := a * lccM(params.LatitudeOfOrigin, e) / (rq * math.Cos(beta0))
+
+	return &LambertAzimuthalEqualArea{e: e, rq: rq, d: d, beta0: beta0, params: params}
+}
+
+func (l *LambertAzimuthalEqualArea) Inverse(easting, northing float64) (float64, float64) {
+	dx := easting - l.params.FalseEasting
+	dy := northing - l.params.FalseNorthing
+
+	rho := math.Hypot(dx/l.d, l.d*dy)
+	if rho == 0 {
+		return l.params.LatitudeOfOrigin, l.params.CentralMeridian
+	}
+	c := 2 * math.Asin(rho/(2*l.rq))
+	beta := math.Asin(math.Cos(c)*math.Sin(l.beta0) + l.d*dy*math.Sin(c)*math.Cos(l.beta0)/rho)
+
+	lon := l.params.CentralMeridian + math.Atan2(
+		dx*math.Sin(c),
+		l.d*rho*math.Cos(l.beta0)*math.Cos(c)-l.d*l.d*dy*math.Sin(l.beta0)*math.Sin(c),
+	)
+
+	e2 := l.e * l.e
+	e4 := e2 * e2
+	e6 := e4 * e2
+	lat := beta +
+		(e2/3+31*e4/180+517*e6/5040)*math.Sin(2*beta) +
+		(23*e4/360+251*e6/3780)*math.Sin(4*beta) +
+		(761*e6/45360)*math.Sin(6*beta)
+	return lat, lon
+}
+
+func laeaQ(lat, e float64) float64 {
+	sinLat := math.Sin(lat)
+	if e == 0 {
+		return 2 * sinLat
+	}
+	return (1 - e*e) * (sinLat/(1-e*e*sinLat*sinLat) - 1/(2*e)*math.Log((1-e*sinLat)/(1+e*sinLat)))
+}
+
+// ObliqueStereographic implements the "double stereographic" projection (EPSG
+// method 9809) used by the Dutch RD New grid
+type ObliqueStereographic struct {
+	e      float64
+	r      float64
+	n      float64
+	c      float64
+	chi0   float64
+	params ProjectionParams
+}
+
+// NewObliqueStereographic creates an Oblique Stereographic projection
+func NewObliqueStereographic(ellipsoid Ellipsoid, params ProjectionParams) *ObliqueStereographic {
+	if params.ScaleFactor == 0 {
+		params.ScaleFactor = 1
+	}
+	a := ellipsoid.SemiMajorAxis
+	e2 := ellipsoid.EccentricitySquared()
+	e := math.Sqrt(e2)
+	lat0 := params.LatitudeOfOrigin
+	sin0 := math.Sin(lat0)
+
+	rho0 := a * (1 - e2) / math.Pow(1-e2*sin0*sin0, 1.5)
+	nu0 := a / math.Sqrt(1-e2*sin0*sin0)
+	n := math.Sqrt(1 + e2*math.Pow(math.Cos(lat0), 4)/(1-e2))
+	s1 := (1 + sin0) / (1 - sin0)
+	s2 := (1 - e*sin0) / (1 + e*sin0)
+	w1 := math.Pow(s1*math.Pow(s2, e), n)
+	sinChi := (w1 - 1) / (w1 + 1)
+	c := (n + sin0) * (1 - sinChi) / ((n - sin0) * (1 + sinChi))
+	w2 := c * w1
+
+	return &ObliqueStereographic{
+		e:      e,
+		r:      math.Sqrt(rho0 * nu0),
+		n:      n,
+		c:      c,
+		chi0:   math.Asin((w2 - 1) / (w2 + 1)),
+		params: params,
+	}
+}
+
+func (s *ObliqueStereographic) Inverse(easting, northing float64) (float64, float64) {
+	k0 := s.params.ScaleFactor
+	dx := easting - s.params.FalseEasting
+	dy := northing - s.params.FalseNorthing
+
+	g := 2 * s.r * k0 * math.Tan(math.Pi/4-s.chi0/2)
+	h := 4*s.r*k0*math.Tan(s.chi0) + g
+	i := math.Atan(dx / (h + dy))
+	j := math.Atan(dx/(g-dy)) - i
+	chi := s.chi0 + 2*math.Atan((dy-dx*math.Tan(j/2))/(2*s.r*k0))
+	bigLambda := j + 2*i + s.params.CentralMeridian
+
+	lon := (bigLambda-s.params.CentralMeridian)/s.n + s.params.CentralMeridian
+
+	psi := 0.5 * math.Log((1+math.Sin(chi))/(s.c*(1-math.Sin(chi)))) / s.n
+	lat := 2*math.Atan(math.Exp(psi)) - math.Pi/2
+	for range 20 {
+		sinLat := math.Sin(lat)
+		psiI := math.Log(math.Tan(lat/2+math.Pi/4) * math.Pow((1-s.e*sinLat)/(1+s.e*sinLat), s.e/2))
+		next := lat - (psiI-psi)*math.Cos(lat)*(1-s.e*s.e*sinLat*sinLat)/(1-s.e*s.e)
+		if math.Abs(next-lat) < 1e-14 {
+			lat = next
+			break
+		}
+		lat = next
+	}
+	return lat, lon
+}
@@ -0,0 +1,151 @@
+package gis
+
+import (
+	"math"
+	"testing"
+)
+
+// dms converts degrees, minutes and seconds to decimal degrees
+func dms(degrees, minutes, seconds float64) float64 {
+	sign := 1.0
+	if degrees < 0 {
+		sign = -1.0
+		degrees = -degrees
+	}
+	return sign * (degrees + minutes/60 + seconds/3600)
+}
+
+func assertLatLon(t *testing.T, gotLat, gotLon, wantLat, wantLon, tolerance float64) {
+	t.Helper()
+	if math.Abs(gotLat-wantLat) > tolerance || math.Abs(gotLon-wantLon) > tolerance {
+		t.Errorf("got (%.9f, %.9f), want (%.9f, %.9f)", gotLat, gotLon, wantLat, wantLon)
+	}
+}
+
+// The reference points below are the worked examples from EPSG Guidance Note 7-2
+
+func TestTransverseMercator_Inverse(t *testing.T) {
+	// OSGB 1936 / British National Grid
+	tm := NewTransverseMercator(EllipsoidAiry1830, ProjectionParams{
+		LatitudeOfOrigin: 49 * degToRad,
+		CentralMeridian:  -2 * degToRad,
+		ScaleFactor:      0.9996012717,
+		FalseEasting:     400000,
+		FalseNorthing:    -100000,
+	})
+
+	lat, lon := tm.Inverse(577274.99, 69740.50)
+	assertLatLon(t, lat*radToDeg, lon*radToDeg, 50.5, 0.5, 1e-7)
+}
+
+func TestTransverseMercator_InverseOrigin(t *testing.T) {
+	tm := NewTransverseMercator(EllipsoidGRS80, ProjectionParams{
+		CentralMeridian: 19 * degToRad,
+		ScaleFactor:     0.9993,
+		FalseEasting:    500000,
+		FalseNorthing:   -5300000,
+	})
+
+	lat, lon := tm.Inverse(500000, -5300000)
+	assertLatLon(t, lat*radToDeg, lon*radToDeg, 0, 19, 1e-9)
+}
+
+func TestWebMercator_Inverse(t *testing.T) {
+	wm := NewWebMercator(wgs84Semimajor, ProjectionParams{})
+
+	lat, lon := wm.Inverse(-11169055.58, 2800000.00)
+	assertLatLon(t, lat*radToDeg, lon*radToDeg, dms(24, 22, 54.433), dms(-100, 20, 0), 1e-6)
+}
+
+func TestMercator_Inverse(t *testing.T) {
+	// WGS 84 / World Mercator at the equator and on the central meridian
+	m := NewMercator(EllipsoidWGS84, ProjectionParams{ScaleFactor: 1})
+
+	lat, lon := m.Inverse(0, 0)
+	assertLatLon(t, lat*radToDeg, lon*radToDeg, 0, 0, 1e-12)
+
+	// One radian of easting on the equator is one radian of longitude
+	lat, lon = m.Inverse(wgs84Semimajor, 0)
+	assertLatLon(t, lat*radToDeg, lon*radToDeg, 0, radToDeg, 1e-9)
+}
+
+func TestLambertConformalConic_Inverse2SP(t *testing.T) {
+	// NAD27 / Texas South Central, in US survey feet
+	lcc := NewLambertConformalConic(EllipsoidClarke1866, ProjectionParams{
+		LatitudeOfOrigin:  dms(27, 50, 0) * degToRad,
+		CentralMeridian:   -99 * degToRad,
+		StandardParallel1: dms(28, 23, 0) * degToRad,
+		StandardParallel2: dms(30, 17, 0) * degToRad,
+		FalseEasting:      2000000 * UnitUSSurveyFoot,
+	})
+
+	lat, lon := lcc.Inverse(2963503.91*UnitUSSurveyFoot, 254759.80*UnitUSSurveyFoot)
+	assertLatLon(t, lat*radToDeg, lon*radToDeg, 28.5, -96, 1e-7)
+}
+
+func TestLambertConformalConic_Inverse1SP(t *testing.T) {
+	// JAD2001 / Jamaica Metric Grid
+	lcc := NewLambertConformalConic(EllipsoidWGS84, ProjectionParams{
+		LatitudeOfOrigin: 18 * degToRad,
+		CentralMeridian:  -77 * degToRad,
+		ScaleFactor:      1,
+		FalseEasting:     750000,
+		FalseNorthing:    650000,
+	})
+
+	lat, lon := lcc.Inverse(750000, 650000)
+	assertLatLon(t, lat*radToDeg, lon*radToDeg, 18, -77, 1e-9)
+}
+
+func TestLambertAzimuthalEqualArea_Inverse(t *testing.T) {
+	// ETRS89 / LAEA Europe
+	laea := NewLambertAzimuthalEqualArea(EllipsoidGRS80, ProjectionParams{
+		LatitudeOfOrigin: 52 * degToRad,
+		CentralMeridian:  10 * degToRad,
+		FalseEasting:     4321000,
+		FalseNorthing:    3210000,
+	})
+
+	lat, lon := laea.Inverse(3962799.45, 2999718.85)
+	assertLatLon(t, lat*radToDeg, lon*radToDeg, 50, 5, 1e-7)
+}
+
+func TestObliqueStereographic_Inverse(t *testing.T) {
+	// Amersfoort / RD New
+	stereo := NewObliqueStereographic(EllipsoidBessel1841, ProjectionParams{
+		LatitudeOfOrigin: dms(52, 9, 22.178) * degToRad,
+		CentralMeridian:  dms(5, 23, 15.5) * degToRad,
+		ScaleFactor:      0.9999079,
+		FalseEasting:     155000,
+		FalseNorthing:    463000,
+	})
+
+	lat, lon := stereo.Inverse(196105.283, 557057.739)
+	assertLatLon(t, lat*radToDeg, lon*radToDeg, 53, 6, 1e-7)
+}
+
+func TestGeodeticToGeocentric(t *testing.T) {
+	lat := dms(53, 48, 33.82) * degToRad
+	lon := dms(2, 7, 46.38) * degToRad
+
+	x, y, z := GeodeticToGeocentric(EllipsoidWGS84, lat, lon, 73)
+	if math.Abs(x-3771793.968) > 1e-3 || math.Abs(y-140253.342) > 1e-3 || math.Abs(z-5124304.349) > 1e-3 {
+		t.Errorf("got (%.3f, %.3f, %.3f)", x, y, z)
+	}
+
+	gotLat, gotLon, height := GeocentricToGeodetic(EllipsoidWGS84, x, y, z)
+	assertLatLon(t, gotLat, gotLon, lat, lon, 1e-11)
+	if math.Abs(height-73) > 1e-3 {
+		t.Errorf("height = %.4f, want 73", height)
+	}
+}
+
+func TestHelmert_Apply(t *testing.T) {
+	// WGS 72 to WGS 84, position vector convention
+	h := &Helmert{Tz: 4.5, Rz: 0.554, Scale: 0.219}
+
+	x, y, z := h.Apply(3657660.66, 255768.55, 5201382.11)
+	if math.Abs(x-3657660.78) > 0.01 || math.Abs(y-255778.43) > 0.01 || math.Abs(z-5201387.75) > 0.01 {
+		t.Errorf("got (%.3f, %.3f, %.3f)", x, y, z)
+	}
+}
@@ -0,0 +1,363 @@
+package gis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// wktNode is a parsed WKT element such as PROJECTION["Transverse_Mercator"].
+// Arguments are either quoted strings, bare numbers/enums or nested nodes.
+type wktNode struct {
+	keyword  string
+	values   []string
+	children []*wktNode
+}
+
+func (n *wktNode) child(keyword string) *wktNode {
+	for _, c := range n.children {
+		if strings.EqualFold(c.keyword, keyword) {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *wktNode) name() string {
+	if len(n.values) == 0 {
+		return ""
+	}
+	return n.values[0]
+}
+
+func (n *wktNode) number(index int) (float64, error) {
+	if index >= len(n.values) {
+		return 0, fmt.Errorf("%s is missing value %d", n.keyword, index+1)
+	}
+	value, err := strconv.ParseFloat(n.values[index], 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s has invalid number %q", n.keyword, n.values[index])
+	}
+	return value, nil
+}
+
+// authorityCode returns the EPSG code from an AUTHORITY["EPSG","xxxx"] child, or 0
+func (n *wktNode) authorityCode() int {
+	authority := n.child("AUTHORITY")
+	if authority == nil || len(authority.values) < 2 || !strings.EqualFold(authority.values[0], "EPSG") {
+		return 0
+	}
+	code, err := strconv.Atoi(authority.values[1])
+	if err != nil {
+		return 0
+	}
+	return code
+}
+
+// ParseWKT parses a WKT1 coordinate system in either the OGC or ESRI flavor, as
+// found in .prj files
+func ParseWKT(text string) (*CRS, error) {
+	p := &wktParser{input: strings.TrimSpace(strings.TrimPrefix(text, "\ufeff"))}
+	root, err := p.parseNode()
+	if err != nil {
+		return nil, fmt.Errorf("invalid WKT: %w", err)
+	}
+
+	switch strings.ToUpper(root.keyword) {
+	case "PROJCS":
+		return projectedFromWKT(root)
+	case "GEOGCS":
+		return geographicFromWKT(root)
+	default:
+		return nil, fmt.Errorf("unsupported WKT coordinate system: %s", root.keyword)
+	}
+}
+
+func geographicFromWKT(node *wktNode) (*CRS, error) {
+	datumNode := node.child("DATUM")
+	if datumNode == nil {
+		// Fall back to the authority code for incomplete definitions
+		if code := node.authorityCode(); code != 0 {
+			return LookupEPSG(code)
+		}
+		return nil, fmt.Errorf("GEOGCS %q has no DATUM", node.name())
+	}
+
+	datum, err := datumFromWKT(datumNode)
+	if err != nil {
+		return nil, err
+	}
+
+	crs := &CRS{Name: node.name(), Datum: datum, AngularUnit: degToRad}
+	if primem := node.child("PRIMEM"); primem != nil {
+		if crs.PrimeMeridian, err = primem.number(1); err != nil {
+			return nil, err
+		}
+	}
+	if unit := node.child("UNIT"); unit != nil {
+		if crs.AngularUnit, err = unit.number(1); err != nil {
+			return nil, err
+		}
+	}
+	return crs, nil
+}
+
+func datumFromWKT(node *wktNode) (Datum, error) {
+	known, isKnown := lookupDatum(node.name())
+	datum := Datum{Name: node.name(), Ellipsoid: known.Ellipsoid, ToWGS84: known.ToWGS84}
+
+	if spheroid := node.child("SPHEROID"); spheroid != nil {
+		a, err := spheroid.number(1)
+		if err != nil {
+			return Datum{}, err
+		}
+		invf, err := spheroid.number(2)
+		if err != nil {
+			return Datum{}, err
+		}
+		datum.Ellipsoid = Ellipsoid{SemiMajorAxis: a, InverseFlattening: invf}
+	} else if !isKnown {
+		return Datum{}, fmt.Errorf("DATUM %q has no SPHEROID", node.name())
+	}
+
+	// An explicit TOWGS84 clause always wins over the built-in shift
+	if towgs84 := node.child("TOWGS84"); towgs84 != nil {
+		var params [7]float64
+		for i := range params {
+			if i >= len(towgs84.values) {
+				break
+			}
+			value, err := towgs84.number(i)
+			if err != nil {
+				return Datum{}, err
+			}
+			params[i] = value
+		}
+		datum.ToWGS84 = &Helmert{
+			Tx: params[0], Ty: params[1], Tz: params[2],
+			Rx: params[3], Ry: params[4], Rz: params[5],
+			Scale: params[6],
+		}
+		if *datum.ToWGS84 == (Helmert{}) {
+			datum.ToWGS84 = nil
+		}
+	}
+
+	return datum, nil
+}
+
+func projectedFromWKT(node *wktNode) (*CRS, error) {
+	geogcs := node.child("GEOGCS")
+	if geogcs == nil {
+		if code := node.authorityCode(); code != 0 {
+			return LookupEPSG(code)
+		}
+		return nil, fmt.Errorf("PROJCS %q has no GEOGCS", node.name())
+	}
+	geographic, err := geographicFromWKT(geogcs)
+	if err != nil {
+		return nil, err
+	}
+
+	crs := &CRS{
+		Name:          node.name(),
+		Datum:         geographic.Datum,
+		LinearUnit:    UnitMeter,
+		PrimeMeridian: geographic.PrimeMeridian,
+	}
+	if unit := node.child("UNIT"); unit != nil {
+		if crs.LinearUnit, err = unit.number(1); err != nil {
+			return nil, err
+		}
+	}
+
+	params, err := projectionParamsFromWKT(node, geographic.AngularUnit, crs.LinearUnit)
+	if err != nil {
+		return nil, err
+	}
+
+	methodName := ""
+	if projection := node.child("PROJECTION"); projection != nil {
+		methodName = projection.name()
+	}
+	method := normalizeName(methodName)
+	ellipsoid := crs.Datum.Ellipsoid
+
+	switch {
+	case isWebMercator(node, method):
+		crs.Projection = NewWebMercator(ellipsoid.SemiMajorAxis, params)
+	case method == "transversemercator" || method == "gausskruger":
+		crs.Projection = NewTransverseMercator(ellipsoid, params)
+	case method == "mercator" || method == "mercator1sp" || method == "mercator2sp":
+		crs.Projection = NewMercator(ellipsoid, params)
+	case strings.HasPrefix(method, "lambertconformalconic"):
+		if method == "lambertconformalconic1sp" {
+			params.StandardParallel1, params.StandardParallel2 = 0, 0
+		}
+		crs.Projection = NewLambertConformalConic(ellipsoid, params)
+	case method == "lambertazimuthalequalarea":
+		crs.Projection = NewLambertAzimuthalEqualArea(ellipsoid, params)
+	case method == "obliquestereographic" || method == "doublestereographic":
+		crs.Projection = NewObliqueStereographic(ellipsoid, params)
+	default:
+		// Unknown methods may still be covered by the built-in EPSG table
+		if code := node.authorityCode(); code != 0 {
+			return LookupEPSG(code)
+		}
+		return nil, fmt.Errorf("unsupported projection: %q", methodName)
+	}
+
+	return crs, nil
+}
+
+func isWebMercator(node *wktNode, method string) bool {
+	switch node.authorityCode() {
+	case 3857, 900913, 3785, 102100:
+		return true
+	}
+	if method == "mercatorauxiliarysphere" || method == "popularvisualisationpseudomercator" {
+		return true
+	}
+	name := normalizeName(node.name())
+	return strings.Contains(name, "pseudomercator") || strings.Contains(name, "webmercator")
+}
+
+// projectionParamsFromWKT collects PARAMETER clauses, converting angles to
+// radians and offsets to meters
+func projectionParamsFromWKT(node *wktNode, angularUnit, linearUnit float64) (ProjectionParams, error) {
+	if angularUnit == 0 {
+		angularUnit = degToRad
+	}
+	var params ProjectionParams
+	for _, child := range node.children {
+		if !strings.EqualFold(child.keyword, "PARAMETER") {
+			continue
+		}
+		value, err := child.number(1)
+		if err != nil {
+			return ProjectionParams{}, err
+		}
+
+		switch normalizeName(child.name()) {
+		case "falseeasting":
+			params.FalseEasting = value * linearUnit
+		case "falsenorthing":
+			params.FalseNorthing = value * linearUnit
+		case "centralmeridian", "longitudeofcenter", "longitudeoforigin", "longitudeofnaturalorigin":
+			params.CentralMeridian = value * angularUnit
+		case "latitudeoforigin", "latitudeofcenter", "latitudeofnaturalorigin":
+			params.LatitudeOfOrigin = value * angularUnit
+		case "scalefactor", "scalefactoratnaturalorigin":
+			params.ScaleFactor = value
+		case "standardparallel1":
+			params.StandardParallel1 = value * angularUnit
+		case "standardparallel2":
+			params.StandardParallel2 = value * angularUnit
+		}
+	}
+	return params, nil
+}
+
+type wktParser struct {
+	input string
+	pos   int
+}
+
+func (p *wktParser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *wktParser) parseNode() (*wktNode, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsLetter(rune(p.input[p.pos])) ||
+		unicode.IsDigit(rune(p.input[p.pos])) || p.input[p.pos] == '_') {
+		p.pos++
+	}
+	node := &wktNode{keyword: p.input[start:p.pos]}
+	if node.keyword == "" {
+		return nil, fmt.Errorf("expected keyword at offset %d", start)
+	}
+
+	p.skipSpace()
+	if p.pos >= len(p.input) || (p.input[p.pos] != '[' && p.input[p.pos] != '(') {
+		return nil, fmt.Errorf("expected '[' after %s", node.keyword)
+	}
+	closing := byte(']')
+	if p.input[p.pos] == '(' {
+		closing = ')'
+	}
+	p.pos++
+
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.input) {
+			return nil, fmt.Errorf("unterminated %s", node.keyword)
+		}
+
+		switch c := p.input[p.pos]; {
+		case c == closing:
+			p.pos++
+			return node, nil
+		case c == ',':
+			p.pos++
+		case c == '"':
+			value, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			node.values = append(node.values, value)
+		case unicode.IsLetter(rune(c)):
+			// Either a nested node or a bare enum value such as AXIS["X",EAST]
+			save := p.pos
+			child, err := p.parseNode()
+			if err == nil {
+				node.children = append(node.children, child)
+				continue
+			}
+			p.pos = save
+			node.values = append(node.values, p.parseBare())
+		default:
+			node.values = append(node.values, p.parseBare())
+		}
+	}
+}
+
+func (p *wktParser) parseString() (string, error) {
+	p.pos++ // opening quote
+	var b strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		p.pos++
+		if c == '"' {
+			// Doubled quotes escape a literal quote
+			if p.pos < len(p.input) && p.input[p.pos] == '"' {
+				b.WriteByte('"')
+				p.pos++
+				continue
+			}
+			return b.String(), nil
+		}
+		b.WriteByte(c)
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+func (p *wktParser) parseBare() string {
+	start := p.pos
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		if c == ',' || c == ']' || c == ')' || unicode.IsSpace(rune(c)) {
+			break
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		// Skip an unexpected character so parsing always makes progress
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
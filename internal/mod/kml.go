@@ -0,0 +1,75 @@
+package mod
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
+	"github.com/supermanifolds/nimby_shapetopoi/pkg/kml"
+)
+
+// ToKML converts POI layers to a KML document with a folder per layer and a
+// placemark per POI, so the output can be checked in Google Earth. Each POI
+// color becomes a shared style, and the POI fields that KML has no place for
+// are kept as ExtendedData.
+func ToKML(name string, layers []Layer) *kml.KML {
+	document := &kml.Document{Name: name}
+
+	colors := make(map[string]bool)
+	for _, layer := range layers {
+		folder := kml.Folder{Name: layer.Name}
+		for _, p := range layer.POIs {
+			colors[p.Color] = true
+			folder.Placemarks = append(folder.Placemarks, poiPlacemark(p))
+		}
+		document.Folders = append(document.Folders, folder)
+	}
+
+	// Sorted so that the same POIs always give the same document
+	sorted := make([]string, 0, len(colors))
+	for color := range colors {
+		sorted = append(sorted, color)
+	}
+	sort.Strings(sorted)
+	for _, color := range sorted {
+		style := kml.Style{ID: colorStyleID(color)}
+		if kmlColor, ok := kml.RGBToColor(color); ok {
+			style.IconStyle = &kml.IconStyle{Color: kmlColor}
+		}
+		document.Styles = append(document.Styles, style)
+	}
+
+	return &kml.KML{Document: document}
+}
+
+// WriteKML writes POI layers to a .kml or .kmz file
+func WriteKML(filePath, name string, layers []Layer) error {
+	return kml.WriteFile(filePath, ToKML(name, layers))
+}
+
+func poiPlacemark(p poi.POI) kml.Placemark {
+	return kml.Placemark{
+		Name:     p.Text,
+		StyleURL: "#" + colorStyleID(p.Color),
+		Point: &kml.Point{
+			Coordinates: kml.FormatCoordinates([]kml.Coordinate{{Lon: p.Lon, Lat: p.Lat}}),
+		},
+		ExtendedData: &kml.ExtendedData{
+			Data: []kml.Data{
+				{Name: "color", Value: p.Color},
+				{Name: "font_size", Value: strconv.Itoa(int(p.FontSize))},
+				{Name: "max_lod", Value: strconv.Itoa(int(p.MaxLod))},
+				{Name: "transparent", Value: strconv.FormatBool(p.Transparent)},
+				{Name: "demand", Value: p.Demand},
+				{Name: "population", Value: strconv.FormatInt(p.Population, 10)},
+			},
+		},
+	}
+}
+
+func colorStyleID(color string) string {
+	if color == "" {
+		return "poi"
+	}
+	return "poi-" + color
+}
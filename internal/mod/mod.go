@@ -2,10 +2,13 @@ package mod
 
 import (
 	"archive/zip"
+	"bytes"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"strings"
 
 	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
@@ -85,6 +88,111 @@ func CreateZip(config Config, poiList poi.List, modContent string) error {
 	return nil
 }
 
+// Layer is a POI layer of a mod
+type Layer struct {
+	Name        string
+	TSVFileName string
+	POIs        poi.List
+}
+
+// ReadZip reads the POI layers of a mod zip, such as one written by CreateZip.
+// mod.txt may sit in a folder inside the zip, as when a mod folder is zipped.
+func ReadZip(filePath string) ([]Layer, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	var modFile *zip.File
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+		if path.Base(file.Name) == "mod.txt" && (modFile == nil || len(file.Name) < len(modFile.Name)) {
+			modFile = file
+		}
+	}
+	if modFile == nil {
+		return nil, errors.New("no mod.txt found in zip")
+	}
+
+	content, err := readZipFile(modFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mod.txt: %w", err)
+	}
+
+	layers := ParseLayers(string(content))
+	if len(layers) == 0 {
+		return nil, errors.New("mod.txt has no POI layers")
+	}
+
+	dir := path.Dir(modFile.Name)
+	for i := range layers {
+		tsvFile := files[path.Join(dir, layers[i].TSVFileName)]
+		if tsvFile == nil {
+			return nil, fmt.Errorf("layer %q: %s not found in zip", layers[i].Name, layers[i].TSVFileName)
+		}
+		data, err := readZipFile(tsvFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", layers[i].TSVFileName, err)
+		}
+		if layers[i].POIs, err = poi.FromTSV(csv.NewReader(bytes.NewReader(data))); err != nil {
+			return nil, fmt.Errorf("%s: %w", layers[i].TSVFileName, err)
+		}
+	}
+	return layers, nil
+}
+
+// ParseLayers returns the [POILayer] sections of mod.txt content, without
+// their POIs
+func ParseLayers(modContent string) []Layer {
+	var layers []Layer
+	var current *Layer
+	for _, line := range strings.Split(modContent, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			current = nil
+			if trimmed == "[POILayer]" {
+				layers = append(layers, Layer{})
+				current = &layers[len(layers)-1]
+			}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		key, value, ok := strings.Cut(trimmed, "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "name":
+			current.Name = strings.TrimSpace(value)
+		case "tsv":
+			current.TSVFileName = strings.TrimSpace(value)
+		}
+	}
+
+	// Layers without a TSV file have nothing to read
+	valid := layers[:0]
+	for _, layer := range layers {
+		if layer.TSVFileName != "" {
+			valid = append(valid, layer)
+		}
+	}
+	return valid
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
+
 // zipStringWriter wraps an io.Writer
 type zipStringWriter struct {
 	w io.Writer
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
+	"github.com/supermanifolds/nimby_shapetopoi/pkg/kml"
 )
 
 func TestGenerateDefaultContent(t *testing.T) {
@@ -275,3 +276,134 @@ func TestCreateZip_InvalidPath(t *testing.T) {
 		t.Error("Expected error for invalid output path, but got none")
 	}
 }
+
+func TestReadZip(t *testing.T) {
+	zipPath := filepath.Join(t.TempDir(), "export.zip")
+	poiList := poi.List{
+		{Lon: 10.123, Lat: 53.456, Color: "ff0000", Text: "Test POI", FontSize: 12, MaxLod: 10, Population: 100},
+		{Lon: 10.2, Lat: 53.5, Color: "0000ff"},
+	}
+
+	config := Config{OutputPath: zipPath, TSVFileName: "export.tsv"}
+	if err := CreateZip(config, poiList, GenerateDefaultContent("export", "export.tsv")); err != nil {
+		t.Fatalf("CreateZip returned error: %v", err)
+	}
+
+	layers, err := ReadZip(zipPath)
+	if err != nil {
+		t.Fatalf("ReadZip returned error: %v", err)
+	}
+	if len(layers) != 1 || layers[0].Name != "export POIs" || layers[0].TSVFileName != "export.tsv" {
+		t.Fatalf("Expected the layer of the default mod.txt, got %+v", layers)
+	}
+	if len(layers[0].POIs) != 2 || layers[0].POIs[0] != poiList[0] {
+		t.Errorf("Expected the POIs to round-trip, got %+v", layers[0].POIs)
+	}
+}
+
+func TestReadZip_Errors(t *testing.T) {
+	tests := map[string]map[string]string{
+		"no mod.txt":    {"pois.tsv": "lon\tlat\n1\t2\n"},
+		"no POI layers": {"mod.txt": "[ModMeta]\nname=empty\n"},
+		"missing tsv":   {"mod.txt": "[POILayer]\nname = Layer\ntsv = missing.tsv\n"},
+	}
+
+	for name, files := range tests {
+		t.Run(name, func(t *testing.T) {
+			zipPath := filepath.Join(t.TempDir(), "mod.zip")
+			file, err := os.Create(zipPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			writer := zip.NewWriter(file)
+			for entryName, content := range files {
+				entry, err := writer.Create(entryName)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := entry.Write([]byte(content)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			writer.Close()
+			file.Close()
+
+			if _, err := ReadZip(zipPath); err == nil {
+				t.Error("Expected error, got none")
+			}
+		})
+	}
+}
+
+func TestParseLayers(t *testing.T) {
+	content := `[ModMeta]
+name=multi
+
+[POILayer]
+id = stations
+name = Stations
+tsv = stations.tsv
+
+[POILayer]
+name = No data
+
+[POILayer]
+name = Tracks
+tsv = tracks.tsv
+`
+	layers := ParseLayers(content)
+	if len(layers) != 2 {
+		t.Fatalf("Expected 2 layers with a TSV file, got %+v", layers)
+	}
+	if layers[0].Name != "Stations" || layers[0].TSVFileName != "stations.tsv" || layers[1].Name != "Tracks" {
+		t.Errorf("Unexpected layers: %+v", layers)
+	}
+}
+
+func TestToKML(t *testing.T) {
+	layers := []Layer{
+		{Name: "Stations", POIs: poi.List{
+			{Lon: 13.3694, Lat: 52.5251, Color: "ff0000", Text: "Hauptbahnhof", FontSize: 14, MaxLod: 10},
+			{Lon: 13.4, Lat: 52.52, Color: "0288d1"},
+		}},
+		{Name: "Depots", POIs: poi.List{{Lon: 13.5, Lat: 52.5, Color: "ff0000", Text: "Depot"}}},
+	}
+
+	document := ToKML("berlin", layers).Document
+	if document.Name != "berlin" || len(document.Folders) != 2 || document.Folders[1].Name != "Depots" {
+		t.Fatalf("Expected a folder per layer, got %+v", document)
+	}
+
+	// One shared style per color
+	if len(document.Styles) != 2 {
+		t.Fatalf("Expected 2 styles, got %+v", document.Styles)
+	}
+	if document.Styles[1].ID != "poi-ff0000" || document.Styles[1].IconStyle.Color != "ff0000ff" {
+		t.Errorf("Expected a red icon style, got %+v", document.Styles[1])
+	}
+
+	placemark := document.Folders[0].Placemarks[0]
+	if placemark.Name != "Hauptbahnhof" || placemark.StyleURL != "#poi-ff0000" || placemark.Point.Coordinates != "13.3694,52.5251" {
+		t.Errorf("Unexpected placemark: %+v", placemark)
+	}
+	if attributes := placemark.Attributes(); attributes["font_size"] != "14" || attributes["max_lod"] != "10" {
+		t.Errorf("Expected the POI fields as extended data, got %v", attributes)
+	}
+}
+
+func TestWriteKML(t *testing.T) {
+	kmzPath := filepath.Join(t.TempDir(), "export.kmz")
+	layers := []Layer{{Name: "Stations", POIs: poi.List{{Lon: 10, Lat: 53, Color: "ff0000", Text: "A"}}}}
+	if err := WriteKML(kmzPath, "export", layers); err != nil {
+		t.Fatalf("WriteKML returned error: %v", err)
+	}
+
+	parsed, err := kml.ParseKMZ(kmzPath)
+	if err != nil {
+		t.Fatalf("ParseKMZ returned error: %v", err)
+	}
+	placemarks := parsed.Document.AllPlacemarks()
+	if len(placemarks) != 1 || placemarks[0].Name != "A" {
+		t.Errorf("Expected the POI as a placemark, got %+v", placemarks)
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/supermanifolds/nimby_shapetopoi/internal/geometry"
 	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/rules"
 )
 
 // BoundingBox represents a geographic bounding box
@@ -31,38 +33,90 @@ func ParseBoundingBox(bbox string) (*BoundingBox, error) {
 		coords[i] = coord
 	}
 
-	return &BoundingBox{
+	bb := &BoundingBox{
 		MinLon: coords[0],
 		MinLat: coords[1],
 		MaxLon: coords[2],
 		MaxLat: coords[3],
-	}, nil
+	}
+	if bb.MinLon < -180 || bb.MaxLon > 180 || bb.MinLat < -90 || bb.MaxLat > 90 {
+		return nil, errors.New("invalid bounding box, coordinates must be longitude/latitude in degrees")
+	}
+	if bb.MinLon >= bb.MaxLon || bb.MinLat >= bb.MaxLat {
+		return nil, errors.New("invalid bounding box, expected minlon,minlat,maxlon,maxlat with the minimum first")
+	}
+	return bb, nil
+}
+
+// maxQueryArea limits the bounding box of GeneratePOIs (square degrees), about
+// 200 × 200 km in central Europe. Larger areas are better served by a PBF extract.
+const maxQueryArea = 4.0
+
+// railwayRules styles the features of RailwayQuery in the colors of the
+// OpenRailwayMap infrastructure layer. Tracks keep the configured zoom level;
+// signals and platforms only show up close in.
+const railwayRules = `{
+	"rules": [
+		{"name": "main lines", "match": {"railway": ["rail", "narrow_gauge"]}, "style": {"color": "#ff8c00"}},
+		{"name": "branch lines", "match": {"railway": "rail", "usage": "branch"}, "style": {"color": "#daca00"}},
+		{"name": "service tracks", "match": {"railway": "rail", "service": "*"}, "style": {"color": "#87491d", "font_size": 8}},
+		{"name": "light rail", "match": {"railway": "light_rail"}, "style": {"color": "#00bd14"}},
+		{"name": "subway", "match": {"railway": "subway"}, "style": {"color": "#0300c3"}},
+		{"name": "tram", "match": {"railway": "tram"}, "style": {"color": "#d877b8"}},
+		{"name": "monorail and funicular", "match": {"railway": ["monorail", "funicular"]}, "style": {"color": "#6d6d6d"}},
+		{"name": "stations", "match": {"railway": "station"}, "style": {"color": "#d40000", "font_size": 14}},
+		{"name": "halts", "match": {"railway": "halt"}, "style": {"color": "#d40000", "font_size": 12}},
+		{"name": "tram stops", "match": {"railway": "tram_stop"}, "style": {"color": "#d877b8", "font_size": 10}},
+		{"name": "platforms", "match": {"railway": "platform"}, "style": {"color": "#a0a0a0", "font_size": 8, "max_lod": 3}},
+		{"name": "signals", "match": {"railway": "signal"}, "style": {"color": "#ffbf00", "font_size": 8, "max_lod": 1}}
+	]
+}`
+
+// Config configures GeneratePOIs
+type Config struct {
+	// Endpoint is the Overpass API interpreter URL; empty uses DefaultOverpassEndpoint
+	Endpoint string
+	// Options controls how railway features become POIs, as for input files.
+	// When Options.Rules is nil the built-in railway styles are used.
+	Options geometry.Options
+	// MaxLod is the zoom level POIs stay visible up to before styling; nil uses
+	// geometry.DefaultMaxLod
+	MaxLod *int32
+	// Color is the hex color of features no style applies to, e.g. "#0000ff";
+	// empty uses the default blue
+	Color string
 }
 
-// GeneratePOIs creates POIs for a bounding box area (for railway endpoint)
-func GeneratePOIs(_ context.Context, bboxStr string) (*poi.List, error) {
+// GeneratePOIs queries an Overpass API for the railway infrastructure in a
+// bounding box given as "minlon,minlat,maxlon,maxlat" and converts it to
+// styled POIs: tracks become lines of POIs, stations, stops and signals single
+// POIs
+func GeneratePOIs(ctx context.Context, bboxStr string, cfg Config) (*poi.List, error) {
 	bbox, err := ParseBoundingBox(bboxStr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid bounding box: %w", err)
 	}
+	if area := (bbox.MaxLon - bbox.MinLon) * (bbox.MaxLat - bbox.MinLat); area > maxQueryArea {
+		return nil, fmt.Errorf("bounding box of %.1f square degrees is too large, the limit is %.0f; use an .osm.pbf extract for larger areas", area, maxQueryArea)
+	}
 
-	// Create a simple center point POI for the bounding box
-	centerLat := (bbox.MinLat + bbox.MaxLat) / 2
-	centerLon := (bbox.MinLon + bbox.MaxLon) / 2
+	opts := cfg.Options
+	if opts.Rules == nil {
+		if opts.Rules, err = rules.Parse([]byte(railwayRules)); err != nil {
+			return nil, fmt.Errorf("invalid railway styles: %w", err)
+		}
+	}
+	maxLod := int32(geometry.DefaultMaxLod)
+	if cfg.MaxLod != nil {
+		maxLod = *cfg.MaxLod
+	}
+	color := geometry.HexToNimbyColor(cfg.Color)
 
-	poiList := poi.List{
-		{
-			Lat:         centerLat,
-			Lon:         centerLon,
-			Color:       "ffff0000", // Red
-			Text:        "Railway Area Center",
-			FontSize:    12,
-			MaxLod:      15,
-			Transparent: false,
-			Demand:      "",
-			Population:  0,
-		},
+	data, err := NewOverpassClient(cfg.Endpoint).Query(ctx, RailwayQuery(bbox))
+	if err != nil {
+		return nil, err
 	}
 
-	return &poiList, nil
+	features := geometry.OSMFeatures(data, nil, "overpass")
+	return opts.ConvertFeatures(features, maxLod, color)
 }
@@ -0,0 +1,86 @@
+package openrailway
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/osm"
+)
+
+const (
+	// DefaultOverpassEndpoint is the public Overpass API instance
+	DefaultOverpassEndpoint = "https://overpass-api.de/api/interpreter"
+	// overpassQueryTimeout is the server-side limit requested in the query (seconds)
+	overpassQueryTimeout = 120
+	overpassTimeout      = (overpassQueryTimeout + 30) * time.Second
+)
+
+// OverpassClient runs queries against an Overpass API compatible endpoint
+type OverpassClient struct {
+	httpClient *http.Client
+	endpoint   string
+}
+
+// NewOverpassClient creates a client for the given interpreter URL, or the
+// public instance when endpoint is empty
+func NewOverpassClient(endpoint string) *OverpassClient {
+	if endpoint == "" {
+		endpoint = DefaultOverpassEndpoint
+	}
+	return &OverpassClient{
+		httpClient: &http.Client{
+			Timeout: overpassTimeout,
+		},
+		endpoint: endpoint,
+	}
+}
+
+// Query runs an Overpass QL query, which must request [out:json]
+func (c *OverpassClient) Query(ctx context.Context, query string) (*osm.Data, error) {
+	form := url.Values{"data": {query}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "NIMBY-Rails-Shapes-to-POIs/1.0")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Overpass API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Overpass explains rate limits and timeouts in a short HTML or text body
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("overpass API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return osm.ReadJSON(resp.Body)
+}
+
+// RailwayQuery returns an Overpass QL query for the railway infrastructure in
+// a bounding box: tracks, stations and stops, platforms and signals, along
+// with the nodes needed to draw the ways
+func RailwayQuery(bbox *BoundingBox) string {
+	// Overpass orders bounding boxes south, west, north, east
+	return fmt.Sprintf(`[out:json][timeout:%d][bbox:%g,%g,%g,%g];
+(
+  way[railway~"^(rail|light_rail|subway|tram|narrow_gauge|monorail|funicular)$"];
+  node[railway~"^(station|halt|tram_stop)$"];
+  node[public_transport=station][railway];
+  way[railway=platform];
+  way[public_transport=platform][railway];
+  node[railway=signal];
+);
+out body;
+>;
+out skel qt;`, overpassQueryTimeout, bbox.MinLat, bbox.MinLon, bbox.MaxLat, bbox.MaxLon)
+}
@@ -0,0 +1,142 @@
+package openrailway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// overpassFixture is a trimmed response to RailwayQuery around Berlin Hbf
+const overpassFixture = `{
+  "version": 0.6,
+  "generator": "Overpass API 0.7.62",
+  "elements": [
+    {"type": "node", "id": 1, "lat": 52.5251, "lon": 13.3694, "tags": {"railway": "station", "public_transport": "station", "name": "Berlin Hauptbahnhof"}},
+    {"type": "node", "id": 5, "lat": 52.5260, "lon": 13.3650, "tags": {"railway": "signal", "railway:signal:main": "DE-ESO:ks"}},
+    {"type": "way", "id": 10, "nodes": [1, 2, 3], "tags": {"railway": "rail", "usage": "main", "name": "Berliner Stadtbahn"}},
+    {"type": "way", "id": 11, "nodes": [2, 4], "tags": {"railway": "rail", "service": "siding"}},
+    {"type": "node", "id": 1, "lat": 52.5251, "lon": 13.3694},
+    {"type": "node", "id": 2, "lat": 52.5255, "lon": 13.3600},
+    {"type": "node", "id": 3, "lat": 52.5260, "lon": 13.3500},
+    {"type": "node", "id": 4, "lat": 52.5240, "lon": 13.3590}
+  ]
+}`
+
+func newOverpassServer(t *testing.T, status int, body string, queries *[]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Expected POST, got %s", r.Method)
+		}
+		if queries != nil {
+			*queries = append(*queries, r.FormValue("data"))
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGeneratePOIs(t *testing.T) {
+	var queries []string
+	server := newOverpassServer(t, http.StatusOK, overpassFixture, &queries)
+
+	poiList, err := GeneratePOIs(context.Background(), "13.34,52.51,13.39,52.54", Config{Endpoint: server.URL})
+	if err != nil {
+		t.Fatalf("GeneratePOIs returned error: %v", err)
+	}
+
+	if len(queries) != 1 {
+		t.Fatalf("Expected 1 query, got %d", len(queries))
+	}
+	if !strings.Contains(queries[0], "[bbox:52.51,13.34,52.54,13.39]") {
+		t.Errorf("Expected the bounding box in south,west,north,east order, got query:\n%s", queries[0])
+	}
+	if !strings.Contains(queries[0], "[out:json]") {
+		t.Errorf("Expected a JSON query, got:\n%s", queries[0])
+	}
+
+	// Station, signal, 3 vertices of the main line and 2 of the siding
+	if len(*poiList) != 7 {
+		t.Fatalf("Expected 7 POIs, got %d", len(*poiList))
+	}
+
+	station := (*poiList)[0]
+	if station.Text != "Berlin Hauptbahnhof" || station.Color != "d40000" || station.FontSize != 14 {
+		t.Errorf("Expected a styled station, got %+v", station)
+	}
+	signal := (*poiList)[1]
+	if signal.Color != "ffbf00" || signal.MaxLod != 1 {
+		t.Errorf("Expected a styled signal, got %+v", signal)
+	}
+	mainLine := (*poiList)[2]
+	if mainLine.Text != "Berliner Stadtbahn" || mainLine.Color != "ff8c00" || mainLine.MaxLod != 10 {
+		t.Errorf("Expected a styled main line, got %+v", mainLine)
+	}
+	siding := (*poiList)[5]
+	if siding.Color != "87491d" {
+		t.Errorf("Expected a styled siding, got %+v", siding)
+	}
+}
+
+func TestGeneratePOIs_MaxLod(t *testing.T) {
+	server := newOverpassServer(t, http.StatusOK, overpassFixture, nil)
+
+	// Zero is a zoom level of its own rather than the default
+	maxLod := int32(0)
+	poiList, err := GeneratePOIs(context.Background(), "13.34,52.51,13.39,52.54", Config{Endpoint: server.URL, MaxLod: &maxLod})
+	if err != nil {
+		t.Fatalf("GeneratePOIs returned error: %v", err)
+	}
+
+	if mainLine := (*poiList)[2]; mainLine.MaxLod != 0 {
+		t.Errorf("Expected the main line to keep max LOD 0, got %d", mainLine.MaxLod)
+	}
+	if signal := (*poiList)[1]; signal.MaxLod != 1 {
+		t.Errorf("Expected the signal style to set max LOD 1, got %d", signal.MaxLod)
+	}
+}
+
+func TestGeneratePOIs_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		bbox   string
+		status int
+		body   string
+	}{
+		{name: "invalid bbox", bbox: "13.34,52.51,13.39", status: http.StatusOK, body: overpassFixture},
+		{name: "inverted bbox", bbox: "13.39,52.54,13.34,52.51", status: http.StatusOK, body: overpassFixture},
+		{name: "bbox too large", bbox: "5,47,15,55", status: http.StatusOK, body: overpassFixture},
+		{name: "rate limited", bbox: "13.34,52.51,13.39,52.54", status: http.StatusTooManyRequests, body: "Too Many Requests"},
+		{name: "runtime error", bbox: "13.34,52.51,13.39,52.54", status: http.StatusOK, body: `{"elements": [], "remark": "runtime error: Query timed out in \"query\" at line 3 after 120 seconds."}`},
+		{name: "invalid response", bbox: "13.34,52.51,13.39,52.54", status: http.StatusOK, body: "<html>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newOverpassServer(t, tt.status, tt.body, nil)
+			if _, err := GeneratePOIs(context.Background(), tt.bbox, Config{Endpoint: server.URL}); err == nil {
+				t.Error("Expected error, but got none")
+			}
+		})
+	}
+}
+
+func TestParseBoundingBox(t *testing.T) {
+	bbox, err := ParseBoundingBox(" 13.34, 52.51 ,13.39,52.54")
+	if err != nil {
+		t.Fatalf("ParseBoundingBox returned error: %v", err)
+	}
+	if bbox.MinLon != 13.34 || bbox.MinLat != 52.51 || bbox.MaxLon != 13.39 || bbox.MaxLat != 52.54 {
+		t.Errorf("Unexpected bounding box %+v", bbox)
+	}
+
+	for _, invalid := range []string{"", "1,2,3", "a,2,3,4", "13.39,52.51,13.34,52.54", "0,-91,1,0", "179,0,181,1"} {
+		if _, err := ParseBoundingBox(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
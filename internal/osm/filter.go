@@ -0,0 +1,57 @@
+package osm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/expr"
+)
+
+// Filter selects elements by their tags, in the expression grammar of the
+// expr package, which --where shares:
+//
+//	railway                     the tag is set
+//	railway=rail|light_rail     the tag is one of the values ("*" wildcards allowed)
+//	ref="S*"                    the tag is exactly the quoted value
+//	usage!=industrial           the tag is missing or none of the values
+//	name~"Hbf$"                 the tag matches a regular expression
+//	service!~siding|yard        the tag is missing or does not match
+//	maxspeed>=160               numeric comparison when both sides are numbers
+//
+// Tests combine with "and", "or", "not" and parentheses. Values containing
+// spaces or parentheses must be quoted.
+type Filter struct {
+	text string
+	expr expr.Expr[map[string]string]
+}
+
+// tagLanguage evaluates expressions over the tags of an element. Tag keys are
+// case-sensitive, as in OpenStreetMap.
+var tagLanguage = &expr.Language[map[string]string]{
+	Attribute: func(tags map[string]string, key string) (string, bool) {
+		value := tags[key]
+		return value, value != ""
+	},
+}
+
+// ParseFilter parses a tag filter expression
+func ParseFilter(text string) (*Filter, error) {
+	e, err := expr.Parse(text, tagLanguage)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	return &Filter{text: strings.TrimSpace(text), expr: e}, nil
+}
+
+// Match reports whether tags satisfy the filter. A nil filter matches any
+// element that has tags.
+func (f *Filter) Match(tags map[string]string) bool {
+	if f == nil {
+		return len(tags) > 0
+	}
+	return f.expr.Match(tags)
+}
+
+func (f *Filter) String() string {
+	return f.text
+}
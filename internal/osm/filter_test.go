@@ -0,0 +1,81 @@
+package osm
+
+import (
+	"testing"
+)
+
+func TestFilter_Match(t *testing.T) {
+	mainLine := map[string]string{"railway": "rail", "usage": "main", "name": "Berlin–Hamburg", "tracks": "2"}
+	siding := map[string]string{"railway": "rail", "service": "siding"}
+	station := map[string]string{"public_transport": "station", "railway": "station", "name": "Berlin Hbf"}
+	tram := map[string]string{"railway": "tram"}
+
+	tests := []struct {
+		filter   string
+		expected []bool // mainLine, siding, station, tram
+	}{
+		{filter: "railway", expected: []bool{true, true, true, true}},
+		{filter: "railway=rail", expected: []bool{true, true, false, false}},
+		{filter: "railway=rail and usage=main", expected: []bool{true, false, false, false}},
+		{filter: "public_transport=station", expected: []bool{false, false, true, false}},
+		{filter: "railway=rail|tram", expected: []bool{true, true, false, true}},
+		{filter: "railway=rail and service!=siding|yard", expected: []bool{true, false, false, false}},
+		{filter: "railway=rail and not service", expected: []bool{true, false, false, false}},
+		{filter: "railway=rail and !service", expected: []bool{true, false, false, false}},
+		{filter: "name~\"Hbf$\"", expected: []bool{false, false, true, false}},
+		{filter: "name!~Hbf", expected: []bool{true, true, false, true}},
+		{filter: "name=Berlin*", expected: []bool{true, false, true, false}},
+		{filter: "name=\"Berlin*\"", expected: []bool{false, false, false, false}},
+		{filter: "name=\"Berlin Hbf\"", expected: []bool{false, false, true, false}},
+		{filter: "railway=tram or (railway=rail AND usage=main)", expected: []bool{true, false, false, true}},
+		{filter: "usage=main or railway=station and name", expected: []bool{true, false, true, false}},
+		{filter: "not (railway=rail or railway=tram)", expected: []bool{false, false, true, false}},
+		{filter: "tracks>=2", expected: []bool{true, false, false, false}},
+		{filter: "railway = 'rail' and usage <> main", expected: []bool{false, true, false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			filter, err := ParseFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("ParseFilter returned error: %v", err)
+			}
+			for i, tags := range []map[string]string{mainLine, siding, station, tram} {
+				if got := filter.Match(tags); got != tt.expected[i] {
+					t.Errorf("Match(%v) = %v, expected %v", tags, got, tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFilter_Nil(t *testing.T) {
+	var filter *Filter
+	if !filter.Match(map[string]string{"railway": "rail"}) {
+		t.Error("Expected a nil filter to match tagged elements")
+	}
+	if filter.Match(nil) {
+		t.Error("Expected a nil filter not to match untagged elements")
+	}
+}
+
+func TestParseFilter_Errors(t *testing.T) {
+	tests := []string{
+		"",
+		"railway=",
+		"railway=rail and",
+		"(railway=rail",
+		"railway=rail)",
+		"name~\"[\"",
+		"name=\"Berlin",
+		"railway=[",
+	}
+
+	for _, filter := range tests {
+		t.Run(filter, func(t *testing.T) {
+			if _, err := ParseFilter(filter); err == nil {
+				t.Errorf("Expected error for %q", filter)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package osm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type jsonResponse struct {
+	Elements []jsonElement `json:"elements"`
+	// Remark carries Overpass runtime errors such as timeouts, which are
+	// reported with a successful HTTP status
+	Remark string `json:"remark"`
+}
+
+type jsonElement struct {
+	Type    string            `json:"type"`
+	ID      int64             `json:"id"`
+	Lat     float64           `json:"lat"`
+	Lon     float64           `json:"lon"`
+	Nodes   []int64           `json:"nodes"`
+	Members []jsonMember      `json:"members"`
+	Tags    map[string]string `json:"tags"`
+}
+
+type jsonMember struct {
+	Type string `json:"type"`
+	Ref  int64  `json:"ref"`
+	Role string `json:"role"`
+}
+
+// ReadJSON reads OSM JSON as returned by Overpass with [out:json]. Elements
+// output more than once are kept the first time they appear, which for the
+// usual "out body; >; out skel;" queries is the copy with tags.
+func ReadJSON(r io.Reader) (*Data, error) {
+	var response jsonResponse
+	if err := json.NewDecoder(r).Decode(&response); err != nil {
+		return nil, fmt.Errorf("invalid OSM JSON: %w", err)
+	}
+	if strings.Contains(response.Remark, "error") {
+		return nil, fmt.Errorf("overpass: %s", response.Remark)
+	}
+
+	data := &Data{}
+	seen := make(map[string]bool, len(response.Elements))
+
+	for _, element := range response.Elements {
+		key := fmt.Sprintf("%s/%d", element.Type, element.ID)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		tags := element.Tags
+		if len(tags) == 0 {
+			tags = nil
+		}
+
+		switch ElementType(element.Type) {
+		case NodeType:
+			data.Nodes = append(data.Nodes, Node{ID: element.ID, Lat: element.Lat, Lon: element.Lon, Tags: tags})
+		case WayType:
+			data.Ways = append(data.Ways, Way{ID: element.ID, Nodes: element.Nodes, Tags: tags})
+		case RelationType:
+			members := make([]Member, len(element.Members))
+			for i, member := range element.Members {
+				members[i] = Member{Type: ElementType(member.Type), Ref: member.Ref, Role: member.Role}
+			}
+			data.Relations = append(data.Relations, Relation{ID: element.ID, Members: members, Tags: tags})
+		}
+	}
+
+	return data, nil
+}
@@ -0,0 +1,51 @@
+package osm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadJSON(t *testing.T) {
+	content := `{
+		"elements": [
+			{"type": "node", "id": 1, "lat": 52.5251, "lon": 13.3694, "tags": {"railway": "station"}},
+			{"type": "way", "id": 10, "nodes": [1, 2], "tags": {"railway": "rail"}},
+			{"type": "relation", "id": 20, "members": [{"type": "way", "ref": 10, "role": ""}], "tags": {"type": "route"}},
+			{"type": "node", "id": 1, "lat": 52.5251, "lon": 13.3694},
+			{"type": "node", "id": 2, "lat": 52.5300, "lon": 13.3000}
+		]
+	}`
+
+	data, err := ReadJSON(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("ReadJSON returned error: %v", err)
+	}
+
+	if len(data.Nodes) != 2 || len(data.Ways) != 1 || len(data.Relations) != 1 {
+		t.Fatalf("Expected 2 nodes, 1 way and 1 relation, got %d, %d and %d", len(data.Nodes), len(data.Ways), len(data.Relations))
+	}
+	if node, _ := data.Node(1); node.Tags["railway"] != "station" {
+		t.Errorf("Expected the tagged copy of node 1 to be kept, got %+v", node)
+	}
+	if node, _ := data.Node(2); node.Tags != nil {
+		t.Errorf("Expected untagged nodes to have nil tags, got %v", node.Tags)
+	}
+	relation, _ := data.Relation(20)
+	if len(relation.Members) != 1 || relation.Members[0].Type != WayType || relation.Members[0].Ref != 10 {
+		t.Errorf("Unexpected relation members: %+v", relation.Members)
+	}
+}
+
+func TestReadJSON_Errors(t *testing.T) {
+	tests := []string{
+		"",
+		"<osm/>",
+		`{"elements": [], "remark": "runtime error: Query run out of memory using about 2048 MB of RAM."}`,
+	}
+
+	for _, content := range tests {
+		if _, err := ReadJSON(strings.NewReader(content)); err == nil {
+			t.Errorf("Expected error for %q", content)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package osm
+
+// ElementType is the kind of an OSM element referenced by a relation member
+type ElementType string
+
+const (
+	NodeType     ElementType = "node"
+	WayType      ElementType = "way"
+	RelationType ElementType = "relation"
+)
+
+// Node is an OSM node. Most nodes are untagged way vertices.
+type Node struct {
+	ID   int64
+	Lat  float64
+	Lon  float64
+	Tags map[string]string
+}
+
+// Way is an ordered list of node references
+type Way struct {
+	ID    int64
+	Nodes []int64
+	Tags  map[string]string
+}
+
+// Relation groups other elements, e.g. the ways of a multipolygon or a train route
+type Relation struct {
+	ID      int64
+	Members []Member
+	Tags    map[string]string
+}
+
+// Member is an element referenced by a relation
+type Member struct {
+	Type ElementType
+	Ref  int64
+	Role string
+}
+
+// Polygon is an outer ring with its holes. Rings are closed: the first node is
+// repeated at the end.
+type Polygon struct {
+	Outer []*Node
+	Inner [][]*Node
+}
+
+// Data holds the elements of an OSM extract in file order
+type Data struct {
+	Nodes     []Node
+	Ways      []Way
+	Relations []Relation
+
+	nodeIndex     map[int64]int
+	wayIndex      map[int64]int
+	relationIndex map[int64]int
+}
+
+// areaKeys are tags that make a closed way an area rather than a closed line,
+// following the common OSM conventions. area=yes and area=no override them.
+var areaKeys = []string{"building", "landuse", "leisure", "amenity", "natural", "place", "aeroway", "man_made"}
+
+// Node returns the node with the given ID
+func (d *Data) Node(id int64) (*Node, bool) {
+	d.buildIndex()
+	i, ok := d.nodeIndex[id]
+	if !ok {
+		return nil, false
+	}
+	return &d.Nodes[i], true
+}
+
+// Way returns the way with the given ID
+func (d *Data) Way(id int64) (*Way, bool) {
+	d.buildIndex()
+	i, ok := d.wayIndex[id]
+	if !ok {
+		return nil, false
+	}
+	return &d.Ways[i], true
+}
+
+// Relation returns the relation with the given ID
+func (d *Data) Relation(id int64) (*Relation, bool) {
+	d.buildIndex()
+	i, ok := d.relationIndex[id]
+	if !ok {
+		return nil, false
+	}
+	return &d.Relations[i], true
+}
+
+func (d *Data) buildIndex() {
+	if d.nodeIndex != nil {
+		return
+	}
+	d.nodeIndex = make(map[int64]int, len(d.Nodes))
+	for i := range d.Nodes {
+		d.nodeIndex[d.Nodes[i].ID] = i
+	}
+	d.wayIndex = make(map[int64]int, len(d.Ways))
+	for i := range d.Ways {
+		d.wayIndex[d.Ways[i].ID] = i
+	}
+	d.relationIndex = make(map[int64]int, len(d.Relations))
+	for i := range d.Relations {
+		d.relationIndex[d.Relations[i].ID] = i
+	}
+}
+
+// invalidateIndex drops the ID lookup tables after elements are added
+func (d *Data) invalidateIndex() {
+	d.nodeIndex = nil
+	d.wayIndex = nil
+	d.relationIndex = nil
+}
+
+// WayNodes resolves the nodes of a way. Nodes missing from the extract are
+// skipped, so the second result is false when the way is incomplete.
+func (d *Data) WayNodes(w *Way) ([]*Node, bool) {
+	nodes := make([]*Node, 0, len(w.Nodes))
+	complete := true
+	for _, id := range w.Nodes {
+		node, ok := d.Node(id)
+		if !ok {
+			complete = false
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, complete
+}
+
+// IsClosed reports whether a way ends where it starts
+func (w *Way) IsClosed() bool {
+	return len(w.Nodes) >= 4 && w.Nodes[0] == w.Nodes[len(w.Nodes)-1]
+}
+
+// IsArea reports whether a closed way describes an area, such as a building
+// or a landuse, rather than a closed line such as a roundabout or a loop track
+func (w *Way) IsArea() bool {
+	if !w.IsClosed() {
+		return false
+	}
+	switch w.Tags["area"] {
+	case "yes":
+		return true
+	case "no":
+		return false
+	}
+	for _, key := range areaKeys {
+		if w.Tags[key] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMultipolygon reports whether a relation describes an area from its member ways
+func (r *Relation) IsMultipolygon() bool {
+	return r.Tags["type"] == "multipolygon" || r.Tags["type"] == "boundary"
+}
+
+// Multipolygons assembles the outer and inner member ways of a multipolygon
+// relation into rings. Ways may be split anywhere along a ring and run in
+// either direction. Rings that cannot be closed, for example because a member
+// lies outside the extract, are dropped, as are holes outside every outer ring.
+func (d *Data) Multipolygons(r *Relation) []Polygon {
+	var outerWays, innerWays [][]int64
+	for _, member := range r.Members {
+		if member.Type != WayType {
+			continue
+		}
+		way, ok := d.Way(member.Ref)
+		if !ok || len(way.Nodes) < 2 {
+			continue
+		}
+		if member.Role == "inner" {
+			innerWays = append(innerWays, way.Nodes)
+		} else {
+			outerWays = append(outerWays, way.Nodes)
+		}
+	}
+
+	polygons := make([]Polygon, 0)
+	for _, ring := range d.assembleRings(outerWays) {
+		polygons = append(polygons, Polygon{Outer: ring})
+	}
+
+	for _, inner := range d.assembleRings(innerWays) {
+		for i := range polygons {
+			if ringContains(polygons[i].Outer, inner[0]) {
+				polygons[i].Inner = append(polygons[i].Inner, inner)
+				break
+			}
+		}
+	}
+
+	return polygons
+}
+
+// assembleRings joins ways that share end nodes into closed rings
+func (d *Data) assembleRings(ways [][]int64) [][]*Node {
+	used := make([]bool, len(ways))
+	rings := make([][]*Node, 0)
+
+	for start := range ways {
+		if used[start] {
+			continue
+		}
+		used[start] = true
+		chain := append([]int64(nil), ways[start]...)
+
+		for chain[0] != chain[len(chain)-1] {
+			last := chain[len(chain)-1]
+			extended := false
+			for i, way := range ways {
+				if used[i] {
+					continue
+				}
+				switch last {
+				case way[0]:
+					chain = append(chain, way[1:]...)
+				case way[len(way)-1]:
+					for j := len(way) - 2; j >= 0; j-- {
+						chain = append(chain, way[j])
+					}
+				default:
+					continue
+				}
+				used[i] = true
+				extended = true
+				break
+			}
+			if !extended {
+				break
+			}
+		}
+
+		if len(chain) < 4 || chain[0] != chain[len(chain)-1] {
+			continue
+		}
+		ring, complete := d.WayNodes(&Way{Nodes: chain})
+		if complete {
+			rings = append(rings, ring)
+		}
+	}
+
+	return rings
+}
+
+// ringContains tests whether a node lies inside a ring using ray casting in
+// longitude/latitude, which is accurate enough to match holes to their outer ring
+func ringContains(ring []*Node, node *Node) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.Lat > node.Lat) != (b.Lat > node.Lat) &&
+			node.Lon < (b.Lon-a.Lon)*(node.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
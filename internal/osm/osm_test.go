@@ -0,0 +1,163 @@
+package osm
+
+import (
+	"strings"
+	"testing"
+)
+
+const testOSM = `<?xml version="1.0" encoding="UTF-8"?>
+<osm version="0.6" generator="osmium/1.16.0">
+	<bounds minlat="52.0" minlon="13.0" maxlat="53.0" maxlon="14.0"/>
+	<node id="1" lat="52.0" lon="13.0"/>
+	<node id="2" lat="52.0" lon="13.1"/>
+	<node id="3" lat="52.1" lon="13.1"/>
+	<node id="4" lat="52.1" lon="13.0"/>
+	<node id="5" lat="52.04" lon="13.04"/>
+	<node id="6" lat="52.04" lon="13.06"/>
+	<node id="7" lat="52.06" lon="13.05"/>
+	<node id="8" lat="52.5" lon="13.5">
+		<tag k="railway" v="station"/>
+		<tag k="name" v="Teststadt"/>
+	</node>
+	<node id="9" lat="52.6" lon="13.6" action="delete"/>
+	<way id="10">
+		<nd ref="1"/>
+		<nd ref="2"/>
+		<nd ref="3"/>
+		<tag k="railway" v="rail"/>
+	</way>
+	<way id="11">
+		<nd ref="1"/>
+		<nd ref="4"/>
+		<nd ref="3"/>
+	</way>
+	<way id="12">
+		<nd ref="5"/>
+		<nd ref="6"/>
+		<nd ref="7"/>
+		<nd ref="5"/>
+	</way>
+	<relation id="20">
+		<member type="way" ref="10" role="outer"/>
+		<member type="way" ref="11" role="outer"/>
+		<member type="way" ref="12" role="inner"/>
+		<tag k="type" v="multipolygon"/>
+		<tag k="landuse" v="railway"/>
+	</relation>
+</osm>`
+
+func TestReadXML(t *testing.T) {
+	data, err := ReadXML(strings.NewReader(testOSM))
+	if err != nil {
+		t.Fatalf("ReadXML returned error: %v", err)
+	}
+
+	if len(data.Nodes) != 8 {
+		t.Errorf("Expected 8 nodes (deleted node skipped), got %d", len(data.Nodes))
+	}
+	if len(data.Ways) != 3 || len(data.Relations) != 1 {
+		t.Fatalf("Expected 3 ways and 1 relation, got %d and %d", len(data.Ways), len(data.Relations))
+	}
+
+	station, ok := data.Node(8)
+	if !ok || station.Tags["name"] != "Teststadt" || station.Lat != 52.5 || station.Lon != 13.5 {
+		t.Errorf("Expected node 8 to be the tagged station, got %+v", station)
+	}
+	if node, _ := data.Node(1); node.Tags != nil {
+		t.Errorf("Expected untagged nodes to have nil tags, got %v", node.Tags)
+	}
+
+	way, _ := data.Way(10)
+	if len(way.Nodes) != 3 || way.Nodes[2] != 3 {
+		t.Errorf("Expected way 10 to reference nodes 1, 2, 3, got %v", way.Nodes)
+	}
+
+	relation, _ := data.Relation(20)
+	if len(relation.Members) != 3 || relation.Members[2].Role != "inner" || relation.Members[2].Type != WayType {
+		t.Errorf("Unexpected relation members: %+v", relation.Members)
+	}
+}
+
+func TestReadXML_Invalid(t *testing.T) {
+	tests := []string{
+		"",
+		"<kml></kml>",
+		"<osm><node id=\"1\" lat=\"abc\" lon=\"13\"/></osm>",
+	}
+
+	for _, content := range tests {
+		if _, err := ReadXML(strings.NewReader(content)); err == nil {
+			t.Errorf("Expected error for %q", content)
+		}
+	}
+}
+
+func TestData_Multipolygons(t *testing.T) {
+	data, err := ReadXML(strings.NewReader(testOSM))
+	if err != nil {
+		t.Fatalf("ReadXML returned error: %v", err)
+	}
+
+	relation, _ := data.Relation(20)
+	if !relation.IsMultipolygon() {
+		t.Fatal("Expected relation 20 to be a multipolygon")
+	}
+
+	polygons := data.Multipolygons(relation)
+	if len(polygons) != 1 {
+		t.Fatalf("Expected 1 polygon, got %d", len(polygons))
+	}
+
+	// Way 11 runs in the opposite direction and has to be reversed to close the ring
+	outer := polygons[0].Outer
+	ids := make([]int64, len(outer))
+	for i, node := range outer {
+		ids[i] = node.ID
+	}
+	if len(ids) != 5 || ids[0] != ids[4] {
+		t.Fatalf("Expected a closed outer ring of 5 nodes, got %v", ids)
+	}
+	if len(polygons[0].Inner) != 1 || len(polygons[0].Inner[0]) != 4 {
+		t.Errorf("Expected 1 inner ring of 4 nodes, got %d", len(polygons[0].Inner))
+	}
+}
+
+func TestData_Multipolygons_Incomplete(t *testing.T) {
+	data := &Data{
+		Nodes: []Node{{ID: 1, Lat: 0, Lon: 0}, {ID: 2, Lat: 0, Lon: 1}, {ID: 3, Lat: 1, Lon: 1}},
+		Ways:  []Way{{ID: 10, Nodes: []int64{1, 2, 3}}},
+	}
+	relation := &Relation{
+		ID:      20,
+		Members: []Member{{Type: WayType, Ref: 10, Role: "outer"}, {Type: WayType, Ref: 11, Role: "outer"}},
+		Tags:    map[string]string{"type": "multipolygon"},
+	}
+
+	if polygons := data.Multipolygons(relation); len(polygons) != 0 {
+		t.Errorf("Expected an unclosed ring to be dropped, got %d polygons", len(polygons))
+	}
+}
+
+func TestWay_IsArea(t *testing.T) {
+	closed := []int64{1, 2, 3, 1}
+
+	tests := []struct {
+		name     string
+		way      Way
+		expected bool
+	}{
+		{name: "open way", way: Way{Nodes: []int64{1, 2, 3}, Tags: map[string]string{"building": "yes"}}, expected: false},
+		{name: "closed building", way: Way{Nodes: closed, Tags: map[string]string{"building": "train_station"}}, expected: true},
+		{name: "closed track loop", way: Way{Nodes: closed, Tags: map[string]string{"railway": "rail"}}, expected: false},
+		{name: "area=yes", way: Way{Nodes: closed, Tags: map[string]string{"railway": "platform", "area": "yes"}}, expected: true},
+		{name: "area=no", way: Way{Nodes: closed, Tags: map[string]string{"landuse": "railway", "area": "no"}}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.way.IsArea(); got != tt.expected {
+				t.Errorf("IsArea() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,989 @@
+package osm
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+// Size limits from the PBF specification
+const (
+	maxBlobHeaderSize = 64 << 10
+	maxBlobSize       = 32 << 20
+)
+
+// supportedFeatures are the required_features of an OSMHeader block this
+// decoder understands. Files needing anything else, such as history extracts
+// with HistoricalInformation, are rejected.
+var supportedFeatures = map[string]bool{
+	"OsmSchema-V0.6": true,
+	"DenseNodes":     true,
+}
+
+// elementKinds records which element types a block contains, so later passes
+// only decompress the blocks they need
+type elementKinds uint8
+
+const (
+	hasNodes elementKinds = 1 << iota
+	hasWays
+	hasRelations
+)
+
+// pbfFile decodes the blocks of an .osm.pbf file in parallel. The first scan
+// reads the whole file and records where each data block starts; later scans
+// read only the blocks that contain the element types they need.
+type pbfFile struct {
+	path    string
+	workers int
+	blocks  []pbfBlock
+}
+
+type pbfBlock struct {
+	offset int64
+	kinds  elementKinds
+}
+
+type pbfJob struct {
+	index  int
+	offset int64
+	blob   []byte
+}
+
+// ReadPBFFile reads the elements of an .osm.pbf file whose tags match filter,
+// together with the ways and nodes needed to build their geometries; a nil
+// filter keeps every tagged element. Node locations are resolved in a second
+// pass once the ways are known, so only the selected elements are held in
+// memory and a whole-country extract can be filtered down to its railways.
+func ReadPBFFile(filePath string, filter *Filter) (*Data, error) {
+	f := &pbfFile{path: filePath, workers: runtime.GOMAXPROCS(0)}
+	data := &Data{}
+
+	// First pass: the matching elements of every type
+	type blockResult struct {
+		kinds     elementKinds
+		nodes     []Node
+		ways      []Way
+		relations []Relation
+	}
+	var mu sync.Mutex
+	results := make(map[int]*blockResult)
+
+	err := f.scan(0, func(index int, block *primitiveBlock) error {
+		result := &blockResult{}
+		kinds, err := block.decode(elementHandlers{
+			nodeTags: true,
+			node: func(node Node) {
+				if filter.Match(node.Tags) {
+					result.nodes = append(result.nodes, node)
+				}
+			},
+			way: func(way Way) {
+				if filter.Match(way.Tags) {
+					result.ways = append(result.ways, way)
+				}
+			},
+			relation: func(relation Relation) {
+				if filter.Match(relation.Tags) {
+					result.relations = append(result.relations, relation)
+				}
+			},
+		})
+		result.kinds = kinds
+
+		mu.Lock()
+		results[index] = result
+		mu.Unlock()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Blocks are decoded out of order; keep the elements in file order
+	for index := range f.blocks {
+		result := results[index]
+		f.blocks[index].kinds = result.kinds
+		data.Nodes = append(data.Nodes, result.nodes...)
+		data.Ways = append(data.Ways, result.ways...)
+		data.Relations = append(data.Relations, result.relations...)
+	}
+
+	// Relations nested in matching ones, such as the routes of a route_master.
+	// Stop once a round finds nothing new; the rest lie outside the extract.
+	for {
+		missing := data.missingMembers(RelationType)
+		if len(missing) == 0 {
+			break
+		}
+		relations, err := f.collectRelations(missing)
+		if err != nil {
+			return nil, err
+		}
+		if len(relations) == 0 {
+			break
+		}
+		data.Relations = append(data.Relations, relations...)
+		data.invalidateIndex()
+	}
+
+	// Member ways of relations that don't match the filter themselves
+	if missing := data.missingMembers(WayType); len(missing) > 0 {
+		ways, err := f.collectWays(missing)
+		if err != nil {
+			return nil, err
+		}
+		data.Ways = append(data.Ways, ways...)
+		data.invalidateIndex()
+	}
+
+	// Second pass over the nodes: the locations of every referenced node
+	nodes, err := f.collectNodes(data.missingNodes())
+	if err != nil {
+		return nil, err
+	}
+	data.Nodes = append(data.Nodes, nodes...)
+	data.invalidateIndex()
+
+	return data, nil
+}
+
+// collectRelations reads the relations with the given sorted IDs
+func (f *pbfFile) collectRelations(ids []int64) ([]Relation, error) {
+	var mu sync.Mutex
+	results := make(map[int][]Relation)
+	err := f.scan(hasRelations, func(index int, block *primitiveBlock) error {
+		var relations []Relation
+		_, err := block.decode(elementHandlers{
+			relation: func(relation Relation) {
+				if _, ok := slices.BinarySearch(ids, relation.ID); ok {
+					relations = append(relations, relation)
+				}
+			},
+		})
+		mu.Lock()
+		results[index] = relations
+		mu.Unlock()
+		return err
+	})
+	return inBlockOrder(f, results), err
+}
+
+// collectWays reads the ways with the given sorted IDs
+func (f *pbfFile) collectWays(ids []int64) ([]Way, error) {
+	var mu sync.Mutex
+	results := make(map[int][]Way)
+	err := f.scan(hasWays, func(index int, block *primitiveBlock) error {
+		var ways []Way
+		_, err := block.decode(elementHandlers{
+			way: func(way Way) {
+				if _, ok := slices.BinarySearch(ids, way.ID); ok {
+					ways = append(ways, way)
+				}
+			},
+		})
+		mu.Lock()
+		results[index] = ways
+		mu.Unlock()
+		return err
+	})
+	return inBlockOrder(f, results), err
+}
+
+// collectNodes reads the locations of the nodes with the given sorted IDs. Each
+// ID has its own slot, so workers fill them in without locking.
+func (f *pbfFile) collectNodes(ids []int64) ([]Node, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	nodes := make([]Node, len(ids))
+	found := make([]bool, len(ids))
+
+	err := f.scan(hasNodes, func(_ int, block *primitiveBlock) error {
+		_, err := block.decode(elementHandlers{
+			node: func(node Node) {
+				if i, ok := slices.BinarySearch(ids, node.ID); ok {
+					nodes[i] = node
+					found[i] = true
+				}
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Nodes outside the extract are left out, as in OSM XML
+	resolved := nodes[:0]
+	for i, node := range nodes {
+		if found[i] {
+			resolved = append(resolved, node)
+		}
+	}
+	return resolved, nil
+}
+
+func inBlockOrder[T any](f *pbfFile, results map[int][]T) []T {
+	var elements []T
+	for index := range f.blocks {
+		elements = append(elements, results[index]...)
+	}
+	return elements
+}
+
+// missingMembers returns the sorted IDs of relation members of the given type
+// that are not loaded
+func (d *Data) missingMembers(elementType ElementType) []int64 {
+	var ids []int64
+	for i := range d.Relations {
+		for _, member := range d.Relations[i].Members {
+			if member.Type != elementType {
+				continue
+			}
+			var ok bool
+			switch elementType {
+			case WayType:
+				_, ok = d.Way(member.Ref)
+			case RelationType:
+				_, ok = d.Relation(member.Ref)
+			}
+			if !ok {
+				ids = append(ids, member.Ref)
+			}
+		}
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// missingNodes returns the sorted IDs of nodes referenced by ways and relations
+// that are not loaded
+func (d *Data) missingNodes() []int64 {
+	var ids []int64
+	for i := range d.Ways {
+		for _, id := range d.Ways[i].Nodes {
+			if _, ok := d.Node(id); !ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	for i := range d.Relations {
+		for _, member := range d.Relations[i].Members {
+			if member.Type != NodeType {
+				continue
+			}
+			if _, ok := d.Node(member.Ref); !ok {
+				ids = append(ids, member.Ref)
+			}
+		}
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// scan decodes data blocks on all workers, calling handle for each. The first
+// scan reads every blob in the file; later scans read only blocks containing
+// any of kinds.
+func (f *pbfFile) scan(kinds elementKinds, handle func(index int, block *primitiveBlock) error) error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	jobs := make(chan pbfJob, f.workers)
+	done := make(chan struct{})
+	var once sync.Once
+	var firstErr error
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			close(done)
+		})
+	}
+
+	var wg sync.WaitGroup
+	for range f.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if err := decodeJob(job, handle); err != nil {
+					fail(fmt.Errorf("invalid PBF block at offset %d: %w", job.offset, err))
+				}
+			}
+		}()
+	}
+
+	var readErr error
+	if f.blocks == nil {
+		readErr = f.readAll(file, jobs, done)
+	} else {
+		readErr = f.readBlocks(file, kinds, jobs, done)
+	}
+	close(jobs)
+	wg.Wait()
+
+	if readErr != nil {
+		fail(readErr)
+	}
+	return firstErr
+}
+
+func decodeJob(job pbfJob, handle func(index int, block *primitiveBlock) error) error {
+	raw, err := decodeBlob(job.blob)
+	if err != nil {
+		return err
+	}
+	block, err := parsePrimitiveBlock(raw)
+	if err != nil {
+		return err
+	}
+	return handle(job.index, block)
+}
+
+// readAll reads the file from the start, checking its header and queueing every data block
+func (f *pbfFile) readAll(file *os.File, jobs chan<- pbfJob, done <-chan struct{}) error {
+	f.blocks = make([]pbfBlock, 0)
+	reader := io.NewSectionReader(file, 0, 1<<62)
+	var offset int64
+
+	for {
+		blobType, blob, size, err := readBlob(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid PBF file at offset %d: %w", offset, err)
+		}
+
+		switch blobType {
+		case "OSMHeader":
+			if err := checkHeader(blob); err != nil {
+				return err
+			}
+		case "OSMData":
+			f.blocks = append(f.blocks, pbfBlock{offset: offset})
+			select {
+			case jobs <- pbfJob{index: len(f.blocks) - 1, offset: offset, blob: blob}:
+			case <-done:
+				return nil
+			}
+		}
+		// Other blob types are skipped, as the specification requires
+		offset += size
+	}
+}
+
+// readBlocks queues the recorded blocks that contain any of kinds
+func (f *pbfFile) readBlocks(file *os.File, kinds elementKinds, jobs chan<- pbfJob, done <-chan struct{}) error {
+	for index, block := range f.blocks {
+		if block.kinds&kinds == 0 {
+			continue
+		}
+		_, blob, _, err := readBlob(io.NewSectionReader(file, block.offset, 1<<62))
+		if err != nil {
+			return fmt.Errorf("invalid PBF file at offset %d: %w", block.offset, err)
+		}
+		select {
+		case jobs <- pbfJob{index: index, offset: block.offset, blob: blob}:
+		case <-done:
+			return nil
+		}
+	}
+	return nil
+}
+
+// readBlob reads a length-prefixed BlobHeader and the Blob it describes,
+// returning the blob type, the encoded Blob and the number of bytes read
+func readBlob(r io.Reader) (string, []byte, int64, error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return "", nil, 0, errTruncated
+		}
+		return "", nil, 0, err
+	}
+	headerSize := binary.BigEndian.Uint32(prefix[:])
+	if headerSize > maxBlobHeaderSize {
+		return "", nil, 0, fmt.Errorf("blob header of %d bytes exceeds the limit", headerSize)
+	}
+
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", nil, 0, errTruncated
+	}
+
+	var blobType string
+	var blobSize uint64
+	m := protoMessage{data: header}
+	for {
+		field, wireType, ok, err := m.next()
+		if err != nil {
+			return "", nil, 0, err
+		}
+		if !ok {
+			break
+		}
+		switch {
+		case field == 1 && wireType == wireBytes:
+			value, err := m.bytes()
+			if err != nil {
+				return "", nil, 0, err
+			}
+			blobType = string(value)
+		case field == 3 && wireType == wireVarint:
+			if blobSize, err = m.varint(); err != nil {
+				return "", nil, 0, err
+			}
+		default:
+			if err := m.skip(wireType); err != nil {
+				return "", nil, 0, err
+			}
+		}
+	}
+	if blobSize > maxBlobSize {
+		return "", nil, 0, fmt.Errorf("blob of %d bytes exceeds the limit", blobSize)
+	}
+
+	blob := make([]byte, blobSize)
+	if _, err := io.ReadFull(r, blob); err != nil {
+		return "", nil, 0, errTruncated
+	}
+	return blobType, blob, int64(4 + int(headerSize) + int(blobSize)), nil
+}
+
+// decodeBlob returns the uncompressed contents of a Blob. Only raw and zlib
+// blobs are supported, which is what osmium, osmosis and Geofabrik produce.
+func decodeBlob(data []byte) ([]byte, error) {
+	var raw, compressed []byte
+	var rawSize uint64
+
+	m := protoMessage{data: data}
+	for {
+		field, wireType, ok, err := m.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		switch field {
+		case 1:
+			if raw, err = m.bytes(); err != nil {
+				return nil, err
+			}
+		case 2:
+			if rawSize, err = m.varint(); err != nil {
+				return nil, err
+			}
+		case 3:
+			if compressed, err = m.bytes(); err != nil {
+				return nil, err
+			}
+		case 4, 5, 6, 7:
+			return nil, fmt.Errorf("unsupported blob compression (field %d), only zlib is supported", field)
+		default:
+			if err := m.skip(wireType); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if raw != nil {
+		return raw, nil
+	}
+	if compressed == nil {
+		return nil, errors.New("blob has no data")
+	}
+	if rawSize > maxBlobSize {
+		return nil, fmt.Errorf("uncompressed blob of %d bytes exceeds the limit", rawSize)
+	}
+
+	reader, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	buf := bytes.NewBuffer(make([]byte, 0, rawSize))
+	if _, err := io.Copy(buf, io.LimitReader(reader, maxBlobSize+1)); err != nil {
+		return nil, err
+	}
+	if buf.Len() > maxBlobSize {
+		return nil, errors.New("uncompressed blob exceeds the limit")
+	}
+	return buf.Bytes(), nil
+}
+
+// checkHeader rejects files that need features this decoder lacks
+func checkHeader(blob []byte) error {
+	data, err := decodeBlob(blob)
+	if err != nil {
+		return fmt.Errorf("invalid PBF header: %w", err)
+	}
+
+	m := protoMessage{data: data}
+	for {
+		field, wireType, ok, err := m.next()
+		if err != nil {
+			return fmt.Errorf("invalid PBF header: %w", err)
+		}
+		if !ok {
+			return nil
+		}
+		if field != 4 || wireType != wireBytes {
+			if err := m.skip(wireType); err != nil {
+				return fmt.Errorf("invalid PBF header: %w", err)
+			}
+			continue
+		}
+		feature, err := m.bytes()
+		if err != nil {
+			return fmt.Errorf("invalid PBF header: %w", err)
+		}
+		if !supportedFeatures[string(feature)] {
+			return fmt.Errorf("unsupported PBF feature %q", feature)
+		}
+	}
+}
+
+// primitiveBlock is a decoded PrimitiveBlock with its groups left encoded
+type primitiveBlock struct {
+	strings     []string
+	granularity int64
+	latOffset   int64
+	lonOffset   int64
+	groups      [][]byte
+}
+
+func parsePrimitiveBlock(data []byte) (*primitiveBlock, error) {
+	block := &primitiveBlock{granularity: 100}
+
+	m := protoMessage{data: data}
+	for {
+		field, wireType, ok, err := m.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return block, nil
+		}
+
+		switch {
+		case field == 1 && wireType == wireBytes:
+			table, err := m.bytes()
+			if err != nil {
+				return nil, err
+			}
+			if block.strings, err = parseStringTable(table); err != nil {
+				return nil, err
+			}
+		case field == 2 && wireType == wireBytes:
+			group, err := m.bytes()
+			if err != nil {
+				return nil, err
+			}
+			block.groups = append(block.groups, group)
+		case field == 17 && wireType == wireVarint:
+			value, err := m.varint()
+			if err != nil {
+				return nil, err
+			}
+			block.granularity = int64(value)
+		case field == 19 && wireType == wireVarint:
+			value, err := m.varint()
+			if err != nil {
+				return nil, err
+			}
+			block.latOffset = int64(value)
+		case field == 20 && wireType == wireVarint:
+			value, err := m.varint()
+			if err != nil {
+				return nil, err
+			}
+			block.lonOffset = int64(value)
+		default:
+			if err := m.skip(wireType); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func parseStringTable(data []byte) ([]string, error) {
+	var table []string
+	m := protoMessage{data: data}
+	for {
+		field, wireType, ok, err := m.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return table, nil
+		}
+		if field != 1 || wireType != wireBytes {
+			if err := m.skip(wireType); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		value, err := m.bytes()
+		if err != nil {
+			return nil, err
+		}
+		table = append(table, string(value))
+	}
+}
+
+// elementHandlers receive the decoded elements of a block. Element types
+// without a handler are skipped without being decoded.
+type elementHandlers struct {
+	node func(Node)
+	// nodeTags decodes node tags, which only the first pass needs
+	nodeTags bool
+	way      func(Way)
+	relation func(Relation)
+}
+
+// decode passes the block's elements to the handlers and reports which
+// element types the block contains
+func (b *primitiveBlock) decode(h elementHandlers) (elementKinds, error) {
+	var kinds elementKinds
+
+	for _, group := range b.groups {
+		m := protoMessage{data: group}
+		for {
+			field, wireType, ok, err := m.next()
+			if err != nil {
+				return kinds, err
+			}
+			if !ok {
+				break
+			}
+			if wireType != wireBytes || field < 1 || field > 4 {
+				if err := m.skip(wireType); err != nil {
+					return kinds, err
+				}
+				continue
+			}
+
+			value, err := m.bytes()
+			if err != nil {
+				return kinds, err
+			}
+			switch field {
+			case 1:
+				kinds |= hasNodes
+				if h.node != nil {
+					node, err := b.node(value, h.nodeTags)
+					if err != nil {
+						return kinds, fmt.Errorf("invalid node: %w", err)
+					}
+					h.node(node)
+				}
+			case 2:
+				kinds |= hasNodes
+				if h.node != nil {
+					if err := b.denseNodes(value, h.nodeTags, h.node); err != nil {
+						return kinds, fmt.Errorf("invalid dense nodes: %w", err)
+					}
+				}
+			case 3:
+				kinds |= hasWays
+				if h.way != nil {
+					way, err := b.way(value)
+					if err != nil {
+						return kinds, fmt.Errorf("invalid way: %w", err)
+					}
+					h.way(way)
+				}
+			case 4:
+				kinds |= hasRelations
+				if h.relation != nil {
+					relation, err := b.relation(value)
+					if err != nil {
+						return kinds, fmt.Errorf("invalid relation: %w", err)
+					}
+					h.relation(relation)
+				}
+			}
+		}
+	}
+
+	return kinds, nil
+}
+
+func (b *primitiveBlock) node(data []byte, withTags bool) (Node, error) {
+	var node Node
+	var keys, values []uint64
+	var lat, lon int64
+
+	m := protoMessage{data: data}
+	for {
+		field, wireType, ok, err := m.next()
+		if err != nil {
+			return node, err
+		}
+		if !ok {
+			break
+		}
+		switch field {
+		case 1:
+			node.ID, err = m.sint()
+		case 2:
+			keys, err = m.varints(wireType, keys)
+		case 3:
+			values, err = m.varints(wireType, values)
+		case 8:
+			lat, err = m.sint()
+		case 9:
+			lon, err = m.sint()
+		default:
+			err = m.skip(wireType)
+		}
+		if err != nil {
+			return node, err
+		}
+	}
+
+	node.Lat, node.Lon = b.location(lat, lon)
+	if withTags {
+		tags, err := b.tags(keys, values)
+		if err != nil {
+			return node, err
+		}
+		node.Tags = tags
+	}
+	return node, nil
+}
+
+// denseNodes decodes delta-encoded DenseNodes, the form most writers use
+func (b *primitiveBlock) denseNodes(data []byte, withTags bool, handle func(Node)) error {
+	var ids, lats, lons []int64
+	var keysValues []uint64
+
+	m := protoMessage{data: data}
+	for {
+		field, wireType, ok, err := m.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		switch field {
+		case 1:
+			ids, err = m.sints(wireType, ids)
+		case 8:
+			lats, err = m.sints(wireType, lats)
+		case 9:
+			lons, err = m.sints(wireType, lons)
+		case 10:
+			if withTags {
+				keysValues, err = m.varints(wireType, keysValues)
+			} else {
+				err = m.skip(wireType)
+			}
+		default:
+			err = m.skip(wireType)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(lats) != len(ids) || len(lons) != len(ids) {
+		return errors.New("mismatched id, lat and lon counts")
+	}
+
+	// keys_vals lists each node's key and value string indexes, ending with a 0.
+	// It is empty when no node in the block has tags.
+	var id, lat, lon int64
+	next := 0
+	for i := range ids {
+		id += ids[i]
+		lat += lats[i]
+		lon += lons[i]
+
+		node := Node{ID: id}
+		node.Lat, node.Lon = b.location(lat, lon)
+
+		if next < len(keysValues) {
+			var keys, values []uint64
+			for next < len(keysValues) && keysValues[next] != 0 {
+				if next+1 >= len(keysValues) {
+					return errors.New("truncated keys_vals")
+				}
+				keys = append(keys, keysValues[next])
+				values = append(values, keysValues[next+1])
+				next += 2
+			}
+			next++ // the 0 ending this node's tags
+
+			tags, err := b.tags(keys, values)
+			if err != nil {
+				return err
+			}
+			node.Tags = tags
+		}
+
+		handle(node)
+	}
+	return nil
+}
+
+func (b *primitiveBlock) way(data []byte) (Way, error) {
+	var way Way
+	var keys, values []uint64
+	var refs []int64
+
+	m := protoMessage{data: data}
+	for {
+		field, wireType, ok, err := m.next()
+		if err != nil {
+			return way, err
+		}
+		if !ok {
+			break
+		}
+		switch field {
+		case 1:
+			var id uint64
+			id, err = m.varint()
+			way.ID = int64(id)
+		case 2:
+			keys, err = m.varints(wireType, keys)
+		case 3:
+			values, err = m.varints(wireType, values)
+		case 8:
+			refs, err = m.sints(wireType, refs)
+		default:
+			err = m.skip(wireType)
+		}
+		if err != nil {
+			return way, err
+		}
+	}
+
+	way.Nodes = make([]int64, len(refs))
+	var ref int64
+	for i, delta := range refs {
+		ref += delta
+		way.Nodes[i] = ref
+	}
+
+	tags, err := b.tags(keys, values)
+	way.Tags = tags
+	return way, err
+}
+
+func (b *primitiveBlock) relation(data []byte) (Relation, error) {
+	var relation Relation
+	var keys, values, roles, types []uint64
+	var memberIDs []int64
+
+	m := protoMessage{data: data}
+	for {
+		field, wireType, ok, err := m.next()
+		if err != nil {
+			return relation, err
+		}
+		if !ok {
+			break
+		}
+		switch field {
+		case 1:
+			var id uint64
+			id, err = m.varint()
+			relation.ID = int64(id)
+		case 2:
+			keys, err = m.varints(wireType, keys)
+		case 3:
+			values, err = m.varints(wireType, values)
+		case 8:
+			roles, err = m.varints(wireType, roles)
+		case 9:
+			memberIDs, err = m.sints(wireType, memberIDs)
+		case 10:
+			types, err = m.varints(wireType, types)
+		default:
+			err = m.skip(wireType)
+		}
+		if err != nil {
+			return relation, err
+		}
+	}
+
+	if len(roles) != len(memberIDs) || len(types) != len(memberIDs) {
+		return relation, errors.New("mismatched member counts")
+	}
+
+	relation.Members = make([]Member, len(memberIDs))
+	var ref int64
+	for i := range memberIDs {
+		ref += memberIDs[i]
+		role, err := b.string(roles[i])
+		if err != nil {
+			return relation, err
+		}
+		var memberType ElementType
+		switch types[i] {
+		case 0:
+			memberType = NodeType
+		case 1:
+			memberType = WayType
+		case 2:
+			memberType = RelationType
+		default:
+			return relation, fmt.Errorf("unknown member type %d", types[i])
+		}
+		relation.Members[i] = Member{Type: memberType, Ref: ref, Role: role}
+	}
+
+	tags, err := b.tags(keys, values)
+	relation.Tags = tags
+	return relation, err
+}
+
+// location converts encoded coordinates to degrees
+func (b *primitiveBlock) location(lat, lon int64) (float64, float64) {
+	return float64(b.latOffset+b.granularity*lat) * 1e-9, float64(b.lonOffset+b.granularity*lon) * 1e-9
+}
+
+func (b *primitiveBlock) tags(keys, values []uint64) (map[string]string, error) {
+	if len(keys) != len(values) {
+		return nil, errors.New("mismatched tag key and value counts")
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	tags := make(map[string]string, len(keys))
+	for i := range keys {
+		key, err := b.string(keys[i])
+		if err != nil {
+			return nil, err
+		}
+		value, err := b.string(values[i])
+		if err != nil {
+			return nil, err
+		}
+		tags[key] = value
+	}
+	return tags, nil
+}
+
+func (b *primitiveBlock) string(index uint64) (string, error) {
+	if index >= uint64(len(b.strings)) {
+		return "", fmt.Errorf("string index %d out of range", index)
+	}
+	return b.strings[index], nil
+}
@@ -0,0 +1,218 @@
+package osm
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// protoBuilder encodes protocol buffer messages for test files
+type protoBuilder struct {
+	buf []byte
+}
+
+func (b *protoBuilder) varint(field int, value uint64) *protoBuilder {
+	b.buf = binary.AppendUvarint(b.buf, uint64(field)<<3|wireVarint)
+	b.buf = binary.AppendUvarint(b.buf, value)
+	return b
+}
+
+func (b *protoBuilder) bytes(field int, value []byte) *protoBuilder {
+	b.buf = binary.AppendUvarint(b.buf, uint64(field)<<3|wireBytes)
+	b.buf = binary.AppendUvarint(b.buf, uint64(len(value)))
+	b.buf = append(b.buf, value...)
+	return b
+}
+
+func (b *protoBuilder) packed(field int, values ...uint64) *protoBuilder {
+	var packed []byte
+	for _, value := range values {
+		packed = binary.AppendUvarint(packed, value)
+	}
+	return b.bytes(field, packed)
+}
+
+// packedDelta encodes values as a packed, delta-coded sint64 field
+func (b *protoBuilder) packedDelta(field int, values ...int64) *protoBuilder {
+	encoded := make([]uint64, len(values))
+	var previous int64
+	for i, value := range values {
+		delta := value - previous
+		encoded[i] = uint64(delta<<1) ^ uint64(delta>>63)
+		previous = value
+	}
+	return b.packed(field, encoded...)
+}
+
+func appendBlob(t *testing.T, file []byte, blobType string, payload []byte, compress bool) []byte {
+	t.Helper()
+
+	blob := &protoBuilder{}
+	if compress {
+		var compressed bytes.Buffer
+		writer := zlib.NewWriter(&compressed)
+		if _, err := writer.Write(payload); err != nil {
+			t.Fatal(err)
+		}
+		writer.Close()
+		blob.varint(2, uint64(len(payload))).bytes(3, compressed.Bytes())
+	} else {
+		blob.bytes(1, payload)
+	}
+
+	header := (&protoBuilder{}).bytes(1, []byte(blobType)).varint(3, uint64(len(blob.buf)))
+	file = binary.BigEndian.AppendUint32(file, uint32(len(header.buf)))
+	file = append(file, header.buf...)
+	return append(file, blob.buf...)
+}
+
+func stringTable(values ...string) []byte {
+	table := &protoBuilder{}
+	for _, value := range values {
+		table.bytes(1, []byte(value))
+	}
+	return table.buf
+}
+
+// createTestPBF writes the same railway as testOSM plus a route_master whose
+// route does not match the filter, split over a node, a way and a relation block
+func createTestPBF(t *testing.T, required ...string) string {
+	t.Helper()
+
+	headerBlock := &protoBuilder{}
+	for _, feature := range append([]string{"OsmSchema-V0.6", "DenseNodes"}, required...) {
+		headerBlock.bytes(4, []byte(feature))
+	}
+	file := appendBlob(t, nil, "OSMHeader", headerBlock.buf, true)
+
+	// Strings: 0 is always empty
+	strings := stringTable("", "railway", "station", "name", "Teststadt", "rail", "type", "multipolygon", "landuse", "outer", "inner", "route", "train", "route_master")
+
+	// Nodes 1-7 as in testOSM at a granularity of 100 nanodegrees, node 8 tagged
+	dense := (&protoBuilder{}).
+		packedDelta(1, 1, 2, 3, 4, 5, 6, 7, 8).
+		packedDelta(8, 520000000, 520000000, 521000000, 521000000, 520400000, 520400000, 520600000, 525000000).
+		packedDelta(9, 130000000, 131000000, 131000000, 130000000, 130400000, 130600000, 130500000, 135000000).
+		packed(10, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4, 0)
+	nodeBlock := (&protoBuilder{}).
+		bytes(1, strings).
+		bytes(2, (&protoBuilder{}).bytes(2, dense.buf).buf)
+	file = appendBlob(t, file, "OSMData", nodeBlock.buf, true)
+
+	// An unknown blob type between the data blocks must be skipped
+	file = appendBlob(t, file, "OSMIndex", []byte("ignored"), false)
+
+	ways := (&protoBuilder{}).
+		bytes(3, (&protoBuilder{}).varint(1, 10).packed(2, 1).packed(3, 5).packedDelta(8, 1, 2, 3).buf).
+		bytes(3, (&protoBuilder{}).varint(1, 11).packedDelta(8, 1, 4, 3).buf).
+		bytes(3, (&protoBuilder{}).varint(1, 12).packedDelta(8, 5, 6, 7, 5).buf)
+	wayBlock := (&protoBuilder{}).bytes(1, strings).bytes(2, ways.buf)
+	file = appendBlob(t, file, "OSMData", wayBlock.buf, false)
+
+	relations := (&protoBuilder{}).
+		bytes(4, (&protoBuilder{}).varint(1, 20).
+			packed(2, 6, 8).packed(3, 7, 1).
+			packed(8, 9, 9, 10).packedDelta(9, 10, 11, 12).packed(10, 1, 1, 1).buf).
+		bytes(4, (&protoBuilder{}).varint(1, 21).
+			packed(2, 6, 11).packed(3, 11, 12).
+			packed(8, 0, 0).packedDelta(9, 10, 8).packed(10, 1, 0).buf).
+		bytes(4, (&protoBuilder{}).varint(1, 22).
+			packed(2, 6, 13).packed(3, 13, 12).
+			packed(8, 0).packedDelta(9, 21).packed(10, 2).buf)
+	relationBlock := (&protoBuilder{}).bytes(1, strings).bytes(2, relations.buf)
+	file = appendBlob(t, file, "OSMData", relationBlock.buf, true)
+
+	filePath := filepath.Join(t.TempDir(), "test.osm.pbf")
+	if err := os.WriteFile(filePath, file, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+func TestReadPBFFile(t *testing.T) {
+	filePath := createTestPBF(t)
+
+	data, err := ReadPBFFile(filePath, nil)
+	if err != nil {
+		t.Fatalf("ReadPBFFile returned error: %v", err)
+	}
+
+	station, ok := data.Node(8)
+	if !ok || station.Tags["name"] != "Teststadt" {
+		t.Fatalf("Expected tagged node 8, got %+v", station)
+	}
+	if station.Lat != 52.5 || station.Lon != 13.5 {
+		t.Errorf("Expected node 8 at 52.5, 13.5, got %v, %v", station.Lat, station.Lon)
+	}
+
+	// Untagged ways are only loaded as members of the multipolygon
+	if len(data.Ways) != 3 || len(data.Relations) != 3 {
+		t.Fatalf("Expected 3 ways and 3 relations, got %d and %d", len(data.Ways), len(data.Relations))
+	}
+
+	relation, _ := data.Relation(20)
+	polygons := data.Multipolygons(relation)
+	if len(polygons) != 1 || len(polygons[0].Outer) != 5 || len(polygons[0].Inner) != 1 {
+		t.Errorf("Expected a polygon with a hole, got %+v", polygons)
+	}
+
+	for _, id := range []int64{1, 2, 3, 4, 5, 6, 7} {
+		if _, ok := data.Node(id); !ok {
+			t.Errorf("Expected way vertex %d to be resolved", id)
+		}
+	}
+}
+
+func TestReadPBFFile_Filter(t *testing.T) {
+	filePath := createTestPBF(t)
+
+	filter, err := ParseFilter("route_master=train")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ReadPBFFile(filePath, filter)
+	if err != nil {
+		t.Fatalf("ReadPBFFile returned error: %v", err)
+	}
+
+	// The route_master pulls in its route, the route its way and station, and
+	// the way its vertices
+	if len(data.Relations) != 2 {
+		t.Errorf("Expected the route_master and its route, got %d relations", len(data.Relations))
+	}
+	if len(data.Ways) != 1 || data.Ways[0].ID != 10 {
+		t.Errorf("Expected only way 10, got %+v", data.Ways)
+	}
+	if len(data.Nodes) != 4 {
+		t.Errorf("Expected the 3 vertices of way 10 and the station, got %d nodes", len(data.Nodes))
+	}
+	if node, ok := data.Node(8); !ok || node.Lat != 52.5 {
+		t.Errorf("Expected the station member to be resolved, got %+v", node)
+	}
+}
+
+func TestReadPBFFile_Unsupported(t *testing.T) {
+	filePath := createTestPBF(t, "HistoricalInformation")
+
+	if _, err := ReadPBFFile(filePath, nil); err == nil {
+		t.Error("Expected error for a history file")
+	}
+}
+
+func TestReadPBFFile_Truncated(t *testing.T) {
+	data, err := os.ReadFile(createTestPBF(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filePath := filepath.Join(t.TempDir(), "truncated.osm.pbf")
+	if err := os.WriteFile(filePath, data[:len(data)-10], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadPBFFile(filePath, nil); err == nil {
+		t.Error("Expected error for a truncated file")
+	}
+}
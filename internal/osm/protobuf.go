@@ -0,0 +1,126 @@
+package osm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Protocol buffer wire types used by the OSM PBF format
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("truncated protobuf message")
+
+// protoMessage walks the fields of an encoded protocol buffer message. The
+// PBF format only needs a handful of messages, so they are decoded by hand
+// rather than through generated code.
+type protoMessage struct {
+	data []byte
+	pos  int
+}
+
+// next reads the key of the next field. It returns false at the end of the message.
+func (m *protoMessage) next() (field int, wireType int, ok bool, err error) {
+	if m.pos >= len(m.data) {
+		return 0, 0, false, nil
+	}
+	key, err := m.varint()
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return int(key >> 3), int(key & 7), true, nil
+}
+
+func (m *protoMessage) varint() (uint64, error) {
+	value, n := binary.Uvarint(m.data[m.pos:])
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	m.pos += n
+	return value, nil
+}
+
+func (m *protoMessage) sint() (int64, error) {
+	value, err := m.varint()
+	return zigzag(value), err
+}
+
+func (m *protoMessage) bytes() ([]byte, error) {
+	length, err := m.varint()
+	if err != nil {
+		return nil, err
+	}
+	if length > uint64(len(m.data)-m.pos) {
+		return nil, errTruncated
+	}
+	value := m.data[m.pos : m.pos+int(length)]
+	m.pos += int(length)
+	return value, nil
+}
+
+// skip steps over the value of a field that is not needed
+func (m *protoMessage) skip(wireType int) error {
+	var size int
+	switch wireType {
+	case wireVarint:
+		_, err := m.varint()
+		return err
+	case wireBytes:
+		_, err := m.bytes()
+		return err
+	case wireFixed64:
+		size = 8
+	case wireFixed32:
+		size = 4
+	default:
+		return fmt.Errorf("unsupported protobuf wire type %d", wireType)
+	}
+	if m.pos+size > len(m.data) {
+		return errTruncated
+	}
+	m.pos += size
+	return nil
+}
+
+// varints reads a repeated varint field, which writers may encode packed or as
+// one field per value, appending to values
+func (m *protoMessage) varints(wireType int, values []uint64) ([]uint64, error) {
+	if wireType == wireVarint {
+		value, err := m.varint()
+		return append(values, value), err
+	}
+	if wireType != wireBytes {
+		return values, fmt.Errorf("unexpected wire type %d for a repeated integer", wireType)
+	}
+	packed, err := m.bytes()
+	if err != nil {
+		return values, err
+	}
+	for len(packed) > 0 {
+		value, n := binary.Uvarint(packed)
+		if n <= 0 {
+			return values, errTruncated
+		}
+		values = append(values, value)
+		packed = packed[n:]
+	}
+	return values, nil
+}
+
+// sints reads a repeated sint64 field
+func (m *protoMessage) sints(wireType int, values []int64) ([]int64, error) {
+	raw, err := m.varints(wireType, nil)
+	for _, value := range raw {
+		values = append(values, zigzag(value))
+	}
+	return values, err
+}
+
+func zigzag(value uint64) int64 {
+	return int64(value>>1) ^ -int64(value&1)
+}
@@ -0,0 +1,152 @@
+package osm
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+type xmlTag struct {
+	Key   string `xml:"k,attr"`
+	Value string `xml:"v,attr"`
+}
+
+type xmlNode struct {
+	ID      int64    `xml:"id,attr"`
+	Lat     float64  `xml:"lat,attr"`
+	Lon     float64  `xml:"lon,attr"`
+	Action  string   `xml:"action,attr"`
+	Visible string   `xml:"visible,attr"`
+	Tags    []xmlTag `xml:"tag"`
+}
+
+type xmlWay struct {
+	ID      int64    `xml:"id,attr"`
+	Action  string   `xml:"action,attr"`
+	Visible string   `xml:"visible,attr"`
+	Nodes   []xmlRef `xml:"nd"`
+	Tags    []xmlTag `xml:"tag"`
+}
+
+type xmlRef struct {
+	Ref int64 `xml:"ref,attr"`
+}
+
+type xmlRelation struct {
+	ID      int64       `xml:"id,attr"`
+	Action  string      `xml:"action,attr"`
+	Visible string      `xml:"visible,attr"`
+	Members []xmlMember `xml:"member"`
+	Tags    []xmlTag    `xml:"tag"`
+}
+
+type xmlMember struct {
+	Type string `xml:"type,attr"`
+	Ref  int64  `xml:"ref,attr"`
+	Role string `xml:"role,attr"`
+}
+
+// ReadXMLFile reads an .osm XML file
+func ReadXMLFile(filePath string) (*Data, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadXML(file)
+}
+
+// ReadXML reads OSM XML as written by the OSM API, Overpass, osmium and JOSM.
+// Elements are decoded one at a time so that large extracts are not held in
+// memory twice. Elements that JOSM marks as deleted are skipped.
+func ReadXML(r io.Reader) (*Data, error) {
+	decoder := xml.NewDecoder(r)
+	data := &Data{}
+	root := false
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid OSM XML: %w", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch start.Name.Local {
+		case "osm":
+			root = true
+		case "node":
+			var node xmlNode
+			if err := decoder.DecodeElement(&node, &start); err != nil {
+				return nil, fmt.Errorf("invalid node: %w", err)
+			}
+			if isDeleted(node.Action, node.Visible) {
+				continue
+			}
+			data.Nodes = append(data.Nodes, Node{ID: node.ID, Lat: node.Lat, Lon: node.Lon, Tags: xmlTags(node.Tags)})
+		case "way":
+			var way xmlWay
+			if err := decoder.DecodeElement(&way, &start); err != nil {
+				return nil, fmt.Errorf("invalid way: %w", err)
+			}
+			if isDeleted(way.Action, way.Visible) {
+				continue
+			}
+			refs := make([]int64, len(way.Nodes))
+			for i, nd := range way.Nodes {
+				refs[i] = nd.Ref
+			}
+			data.Ways = append(data.Ways, Way{ID: way.ID, Nodes: refs, Tags: xmlTags(way.Tags)})
+		case "relation":
+			var relation xmlRelation
+			if err := decoder.DecodeElement(&relation, &start); err != nil {
+				return nil, fmt.Errorf("invalid relation: %w", err)
+			}
+			if isDeleted(relation.Action, relation.Visible) {
+				continue
+			}
+			members := make([]Member, len(relation.Members))
+			for i, member := range relation.Members {
+				members[i] = Member{Type: ElementType(member.Type), Ref: member.Ref, Role: member.Role}
+			}
+			data.Relations = append(data.Relations, Relation{ID: relation.ID, Members: members, Tags: xmlTags(relation.Tags)})
+		default:
+			if !root {
+				return nil, fmt.Errorf("invalid OSM XML: unexpected root element <%s>", start.Name.Local)
+			}
+			// Skip <bounds>, <note>, <meta> and anything else we don't use
+			if err := decoder.Skip(); err != nil {
+				return nil, fmt.Errorf("invalid OSM XML: %w", err)
+			}
+		}
+	}
+
+	if !root {
+		return nil, errors.New("invalid OSM XML: no <osm> element")
+	}
+	return data, nil
+}
+
+func isDeleted(action, visible string) bool {
+	return action == "delete" || visible == "false"
+}
+
+func xmlTags(tags []xmlTag) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		m[tag.Key] = tag.Value
+	}
+	return m
+}
@@ -2,8 +2,12 @@ package poi
 
 import (
 	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
 	"math"
 	"strconv"
+	"strings"
 
 	"github.com/supermanifolds/nimby_shapetopoi/internal/gis"
 )
@@ -51,6 +55,88 @@ func (p *List) ToTSV(w *csv.Writer) error {
 	return nil
 }
 
+// FromTSV reads POIs in the format written by ToTSV. Columns are matched by
+// the header, so they may come in any order; lon and lat are required and the
+// other fields keep their zero value when their column is missing.
+func FromTSV(r *csv.Reader) (List, error) {
+	r.Comma = '\t'
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["lon"]; !ok {
+		return nil, errors.New("no lon column")
+	}
+	if _, ok := columns["lat"]; !ok {
+		return nil, errors.New("no lat column")
+	}
+
+	list := make(List, 0)
+	for row := 2; ; row++ {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return list, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+
+		value := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		number := func(name string, bits int) (int64, error) {
+			if v := value(name); v != "" {
+				return strconv.ParseInt(v, 10, bits)
+			}
+			return 0, nil
+		}
+
+		var poi POI
+		if poi.Lon, err = strconv.ParseFloat(value("lon"), 64); err != nil {
+			return nil, fmt.Errorf("row %d: invalid lon: %w", row, err)
+		}
+		if poi.Lat, err = strconv.ParseFloat(value("lat"), 64); err != nil {
+			return nil, fmt.Errorf("row %d: invalid lat: %w", row, err)
+		}
+		poi.Color = value("color")
+		if i, ok := columns["text"]; ok && i < len(record) {
+			// Labels keep their spaces
+			poi.Text = record[i]
+		}
+		fontSize, err := number("font_size", 32)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: invalid font_size: %w", row, err)
+		}
+		poi.FontSize = int32(fontSize)
+		maxLod, err := number("max_lod", 32)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: invalid max_lod: %w", row, err)
+		}
+		poi.MaxLod = int32(maxLod)
+		if v := value("transparent"); v != "" {
+			if poi.Transparent, err = strconv.ParseBool(v); err != nil {
+				return nil, fmt.Errorf("row %d: invalid transparent: %w", row, err)
+			}
+		}
+		poi.Demand = value("demand")
+		if poi.Population, err = number("population", 64); err != nil {
+			return nil, fmt.Errorf("row %d: invalid population: %w", row, err)
+		}
+
+		list = append(list, poi)
+	}
+}
+
 // InterpolateByDistance adds intermediate points to the list if segments exceed maxDistance
 func (p *List) InterpolateByDistance(maxDistanceMeters float64) *List {
 	if len(*p) < 2 || maxDistanceMeters <= 0 {
@@ -103,3 +189,59 @@ func (p *List) InterpolateByDistance(maxDistanceMeters float64) *List {
 
 	return &interpolated
 }
+
+// ResampleByDistance walks the line and returns points exactly spacingMeters
+// apart along it, starting at the first point and ending at the last. Original
+// vertices in between are dropped unless keepVertices is set, in which case they
+// are kept alongside the samples without affecting their spacing.
+func (p *List) ResampleByDistance(spacingMeters float64, keepVertices bool) *List {
+	if len(*p) < 2 || spacingMeters <= 0 {
+		return p
+	}
+
+	// Samples closer than this to the end of a segment fall on its last vertex
+	const epsilon = 1e-6
+
+	resampled := make(List, 0, len(*p))
+	resampled = append(resampled, (*p)[0])
+
+	// Distance along the current segment at which the next sample is placed
+	offset := spacingMeters
+
+	for i := 0; i < len(*p)-1; i++ {
+		current := (*p)[i]
+		next := (*p)[i+1]
+		distance := gis.HaversineDistance(current.Lat, current.Lon, next.Lat, next.Lon)
+
+		for ; offset < distance-epsilon; offset += spacingMeters {
+			lat, lon := gis.InterpolatePoint(current.Lat, current.Lon, next.Lat, next.Lon, offset/distance)
+
+			sample := current
+			sample.Lat = lat
+			sample.Lon = lon
+			sample.Text = ""
+			resampled = append(resampled, sample)
+		}
+		offset -= distance
+
+		if i == len(*p)-2 {
+			break
+		}
+
+		// A sample landing on the vertex is replaced by the vertex itself
+		onVertex := offset < epsilon
+		if keepVertices || onVertex {
+			vertex := next
+			if !keepVertices {
+				vertex.Text = ""
+			}
+			resampled = append(resampled, vertex)
+		}
+		if onVertex {
+			offset += spacingMeters
+		}
+	}
+
+	resampled = append(resampled, (*p)[len(*p)-1])
+	return &resampled
+}
@@ -2,8 +2,11 @@ package poi
 
 import (
 	"encoding/csv"
+	"math"
 	"strings"
 	"testing"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/gis"
 )
 
 func TestPOI_Creation(t *testing.T) {
@@ -201,3 +204,116 @@ func TestList_ToTSV_SpecialCharacters(t *testing.T) {
 		t.Errorf("Expected special characters to be properly escaped in output:\n%s", result)
 	}
 }
+
+func TestList_ResampleByDistance(t *testing.T) {
+	// An L-shaped line north along the 10°E meridian then east, with legs of about 1001 m and 503 m
+	list := List{
+		{Lon: 10.0, Lat: 53.0, Text: "Start", FontSize: 12},
+		{Lon: 10.0, Lat: 53.009, FontSize: 12},
+		{Lon: 10.0075, Lat: 53.009, FontSize: 12},
+	}
+
+	resampled := *list.ResampleByDistance(100, false)
+
+	// Start, samples every 100 m over about 1504 m, and the end
+	if len(resampled) != 17 {
+		t.Fatalf("Expected 17 points, got %d", len(resampled))
+	}
+	if resampled[0].Text != "Start" {
+		t.Errorf("Expected the first point to keep its label, got '%s'", resampled[0].Text)
+	}
+	last := resampled[len(resampled)-1]
+	if last.Lon != 10.0075 || last.Lat != 53.009 {
+		t.Errorf("Expected the line to end at its last vertex, got (%f, %f)", last.Lon, last.Lat)
+	}
+
+	// The corner vertex is dropped and the samples either side of it are 100 m
+	// apart along the line, so less than 100 m apart in a straight line
+	for i := 1; i < len(resampled)-1; i++ {
+		if resampled[i].Lat == 53.009 && resampled[i].Lon == 10.0 {
+			t.Errorf("Expected the corner vertex to be dropped, found it at index %d", i)
+		}
+		if resampled[i].Text != "" || resampled[i].FontSize != 12 {
+			t.Errorf("Point %d: Expected an unlabelled sample with the line's font size, got %+v", i, resampled[i])
+		}
+	}
+	for i := 1; i < len(resampled)-1; i++ {
+		distance := gis.HaversineDistance(resampled[i-1].Lat, resampled[i-1].Lon, resampled[i].Lat, resampled[i].Lon)
+		straight := resampled[i-1].Lon == resampled[i].Lon || resampled[i-1].Lat == resampled[i].Lat
+		if straight && math.Abs(distance-100) > 0.01 {
+			t.Errorf("Points %d-%d: Expected 100 m spacing, got %.3f m", i-1, i, distance)
+		}
+	}
+
+	withVertices := *list.ResampleByDistance(100, true)
+	if len(withVertices) != len(resampled)+1 {
+		t.Errorf("Expected the corner vertex to be kept in addition to the samples, got %d points", len(withVertices))
+	}
+}
+
+func TestList_ResampleByDistance_Disabled(t *testing.T) {
+	list := List{{Lon: 10.0, Lat: 53.0}, {Lon: 10.0, Lat: 53.1}}
+
+	if resampled := list.ResampleByDistance(0, false); len(*resampled) != 2 {
+		t.Errorf("Expected a zero spacing to leave the line unchanged, got %d points", len(*resampled))
+	}
+
+	single := List{{Lon: 10.0, Lat: 53.0}}
+	if resampled := single.ResampleByDistance(100, false); len(*resampled) != 1 {
+		t.Errorf("Expected a single point to be returned as is, got %d points", len(*resampled))
+	}
+}
+
+func TestFromTSV_RoundTrip(t *testing.T) {
+	list := List{
+		{Lon: 10.123, Lat: 53.456, Color: "ff0000", Text: " Platform 1 ", FontSize: 12, MaxLod: 10, Population: 100},
+		{Lon: -0.5, Lat: 51.25, Color: "00ff00", Text: "Quoted \"name\"", Transparent: true, Demand: "5"},
+	}
+
+	var output strings.Builder
+	writer := csv.NewWriter(&output)
+	if err := list.ToTSV(writer); err != nil {
+		t.Fatalf("ToTSV returned error: %v", err)
+	}
+	writer.Flush()
+
+	parsed, err := FromTSV(csv.NewReader(strings.NewReader(output.String())))
+	if err != nil {
+		t.Fatalf("FromTSV returned error: %v", err)
+	}
+	if len(parsed) != len(list) {
+		t.Fatalf("Expected %d POIs, got %d", len(list), len(parsed))
+	}
+	for i := range list {
+		if parsed[i] != list[i] {
+			t.Errorf("POI %d: expected %+v, got %+v", i, list[i], parsed[i])
+		}
+	}
+}
+
+func TestFromTSV_ColumnOrder(t *testing.T) {
+	parsed, err := FromTSV(csv.NewReader(strings.NewReader("text\tlat\tlon\nDepot\t52.5\t13.4\n")))
+	if err != nil {
+		t.Fatalf("FromTSV returned error: %v", err)
+	}
+	expected := POI{Lon: 13.4, Lat: 52.5, Text: "Depot"}
+	if len(parsed) != 1 || parsed[0] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, parsed)
+	}
+}
+
+func TestFromTSV_Errors(t *testing.T) {
+	tests := map[string]string{
+		"missing lat column": "lon\ttext\n13.4\tDepot\n",
+		"invalid lon":        "lon\tlat\neast\t52.5\n",
+		"invalid max_lod":    "lon\tlat\tmax_lod\n13.4\t52.5\tfar\n",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := FromTSV(csv.NewReader(strings.NewReader(input))); err == nil {
+				t.Error("Expected error, got none")
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
+)
+
+// Pseudo-attributes that readers add next to a feature's own attributes
+const (
+	// FolderAttribute holds the KML folder path of a placemark, e.g. "Network/Stations".
+	// Patterns match either the whole path or any single folder name.
+	FolderAttribute = "@folder"
+	// FileAttribute holds the base name of the input file, e.g. "stations.shp"
+	FileAttribute = "@file"
+)
+
+const maxLod = 10
+
+// RuleSet is an ordered list of styling rules. Every matching rule is applied in
+// order, so later rules override fields set by earlier ones and a rule without
+// "match" styles every feature.
+type RuleSet struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule sets POI fields on features whose attributes match all of its patterns
+type Rule struct {
+	// Name is optional and only used in error messages
+	Name  string              `json:"name,omitempty"`
+	Match map[string]Patterns `json:"match,omitempty"`
+	Style Style               `json:"style"`
+}
+
+// Patterns is one or more alternatives for an attribute value. In JSON it is
+// either a string or an array of strings. Matching is case-insensitive and
+// supports glob wildcards; "*" requires a non-empty value and "" requires the
+// attribute to be missing or empty.
+type Patterns []string
+
+// Style lists the POI fields a rule sets. Fields left out of the JSON are not changed.
+type Style struct {
+	Color       *string `json:"color,omitempty"`
+	FontSize    *int32  `json:"font_size,omitempty"`
+	MaxLod      *int32  `json:"max_lod,omitempty"`
+	Transparent *bool   `json:"transparent,omitempty"`
+	Demand      *string `json:"demand,omitempty"`
+	Population  *int64  `json:"population,omitempty"`
+}
+
+func (p *Patterns) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*p = Patterns{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return fmt.Errorf("match values must be a string or an array of strings")
+	}
+	*p = multiple
+	return nil
+}
+
+// Load reads a rules file
+func Load(filePath string) (*RuleSet, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	return rs, nil
+}
+
+// Parse decodes and validates a rules file. Unknown keys are rejected so that
+// typos such as "fontsize" are reported rather than silently ignored.
+func Parse(data []byte) (*RuleSet, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var rs RuleSet
+	if err := decoder.Decode(&rs); err != nil {
+		return nil, fmt.Errorf("invalid rules: %w", err)
+	}
+
+	for i := range rs.Rules {
+		if err := rs.Rules[i].validate(); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rs.Rules[i].describe(i), err)
+		}
+	}
+
+	return &rs, nil
+}
+
+func (r *Rule) describe(index int) string {
+	if r.Name != "" {
+		return strconv.Quote(r.Name)
+	}
+	return strconv.Itoa(index + 1)
+}
+
+func (r *Rule) validate() error {
+	for attribute, patterns := range r.Match {
+		for _, pattern := range patterns {
+			if _, err := path.Match(strings.ToLower(pattern), ""); err != nil {
+				return fmt.Errorf("invalid pattern %q for %s", pattern, attribute)
+			}
+		}
+	}
+
+	return r.Style.Validate()
+}
+
+// Validate checks the style's values and normalizes its color to rrggbb
+func (s *Style) Validate() error {
+	if s.Color != nil {
+		color, ok := normalizeColor(*s.Color)
+		if !ok {
+			return fmt.Errorf("invalid color %q, expected #rrggbb", *s.Color)
+		}
+		s.Color = &color
+	}
+	if s.FontSize != nil && *s.FontSize <= 0 {
+		return fmt.Errorf("font_size must be positive")
+	}
+	if s.MaxLod != nil && (*s.MaxLod < 0 || *s.MaxLod > maxLod) {
+		return fmt.Errorf("max_lod must be between 0 and %d", maxLod)
+	}
+	if s.Population != nil && *s.Population < 0 {
+		return fmt.Errorf("population must not be negative")
+	}
+	return nil
+}
+
+// normalizeColor converts #rrggbb to the rrggbb form used by NIMBY Rails
+func normalizeColor(color string) (string, bool) {
+	color = strings.TrimPrefix(strings.TrimSpace(color), "#")
+	if len(color) != 6 {
+		return "", false
+	}
+	if _, err := strconv.ParseUint(color, 16, 32); err != nil {
+		return "", false
+	}
+	return strings.ToLower(color), true
+}
+
+// Apply sets the fields of every rule matching the attributes on the POI
+func (rs *RuleSet) Apply(p *poi.POI, attributes map[string]string) {
+	if rs == nil {
+		return
+	}
+	for i := range rs.Rules {
+		if rs.Rules[i].Matches(attributes) {
+			rs.Rules[i].Style.Apply(p)
+		}
+	}
+}
+
+// Matches reports whether the attributes satisfy every pattern of the rule.
+// Attribute names are compared case-insensitively.
+func (r *Rule) Matches(attributes map[string]string) bool {
+	for attribute, patterns := range r.Match {
+		value := LookupAttribute(attributes, attribute)
+		if !patterns.matches(attribute, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p Patterns) matches(attribute, value string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for _, pattern := range p {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+
+		switch pattern {
+		case "":
+			if value == "" {
+				return true
+			}
+			continue
+		case "*":
+			if value != "" {
+				return true
+			}
+			continue
+		}
+
+		if globMatch(pattern, value) {
+			return true
+		}
+		// Folder patterns may name any folder along the path
+		if attribute == FolderAttribute {
+			for _, folder := range strings.Split(value, "/") {
+				if globMatch(pattern, folder) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func globMatch(pattern, value string) bool {
+	matched, err := path.Match(pattern, value)
+	return err == nil && matched
+}
+
+// LookupAttribute returns the trimmed value of the named attribute, ignoring
+// case. An exact-case key with a blank value does not hide a differently
+// cased key that has one. When several keys differ only in case, such as
+// "Name" and "NAME", the lexically smallest one with a value wins, so that
+// the result does not depend on map order.
+func LookupAttribute(attributes map[string]string, name string) string {
+	if value := strings.TrimSpace(attributes[name]); value != "" {
+		return value
+	}
+	var match, result string
+	for key, value := range attributes {
+		if value = strings.TrimSpace(value); value != "" && strings.EqualFold(key, name) && (result == "" || key < match) {
+			match, result = key, value
+		}
+	}
+	return result
+}
+
+// Apply sets the fields of the style on the POI
+func (s *Style) Apply(p *poi.POI) {
+	if s.Color != nil {
+		p.Color = *s.Color
+	}
+	if s.FontSize != nil {
+		p.FontSize = *s.FontSize
+	}
+	if s.MaxLod != nil {
+		p.MaxLod = *s.MaxLod
+	}
+	if s.Transparent != nil {
+		p.Transparent = *s.Transparent
+	}
+	if s.Demand != nil {
+		p.Demand = *s.Demand
+	}
+	if s.Population != nil {
+		p.Population = *s.Population
+	}
+}
@@ -0,0 +1,200 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
+)
+
+const railwayRules = `{
+	"rules": [
+		{"style": {"font_size": 10}},
+		{"match": {"railway": "station"}, "style": {"color": "#FF0000", "font_size": 16, "max_lod": 8}},
+		{"match": {"railway": ["platform", "platform_edge"]}, "style": {"color": "#808080", "font_size": 8}},
+		{"match": {"railway": "station", "usage": "tourism"}, "style": {"transparent": true, "demand": "tourist", "population": 500}},
+		{"match": {"@folder": "Depot*"}, "style": {"max_lod": 2}}
+	]
+}`
+
+func TestParse(t *testing.T) {
+	rs, err := Parse([]byte(railwayRules))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if len(rs.Rules) != 5 {
+		t.Fatalf("Expected 5 rules, got %d", len(rs.Rules))
+	}
+	if got := *rs.Rules[1].Style.Color; got != "ff0000" {
+		t.Errorf("Expected color to be normalized to 'ff0000', got '%s'", got)
+	}
+	if got := rs.Rules[2].Match["railway"]; len(got) != 2 {
+		t.Errorf("Expected 2 alternatives for railway, got %v", got)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		message string
+	}{
+		{name: "invalid JSON", data: `{"rules": [`, message: "invalid rules"},
+		{name: "unknown field", data: `{"rules": [{"style": {"fontsize": 12}}]}`, message: "fontsize"},
+		{name: "invalid color", data: `{"rules": [{"style": {"color": "red"}}]}`, message: "invalid color"},
+		{name: "max lod out of range", data: `{"rules": [{"style": {"max_lod": 11}}]}`, message: "max_lod"},
+		{name: "zero font size", data: `{"rules": [{"style": {"font_size": 0}}]}`, message: "font_size"},
+		{name: "negative population", data: `{"rules": [{"style": {"population": -1}}]}`, message: "population"},
+		{name: "bad match value", data: `{"rules": [{"match": {"railway": 1}, "style": {}}]}`, message: "string or an array"},
+		{name: "bad pattern", data: `{"rules": [{"match": {"railway": "[station"}, "style": {}}]}`, message: "invalid pattern"},
+		{name: "named rule", data: `{"rules": [{"name": "stations", "style": {"color": "#12"}}]}`, message: `rule "stations"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected error containing %q, got %q", tt.message, err.Error())
+			}
+		})
+	}
+}
+
+func TestRuleSet_Apply(t *testing.T) {
+	rs, err := Parse([]byte(railwayRules))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		attributes map[string]string
+		expected   poi.POI
+	}{
+		{
+			name:       "station",
+			attributes: map[string]string{"railway": "station"},
+			expected:   poi.POI{Color: "ff0000", FontSize: 16, MaxLod: 8},
+		},
+		{
+			name:       "attribute names and values are case-insensitive",
+			attributes: map[string]string{"RAILWAY": "Station"},
+			expected:   poi.POI{Color: "ff0000", FontSize: 16, MaxLod: 8},
+		},
+		{
+			name:       "later rules override earlier ones",
+			attributes: map[string]string{"railway": "station", "usage": "tourism"},
+			expected:   poi.POI{Color: "ff0000", FontSize: 16, MaxLod: 8, Transparent: true, Demand: "tourist", Population: 500},
+		},
+		{
+			name:       "alternatives",
+			attributes: map[string]string{"railway": "platform_edge"},
+			expected:   poi.POI{Color: "808080", FontSize: 8, MaxLod: 10},
+		},
+		{
+			name:       "folder name anywhere in the path",
+			attributes: map[string]string{FolderAttribute: "Network/Depots"},
+			expected:   poi.POI{Color: "0000ff", FontSize: 10, MaxLod: 2},
+		},
+		{
+			name:       "only the catch-all rule",
+			attributes: map[string]string{"railway": "signal"},
+			expected:   poi.POI{Color: "0000ff", FontSize: 10, MaxLod: 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := poi.POI{Color: "0000ff", FontSize: 12, MaxLod: 10}
+			rs.Apply(&p, tt.attributes)
+			if p != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, p)
+			}
+		})
+	}
+}
+
+func TestRuleSet_Apply_Nil(t *testing.T) {
+	var rs *RuleSet
+	p := poi.POI{Color: "0000ff"}
+	rs.Apply(&p, map[string]string{"railway": "station"})
+	if p.Color != "0000ff" {
+		t.Errorf("Expected a nil rule set to leave the POI unchanged, got %+v", p)
+	}
+}
+
+func TestRule_Matches(t *testing.T) {
+	tests := []struct {
+		name       string
+		match      map[string]Patterns
+		attributes map[string]string
+		expected   bool
+	}{
+		{name: "no patterns", match: nil, attributes: nil, expected: true},
+		{name: "glob", match: map[string]Patterns{"name": {"Berlin *"}}, attributes: map[string]string{"name": "Berlin Hbf"}, expected: true},
+		{name: "glob mismatch", match: map[string]Patterns{"name": {"Berlin *"}}, attributes: map[string]string{"name": "Hamburg Hbf"}, expected: false},
+		{name: "any value", match: map[string]Patterns{"ref": {"*"}}, attributes: map[string]string{"ref": "S1"}, expected: true},
+		{name: "any value requires a value", match: map[string]Patterns{"ref": {"*"}}, attributes: map[string]string{"ref": " "}, expected: false},
+		{name: "missing", match: map[string]Patterns{"ref": {""}}, attributes: map[string]string{}, expected: true},
+		{name: "missing but present", match: map[string]Patterns{"ref": {""}}, attributes: map[string]string{"ref": "S1"}, expected: false},
+		{name: "all patterns must match", match: map[string]Patterns{"a": {"1"}, "b": {"2"}}, attributes: map[string]string{"a": "1", "b": "3"}, expected: false},
+		{name: "full folder path", match: map[string]Patterns{FolderAttribute: {"network/*"}}, attributes: map[string]string{FolderAttribute: "Network/Depots"}, expected: true},
+		{name: "case-insensitive name", match: map[string]Patterns{"Railway": {"station"}}, attributes: map[string]string{"RAILWAY": "Station"}, expected: true},
+		{name: "blank exact-case key", match: map[string]Patterns{"name": {"berlin *"}}, attributes: map[string]string{"name": " ", "NAME": "Berlin Hbf"}, expected: true},
+		{name: "top-level placemark", match: map[string]Patterns{FolderAttribute: {""}}, attributes: map[string]string{FolderAttribute: ""}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := Rule{Match: tt.match}
+			if got := rule.Matches(tt.attributes); got != tt.expected {
+				t.Errorf("Matches() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLookupAttribute(t *testing.T) {
+	attributes := map[string]string{"NAME": "Upper", "Name": "Title", "nAME": "Mixed", "ref": " ", "REF": "S1"}
+
+	// Repeated lookups see the map in different orders
+	for range 20 {
+		if got := LookupAttribute(attributes, "name"); got != "Upper" {
+			t.Fatalf("Expected the lexically smallest key to win, got %q", got)
+		}
+	}
+	if got := LookupAttribute(attributes, "Name"); got != "Title" {
+		t.Errorf("Expected the exact key to win, got %q", got)
+	}
+	if got := LookupAttribute(attributes, "ref"); got != "S1" {
+		t.Errorf("Expected a blank exact key to fall back, got %q", got)
+	}
+	if got := LookupAttribute(attributes, "missing"); got != "" {
+		t.Errorf("Expected no value for a missing key, got %q", got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(filePath, []byte(railwayRules), 0644); err != nil {
+		t.Fatalf("Failed to write rules file: %v", err)
+	}
+
+	rs, err := Load(filePath)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if len(rs.Rules) != 5 {
+		t.Errorf("Expected 5 rules, got %d", len(rs.Rules))
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
@@ -11,6 +11,11 @@ import (
 	"time"
 )
 
+var downloadContentTypes = map[string]string{
+	".zip": "application/zip",
+	".kmz": "application/vnd.google-earth.kmz",
+}
+
 type DownloadHandler struct {
 	logger *slog.Logger
 }
@@ -28,8 +33,9 @@ func (h *DownloadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Security: only allow downloading zip files from temp directory
-	if !strings.HasSuffix(filename, ".zip") || strings.Contains(filename, "..") {
+	// Security: only allow downloading mod zips and their KMZ exports from temp directory
+	contentType, ok := downloadContentTypes[filepath.Ext(filename)]
+	if !ok || strings.Contains(filename, "..") {
 		http.Error(w, "Invalid file", http.StatusBadRequest)
 		return
 	}
@@ -40,7 +46,7 @@ func (h *DownloadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Type", contentType)
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
 
 	file, err := os.Open(tempPath)
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/supermanifolds/nimby_shapetopoi/internal/openrailway"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/server/templates"
+)
+
+// RailwayHandler builds mods from the OpenStreetMap railway infrastructure in
+// a bounding box, queried through the Overpass API
+type RailwayHandler struct {
+	logger     *slog.Logger
+	tileClient *openrailway.TileClient
+	endpoint   string
+}
+
+func NewRailwayHandler(logger *slog.Logger, endpoint string) *RailwayHandler {
+	return &RailwayHandler{
+		logger:     logger,
+		tileClient: openrailway.NewTileClient(),
+		endpoint:   endpoint,
+	}
+}
+
+func (h *RailwayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	bbox := strings.TrimSpace(r.FormValue("bbox"))
+	if bbox == "" {
+		renderError(w, r, h.logger, "No bounding box given")
+		return
+	}
+	if _, err := openrailway.ParseBoundingBox(bbox); err != nil {
+		renderError(w, r, h.logger, err.Error())
+		return
+	}
+
+	outputName := r.FormValue("output-name")
+	if outputName == "" {
+		outputName = "railway-mod"
+	}
+
+	cfg := openrailway.Config{
+		Endpoint: h.endpoint,
+		Color:    r.FormValue("poi-color"),
+	}
+	if maxLodStr := r.FormValue("max-lod"); maxLodStr != "" {
+		if lod, err := strconv.ParseInt(maxLodStr, 10, 32); err == nil && lod >= 0 && lod <= 10 {
+			maxLod := int32(lod)
+			cfg.MaxLod = &maxLod
+		}
+	}
+
+	h.logger.InfoContext(r.Context(), "Querying railway infrastructure", "bbox", bbox)
+	poiList, err := openrailway.GeneratePOIs(r.Context(), bbox, cfg)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to query railway infrastructure", "error", err)
+		renderError(w, r, h.logger, "Failed to query railway infrastructure: "+err.Error())
+		return
+	}
+	if len(*poiList) == 0 {
+		renderError(w, r, h.logger, "No railway infrastructure found in the bounding box")
+		return
+	}
+
+	result, err := createModZip(outputName, *poiList)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to create mod zip", "error", err)
+		renderError(w, r, h.logger, err.Error())
+		return
+	}
+
+	previewPath, err := generateMapPreview(r.Context(), h.tileClient, result.POIList, result.ModName)
+	if err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to generate map preview", "error", err)
+		// Continue without preview - it's not critical
+	}
+
+	component := templates.ConversionResult(
+		result.ModName,
+		len(*result.POIList),
+		result.DownloadPath,
+		result.KMZDownloadPath,
+		previewPath,
+	)
+
+	if err := component.Render(r.Context(), w); err != nil {
+		h.logger.ErrorContext(r.Context(), "Failed to render result template", "error", err)
+		http.Error(w, "Failed to render response", http.StatusInternalServerError)
+	}
+}
@@ -10,19 +10,49 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/supermanifolds/nimby_shapetopoi/internal/geometry"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/gis"
 	"github.com/supermanifolds/nimby_shapetopoi/internal/mod"
 	"github.com/supermanifolds/nimby_shapetopoi/internal/openrailway"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/osm"
 	"github.com/supermanifolds/nimby_shapetopoi/internal/poi"
+	"github.com/supermanifolds/nimby_shapetopoi/internal/rules"
 	"github.com/supermanifolds/nimby_shapetopoi/internal/server/templates"
+	"github.com/supermanifolds/nimby_shapetopoi/pkg/kml"
 )
 
 const maxUploadSize = 50 << 20 // 50MB
 
+// allowedExtensions lists the file types accepted by the upload form
+var allowedExtensions = map[string]bool{
+	".shp":     true,
+	".dbf":     true,
+	".shx":     true,
+	".prj":     true,
+	".kml":     true,
+	".kmz":     true,
+	".geojson": true,
+	".json":    true,
+	".gpx":     true,
+	".csv":     true,
+	".tsv":     true,
+	".osm":     true,
+	".pbf":     true,
+	".zip":     true,
+}
+
+// sidecarExtensions are saved next to a shapefile but not parsed on their own
+var sidecarExtensions = map[string]bool{
+	".dbf": true,
+	".shx": true,
+	".prj": true,
+}
+
 type UploadHandler struct {
 	logger     *slog.Logger
 	tileClient *openrailway.TileClient
@@ -61,11 +91,147 @@ func (h *UploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse interpolation distance
-	var interpolateDistance float64
+	var opts geometry.Options
 	if distanceStr := r.FormValue("interpolate-distance"); distanceStr != "" {
 		if dist, err := strconv.ParseFloat(distanceStr, 64); err == nil && dist > 0 {
-			interpolateDistance = dist
+			opts.InterpolateDistance = dist
+		}
+	}
+
+	// Parse resampling
+	if distanceStr := r.FormValue("resample-distance"); distanceStr != "" {
+		if dist, err := strconv.ParseFloat(distanceStr, 64); err == nil && dist > 0 {
+			opts.ResampleDistance = dist
+		}
+	}
+	opts.ResampleKeepVertices = r.FormValue("resample-keep-vertices") == "true"
+
+	// Parse line simplification
+	if toleranceStr := r.FormValue("simplify-tolerance"); toleranceStr != "" {
+		if tolerance, err := strconv.ParseFloat(toleranceStr, 64); err == nil && tolerance > 0 {
+			opts.SimplifyTolerance = tolerance
+		}
+	}
+	simplify, err := geometry.ParseSimplifyMethod(r.FormValue("simplify-method"))
+	if err != nil {
+		h.renderError(w, r, err.Error())
+		return
+	}
+	opts.Simplify = simplify
+
+	// Parse polygon output
+	polygonMode, err := geometry.ParsePolygonMode(r.FormValue("polygon-mode"))
+	if err != nil {
+		h.renderError(w, r, err.Error())
+		return
+	}
+	opts.PolygonMode = polygonMode
+
+	// Parse polygon fill
+	if spacingStr := r.FormValue("fill-spacing"); spacingStr != "" {
+		if spacing, err := strconv.ParseFloat(spacingStr, 64); err == nil && spacing > 0 {
+			opts.FillSpacing = spacing
+		}
+	}
+	fill, err := geometry.ParseFillMethod(r.FormValue("fill-method"))
+	if err != nil {
+		h.renderError(w, r, err.Error())
+		return
+	}
+	opts.Fill = fill
+
+	// Parse label field
+	opts.LabelField = strings.TrimSpace(r.FormValue("label-field"))
+
+	// Parse source coordinate system override
+	opts.SourceCRS = strings.TrimSpace(r.FormValue("source-crs"))
+	if opts.SourceCRS != "" {
+		if _, err := gis.ParseCRS(opts.SourceCRS); err != nil {
+			h.renderError(w, r, "Invalid source coordinate system: "+err.Error())
+			return
+		}
+	}
+
+	// Parse CSV/TSV column names
+	opts.LonColumn = strings.TrimSpace(r.FormValue("lon-col"))
+	opts.LatColumn = strings.TrimSpace(r.FormValue("lat-col"))
+	opts.WKTColumn = strings.TrimSpace(r.FormValue("wkt-col"))
+
+	// Parse OpenStreetMap tag filter
+	if filterStr := strings.TrimSpace(r.FormValue("osm-filter")); filterStr != "" {
+		filter, err := osm.ParseFilter(filterStr)
+		if err != nil {
+			h.renderError(w, r, err.Error())
+			return
+		}
+		opts.OSMFilter = filter
+	}
+	// PBF extracts are only read through a filter to bound memory use
+	if opts.OSMFilter == nil && slices.ContainsFunc(files, func(fh *multipart.FileHeader) bool {
+		return strings.EqualFold(filepath.Ext(fh.Filename), ".pbf")
+	}) {
+		h.renderError(w, r, "An OpenStreetMap tag filter is required for .osm.pbf files, e.g. railway=rail")
+		return
+	}
+
+	// Parse KML folder selection
+	folders, err := geometry.NewFolderFilter(
+		strings.Split(r.FormValue("include-folders"), "\n"),
+		strings.Split(r.FormValue("exclude-folders"), "\n"),
+	)
+	if err != nil {
+		h.renderError(w, r, err.Error())
+		return
+	}
+	opts.Folders = folders
+
+	// Parse feature filter expression
+	if whereStr := strings.TrimSpace(r.FormValue("where")); whereStr != "" {
+		featureFilter, err := geometry.ParseFeatureFilter(whereStr)
+		if err != nil {
+			h.renderError(w, r, err.Error())
+			return
 		}
+		opts.Where = featureFilter
+	}
+
+	// Parse clip area
+	clipBBox := strings.TrimSpace(r.FormValue("clip-bbox"))
+	clipFiles := r.MultipartForm.File["clip-file"]
+	switch {
+	case clipBBox != "" && len(clipFiles) > 0:
+		h.renderError(w, r, "Use either a clip bounding box or a clip boundary file, not both")
+		return
+	case clipBBox != "":
+		bbox, err := openrailway.ParseBoundingBox(clipBBox)
+		if err != nil {
+			h.renderError(w, r, "Invalid clip bounding box: "+err.Error())
+			return
+		}
+		opts.Clip = geometry.NewClipBox(bbox.MinLon, bbox.MinLat, bbox.MaxLon, bbox.MaxLat)
+	case len(clipFiles) > 0:
+		clip, err := h.readClipArea(clipFiles, opts)
+		if err != nil {
+			h.renderError(w, r, "Invalid clip boundary: "+err.Error())
+			return
+		}
+		opts.Clip = clip
+	}
+
+	// Parse style preference
+	opts.PreferFileStyles = r.FormValue("prefer-file-styles") == "true"
+
+	// Uploaded KML may only link to the other files of the same upload
+	opts.KMLLinks = kml.LinksInDirectory
+
+	// Parse styling rules
+	if ruleFiles := r.MultipartForm.File["rules"]; len(ruleFiles) > 0 {
+		ruleSet, err := readRules(ruleFiles[0])
+		if err != nil {
+			h.renderError(w, r, "Invalid rules file: "+err.Error())
+			return
+		}
+		opts.Rules = ruleSet
 	}
 
 	// Parse max LOD value
@@ -83,7 +249,7 @@ func (h *UploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Process uploaded files
-	result, err := h.processUploadedFiles(r.Context(), files, outputName, interpolateDistance, maxLod, poiColor)
+	result, err := h.processUploadedFiles(r.Context(), files, outputName, opts, maxLod, poiColor)
 	if err != nil {
 		h.logger.ErrorContext(r.Context(), "Failed to process uploaded files", "error", err)
 		h.renderError(w, r, "Failed to process uploaded files: "+err.Error())
@@ -102,6 +268,7 @@ func (h *UploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		result.ModName,
 		len(*result.POIList),
 		result.DownloadPath,
+		result.KMZDownloadPath,
 		previewPath,
 	)
 
@@ -112,13 +279,14 @@ func (h *UploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 type ProcessResult struct {
-	POIList      *poi.List
-	ModName      string
-	DownloadPath string
-	OutputPath   string
+	POIList         *poi.List
+	ModName         string
+	DownloadPath    string
+	KMZDownloadPath string
+	OutputPath      string
 }
 
-func (h *UploadHandler) processUploadedFiles(ctx context.Context, files []*multipart.FileHeader, outputName string, interpolateDistance float64, maxLod int32, poiColor string) (*ProcessResult, error) {
+func (h *UploadHandler) processUploadedFiles(ctx context.Context, files []*multipart.FileHeader, outputName string, opts geometry.Options, maxLod int32, poiColor string) (*ProcessResult, error) {
 	// Create temporary directory for uploaded files
 	tempDir, err := os.MkdirTemp("", "shapetopoi-upload-*")
 	if err != nil {
@@ -139,8 +307,8 @@ func (h *UploadHandler) processUploadedFiles(ctx context.Context, files []*multi
 	for _, inputFile := range inputFiles {
 		h.logger.InfoContext(ctx, "Processing uploaded file", "path", inputFile)
 
-		// Create reader with interpolation distance
-		reader, err := geometry.GetReaderWithInterpolation(inputFile, interpolateDistance)
+		// Create reader with interpolation and label options
+		reader, err := geometry.GetReaderWithOptions(inputFile, opts)
 		if err != nil {
 			h.logger.ErrorContext(ctx, "Error getting reader for file", "path", inputFile, "error", err)
 			continue
@@ -149,6 +317,9 @@ func (h *UploadHandler) processUploadedFiles(ctx context.Context, files []*multi
 		// Convert hex color to NIMBY format
 		nimbyColor := geometry.HexToNimbyColor(poiColor)
 		poiList, err := reader.ParseFileWithFullConfig(inputFile, maxLod, nimbyColor)
+		if errors.Is(err, geometry.ErrTooManyFillPoints) {
+			return nil, err
+		}
 		if err != nil {
 			h.logger.ErrorContext(ctx, "Error parsing file", "path", inputFile, "error", err)
 			continue
@@ -164,8 +335,15 @@ func (h *UploadHandler) processUploadedFiles(ctx context.Context, files []*multi
 
 	// Note: Interpolation is now done during parsing for each line segment
 
-	// Generate output file
-	outputPath := filepath.Join(os.TempDir(), outputName+"-"+generateTimestamp()+".zip")
+	return createModZip(outputName, combinedPOIList)
+}
+
+// createModZip writes the POIs to a mod zip in the temp directory, where the
+// download handler serves it from, along with a KMZ of the same POIs for
+// viewing in Google Earth
+func createModZip(outputName string, poiList poi.List) (*ProcessResult, error) {
+	outputBase := filepath.Join(os.TempDir(), outputName+"-"+generateTimestamp())
+	outputPath := outputBase + ".zip"
 	tsvFileName := outputName + ".tsv"
 
 	config := mod.Config{
@@ -175,17 +353,24 @@ func (h *UploadHandler) processUploadedFiles(ctx context.Context, files []*multi
 
 	modContent := mod.GenerateDefaultContent(outputName, tsvFileName)
 
-	if err := mod.CreateZip(config, combinedPOIList, modContent); err != nil {
+	if err := mod.CreateZip(config, poiList, modContent); err != nil {
 		return nil, fmt.Errorf("failed to create mod zip: %w", err)
 	}
 
+	kmzPath := outputBase + ".kmz"
+	layers := []mod.Layer{{Name: outputName + " POIs", POIs: poiList}}
+	if err := mod.WriteKML(kmzPath, outputName, layers); err != nil {
+		return nil, fmt.Errorf("failed to create KMZ: %w", err)
+	}
+
 	downloadPath := "/download/" + filepath.Base(outputPath)
 
 	return &ProcessResult{
-		POIList:      &combinedPOIList,
-		ModName:      outputName,
-		DownloadPath: downloadPath,
-		OutputPath:   outputPath,
+		POIList:         &poiList,
+		ModName:         outputName,
+		DownloadPath:    downloadPath,
+		KMZDownloadPath: "/download/" + filepath.Base(kmzPath),
+		OutputPath:      outputPath,
 	}, nil
 }
 
@@ -198,7 +383,7 @@ func (h *UploadHandler) saveUploadedFile(fh *multipart.FileHeader, tempDir strin
 
 	// Validate file extension
 	ext := strings.ToLower(filepath.Ext(fh.Filename))
-	if ext != ".shp" && ext != ".kml" && ext != ".kmz" {
+	if !allowedExtensions[ext] {
 		return fmt.Errorf("unsupported file type: %s", ext)
 	}
 
@@ -213,11 +398,63 @@ func (h *UploadHandler) saveUploadedFile(fh *multipart.FileHeader, tempDir strin
 		return fmt.Errorf("failed to save uploaded file: %w", err)
 	}
 
-	*inputFiles = append(*inputFiles, tempPath)
+	// Sidecar files are picked up by the reader of the file they belong to
+	if !sidecarExtensions[ext] {
+		*inputFiles = append(*inputFiles, tempPath)
+	}
 	return nil
 }
 
+// readClipArea reads the polygons of an uploaded boundary file, which may
+// come with the sidecar files of a shapefile. It is read with the same
+// coordinate settings as the input files, but none of their filters.
+func (h *UploadHandler) readClipArea(files []*multipart.FileHeader, opts geometry.Options) (*geometry.ClipArea, error) {
+	tempDir, err := os.MkdirTemp("", "shapetopoi-clip-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	var boundaryFiles []string
+	for _, fileHeader := range files {
+		if err := h.saveUploadedFile(fileHeader, tempDir, &boundaryFiles); err != nil {
+			return nil, err
+		}
+	}
+	if len(boundaryFiles) != 1 {
+		return nil, fmt.Errorf("expected one boundary file, got %d", len(boundaryFiles))
+	}
+
+	return geometry.ReadClipArea(boundaryFiles[0], geometry.Options{
+		SourceCRS: opts.SourceCRS,
+		LonColumn: opts.LonColumn,
+		LatColumn: opts.LatColumn,
+		WKTColumn: opts.WKTColumn,
+		KMLLinks:  kml.LinksInDirectory,
+	})
+}
+
+func readRules(fh *multipart.FileHeader) (*rules.RuleSet, error) {
+	file, err := fh.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rules file: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+	return rules.Parse(data)
+}
+
 func (h *UploadHandler) generateMapPreview(ctx context.Context, poiList *poi.List, modName string) (string, error) {
+	return generateMapPreview(ctx, h.tileClient, poiList, modName)
+}
+
+// generateMapPreview renders the POIs over map tiles and returns the preview's
+// web-accessible path
+func generateMapPreview(ctx context.Context, tileClient *openrailway.TileClient, poiList *poi.List, modName string) (string, error) {
 	if len(*poiList) == 0 {
 		return "", errors.New("no POIs to preview")
 	}
@@ -234,7 +471,7 @@ func (h *UploadHandler) generateMapPreview(ctx context.Context, poiList *poi.Lis
 	defer previewFile.Close()
 
 	// Generate map with POI overlays (800x600 max size)
-	if err := h.tileClient.SaveMapWithPOIs(ctx, previewFile, bbox, poiList, 800, 600); err != nil {
+	if err := tileClient.SaveMapWithPOIs(ctx, previewFile, bbox, poiList, 800, 600); err != nil {
 		os.Remove(previewPath) // Clean up on error
 		return "", fmt.Errorf("failed to generate map preview: %w", err)
 	}
@@ -244,9 +481,13 @@ func (h *UploadHandler) generateMapPreview(ctx context.Context, poiList *poi.Lis
 }
 
 func (h *UploadHandler) renderError(w http.ResponseWriter, r *http.Request, message string) {
+	renderError(w, r, h.logger, message)
+}
+
+func renderError(w http.ResponseWriter, r *http.Request, logger *slog.Logger, message string) {
 	component := templates.Error(message)
 	if err := component.Render(r.Context(), w); err != nil {
-		h.logger.ErrorContext(r.Context(), "Failed to render error template", "error", err)
+		logger.ErrorContext(r.Context(), "Failed to render error template", "error", err)
 		http.Error(w, message, http.StatusInternalServerError)
 	}
 }
@@ -12,14 +12,18 @@ import (
 )
 
 type Server struct {
-	logger *slog.Logger
-	port   string
-	server *http.Server
+	logger      *slog.Logger
+	port        string
+	overpassURL string
+	server      *http.Server
 }
 
 type Config struct {
 	Port   string
 	Logger *slog.Logger
+	// OverpassURL is the Overpass API endpoint for railway imports, the public
+	// instance when empty
+	OverpassURL string
 }
 
 func New(cfg Config) *Server {
@@ -31,8 +35,9 @@ func New(cfg Config) *Server {
 	}
 
 	return &Server{
-		logger: cfg.Logger,
-		port:   cfg.Port,
+		logger:      cfg.Logger,
+		port:        cfg.Port,
+		overpassURL: cfg.OverpassURL,
 	}
 }
 
@@ -42,6 +47,7 @@ func (s *Server) setupRoutes() {
 	// Initialize handlers
 	homeHandler := handlers.NewHomeHandler()
 	uploadHandler := handlers.NewUploadHandler(s.logger)
+	railwayHandler := handlers.NewRailwayHandler(s.logger, s.overpassURL)
 	downloadHandler := handlers.NewDownloadHandler(s.logger)
 	previewHandler := handlers.NewPreviewHandler(s.logger)
 	healthHandler := handlers.NewHealthHandler()
@@ -53,6 +59,7 @@ func (s *Server) setupRoutes() {
 	// Routes using new Go 1.22 patterns
 	mux.Handle("GET /", homeHandler)
 	mux.Handle("POST /upload", uploadHandler)
+	mux.Handle("POST /railway", railwayHandler)
 	mux.Handle("GET /download/{filename}", downloadHandler)
 	mux.Handle("GET /preview/{filename}", previewHandler)
 	mux.Handle("GET /health", healthHandler)
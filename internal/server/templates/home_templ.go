@@ -41,7 +41,7 @@ func Home() templ.Component {
 				}()
 			}
 			ctx = templ.InitializeContext(ctx)
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<header><h1>NIMBY Rails Shape to POI Converter</h1><p>Convert geographic data files into NIMBY Rails POI mods</p></header><main><div class=\"card\"><h2>Upload Files</h2><p>Upload shapefile (.shp), KML (.kml), or KMZ (.kmz) files to convert them into NIMBY Rails POI mods.</p><form hx-post=\"/upload\" hx-target=\"#result-area\" hx-encoding=\"multipart/form-data\" hx-indicator=\"#upload-spinner\"><div class=\"upload-area\" ondrop=\"handleDrop(event)\" ondragover=\"handleDragOver(event)\" ondragleave=\"handleDragLeave(event)\"><input type=\"file\" name=\"files\" multiple accept=\".shp,.kml,.kmz\" required id=\"file-input\"><div class=\"upload-click-area\"><div class=\"upload-icon\">📁</div><h3>Click to select files or drag & drop</h3><p>Supported formats: .shp, .kml, .kmz</p><div id=\"file-list\"></div></div></div><div class=\"form-group\"><label for=\"output-name\">Mod Name (optional)</label> <input type=\"text\" id=\"output-name\" name=\"output-name\" placeholder=\"my-awesome-mod\"></div><div class=\"form-group\"><label for=\"interpolate-distance\">Point Interpolation Distance (optional)</label> <input type=\"number\" id=\"interpolate-distance\" name=\"interpolate-distance\" placeholder=\"500\" min=\"10\" max=\"10000\" step=\"10\"> <small>Add extra points along lines if segments are longer than this distance (meters). Leave empty to disable.</small></div><div class=\"form-group\"><label for=\"max-lod\">Max Zoom Level</label> <input type=\"range\" id=\"max-lod\" name=\"max-lod\" min=\"0\" max=\"10\" value=\"0\" step=\"1\"><div class=\"slider-labels\"><span>0 (Close zoom only)</span> <span id=\"max-lod-value\">0</span> <span>10 (Always visible)</span></div><small>Controls at what zoom level POIs disappear. 0 = only visible when zoomed in close, 10 = always visible.</small></div><div class=\"form-group\"><label for=\"poi-color\">POI Color</label> <input type=\"color\" id=\"poi-color\" name=\"poi-color\" value=\"#0000ff\"> <small>Color for POIs in the generated mod.</small></div><button type=\"submit\" class=\"btn\" id=\"submit-button\"><span id=\"upload-spinner\" class=\"spinner hidden\"></span> <span id=\"button-text\">Convert to NIMBY Rails Mod</span></button></form></div><div id=\"result-area\"><!-- Results will be displayed here via HTMX --></div></main><footer><h3>About</h3><p>This tool converts geographic data files into NIMBY Rails mod files containing Points of Interest (POI).</p><p><strong>Supported formats:</strong> Shapefiles (.shp), KML (.kml), KMZ (.kmz)</p><hr style=\"margin: 20px 0; border: none; border-top: 1px solid #e5e7eb;\"><p style=\"text-align: center; color: #6b7280; font-size: 14px;\">Created by <a href=\"https://github.com/supermanifolds\" target=\"_blank\" rel=\"noopener noreferrer\" style=\"color: #3b82f6; text-decoration: none;\">Alex Sørlie (SuperManifolds)</a></p></footer>")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 1, "<header><h1>NIMBY Rails Shape to POI Converter</h1><p>Convert geographic data files into NIMBY Rails POI mods</p></header><main><div class=\"card\"><h2>Upload Files</h2><p>Upload shapefile (.shp), KML (.kml), KMZ (.kmz), GeoJSON (.geojson), GPX (.gpx), CSV/TSV (.csv, .tsv), OpenStreetMap (.osm, .osm.pbf), or GTFS feed (.zip) files to convert them into NIMBY Rails POI mods.</p><form hx-post=\"/upload\" hx-target=\"#result-area\" hx-encoding=\"multipart/form-data\" hx-indicator=\"#upload-spinner\"><div class=\"upload-area\" ondrop=\"handleDrop(event)\" ondragover=\"handleDragOver(event)\" ondragleave=\"handleDragLeave(event)\"><input type=\"file\" name=\"files\" multiple accept=\".shp,.dbf,.shx,.prj,.kml,.kmz,.geojson,.json,.gpx,.csv,.tsv,.osm,.pbf,.zip\" required id=\"file-input\"><div class=\"upload-click-area\"><div class=\"upload-icon\">📁</div><h3>Click to select files or drag & drop</h3><p>Supported formats: .shp, .kml, .kmz, .geojson, .gpx, .csv, .tsv, .osm, .osm.pbf, .zip (GTFS)</p><div id=\"file-list\"></div></div></div><div class=\"form-group\"><label for=\"output-name\">Mod Name (optional)</label> <input type=\"text\" id=\"output-name\" name=\"output-name\" placeholder=\"my-awesome-mod\"></div><div class=\"form-group\"><label for=\"label-field\">Label Field (optional)</label> <input type=\"text\" id=\"label-field\" name=\"label-field\" placeholder=\"Label\"> <small>Attribute used as POI text. Defaults to \"Label\", then \"name\". Upload the .dbf next to your .shp for shapefile labels. Enter \"none\" to disable labels.</small></div><div class=\"form-group\"><label for=\"source-crs\">Source Coordinate System (optional)</label> <input type=\"text\" id=\"source-crs\" name=\"source-crs\" placeholder=\"EPSG:2180\"> <small>Shapefiles are reprojected using their .prj file, so upload it next to your .shp. Enter an EPSG code to override it or if the .prj is missing. CSV/TSV coordinates are read in this system too.</small></div><div class=\"form-group\"><label for=\"lon-col\">CSV Longitude Column (optional)</label> <input type=\"text\" id=\"lon-col\" name=\"lon-col\" placeholder=\"lon\"></div><div class=\"form-group\"><label for=\"lat-col\">CSV Latitude Column (optional)</label> <input type=\"text\" id=\"lat-col\" name=\"lat-col\" placeholder=\"lat\"> <small>Columns holding the coordinates of CSV/TSV rows. Columns named lon/lat, lng/lat, longitude/latitude or x/y are found automatically.</small></div><div class=\"form-group\"><label for=\"wkt-col\">CSV Geometry Column (optional)</label> <input type=\"text\" id=\"wkt-col\" name=\"wkt-col\" placeholder=\"wkt\"> <small>Column holding WKT geometries such as LINESTRING (...), used instead of coordinate columns. A column named wkt or geometry is found automatically.</small></div><div class=\"form-group\"><label for=\"osm-filter\">OSM Tag Filter (required for .osm.pbf)</label> <input type=\"text\" id=\"osm-filter\" name=\"osm-filter\" placeholder=\"railway=rail and usage=main\"> <small>Selects the OpenStreetMap elements to convert, e.g. \"railway=rail and usage=main\" or \"public_transport=station\". Combine tests with and, or, not and parentheses; use key!=value, key~regex and value1|value2 for alternatives. Required for .osm.pbf files; leave empty to convert every tagged element of an .osm file.</small></div><div class=\"form-group\"><label for=\"include-folders\">Include KML Folders (optional)</label> <textarea id=\"include-folders\" name=\"include-folders\" rows=\"2\" placeholder=\"Lines/S-Bahn/*\"></textarea> <small>Only convert placemarks in these KML folders, one path or glob per line, e.g. \"Lines\" or \"Lines/S-Bahn/*\". Matching is case-insensitive. Run the inspect command to list the folders of a file. Leave empty to convert every folder.</small></div><div class=\"form-group\"><label for=\"exclude-folders\">Exclude KML Folders (optional)</label> <textarea id=\"exclude-folders\" name=\"exclude-folders\" rows=\"2\" placeholder=\"*/Closed\"></textarea> <small>Skip placemarks in these KML folders, one path or glob per line.</small></div><div class=\"form-group\"><label for=\"where\">Feature Filter (optional)</label> <input type=\"text\" id=\"where\" name=\"where\" placeholder=\"type = 'station' and name ~ '^S'\"> <small>Only convert features matching this expression, in any file format. Compare attributes with =, !=, &lt;, &lt;=, &gt;, &gt;= and ~ for regular expressions, test the geometry with $geometry = point, line or polygon, and the area with bbox(minLon, minLat, maxLon, maxLat). Combine tests with and, or, not and parentheses. Leave empty to convert every feature.</small></div><div class=\"form-group\"><label for=\"clip-bbox\">Clip to Bounding Box (optional)</label> <input type=\"text\" id=\"clip-bbox\" name=\"clip-bbox\" placeholder=\"13.08,52.33,13.77,52.68\"> <small>Only keep POIs inside this area, given as minLon,minLat,maxLon,maxLat. Lines crossing its edges are cut there rather than dropped.</small></div><div class=\"form-group\"><label for=\"clip-file\">Clip to Boundary (optional)</label> <input type=\"file\" id=\"clip-file\" name=\"clip-file\" multiple accept=\".shp,.dbf,.shx,.prj,.kml,.kmz,.geojson,.json,.gpx,.csv,.tsv,.osm,.pbf\"> <small>Only keep POIs inside the polygons of this file, such as the outline of a region, with lines cut at the boundary. Include the .dbf, .shx and .prj of a shapefile. Use either this or a bounding box.</small></div><div class=\"form-group\"><label for=\"simplify-tolerance\">Line Simplification Tolerance (optional)</label> <input type=\"number\" id=\"simplify-tolerance\" name=\"simplify-tolerance\" placeholder=\"5\" min=\"0.1\" max=\"10000\" step=\"0.1\"> <small>Drop vertices that change lines by less than this distance (meters), applied before interpolation. Useful for GPS traces and OSM ways with points every few meters. Leave empty to disable.</small></div><div class=\"form-group\"><label for=\"simplify-method\">Simplification Method</label> <select id=\"simplify-method\" name=\"simplify-method\"><option value=\"douglas-peucker\">Douglas-Peucker (keeps corners)</option> <option value=\"visvalingam\">Visvalingam-Whyatt (smoother curves)</option></select></div><div class=\"form-group\"><label for=\"polygon-mode\">Polygons</label> <select id=\"polygon-mode\" name=\"polygon-mode\"><option value=\"outline\">Outline</option> <option value=\"point\">Single label inside</option> <option value=\"both\">Outline and label inside</option></select> <small>Show town and district boundaries as a single name each, placed at the point inside the area furthest from its edges.</small></div><div class=\"form-group\"><label for=\"fill-spacing\">Polygon Fill Spacing (optional)</label> <input type=\"number\" id=\"fill-spacing\" name=\"fill-spacing\" placeholder=\"50\" min=\"10\" max=\"10000\" step=\"1\"> <small>Cover the inside of polygons such as yards and lakes with POIs this far apart (meters), leaving out their holes. Leave empty to draw outlines only.</small></div><div class=\"form-group\"><label for=\"fill-method\">Fill Pattern</label> <select id=\"fill-method\" name=\"fill-method\"><option value=\"grid\">Grid (evenly dotted)</option> <option value=\"hatch\">Hatch (diagonal lines)</option></select></div><div class=\"form-group\"><label for=\"interpolate-distance\">Point Interpolation Distance (optional)</label> <input type=\"number\" id=\"interpolate-distance\" name=\"interpolate-distance\" placeholder=\"500\" min=\"1\" max=\"10000\" step=\"1\"> <small>Add extra points along lines if segments are longer than this distance (meters). Leave empty to disable.</small></div><div class=\"form-group\"><label for=\"resample-distance\">Even Point Spacing (optional)</label> <input type=\"number\" id=\"resample-distance\" name=\"resample-distance\" placeholder=\"100\" min=\"1\" max=\"10000\" step=\"1\"> <small>Place points exactly this far apart along lines (meters) so they look evenly dotted. Replaces interpolation. Leave empty to disable.</small></div><div class=\"form-group\"><label class=\"checkbox-label\" for=\"resample-keep-vertices\"><input type=\"checkbox\" id=\"resample-keep-vertices\" name=\"resample-keep-vertices\" value=\"true\"> Keep original vertices when spacing points evenly</label></div><div class=\"form-group\"><label for=\"max-lod\">Max Zoom Level</label> <input type=\"range\" id=\"max-lod\" name=\"max-lod\" min=\"0\" max=\"10\" value=\"0\" step=\"1\"><div class=\"slider-labels\"><span>0 (Close zoom only)</span> <span id=\"max-lod-value\">0</span> <span>10 (Always visible)</span></div><small>Controls at what zoom level POIs disappear. 0 = only visible when zoomed in close, 10 = always visible.</small></div><div class=\"form-group\"><label for=\"poi-color\">POI Color</label> <input type=\"color\" id=\"poi-color\" name=\"poi-color\" value=\"#0000ff\"> <small>Color for POIs in the generated mod.</small></div><div class=\"form-group\"><label class=\"checkbox-label\" for=\"prefer-file-styles\"><input type=\"checkbox\" id=\"prefer-file-styles\" name=\"prefer-file-styles\" value=\"true\"> Use colors from file styles</label> <small>Color KML placemarks with their own style colors, such as the per-route colors of Google My Maps exports, and GTFS shapes with their route_color. The POI color above is used for features without a style.</small></div><div class=\"form-group\"><label for=\"rules\">Styling Rules (optional)</label> <input type=\"file\" id=\"rules\" name=\"rules\" accept=\".json\"> <small>JSON rules file that sets color, font size, zoom level, transparency, demand and population from feature attributes. Matching rules override the settings above.</small></div><button type=\"submit\" class=\"btn\" id=\"submit-button\"><span id=\"upload-spinner\" class=\"spinner hidden\"></span> <span id=\"button-text\">Convert to NIMBY Rails Mod</span></button></form></div><div class=\"card\"><h2>Import Railways from OpenStreetMap</h2><p>Build a mod from the tracks, stations, platforms and signals in an area, styled by track type. Data is queried live from OpenStreetMap through the Overpass API.</p><form hx-post=\"/railway\" hx-target=\"#result-area\" hx-indicator=\"#railway-spinner\"><div class=\"form-group\"><label for=\"bbox\">Bounding Box</label> <input type=\"text\" id=\"bbox\" name=\"bbox\" placeholder=\"13.30,52.49,13.45,52.56\" required> <small>Area to import as min longitude, min latitude, max longitude, max latitude, at most 4 square degrees. Large cities can take a minute to download.</small></div><div class=\"form-group\"><label for=\"railway-output-name\">Mod Name (optional)</label> <input type=\"text\" id=\"railway-output-name\" name=\"output-name\" placeholder=\"berlin-railways\"></div><div class=\"form-group\"><label for=\"railway-max-lod\">Max Zoom Level</label> <input type=\"number\" id=\"railway-max-lod\" name=\"max-lod\" min=\"0\" max=\"10\" value=\"10\" step=\"1\"> <small>Zoom level up to which tracks and stations stay visible. Platforms and signals only show when zoomed in.</small></div><button type=\"submit\" class=\"btn\"><span id=\"railway-spinner\" class=\"spinner hidden\"></span> <span>Import Railways</span></button></form></div><div id=\"result-area\"><!-- Results will be displayed here via HTMX --></div></main><footer><h3>About</h3><p>This tool converts geographic data files into NIMBY Rails mod files containing Points of Interest (POI).</p><p><strong>Supported formats:</strong> Shapefiles (.shp), KML (.kml), KMZ (.kmz), GeoJSON (.geojson, .json), GPX (.gpx), CSV/TSV (.csv, .tsv), OpenStreetMap (.osm, .osm.pbf), GTFS (.zip)</p><hr style=\"margin: 20px 0; border: none; border-top: 1px solid #e5e7eb;\"><p style=\"text-align: center; color: #6b7280; font-size: 14px;\">Created by <a href=\"https://github.com/supermanifolds\" target=\"_blank\" rel=\"noopener noreferrer\" style=\"color: #3b82f6; text-decoration: none;\">Alex Sørlie (SuperManifolds)</a></p></footer>")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
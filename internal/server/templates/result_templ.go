@@ -10,7 +10,7 @@ import templruntime "github.com/a-h/templ/runtime"
 
 import "fmt"
 
-func ConversionResult(modName string, poiCount int, downloadPath string, previewPath string) templ.Component {
+func ConversionResult(modName string, poiCount int, downloadPath string, kmzPath string, previewPath string) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -106,7 +106,30 @@ func ConversionResult(modName string, poiCount int, downloadPath string, preview
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "</a> <button class=\"btn btn-secondary\" onclick=\"location.reload()\" style=\"margin-left: 12px\">🔄 Convert Another File</button></div><div class=\"installation-instructions\" style=\"margin-top: 24px; padding: 16px; background: #f8fafc; border-radius: 8px; border-left: 4px solid #3b82f6;\"><h3>📦 Installation Instructions</h3><ol style=\"margin: 12px 0; padding-left: 20px;\"><li>Download the mod file above</li><li>Unzip the downloaded file</li><li>Copy the unzipped folder to your NIMBY Rails mods directory:</li></ol><div style=\"background: #1f2937; color: #f3f4f6; padding: 12px; border-radius: 6px; font-family: 'SF Mono', 'Monaco', 'Inconsolata', 'Roboto Mono', monospace; font-size: 14px; margin: 8px 0;\"><strong>Windows:</strong><br><code>%USERPROFILE%\\Saved Games\\Weird and Wry\\NIMBY Rails\\mods\\</code><br><br></div><p style=\"margin: 12px 0 0 0; font-size: 14px; color: #6b7280;\"><strong>Note:</strong> Create the mods folder if it doesn't exist. Go to the Mods tab in the top right of NIMBY Rails and enable your mod to see the POIs in the game.</p></div></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, "</a> ")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		if kmzPath != "" {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "<a href=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var7 templ.SafeURL
+			templ_7745c5c3_Var7, templ_7745c5c3_Err = templ.JoinURLErrs(templ.URL(kmzPath))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/server/templates/result.templ`, Line: 22, Col: 32}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var7))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "\" class=\"btn btn-secondary\" download style=\"margin-left: 12px\">🌍 Download KMZ for Google Earth</a> ")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "<button class=\"btn btn-secondary\" onclick=\"location.reload()\" style=\"margin-left: 12px\">🔄 Convert Another File</button></div><div class=\"installation-instructions\"><h3>📦 Installation Instructions</h3><ol><li>Download the mod file above</li><li>Unzip the downloaded file</li><li>Copy the unzipped folder to your NIMBY Rails mods directory:</li></ol><div class=\"code-block\"><strong>Windows:</strong><br><code>%USERPROFILE%\\Saved Games\\Weird and Wry\\NIMBY Rails\\mods\\</code><br><br></div><p class=\"note\"><strong>Note:</strong> Create the mods folder if it doesn't exist. Go to the Mods tab in the top right of NIMBY Rails and enable your mod to see the POIs in the game.</p></div></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -130,25 +153,25 @@ func Error(message string) templ.Component {
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var7 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var7 == nil {
-			templ_7745c5c3_Var7 = templ.NopComponent
+		templ_7745c5c3_Var8 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var8 == nil {
+			templ_7745c5c3_Var8 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, "<div class=\"card error\"><h2>❌ Error</h2><p>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, "<div class=\"card error\"><h2>❌ Error</h2><p>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var8 string
-		templ_7745c5c3_Var8, templ_7745c5c3_Err = templ.JoinStringErrs(message)
+		var templ_7745c5c3_Var9 string
+		templ_7745c5c3_Var9, templ_7745c5c3_Err = templ.JoinStringErrs(message)
 		if templ_7745c5c3_Err != nil {
 			return templ.Error{Err: templ_7745c5c3_Err, FileName: `internal/server/templates/result.templ`, Line: 54, Col: 14}
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var8))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var9))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "</p><button class=\"btn btn-secondary\" onclick=\"location.reload()\" style=\"margin-top: 16px\">🔄 Try Again</button></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, "</p><button class=\"btn btn-secondary\" onclick=\"location.reload()\" style=\"margin-top: 16px\">🔄 Try Again</button></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
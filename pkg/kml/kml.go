@@ -1,13 +1,8 @@
 package kml
 
 import (
-	"archive/zip"
 	"encoding/xml"
-	"errors"
 	"fmt"
-	"io"
-	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 )
@@ -15,47 +10,83 @@ import (
 type KML struct {
 	XMLName  xml.Name  `xml:"kml"`
 	Document *Document `xml:"Document"`
+	// Resources holds the other KML files of a KMZ archive, keyed by their path
+	// relative to the main document, so references such as "styles.kml#id" resolve
+	Resources map[string]*KML `xml:"-"`
 }
 
 type Document struct {
-	Name        string      `xml:"name"`
-	Description string      `xml:"description"`
-	Placemarks  []Placemark `xml:"Placemark"`
-	Folders     []Folder    `xml:"Folder"`
-	Styles      []Style     `xml:"Style"`
-	StyleMaps   []StyleMap  `xml:"StyleMap"`
+	Name         string        `xml:"name,omitempty"`
+	Description  string        `xml:"description,omitempty"`
+	Placemarks   []Placemark   `xml:"Placemark"`
+	Folders      []Folder      `xml:"Folder"`
+	NetworkLinks []NetworkLink `xml:"NetworkLink"`
+	Styles       []Style       `xml:"Style"`
+	StyleMaps    []StyleMap    `xml:"StyleMap"`
 }
 
 type Folder struct {
-	Name        string      `xml:"name"`
-	Description string      `xml:"description"`
-	Placemarks  []Placemark `xml:"Placemark"`
-	Folders     []Folder    `xml:"Folder"`
+	Name         string        `xml:"name,omitempty"`
+	Description  string        `xml:"description,omitempty"`
+	Placemarks   []Placemark   `xml:"Placemark"`
+	Folders      []Folder      `xml:"Folder"`
+	NetworkLinks []NetworkLink `xml:"NetworkLink"`
+	Styles       []Style       `xml:"Style"`
+	StyleMaps    []StyleMap    `xml:"StyleMap"`
+}
+
+// NetworkLink references another KML file, whose features belong in place of
+// the link
+type NetworkLink struct {
+	Name string `xml:"name,omitempty"`
+	Link *Link  `xml:"Link"`
+	// URL is the KML 2.0 form of Link, still written by some tools
+	URL *Link `xml:"Url"`
+	// Target is the linked file once resolved by ParseFile, or nil
+	Target *KML `xml:"-"`
+}
+
+type Link struct {
+	Href string `xml:"href,omitempty"`
+}
+
+// Href returns the address the link points to
+func (n *NetworkLink) Href() string {
+	switch {
+	case n.Link != nil:
+		return strings.TrimSpace(n.Link.Href)
+	case n.URL != nil:
+		return strings.TrimSpace(n.URL.Href)
+	}
+	return ""
 }
 
 type Placemark struct {
-	Name          string         `xml:"name"`
-	Description   string         `xml:"description"`
-	StyleURL      string         `xml:"styleUrl"`
+	Name          string         `xml:"name,omitempty"`
+	Description   string         `xml:"description,omitempty"`
+	StyleURL      string         `xml:"styleUrl,omitempty"`
+	Style         *Style         `xml:"Style"`
 	Point         *Point         `xml:"Point"`
 	LineString    *LineString    `xml:"LineString"`
 	LinearRing    *LinearRing    `xml:"LinearRing"`
 	Polygon       *Polygon       `xml:"Polygon"`
 	MultiGeometry *MultiGeometry `xml:"MultiGeometry"`
+	Track         *Track         `xml:"Track"`
+	MultiTrack    *MultiTrack    `xml:"MultiTrack"`
 	ExtendedData  *ExtendedData  `xml:"ExtendedData"`
 }
 
 type Point struct {
-	Coordinates string `xml:"coordinates"`
+	Coordinates string `xml:"coordinates,omitempty"`
 }
 
 type LineString struct {
-	Tessellate  int    `xml:"tessellate"`
-	Coordinates string `xml:"coordinates"`
+	Tessellate  int    `xml:"tessellate,omitempty"`
+	Coordinates string `xml:"coordinates,omitempty"`
 }
 
 type LinearRing struct {
-	Coordinates string `xml:"coordinates"`
+	Coordinates string `xml:"coordinates,omitempty"`
 }
 
 type Polygon struct {
@@ -77,6 +108,23 @@ type MultiGeometry struct {
 	LinearRings     []LinearRing    `xml:"LinearRing"`
 	Polygons        []Polygon       `xml:"Polygon"`
 	MultiGeometries []MultiGeometry `xml:"MultiGeometry"`
+	Tracks          []Track         `xml:"Track"`
+	MultiTracks     []MultiTrack    `xml:"MultiTrack"`
+}
+
+// Track is a gx:Track, a path recorded as a list of positions with a
+// timestamp each, as written by Google Earth and GPS loggers. Elements are
+// matched in any namespace, so KML 2.3 <Track> is read too.
+type Track struct {
+	When   []string `xml:"when,omitempty"`
+	Coords []string `xml:"coord,omitempty"`
+}
+
+// MultiTrack is a gx:MultiTrack, a set of tracks such as the legs of a trip.
+// Interpolate is 1 when the tracks form one continuous path.
+type MultiTrack struct {
+	Interpolate int     `xml:"interpolate,omitempty"`
+	Tracks      []Track `xml:"Track"`
 }
 
 type ExtendedData struct {
@@ -85,56 +133,58 @@ type ExtendedData struct {
 }
 
 type Data struct {
-	Name  string `xml:"name,attr"`
-	Value string `xml:"value"`
+	Name  string `xml:"name,attr,omitempty"`
+	Value string `xml:"value,omitempty"`
 }
 
 type SchemaData struct {
-	SchemaURL  string       `xml:"schemaUrl,attr"`
+	SchemaURL  string       `xml:"schemaUrl,attr,omitempty"`
 	SimpleData []SimpleData `xml:"SimpleData"`
 }
 
 type SimpleData struct {
-	Name  string `xml:"name,attr"`
+	Name  string `xml:"name,attr,omitempty"`
 	Value string `xml:",chardata"`
 }
 
 type Style struct {
-	ID        string     `xml:"id,attr"`
+	ID        string     `xml:"id,attr,omitempty"`
 	IconStyle *IconStyle `xml:"IconStyle"`
 	LineStyle *LineStyle `xml:"LineStyle"`
 	PolyStyle *PolyStyle `xml:"PolyStyle"`
 }
 
 type IconStyle struct {
-	Color string  `xml:"color"`
-	Scale float64 `xml:"scale"`
+	Color string  `xml:"color,omitempty"`
+	Scale float64 `xml:"scale,omitempty"`
 	Icon  *Icon   `xml:"Icon"`
 }
 
 type Icon struct {
-	Href string `xml:"href"`
+	Href string `xml:"href,omitempty"`
 }
 
 type LineStyle struct {
-	Color string  `xml:"color"`
-	Width float64 `xml:"width"`
+	Color string  `xml:"color,omitempty"`
+	Width float64 `xml:"width,omitempty"`
 }
 
+// PolyStyle.Fill and Outline are nil when not set, which KML treats as 1
 type PolyStyle struct {
-	Color   string `xml:"color"`
-	Fill    int    `xml:"fill"`
-	Outline int    `xml:"outline"`
+	Color   string `xml:"color,omitempty"`
+	Fill    *int   `xml:"fill,omitempty"`
+	Outline *int   `xml:"outline,omitempty"`
 }
 
 type StyleMap struct {
-	ID    string `xml:"id,attr"`
+	ID    string `xml:"id,attr,omitempty"`
 	Pairs []Pair `xml:"Pair"`
 }
 
 type Pair struct {
-	Key      string `xml:"key"`
-	StyleURL string `xml:"styleUrl"`
+	Key      string `xml:"key,omitempty"`
+	StyleURL string `xml:"styleUrl,omitempty"`
+	Style    *Style `xml:"Style"`
 }
 
 type Coordinate struct {
@@ -143,19 +193,25 @@ type Coordinate struct {
 	Alt float64
 }
 
+// ParseFile parses a .kml or .kmz file. NetworkLinks to other files in the
+// archive or on local disk are resolved recursively into their Target; links
+// to the web, to missing files and back to a file already read are left
+// unresolved. The other files of a KMZ archive are added as by ParseKMZ.
 func ParseFile(filePath string) (*KML, error) {
-	ext := strings.ToLower(filepath.Ext(filePath))
+	return ParseFileWithLinks(filePath, LinksAnywhere)
+}
 
-	if ext == ".kmz" {
-		return ParseKMZ(filePath)
-	}
+// ParseFileWithLinks parses a .kml or .kmz file as ParseFile, only following
+// NetworkLinks to files on disk that scope permits
+func ParseFileWithLinks(filePath string, scope LinkScope) (*KML, error) {
+	resolver := newLinkResolver(scope)
+	defer resolver.close()
 
-	data, err := os.ReadFile(filePath)
+	src, err := resolver.start(filePath)
 	if err != nil {
 		return nil, err
 	}
-
-	return Parse(data)
+	return resolver.parse(src)
 }
 
 func Parse(data []byte) (*KML, error) {
@@ -167,31 +223,20 @@ func Parse(data []byte) (*KML, error) {
 	return &kml, nil
 }
 
+// ParseKMZ parses a KMZ archive. The main document is doc.kml at the root of
+// the archive, or else the first KML file in it, and the archive's other KML
+// files are kept in Resources. NetworkLinks are resolved as by ParseFile, and
+// the other files no link points to are added to the main document as
+// resolved links named after the file, so that their placemarks are not lost.
 func ParseKMZ(filePath string) (*KML, error) {
-	reader, err := zip.OpenReader(filePath)
+	resolver := newLinkResolver(LinksAnywhere)
+	defer resolver.close()
+
+	src, err := resolver.archiveSource(filePath)
 	if err != nil {
 		return nil, err
 	}
-	defer reader.Close()
-
-	for _, file := range reader.File {
-		if strings.HasSuffix(strings.ToLower(file.Name), ".kml") {
-			rc, err := file.Open()
-			if err != nil {
-				return nil, err
-			}
-			defer rc.Close()
-
-			data, err := io.ReadAll(rc)
-			if err != nil {
-				return nil, err
-			}
-
-			return Parse(data)
-		}
-	}
-
-	return nil, errors.New("no KML file found in KMZ archive")
+	return resolver.parse(src)
 }
 
 func ParseCoordinates(coordStr string) ([]Coordinate, error) {
@@ -233,6 +278,61 @@ func ParseCoordinates(coordStr string) ([]Coordinate, error) {
 	return coords, nil
 }
 
+// Coordinates parses the track's gx:coord elements, which unlike <coordinates>
+// separate longitude, latitude and altitude with spaces
+func (t *Track) Coordinates() ([]Coordinate, error) {
+	coords := make([]Coordinate, 0, len(t.Coords))
+	for i, coord := range t.Coords {
+		parts := strings.Fields(coord)
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid gx:coord %d: %q", i+1, coord)
+		}
+
+		lon, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid longitude: %w", err)
+		}
+
+		lat, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid latitude: %w", err)
+		}
+
+		alt := 0.0
+		if len(parts) >= 3 {
+			alt, _ = strconv.ParseFloat(parts[2], 64)
+		}
+
+		coords = append(coords, Coordinate{
+			Lon: lon,
+			Lat: lat,
+			Alt: alt,
+		})
+	}
+
+	return coords, nil
+}
+
+// Attributes returns the placemark's ExtendedData as a name/value map. Both
+// untyped <Data> entries and <SchemaData>/<SimpleData> entries are included.
+func (p *Placemark) Attributes() map[string]string {
+	attributes := make(map[string]string)
+	if p.ExtendedData == nil {
+		return attributes
+	}
+
+	for _, data := range p.ExtendedData.Data {
+		attributes[data.Name] = strings.TrimSpace(data.Value)
+	}
+	for _, schemaData := range p.ExtendedData.SchemaData {
+		for _, simpleData := range schemaData.SimpleData {
+			attributes[simpleData.Name] = strings.TrimSpace(simpleData.Value)
+		}
+	}
+
+	return attributes
+}
+
 func (d *Document) AllPlacemarks() []Placemark {
 	var placemarks []Placemark
 	placemarks = append(placemarks, d.Placemarks...)
@@ -240,6 +340,7 @@ func (d *Document) AllPlacemarks() []Placemark {
 	for _, folder := range d.Folders {
 		placemarks = append(placemarks, folder.AllPlacemarks()...)
 	}
+	placemarks = append(placemarks, linkedPlacemarks(d.NetworkLinks)...)
 
 	return placemarks
 }
@@ -251,6 +352,64 @@ func (f *Folder) AllPlacemarks() []Placemark {
 	for _, subfolder := range f.Folders {
 		placemarks = append(placemarks, subfolder.AllPlacemarks()...)
 	}
+	placemarks = append(placemarks, linkedPlacemarks(f.NetworkLinks)...)
 
 	return placemarks
 }
+
+func linkedPlacemarks(links []NetworkLink) []Placemark {
+	var placemarks []Placemark
+	for _, link := range links {
+		if link.Target != nil && link.Target.Document != nil {
+			placemarks = append(placemarks, link.Target.Document.AllPlacemarks()...)
+		}
+	}
+	return placemarks
+}
+
+// WalkPlacemarks calls fn for every placemark in document order, along with
+// the names of the folders containing it from the outermost in. The
+// placemarks of resolved NetworkLinks follow those of the folder holding the
+// link, in a folder named after the link.
+func (d *Document) WalkPlacemarks(fn func(placemark *Placemark, folders []string)) {
+	d.walkPlacemarks(nil, fn)
+}
+
+func (d *Document) walkPlacemarks(parents []string, fn func(placemark *Placemark, folders []string)) {
+	for i := range d.Placemarks {
+		fn(&d.Placemarks[i], parents)
+	}
+	for i := range d.Folders {
+		d.Folders[i].walkPlacemarks(parents, fn)
+	}
+	walkLinkedPlacemarks(d.NetworkLinks, parents, fn)
+}
+
+func (f *Folder) walkPlacemarks(parents []string, fn func(placemark *Placemark, folders []string)) {
+	folders := append(parents[:len(parents):len(parents)], f.Name)
+	for i := range f.Placemarks {
+		fn(&f.Placemarks[i], folders)
+	}
+	for i := range f.Folders {
+		f.Folders[i].walkPlacemarks(folders, fn)
+	}
+	walkLinkedPlacemarks(f.NetworkLinks, folders, fn)
+}
+
+func walkLinkedPlacemarks(links []NetworkLink, parents []string, fn func(placemark *Placemark, folders []string)) {
+	for i := range links {
+		if links[i].Target != nil && links[i].Target.Document != nil {
+			links[i].Target.Document.walkPlacemarks(linkFolders(parents, &links[i]), fn)
+		}
+	}
+}
+
+// linkFolders returns the folder path of a linked file's placemarks: the
+// folders holding the link, then the link itself when it is named
+func linkFolders(parents []string, link *NetworkLink) []string {
+	folders := parents[:len(parents):len(parents)]
+	if link.Name != "" {
+		folders = append(folders, link.Name)
+	}
+	return folders
+}
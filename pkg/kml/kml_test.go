@@ -1,6 +1,7 @@
 package kml
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -246,6 +247,86 @@ func TestParse_MultiGeometry(t *testing.T) {
 	}
 }
 
+func TestParse_Tracks(t *testing.T) {
+	kmlData := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2" xmlns:gx="http://www.google.com/kml/ext/2.2">
+<Document>
+	<Placemark>
+		<name>Morning ride</name>
+		<gx:Track>
+			<when>2024-05-01T08:00:00Z</when>
+			<when>2024-05-01T08:01:00Z</when>
+			<gx:coord>13.3694 52.5251 34</gx:coord>
+			<gx:coord>13.3780 52.5230 35</gx:coord>
+			<gx:angles>0 0 0</gx:angles>
+		</gx:Track>
+	</Placemark>
+	<Placemark>
+		<name>Trip</name>
+		<gx:MultiTrack>
+			<gx:interpolate>1</gx:interpolate>
+			<gx:Track>
+				<gx:coord>10.0 53.0 0</gx:coord>
+				<gx:coord>10.1 53.1 0</gx:coord>
+			</gx:Track>
+			<gx:Track>
+				<gx:coord>10.2 53.2 0</gx:coord>
+			</gx:Track>
+		</gx:MultiTrack>
+	</Placemark>
+</Document>
+</kml>`
+
+	kml, err := Parse([]byte(kmlData))
+	if err != nil {
+		t.Fatalf("Failed to parse KML: %v", err)
+	}
+
+	track := kml.Document.Placemarks[0].Track
+	if track == nil {
+		t.Fatal("Track is nil")
+	}
+	if len(track.When) != 2 || len(track.Coords) != 2 {
+		t.Errorf("Expected 2 timestamps and 2 positions, got %d and %d", len(track.When), len(track.Coords))
+	}
+
+	coords, err := track.Coordinates()
+	if err != nil {
+		t.Fatalf("Coordinates returned error: %v", err)
+	}
+	if coords[1].Lon != 13.378 || coords[1].Lat != 52.523 || coords[1].Alt != 35 {
+		t.Errorf("Expected {13.378, 52.523, 35}, got %+v", coords[1])
+	}
+
+	multiTrack := kml.Document.Placemarks[1].MultiTrack
+	if multiTrack == nil {
+		t.Fatal("MultiTrack is nil")
+	}
+	if multiTrack.Interpolate != 1 || len(multiTrack.Tracks) != 2 {
+		t.Errorf("Expected 2 interpolated tracks, got %+v", multiTrack)
+	}
+}
+
+func TestTrack_Coordinates_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		coords []string
+	}{
+		{"comma separated", []string{"10.0,53.0,0"}},
+		{"invalid longitude", []string{"east 53.0 0"}},
+		{"invalid latitude", []string{"10.0 north 0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			track := &Track{Coords: tt.coords}
+			if _, err := track.Coordinates(); err == nil {
+				t.Errorf("Expected error for %q", tt.coords)
+			}
+		})
+	}
+}
+
 func TestParse_NestedMultiGeometry(t *testing.T) {
 	kmlData := `<?xml version="1.0" encoding="UTF-8"?>
 <kml xmlns="http://www.opengis.net/kml/2.2">
@@ -334,6 +415,59 @@ func TestParse_ExtendedData(t *testing.T) {
 	}
 }
 
+func TestPlacemark_Attributes(t *testing.T) {
+	kmlData := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+	<Placemark>
+		<name>Station</name>
+		<ExtendedData>
+			<Data name="Label">
+				<value> Custom Label </value>
+			</Data>
+			<SchemaData schemaUrl="#stations">
+				<SimpleData name="railway">station</SimpleData>
+				<SimpleData name="operator">DB</SimpleData>
+			</SchemaData>
+		</ExtendedData>
+		<Point>
+			<coordinates>10.0,53.0,0</coordinates>
+		</Point>
+	</Placemark>
+	<Placemark>
+		<name>No Data</name>
+		<Point>
+			<coordinates>10.0,53.0,0</coordinates>
+		</Point>
+	</Placemark>
+</Document>
+</kml>`
+
+	kml, err := Parse([]byte(kmlData))
+	if err != nil {
+		t.Fatalf("Failed to parse KML: %v", err)
+	}
+
+	attributes := kml.Document.Placemarks[0].Attributes()
+	expected := map[string]string{
+		"Label":    "Custom Label",
+		"railway":  "station",
+		"operator": "DB",
+	}
+	if len(attributes) != len(expected) {
+		t.Errorf("Expected %d attributes, got %d", len(expected), len(attributes))
+	}
+	for name, value := range expected {
+		if attributes[name] != value {
+			t.Errorf("Expected attribute %s to be '%s', got '%s'", name, value, attributes[name])
+		}
+	}
+
+	if empty := kml.Document.Placemarks[1].Attributes(); len(empty) != 0 {
+		t.Errorf("Expected no attributes for placemark without ExtendedData, got %d", len(empty))
+	}
+}
+
 func TestParse_InvalidKML(t *testing.T) {
 	invalidKML := `<?xml version="1.0" encoding="UTF-8"?>
 <kml xmlns="http://www.opengis.net/kml/2.2">
@@ -401,3 +535,39 @@ func TestAllPlacemarks_NestedFolders(t *testing.T) {
 		}
 	}
 }
+
+func TestDocument_WalkPlacemarks(t *testing.T) {
+	kmlData := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+	<Placemark><name>Root</name></Placemark>
+	<Folder>
+		<name>Network</name>
+		<Placemark><name>Line</name></Placemark>
+		<Folder>
+			<name>Stations</name>
+			<Placemark><name>Central</name></Placemark>
+		</Folder>
+		<Folder>
+			<name>Depots</name>
+			<Placemark><name>Yard</name></Placemark>
+		</Folder>
+	</Folder>
+</Document>
+</kml>`
+
+	kml, err := Parse([]byte(kmlData))
+	if err != nil {
+		t.Fatalf("Failed to parse KML: %v", err)
+	}
+
+	var visited []string
+	kml.Document.WalkPlacemarks(func(placemark *Placemark, folders []string) {
+		visited = append(visited, strings.Join(append(folders, placemark.Name), "/"))
+	})
+
+	expected := []string{"Root", "Network/Line", "Network/Stations/Central", "Network/Depots/Yard"}
+	if strings.Join(visited, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, visited)
+	}
+}
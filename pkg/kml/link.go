@@ -0,0 +1,433 @@
+package kml
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// source is a KML file on disk, or a file inside a KMZ archive on disk
+type source struct {
+	// path is the absolute path of the file or archive
+	path string
+	// entry is the name of the file inside the archive, or "" for a plain file
+	entry string
+}
+
+func (s source) String() string {
+	if s.entry == "" {
+		return s.path
+	}
+	return s.path + "/" + s.entry
+}
+
+// kmzArchive is an open KMZ archive with its files indexed by name
+type kmzArchive struct {
+	reader *zip.ReadCloser
+	files  map[string]*zip.File
+	// main is the name of the archive's main document
+	main string
+	// sheets holds the styles of the archive's KML files that have been
+	// streamed, so that they are not read again as resources
+	sheets map[string]*StyleSheet
+}
+
+// LinkScope limits the files on local disk that NetworkLinks are followed to.
+// Links to files inside the archive being read are always followed.
+type LinkScope int
+
+const (
+	// LinksAnywhere follows links to any file on local disk
+	LinksAnywhere LinkScope = iota
+	// LinksInDirectory only follows links to files in the directory of the
+	// file being read or below it, e.g. for files uploaded to a directory of
+	// their own
+	LinksInDirectory
+	// LinksInArchive follows no links to files on disk
+	LinksInArchive
+)
+
+// linkResolver reads KML files and the files their NetworkLinks point to.
+// Every file is read at most once, which both stops link cycles and keeps a
+// file linked from several places from being read twice.
+type linkResolver struct {
+	archives map[string]*kmzArchive
+	visited  map[source]bool
+	// trees holds the files parsed so far, which the Resources of an
+	// archive's main document are taken from
+	trees map[source]*KML
+	scope LinkScope
+	// root is the directory LinksInDirectory keeps links in
+	root string
+}
+
+func newLinkResolver(scope LinkScope) *linkResolver {
+	return &linkResolver{
+		archives: make(map[string]*kmzArchive),
+		visited:  make(map[source]bool),
+		trees:    make(map[source]*KML),
+		scope:    scope,
+	}
+}
+
+// start returns the source of the file a read starts from, whose directory
+// bounds the links followed under LinksInDirectory
+func (r *linkResolver) start(filePath string) (source, error) {
+	src, err := r.fileSource(filePath)
+	if err != nil {
+		return source{}, err
+	}
+	if r.root, err = filepath.EvalSymlinks(filepath.Dir(src.path)); err != nil {
+		return source{}, err
+	}
+	return src, nil
+}
+
+// allowed reports whether the scope permits following a link to a file on disk
+func (r *linkResolver) allowed(diskPath string) bool {
+	switch r.scope {
+	case LinksAnywhere:
+		return true
+	case LinksInDirectory:
+		// Symlinks are resolved so that they cannot lead out of the directory
+		realPath, err := filepath.EvalSymlinks(diskPath)
+		if err != nil {
+			return false
+		}
+		rel, err := filepath.Rel(r.root, realPath)
+		return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	default:
+		return false
+	}
+}
+
+func (r *linkResolver) close() {
+	for _, archive := range r.archives {
+		archive.reader.Close()
+	}
+}
+
+// fileSource returns the source for a .kml or .kmz file on disk
+func (r *linkResolver) fileSource(filePath string) (source, error) {
+	if strings.EqualFold(filepath.Ext(filePath), ".kmz") {
+		return r.archiveSource(filePath)
+	}
+
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return source{}, err
+	}
+	return source{path: absPath}, nil
+}
+
+// archiveSource opens a KMZ archive and returns the source of its main document
+func (r *linkResolver) archiveSource(filePath string) (source, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return source{}, err
+	}
+	archive, err := r.openArchive(absPath)
+	if err != nil {
+		return source{}, err
+	}
+	return source{path: absPath, entry: archive.main}, nil
+}
+
+func (r *linkResolver) openArchive(absPath string) (*kmzArchive, error) {
+	if archive, ok := r.archives[absPath]; ok {
+		return archive, nil
+	}
+
+	reader, err := zip.OpenReader(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &kmzArchive{
+		reader: reader,
+		files:  make(map[string]*zip.File, len(reader.File)),
+		sheets: make(map[string]*StyleSheet),
+	}
+	for _, file := range reader.File {
+		if !isKMLFile(file.Name) {
+			continue
+		}
+		archive.files[file.Name] = file
+		// By convention the main document is doc.kml, which Google Earth
+		// also falls back from to the first KML file
+		if archive.main == "" || (strings.EqualFold(file.Name, "doc.kml") && !strings.EqualFold(archive.main, "doc.kml")) {
+			archive.main = file.Name
+		}
+	}
+	if archive.main == "" {
+		reader.Close()
+		return nil, errors.New("no KML file found in KMZ archive")
+	}
+
+	r.archives[absPath] = archive
+	return archive, nil
+}
+
+func isKMLFile(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), ".kml")
+}
+
+func (r *linkResolver) open(src source) (io.ReadCloser, error) {
+	if src.entry == "" {
+		return os.Open(src.path)
+	}
+	file, ok := r.archives[src.path].files[src.entry]
+	if !ok {
+		return nil, fmt.Errorf("%s not found in KMZ archive", src.entry)
+	}
+	return file.Open()
+}
+
+// resolve returns the file a NetworkLink href points to, relative to the file
+// holding the link. Files inside the same archive take precedence over files
+// on disk, as in Google Earth. It returns false for links to the web, to files
+// that do not exist or are outside the resolver's scope and to files that have
+// already been read.
+func (r *linkResolver) resolve(from source, href string) (source, bool) {
+	// Fragments address features inside the linked file, which is read whole
+	href, _, _ = strings.Cut(strings.TrimSpace(href), "#")
+	if href == "" {
+		return source{}, false
+	}
+	if u, err := url.Parse(href); err == nil && u.Scheme != "" {
+		switch {
+		case u.Scheme == "file":
+			href = u.Path
+		case len(u.Scheme) == 1:
+			// A Windows drive letter rather than a scheme
+		default:
+			return source{}, false
+		}
+	}
+
+	if from.entry != "" && !filepath.IsAbs(href) {
+		name := path.Join(path.Dir(from.entry), filepath.ToSlash(href))
+		if _, ok := r.archives[from.path].files[name]; ok {
+			target := source{path: from.path, entry: name}
+			return target, !r.visited[target]
+		}
+		// Outside the archive, paths are relative to the folder holding it,
+		// which "../" at the archive root leads to
+		href = strings.TrimPrefix(name, "../")
+	}
+
+	diskPath := filepath.FromSlash(href)
+	if !filepath.IsAbs(diskPath) {
+		diskPath = filepath.Join(filepath.Dir(from.path), diskPath)
+	}
+	if info, err := os.Stat(diskPath); err != nil || info.IsDir() || !r.allowed(diskPath) {
+		return source{}, false
+	}
+	target, err := r.fileSource(diskPath)
+	if err != nil {
+		return source{}, false
+	}
+	return target, !r.visited[target]
+}
+
+// parse reads a file and the files its NetworkLinks point to into a tree
+func (r *linkResolver) parse(src source) (*KML, error) {
+	r.visited[src] = true
+
+	// The file is decoded straight from the disk or the archive, without
+	// reading it into memory first
+	rc, err := r.open(src)
+	if err != nil {
+		return nil, err
+	}
+	var k KML
+	err = xml.NewDecoder(rc).Decode(&k)
+	rc.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.trees[src] = &k
+
+	main := src.entry != "" && src.entry == r.archives[src.path].main
+	if k.Document != nil {
+		if err := r.parseLinks(src, k.Document.NetworkLinks); err != nil {
+			return nil, err
+		}
+		if err := r.parseFolderLinks(src, k.Document.Folders); err != nil {
+			return nil, err
+		}
+		if main {
+			if err := r.parseUnlinked(src, k.Document); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if main {
+		r.parseResources(src, &k)
+	}
+	return &k, nil
+}
+
+// parseUnlinked reads the archive's KML files that no NetworkLink led to,
+// adding each to the main document as a resolved link named after the file
+func (r *linkResolver) parseUnlinked(main source, doc *Document) error {
+	archive := r.archives[main.path]
+	for _, name := range slices.Sorted(maps.Keys(archive.files)) {
+		target := source{path: main.path, entry: name}
+		if r.visited[target] {
+			continue
+		}
+		linked, err := r.parse(target)
+		if err != nil {
+			return fmt.Errorf("%s: %w", target, err)
+		}
+		doc.NetworkLinks = append(doc.NetworkLinks, NetworkLink{
+			Name:   unlinkedFolder(name),
+			Link:   &Link{Href: resourceKey(main.entry, name)},
+			Target: linked,
+		})
+	}
+	return nil
+}
+
+// unlinkedFolder returns the folder the placemarks of an archive file no
+// NetworkLink points to are put in: the file name without its extension
+func unlinkedFolder(name string) string {
+	base := path.Base(name)
+	return strings.TrimSuffix(base, path.Ext(base))
+}
+
+// parseResources adds the archive's other KML files to the main document's
+// Resources, where their shared styles are looked up. Every file of the
+// archive has been parsed by now, through a NetworkLink or parseUnlinked, so
+// the same trees are used rather than reading the files again.
+func (r *linkResolver) parseResources(main source, k *KML) {
+	for name := range r.archives[main.path].files {
+		resource, ok := r.trees[source{path: main.path, entry: name}]
+		if name == main.entry || !ok {
+			continue
+		}
+		if k.Resources == nil {
+			k.Resources = make(map[string]*KML)
+		}
+		k.Resources[resourceKey(main.entry, name)] = resource
+	}
+}
+
+func (r *linkResolver) parseFolderLinks(src source, folders []Folder) error {
+	for i := range folders {
+		if err := r.parseLinks(src, folders[i].NetworkLinks); err != nil {
+			return err
+		}
+		if err := r.parseFolderLinks(src, folders[i].Folders); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *linkResolver) parseLinks(src source, links []NetworkLink) error {
+	for i := range links {
+		target, ok := r.resolve(src, links[i].Href())
+		if !ok {
+			continue
+		}
+		linked, err := r.parse(target)
+		if err != nil {
+			return fmt.Errorf("NetworkLink %s: %w", target, err)
+		}
+		links[i].Target = linked
+	}
+	return nil
+}
+
+// stream calls fn for every placemark of a file and, in place of each
+// NetworkLink, of the file it points to. The main document of an archive is
+// followed by the archive's files that no link led to, as by parseUnlinked.
+// parents holds the folders that the file's own folders are nested in.
+func (r *linkResolver) stream(src source, parents []string, fn PlacemarkFunc) error {
+	r.visited[src] = true
+
+	rc, err := r.open(src)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	decoder := NewDecoder(rc)
+	decoder.HandleNetworkLink = func(link *NetworkLink, folders []string) error {
+		target, ok := r.resolve(src, link.Href())
+		if !ok {
+			return nil
+		}
+		if err := r.stream(target, linkFolders(append(parents[:len(parents):len(parents)], folders...), link), fn); err != nil {
+			return fmt.Errorf("NetworkLink %s: %w", target, err)
+		}
+		return nil
+	}
+
+	var nested []string
+	for {
+		placemark, folders, err := decoder.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		nested = append(append(nested[:0], parents...), folders...)
+		if err := fn(placemark, nested, decoder.Styles()); err != nil {
+			return err
+		}
+	}
+
+	if src.entry == "" {
+		return nil
+	}
+	archive := r.archives[src.path]
+	archive.sheets[src.entry] = decoder.Styles()
+	if src.entry != archive.main {
+		return nil
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(archive.files)) {
+		target := source{path: src.path, entry: name}
+		if r.visited[target] {
+			continue
+		}
+		if err := r.stream(target, append(parents[:len(parents):len(parents)], unlinkedFolder(name)), fn); err != nil {
+			return fmt.Errorf("%s: %w", target, err)
+		}
+	}
+	r.streamResources(src, decoder.Styles())
+	return nil
+}
+
+// streamResources adds the styles of the archive's other KML files, all of
+// which have been streamed by now, to the main document's style sheet
+func (r *linkResolver) streamResources(main source, styles *StyleSheet) {
+	archive := r.archives[main.path]
+	for name, sheet := range archive.sheets {
+		if name != main.entry {
+			styles.resources[resourceKey(main.entry, name)] = sheet
+		}
+	}
+}
+
+// resourceKey returns the name of an archive file relative to the main
+// document, as styleUrls refer to it
+func resourceKey(main, name string) string {
+	if dir := path.Dir(main); dir != "." {
+		return strings.TrimPrefix(name, dir+"/")
+	}
+	return name
+}
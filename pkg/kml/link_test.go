@@ -0,0 +1,312 @@
+package kml
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type zipEntry struct{ name, content string }
+
+func writeTestKMZ(t *testing.T, filePath string, entries ...zipEntry) {
+	t.Helper()
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	for _, entry := range entries {
+		w, err := archive.Create(entry.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testDocument(body string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2"><Document>` + body + `</Document></kml>`
+}
+
+func testLink(name, href string) string {
+	return `<NetworkLink><name>` + name + `</name><Link><href>` + href + `</href></Link></NetworkLink>`
+}
+
+// streamNames returns the folder path and name of every streamed placemark
+func streamNames(t *testing.T, filePath string) []string {
+	t.Helper()
+
+	var names []string
+	err := StreamFile(filePath, func(placemark *Placemark, folders []string, _ *StyleSheet) error {
+		names = append(names, strings.Join(append(folders[:len(folders):len(folders)], placemark.Name), "/"))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamFile returned error: %v", err)
+	}
+	return names
+}
+
+// walkNames returns the folder path and name of every placemark of a parsed file
+func walkNames(t *testing.T, filePath string) []string {
+	t.Helper()
+
+	k, err := ParseFile(filePath)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	var names []string
+	k.Document.WalkPlacemarks(func(placemark *Placemark, folders []string) {
+		names = append(names, strings.Join(append(folders[:len(folders):len(folders)], placemark.Name), "/"))
+	})
+	return names
+}
+
+func TestParseKMZ_PrefersDocKML(t *testing.T) {
+	kmzPath := filepath.Join(t.TempDir(), "layers.kmz")
+	writeTestKMZ(t, kmzPath,
+		zipEntry{"layers/stations.kml", testDocument(`<Placemark><name>Station</name></Placemark>`)},
+		zipEntry{"doc.kml", testDocument(`<Placemark><name>Main</name></Placemark>`)},
+	)
+
+	k, err := ParseKMZ(kmzPath)
+	if err != nil {
+		t.Fatalf("ParseKMZ returned error: %v", err)
+	}
+	if len(k.Document.Placemarks) != 1 || k.Document.Placemarks[0].Name != "Main" {
+		t.Errorf("Expected doc.kml as the main document, got %+v", k.Document.Placemarks)
+	}
+	resource, ok := k.Resources["layers/stations.kml"]
+	if !ok {
+		t.Errorf("Expected the other KML file in Resources, got %v", k.Resources)
+	}
+	// The file is parsed once, for both its placemarks and its styles
+	if links := k.Document.NetworkLinks; len(links) != 1 || links[0].Target != resource {
+		t.Errorf("Expected the unlinked file's tree to be shared with Resources, got %+v", links)
+	}
+
+	if names := streamNames(t, kmzPath); strings.Join(names, ",") != "Main,stations/Station" {
+		t.Errorf("Expected StreamFile to read doc.kml first, got %v", names)
+	}
+}
+
+func TestUnlinkedFiles(t *testing.T) {
+	// files/north.kml is linked, the other two files are not, and
+	// files/south.kml links to the main document and to files/west.kml
+	kmzPath := filepath.Join(t.TempDir(), "layers.kmz")
+	writeTestKMZ(t, kmzPath,
+		zipEntry{"doc.kml", testDocument(
+			`<Placemark><name>Main</name></Placemark>` + testLink("North", "files/north.kml"),
+		)},
+		zipEntry{"files/south.kml", testDocument(
+			`<Style id="blue"><LineStyle><color>ffff0000</color></LineStyle></Style>` +
+				`<Folder><name>S-Bahn</name><Placemark><name>S2</name><styleUrl>#blue</styleUrl></Placemark></Folder>` +
+				testLink("Back", "../doc.kml") + testLink("West", "west.kml"),
+		)},
+		zipEntry{"files/north.kml", testDocument(`<Placemark><name>S1</name></Placemark>`)},
+		zipEntry{"files/west.kml", testDocument(`<Placemark><name>S3</name></Placemark>`)},
+	)
+
+	expected := "Main,North/S1,south/S-Bahn/S2,south/West/S3"
+	if names := streamNames(t, kmzPath); strings.Join(names, ",") != expected {
+		t.Errorf("StreamFile: expected %s, got %s", expected, strings.Join(names, ","))
+	}
+	if names := walkNames(t, kmzPath); strings.Join(names, ",") != expected {
+		t.Errorf("ParseFile: expected %s, got %s", expected, strings.Join(names, ","))
+	}
+
+	// Unlinked files keep their own styles
+	var style *Style
+	err := StreamFile(kmzPath, func(placemark *Placemark, _ []string, styles *StyleSheet) error {
+		if placemark.Name == "S2" {
+			style = styles.PlacemarkStyle(placemark)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamFile returned error: %v", err)
+	}
+	if style == nil || style.LineStyle == nil || style.LineStyle.Color != "ffff0000" {
+		t.Errorf("Expected the unlinked file's style, got %+v", style)
+	}
+}
+
+func TestNetworkLinks(t *testing.T) {
+	dir := t.TempDir()
+
+	// The archive links to a file inside it, to a KML and a KMZ next to it on
+	// disk, to the web and to itself
+	kmzPath := filepath.Join(dir, "network.kmz")
+	writeTestKMZ(t, kmzPath,
+		zipEntry{"doc.kml", testDocument(
+			`<Placemark><name>Main</name></Placemark>` +
+				`<Folder><name>Lines</name>` +
+				testLink("North", "files/north.kml") +
+				testLink("Web", "https://example.com/live.kml") +
+				`</Folder>` +
+				testLink("Depots", "../depots.kml") +
+				testLink("Self", "doc.kml"),
+		)},
+		zipEntry{"files/north.kml", testDocument(
+			`<Folder><name>S-Bahn</name><Placemark><name>S1</name></Placemark></Folder>` +
+				testLink("", "south.kml"),
+		)},
+		zipEntry{"files/south.kml", testDocument(`<Placemark><name>S2</name></Placemark>`)},
+	)
+
+	// depots.kml links to a KMZ and back to the archive, closing a cycle
+	if err := os.MkdirAll(filepath.Join(dir, "more"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestKMZ(t, filepath.Join(dir, "more", "yards.kmz"),
+		zipEntry{"doc.kml", testDocument(`<Placemark><name>Yard</name></Placemark>`)},
+	)
+	depots := testDocument(
+		`<Placemark><name>Depot</name></Placemark>` +
+			testLink("Yards", "more/yards.kmz") +
+			`<NetworkLink><name>Back</name><Url><href>file://` + filepath.ToSlash(kmzPath) + `</href></Url></NetworkLink>`,
+	)
+	if err := os.WriteFile(filepath.Join(dir, "depots.kml"), []byte(depots), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "Main,Lines/North/S-Bahn/S1,Lines/North/S2,Depots/Depot,Depots/Yards/Yard"
+	if names := streamNames(t, kmzPath); strings.Join(names, ",") != expected {
+		t.Errorf("StreamFile: expected %s, got %s", expected, strings.Join(names, ","))
+	}
+	if names := walkNames(t, kmzPath); strings.Join(names, ",") != expected {
+		t.Errorf("ParseFile: expected %s, got %s", expected, strings.Join(names, ","))
+	}
+}
+
+func TestNetworkLinks_Styles(t *testing.T) {
+	kmzPath := filepath.Join(t.TempDir(), "styled.kmz")
+	writeTestKMZ(t, kmzPath,
+		zipEntry{"doc.kml", testDocument(
+			`<Style id="red"><LineStyle><color>ff0000ff</color></LineStyle></Style>` +
+				testLink("Linked", "linked.kml"),
+		)},
+		zipEntry{"linked.kml", testDocument(
+			`<Placemark><name>Route</name><styleUrl>#green</styleUrl></Placemark>` +
+				`<Style id="green"><LineStyle><color>ff00ff00</color></LineStyle></Style>`,
+		)},
+	)
+
+	var placemarks []Placemark
+	var sheets []*StyleSheet
+	err := StreamFile(kmzPath, func(placemark *Placemark, _ []string, styles *StyleSheet) error {
+		placemarks = append(placemarks, *placemark)
+		sheets = append(sheets, styles)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamFile returned error: %v", err)
+	}
+	if len(placemarks) != 1 {
+		t.Fatalf("Expected 1 placemark, got %d", len(placemarks))
+	}
+
+	// Linked placemarks resolve styles in their own document
+	style := sheets[0].PlacemarkStyle(&placemarks[0])
+	if style == nil || style.LineStyle == nil || style.LineStyle.Color != "ff00ff00" {
+		t.Errorf("Expected the linked document's style, got %+v", style)
+	}
+}
+
+func TestNetworkLinks_BrokenTarget(t *testing.T) {
+	dir := t.TempDir()
+	mainPath := filepath.Join(dir, "main.kml")
+	main := testDocument(`<Placemark><name>Main</name></Placemark>` + testLink("Missing", "missing.kml") + testLink("Broken", "broken.kml"))
+	if err := os.WriteFile(mainPath, []byte(main), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.kml"), []byte("<kml><Document>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Missing files are skipped, but a linked file that cannot be parsed is an error
+	err := StreamFile(mainPath, func(*Placemark, []string, *StyleSheet) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "broken.kml") {
+		t.Errorf("Expected an error naming broken.kml, got %v", err)
+	}
+	if _, err := ParseFile(mainPath); err == nil {
+		t.Error("Expected ParseFile to fail on broken.kml")
+	}
+}
+
+func TestNetworkLinks_Scope(t *testing.T) {
+	dir := t.TempDir()
+	secretPath := filepath.Join(dir, "secret.kml")
+	if err := os.WriteFile(secretPath, []byte(testDocument(`<Placemark><name>Secret</name></Placemark>`)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// An upload directory holding a file that links to its neighbour and, in
+	// several ways, to a file outside the directory
+	upload := filepath.Join(dir, "upload")
+	if err := os.MkdirAll(upload, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(upload, "local.kml"), []byte(testDocument(`<Placemark><name>L</name></Placemark>`)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secretPath, filepath.Join(upload, "alias.kml")); err != nil {
+		t.Fatal(err)
+	}
+	mainPath := filepath.Join(upload, "main.kml")
+	main := testDocument(`<Placemark><name>Main</name></Placemark>` +
+		testLink("Local", "local.kml") +
+		testLink("Absolute", secretPath) +
+		testLink("URL", "file://"+filepath.ToSlash(secretPath)) +
+		testLink("Parent", "../secret.kml") +
+		testLink("Alias", "alias.kml"))
+	if err := os.WriteFile(mainPath, []byte(main), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		scope    LinkScope
+		expected string
+	}{
+		{scope: LinksAnywhere, expected: "Main,Local/L,Absolute/Secret,Alias/Secret"},
+		{scope: LinksInDirectory, expected: "Main,Local/L"},
+		{scope: LinksInArchive, expected: "Main"},
+	}
+
+	for _, tt := range tests {
+		var names []string
+		err := StreamFileWithLinks(mainPath, tt.scope, func(placemark *Placemark, folders []string, _ *StyleSheet) error {
+			names = append(names, strings.Join(append(folders[:len(folders):len(folders)], placemark.Name), "/"))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("StreamFileWithLinks returned error: %v", err)
+		}
+		if strings.Join(names, ",") != tt.expected {
+			t.Errorf("Scope %d: StreamFileWithLinks expected %s, got %s", tt.scope, tt.expected, strings.Join(names, ","))
+		}
+
+		k, err := ParseFileWithLinks(mainPath, tt.scope)
+		if err != nil {
+			t.Fatalf("ParseFileWithLinks returned error: %v", err)
+		}
+		names = names[:0]
+		k.Document.WalkPlacemarks(func(placemark *Placemark, folders []string) {
+			names = append(names, strings.Join(append(folders[:len(folders):len(folders)], placemark.Name), "/"))
+		})
+		if strings.Join(names, ",") != tt.expected {
+			t.Errorf("Scope %d: ParseFileWithLinks expected %s, got %s", tt.scope, tt.expected, strings.Join(names, ","))
+		}
+	}
+}
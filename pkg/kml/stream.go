@@ -0,0 +1,163 @@
+package kml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Decoder reads a KML document one placemark at a time, so that only the
+// current placemark is held in memory rather than the whole document. Shared
+// styles are collected as they are passed.
+type Decoder struct {
+	decoder *xml.Decoder
+	// elements holds the local names of the open containers, outermost first
+	elements []string
+	// folders holds the names of the open folders, outermost first
+	folders []string
+	styles  *StyleSheet
+	// root is set once the <kml> element has been read
+	root bool
+
+	// HandleNetworkLink, when set, is called with every NetworkLink in
+	// document order, along with the folders containing it. An error stops
+	// the decoder and is returned by Next.
+	HandleNetworkLink func(link *NetworkLink, folders []string) error
+}
+
+// PlacemarkFunc is called with each placemark of a streamed file, the names of
+// the folders containing it and the shared styles of the document it is in.
+// The style sheet is only complete once the whole file has been read.
+type PlacemarkFunc func(placemark *Placemark, folders []string, styles *StyleSheet) error
+
+// NewDecoder returns a Decoder reading KML from r. Elements are matched by
+// their local name, so KML 2.2, 2.3 and unqualified documents are all read.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		decoder: xml.NewDecoder(r),
+		styles:  newStyleSheet(),
+	}
+}
+
+// Next returns the next placemark in document order, along with the names of
+// the folders containing it from the outermost in. It returns io.EOF after the
+// last placemark. The folders slice is only valid until the next call.
+func (d *Decoder) Next() (*Placemark, []string, error) {
+	for {
+		token, err := d.decoder.Token()
+		if err != nil {
+			// The decoder reports documents that end early as syntax errors
+			if errors.Is(err, io.EOF) && !d.root {
+				return nil, nil, errors.New("no <kml> element found")
+			}
+			return nil, nil, err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			placemark, err := d.start(element)
+			if err != nil {
+				return nil, nil, err
+			}
+			if placemark != nil {
+				return placemark, d.folders, nil
+			}
+		case xml.EndElement:
+			if len(d.elements) == 0 {
+				continue
+			}
+			if d.elements[len(d.elements)-1] == "Folder" {
+				d.folders = d.folders[:len(d.folders)-1]
+			}
+			d.elements = d.elements[:len(d.elements)-1]
+		}
+	}
+}
+
+// start handles an element opening, decoding placemarks and styles whole and
+// skipping everything that cannot contain them
+func (d *Decoder) start(element xml.StartElement) (*Placemark, error) {
+	if !d.root {
+		if element.Name.Local != "kml" {
+			return nil, fmt.Errorf("expected element type <kml> but have <%s>", element.Name.Local)
+		}
+		d.root = true
+	}
+
+	switch element.Name.Local {
+	case "kml", "Document":
+		d.elements = append(d.elements, element.Name.Local)
+	case "Folder":
+		d.elements = append(d.elements, element.Name.Local)
+		d.folders = append(d.folders, "")
+	case "name":
+		if len(d.elements) == 0 || d.elements[len(d.elements)-1] != "Folder" {
+			return nil, d.decoder.Skip()
+		}
+		var name string
+		if err := d.decoder.DecodeElement(&name, &element); err != nil {
+			return nil, err
+		}
+		d.folders[len(d.folders)-1] = name
+	case "Placemark":
+		var placemark Placemark
+		if err := d.decoder.DecodeElement(&placemark, &element); err != nil {
+			return nil, err
+		}
+		return &placemark, nil
+	case "Style":
+		var style Style
+		if err := d.decoder.DecodeElement(&style, &element); err != nil {
+			return nil, err
+		}
+		d.styles.addStyle(&style)
+	case "NetworkLink":
+		var link NetworkLink
+		if err := d.decoder.DecodeElement(&link, &element); err != nil {
+			return nil, err
+		}
+		if d.HandleNetworkLink != nil {
+			return nil, d.HandleNetworkLink(&link, d.folders)
+		}
+	case "StyleMap":
+		var styleMap StyleMap
+		if err := d.decoder.DecodeElement(&styleMap, &element); err != nil {
+			return nil, err
+		}
+		d.styles.addStyleMap(&styleMap)
+	default:
+		return nil, d.decoder.Skip()
+	}
+	return nil, nil
+}
+
+// Styles returns the shared styles read so far. Styles may follow the
+// placemarks using them, so they are only complete once Next returns io.EOF.
+func (d *Decoder) Styles() *StyleSheet {
+	return d.styles
+}
+
+// StreamFile calls fn for every placemark of a .kml or .kmz file in document
+// order, reading the file incrementally; see Decoder. The placemarks of the
+// files that NetworkLinks point to are streamed in place of the links, as
+// resolved by ParseFile, in a folder named after the link. The placemarks of
+// a KMZ archive's files that no link points to follow those of its main
+// document, in a folder named after the file. An error returned by fn stops
+// the stream and is returned.
+func StreamFile(filePath string, fn PlacemarkFunc) error {
+	return StreamFileWithLinks(filePath, LinksAnywhere, fn)
+}
+
+// StreamFileWithLinks streams a .kml or .kmz file as StreamFile, only
+// following NetworkLinks to files on disk that scope permits
+func StreamFileWithLinks(filePath string, scope LinkScope, fn PlacemarkFunc) error {
+	resolver := newLinkResolver(scope)
+	defer resolver.close()
+
+	src, err := resolver.start(filePath)
+	if err != nil {
+		return err
+	}
+	return resolver.stream(src, nil, fn)
+}
@@ -0,0 +1,143 @@
+package kml
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const streamTestKML = `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+	<name>Network</name>
+	<Schema id="lines"><SimpleField name="operator" type="string"/></Schema>
+	<Placemark>
+		<name>Depot</name>
+		<styleUrl>#late</styleUrl>
+		<Point><coordinates>10,53</coordinates></Point>
+	</Placemark>
+	<Folder>
+		<name>Lines</name>
+		<Folder>
+			<name>S-Bahn</name>
+			<Placemark><name>S1</name></Placemark>
+		</Folder>
+		<Placemark><name>RE1</name></Placemark>
+	</Folder>
+	<Placemark><name>Yard</name></Placemark>
+	<Style id="late"><IconStyle><color>ff0000ff</color></IconStyle></Style>
+</Document>
+</kml>`
+
+func TestDecoder_Next(t *testing.T) {
+	decoder := NewDecoder(strings.NewReader(streamTestKML))
+
+	expected := []struct {
+		name    string
+		folders string
+	}{
+		{"Depot", ""},
+		{"S1", "Lines/S-Bahn"},
+		{"RE1", "Lines"},
+		{"Yard", ""},
+	}
+	for i, want := range expected {
+		placemark, folders, err := decoder.Next()
+		if err != nil {
+			t.Fatalf("Placemark %d: Next returned error: %v", i, err)
+		}
+		if placemark.Name != want.name || strings.Join(folders, "/") != want.folders {
+			t.Errorf("Placemark %d: expected %s in %q, got %s in %q", i, want.name, want.folders, placemark.Name, strings.Join(folders, "/"))
+		}
+	}
+
+	if _, _, err := decoder.Next(); !errors.Is(err, io.EOF) {
+		t.Fatalf("Expected io.EOF after the last placemark, got %v", err)
+	}
+
+	// The style after the placemark using it is known once the document is read
+	style := decoder.Styles().Resolve("#late")
+	if style == nil || style.IconStyle == nil || style.IconStyle.Color != "ff0000ff" {
+		t.Errorf("Expected the trailing style to resolve, got %+v", style)
+	}
+}
+
+func TestDecoder_Errors(t *testing.T) {
+	tests := map[string]string{
+		"empty":       "",
+		"not KML":     `<?xml version="1.0"?><gpx><trk/></gpx>`,
+		"truncated":   `<kml><Document><Placemark><name>A</name></Placemark>`,
+		"bad nesting": `<kml><Document><Placemark><name>A</Placemark></Document></kml>`,
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			decoder := NewDecoder(strings.NewReader(content))
+			for {
+				_, _, err := decoder.Next()
+				if errors.Is(err, io.EOF) {
+					t.Fatal("Expected error, got io.EOF")
+				}
+				if err != nil {
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestStreamFile_KMZ(t *testing.T) {
+	k := &KML{
+		Document: &Document{
+			Folders: []Folder{{
+				Name:       "Routes",
+				Placemarks: []Placemark{{Name: "Route", StyleURL: "styles/shared.kml#route"}},
+			}},
+		},
+		Resources: map[string]*KML{
+			"styles/shared.kml": {Document: &Document{Styles: []Style{{ID: "route", LineStyle: &LineStyle{Color: "ff3366cc"}}}}},
+		},
+	}
+	kmzPath := filepath.Join(t.TempDir(), "routes.kmz")
+	if err := WriteKMZ(kmzPath, k); err != nil {
+		t.Fatalf("WriteKMZ returned error: %v", err)
+	}
+
+	var names []string
+	var styles *StyleSheet
+	err := StreamFile(kmzPath, func(placemark *Placemark, folders []string, documentStyles *StyleSheet) error {
+		names = append(names, strings.Join(folders, "/")+"/"+placemark.Name)
+		styles = documentStyles
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamFile returned error: %v", err)
+	}
+	if len(names) != 1 || names[0] != "Routes/Route" {
+		t.Errorf("Expected Routes/Route, got %v", names)
+	}
+	style := styles.Resolve("styles/shared.kml#route")
+	if style == nil || style.LineStyle == nil || style.LineStyle.Color != "ff3366cc" {
+		t.Errorf("Expected the shared style to resolve from the archive, got %+v", style)
+	}
+}
+
+func TestStreamFile_StopsOnError(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "network.kml")
+	if err := os.WriteFile(filePath, []byte(streamTestKML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := errors.New("stop")
+	count := 0
+	err := StreamFile(filePath, func(*Placemark, []string, *StyleSheet) error {
+		count++
+		return stop
+	})
+	if !errors.Is(err, stop) || count != 1 {
+		t.Errorf("Expected the stream to stop after the first placemark, got %d placemarks and error %v", count, err)
+	}
+}
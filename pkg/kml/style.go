@@ -0,0 +1,188 @@
+package kml
+
+import (
+	"path"
+	"strconv"
+	"strings"
+)
+
+// maxStyleDepth limits how many styleUrl indirections are followed, guarding
+// against StyleMaps that reference each other
+const maxStyleDepth = 8
+
+// StyleSheet indexes the shared styles of a KML file so that placemark
+// styleUrls can be resolved
+type StyleSheet struct {
+	styles    map[string]*Style
+	styleMaps map[string]*StyleMap
+	resources map[string]*StyleSheet
+}
+
+// NewStyleSheet indexes the Styles and StyleMaps of the document and its
+// folders, along with those of any other KML files in a KMZ archive
+func NewStyleSheet(k *KML) *StyleSheet {
+	sheet := newStyleSheet()
+
+	if k.Document != nil {
+		sheet.add(k.Document.Styles, k.Document.StyleMaps)
+		for i := range k.Document.Folders {
+			sheet.addFolder(&k.Document.Folders[i])
+		}
+	}
+	for name, resource := range k.Resources {
+		sheet.resources[name] = NewStyleSheet(resource)
+	}
+
+	return sheet
+}
+
+func newStyleSheet() *StyleSheet {
+	return &StyleSheet{
+		styles:    make(map[string]*Style),
+		styleMaps: make(map[string]*StyleMap),
+		resources: make(map[string]*StyleSheet),
+	}
+}
+
+func (s *StyleSheet) add(styles []Style, styleMaps []StyleMap) {
+	for i := range styles {
+		s.addStyle(&styles[i])
+	}
+	for i := range styleMaps {
+		s.addStyleMap(&styleMaps[i])
+	}
+}
+
+func (s *StyleSheet) addStyle(style *Style) {
+	if style.ID != "" {
+		s.styles[style.ID] = style
+	}
+}
+
+func (s *StyleSheet) addStyleMap(styleMap *StyleMap) {
+	if styleMap.ID != "" {
+		s.styleMaps[styleMap.ID] = styleMap
+	}
+}
+
+func (s *StyleSheet) addFolder(folder *Folder) {
+	s.add(folder.Styles, folder.StyleMaps)
+	for i := range folder.Folders {
+		s.addFolder(&folder.Folders[i])
+	}
+}
+
+// Resolve returns the style a styleUrl points to. Both local references
+// ("#id") and references into other files of a KMZ ("styles.kml#id") are
+// supported; StyleMaps resolve to their "normal" style. Unknown references
+// return nil.
+func (s *StyleSheet) Resolve(styleURL string) *Style {
+	return s.resolve(styleURL, 0)
+}
+
+func (s *StyleSheet) resolve(styleURL string, depth int) *Style {
+	if depth > maxStyleDepth {
+		return nil
+	}
+
+	file, id, found := strings.Cut(strings.TrimSpace(styleURL), "#")
+	if !found {
+		// A bare id is not valid KML, but some writers omit the '#'
+		file, id = "", file
+	}
+
+	sheet := s
+	if file != "" {
+		sheet = s.resources[path.Clean(file)]
+		if sheet == nil {
+			return nil
+		}
+	}
+
+	if style, ok := sheet.styles[id]; ok {
+		return style
+	}
+	if styleMap, ok := sheet.styleMaps[id]; ok {
+		return sheet.resolveStyleMap(styleMap, depth)
+	}
+	return nil
+}
+
+func (s *StyleSheet) resolveStyleMap(styleMap *StyleMap, depth int) *Style {
+	for i := range styleMap.Pairs {
+		pair := &styleMap.Pairs[i]
+		if strings.TrimSpace(pair.Key) != "normal" {
+			continue
+		}
+		if pair.Style != nil {
+			return pair.Style
+		}
+		return s.resolve(pair.StyleURL, depth+1)
+	}
+	return nil
+}
+
+// PlacemarkStyle returns the effective style of a placemark: its shared style
+// with any inline <Style> sub-styles taking precedence. Returns nil when the
+// placemark has no style at all.
+func (s *StyleSheet) PlacemarkStyle(placemark *Placemark) *Style {
+	var shared *Style
+	if placemark.StyleURL != "" {
+		shared = s.Resolve(placemark.StyleURL)
+	}
+
+	inline := placemark.Style
+	switch {
+	case inline == nil:
+		return shared
+	case shared == nil:
+		return inline
+	}
+
+	merged := *shared
+	if inline.IconStyle != nil {
+		merged.IconStyle = inline.IconStyle
+	}
+	if inline.LineStyle != nil {
+		merged.LineStyle = inline.LineStyle
+	}
+	if inline.PolyStyle != nil {
+		merged.PolyStyle = inline.PolyStyle
+	}
+	return &merged
+}
+
+// ColorToRGB converts a KML color, which is written as aabbggrr, to rrggbb.
+// It returns false for colors that cannot be parsed.
+func ColorToRGB(color string) (string, bool) {
+	color = strings.TrimPrefix(strings.TrimSpace(color), "#")
+
+	if len(color) != 8 && len(color) != 6 {
+		return "", false
+	}
+	if _, err := strconv.ParseUint(color, 16, 32); err != nil {
+		return "", false
+	}
+
+	// Drop the alpha channel; a six digit color is tolerated without one
+	color = color[len(color)-6:]
+
+	color = strings.ToLower(color)
+	return color[4:6] + color[2:4] + color[0:2], true
+}
+
+// RGBToColor converts an rrggbb color to an opaque KML color (aabbggrr). It
+// returns false for colors that cannot be parsed.
+func RGBToColor(rgb string) (string, bool) {
+	rgb = strings.TrimPrefix(strings.TrimSpace(rgb), "#")
+
+	if len(rgb) != 6 {
+		return "", false
+	}
+	if _, err := strconv.ParseUint(rgb, 16, 32); err != nil {
+		return "", false
+	}
+
+	rgb = strings.ToLower(rgb)
+	return "ff" + rgb[4:6] + rgb[2:4] + rgb[0:2], true
+}
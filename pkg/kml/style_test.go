@@ -0,0 +1,233 @@
+package kml
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestColorToRGB(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		ok       bool
+	}{
+		{input: "ff0000ff", expected: "ff0000", ok: true},
+		{input: "7f00ff00", expected: "00ff00", ok: true},
+		{input: "FFD18802", expected: "0288d1", ok: true},
+		{input: " #ff112233 ", expected: "332211", ok: true},
+		{input: "112233", expected: "332211", ok: true},
+		{input: "", ok: false},
+		{input: "ff00", ok: false},
+		{input: "zz0000ff", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, ok := ColorToRGB(tt.input)
+			if ok != tt.ok || result != tt.expected {
+				t.Errorf("ColorToRGB(%q) = (%q, %v), want (%q, %v)", tt.input, result, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestRGBToColor(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		ok       bool
+	}{
+		{input: "ff0000", expected: "ff0000ff", ok: true},
+		{input: "#0288D1", expected: "ffd18802", ok: true},
+		{input: "", ok: false},
+		{input: "ff00", ok: false},
+		{input: "zz0000", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, ok := RGBToColor(tt.input)
+			if ok != tt.ok || result != tt.expected {
+				t.Errorf("RGBToColor(%q) = (%q, %v), want (%q, %v)", tt.input, result, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestStyleSheet_Resolve(t *testing.T) {
+	kmlData := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+	<Style id="line-red">
+		<LineStyle><color>ff0000ff</color></LineStyle>
+	</Style>
+	<Style id="line-red-highlight">
+		<LineStyle><color>ffffffff</color></LineStyle>
+	</Style>
+	<StyleMap id="route">
+		<Pair><key>highlight</key><styleUrl>#line-red-highlight</styleUrl></Pair>
+		<Pair><key>normal</key><styleUrl>#line-red</styleUrl></Pair>
+	</StyleMap>
+	<StyleMap id="inline-pair">
+		<Pair>
+			<key>normal</key>
+			<Style><IconStyle><color>ff00ff00</color></IconStyle></Style>
+		</Pair>
+	</StyleMap>
+	<StyleMap id="loop-a">
+		<Pair><key>normal</key><styleUrl>#loop-b</styleUrl></Pair>
+	</StyleMap>
+	<StyleMap id="loop-b">
+		<Pair><key>normal</key><styleUrl>#loop-a</styleUrl></Pair>
+	</StyleMap>
+	<Folder>
+		<Style id="folder-style">
+			<PolyStyle><color>ffff0000</color></PolyStyle>
+		</Style>
+	</Folder>
+</Document>
+</kml>`
+
+	kml, err := Parse([]byte(kmlData))
+	if err != nil {
+		t.Fatalf("Failed to parse KML: %v", err)
+	}
+	sheet := NewStyleSheet(kml)
+
+	if style := sheet.Resolve("#line-red"); style == nil || style.LineStyle.Color != "ff0000ff" {
+		t.Errorf("Expected #line-red to resolve to its style, got %+v", style)
+	}
+	if style := sheet.Resolve("#route"); style == nil || style.ID != "line-red" {
+		t.Errorf("Expected StyleMap to resolve to its normal style, got %+v", style)
+	}
+	if style := sheet.Resolve("#inline-pair"); style == nil || style.IconStyle.Color != "ff00ff00" {
+		t.Errorf("Expected StyleMap pair with an inline style to resolve, got %+v", style)
+	}
+	if style := sheet.Resolve("#folder-style"); style == nil || style.PolyStyle == nil {
+		t.Errorf("Expected styles declared in folders to resolve, got %+v", style)
+	}
+	if style := sheet.Resolve("line-red"); style == nil {
+		t.Error("Expected a styleUrl without '#' to resolve")
+	}
+	if style := sheet.Resolve("#missing"); style != nil {
+		t.Errorf("Expected unknown style to resolve to nil, got %+v", style)
+	}
+	if style := sheet.Resolve("#loop-a"); style != nil {
+		t.Errorf("Expected circular StyleMaps to resolve to nil, got %+v", style)
+	}
+	if style := sheet.Resolve("http://example.com/styles.kml#line-red"); style != nil {
+		t.Errorf("Expected remote styles to resolve to nil, got %+v", style)
+	}
+}
+
+func TestStyleSheet_PlacemarkStyle(t *testing.T) {
+	kmlData := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+	<Style id="shared">
+		<IconStyle><color>ff0000ff</color></IconStyle>
+		<LineStyle><color>ff00ff00</color></LineStyle>
+	</Style>
+	<Placemark>
+		<name>Shared only</name>
+		<styleUrl>#shared</styleUrl>
+	</Placemark>
+	<Placemark>
+		<name>Inline only</name>
+		<Style><LineStyle><color>ffff0000</color></LineStyle></Style>
+	</Placemark>
+	<Placemark>
+		<name>Inline overrides shared</name>
+		<styleUrl>#shared</styleUrl>
+		<Style><LineStyle><color>ffff0000</color></LineStyle></Style>
+	</Placemark>
+	<Placemark>
+		<name>Unstyled</name>
+	</Placemark>
+</Document>
+</kml>`
+
+	kml, err := Parse([]byte(kmlData))
+	if err != nil {
+		t.Fatalf("Failed to parse KML: %v", err)
+	}
+	sheet := NewStyleSheet(kml)
+	placemarks := kml.Document.Placemarks
+
+	if style := sheet.PlacemarkStyle(&placemarks[0]); style == nil || style.LineStyle.Color != "ff00ff00" {
+		t.Errorf("Expected shared style, got %+v", style)
+	}
+	if style := sheet.PlacemarkStyle(&placemarks[1]); style == nil || style.LineStyle.Color != "ffff0000" {
+		t.Errorf("Expected inline style, got %+v", style)
+	}
+
+	merged := sheet.PlacemarkStyle(&placemarks[2])
+	if merged == nil || merged.LineStyle.Color != "ffff0000" || merged.IconStyle.Color != "ff0000ff" {
+		t.Errorf("Expected inline LineStyle merged over shared style, got %+v", merged)
+	}
+	if shared := sheet.Resolve("#shared"); shared.LineStyle.Color != "ff00ff00" {
+		t.Error("Merging an inline style must not modify the shared style")
+	}
+
+	if style := sheet.PlacemarkStyle(&placemarks[3]); style != nil {
+		t.Errorf("Expected nil style for unstyled placemark, got %+v", style)
+	}
+}
+
+func TestParseKMZ_SharedStyles(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+	<Placemark>
+		<name>Route</name>
+		<styleUrl>styles/shared.kml#route</styleUrl>
+		<LineString><coordinates>10,53 11,54</coordinates></LineString>
+	</Placemark>
+</Document>
+</kml>`
+	styles := `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+<Document>
+	<Style id="route"><LineStyle><color>ff3366cc</color></LineStyle></Style>
+</Document>
+</kml>`
+
+	kmzPath := filepath.Join(t.TempDir(), "routes.kmz")
+	file, err := os.Create(kmzPath)
+	if err != nil {
+		t.Fatalf("Failed to create KMZ: %v", err)
+	}
+	archive := zip.NewWriter(file)
+	entries := []struct{ name, content string }{
+		{"doc.kml", doc},
+		{"styles/shared.kml", styles},
+	}
+	for _, entry := range entries {
+		w, err := archive.Create(entry.name)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", entry.name, err)
+		}
+		if _, err := w.Write([]byte(entry.content)); err != nil {
+			t.Fatalf("Failed to write %s: %v", entry.name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("Failed to close KMZ: %v", err)
+	}
+	file.Close()
+
+	kml, err := ParseKMZ(kmzPath)
+	if err != nil {
+		t.Fatalf("ParseKMZ returned error: %v", err)
+	}
+	if len(kml.Document.Placemarks) != 1 {
+		t.Fatalf("Expected the placemark from doc.kml, got %d placemarks", len(kml.Document.Placemarks))
+	}
+
+	style := NewStyleSheet(kml).PlacemarkStyle(&kml.Document.Placemarks[0])
+	if style == nil || style.LineStyle == nil || style.LineStyle.Color != "ff3366cc" {
+		t.Errorf("Expected style from styles/shared.kml, got %+v", style)
+	}
+}
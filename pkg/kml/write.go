@@ -0,0 +1,121 @@
+package kml
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Namespace is the KML 2.2 namespace written on documents that have none
+const Namespace = "http://www.opengis.net/kml/2.2"
+
+// Marshal serializes a document as indented KML. Tracks are written as KML 2.3
+// <Track> elements.
+func Marshal(k *KML) ([]byte, error) {
+	name := k.XMLName
+	if name.Space == "" {
+		name.Space = Namespace
+	}
+	name.Local = "kml"
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.EncodeElement(k, xml.StartElement{Name: name}); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// WriteFile writes a document to a .kml file, or to a KMZ archive when the
+// path ends in .kmz
+func WriteFile(filePath string, k *KML) error {
+	if strings.EqualFold(filepath.Ext(filePath), ".kmz") {
+		return WriteKMZ(filePath, k)
+	}
+
+	data, err := Marshal(k)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, data, 0o644)
+}
+
+// WriteKMZ writes a document to a KMZ archive as doc.kml, along with its
+// Resources under their relative paths. The archive is removed again if it
+// cannot be written completely.
+func WriteKMZ(filePath string, k *KML) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(file)
+	err = writeKMZEntries(archive, k)
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(filePath)
+		return err
+	}
+	return nil
+}
+
+// writeKMZEntries writes the main document followed by the resources
+func writeKMZEntries(archive *zip.Writer, k *KML) error {
+	// The main document comes first, since readers take the first KML file
+	if err := writeZipEntry(archive, "doc.kml", k); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(k.Resources))
+	for name := range k.Resources {
+		if name != "doc.kml" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := writeZipEntry(archive, name, k.Resources[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeZipEntry(archive *zip.Writer, name string, k *KML) error {
+	data, err := Marshal(k)
+	if err != nil {
+		return err
+	}
+	writer, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	return err
+}
+
+// FormatCoordinates writes coordinates in the lon,lat[,alt] form of
+// <coordinates>, leaving out altitudes of zero
+func FormatCoordinates(coords []Coordinate) string {
+	parts := make([]string, len(coords))
+	for i, coord := range coords {
+		part := strconv.FormatFloat(coord.Lon, 'f', -1, 64) + "," + strconv.FormatFloat(coord.Lat, 'f', -1, 64)
+		if coord.Alt != 0 {
+			part += "," + strconv.FormatFloat(coord.Alt, 'f', -1, 64)
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, " ")
+}
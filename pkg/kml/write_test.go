@@ -0,0 +1,93 @@
+package kml
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMarshal_RoundTrip(t *testing.T) {
+	fill := 0
+	original := &KML{
+		Document: &Document{
+			Name:   "Export",
+			Styles: []Style{{ID: "area", PolyStyle: &PolyStyle{Color: "7f0000ff", Fill: &fill}}},
+			Folders: []Folder{{
+				Name: "Stations",
+				Placemarks: []Placemark{{
+					Name:     "Central & Co",
+					StyleURL: "#area",
+					Point:    &Point{Coordinates: FormatCoordinates([]Coordinate{{Lon: 13.3694, Lat: 52.5251}})},
+					ExtendedData: &ExtendedData{
+						Data: []Data{{Name: "platforms", Value: "12"}},
+					},
+				}},
+			}},
+		},
+	}
+
+	data, err := Marshal(original)
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+	text := string(data)
+	if !strings.HasPrefix(text, "<?xml") || !strings.Contains(text, `<kml xmlns="`+Namespace+`">`) {
+		t.Errorf("Expected an XML header and the KML namespace, got:\n%s", text)
+	}
+	// Unset fields are left out rather than written empty
+	if strings.Contains(text, "<description>") || strings.Contains(text, "<outline>") {
+		t.Errorf("Expected unset elements to be omitted, got:\n%s", text)
+	}
+
+	parsed, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	placemark := parsed.Document.Folders[0].Placemarks[0]
+	if placemark.Name != "Central & Co" || placemark.Attributes()["platforms"] != "12" {
+		t.Errorf("Expected the placemark to round-trip, got %+v", placemark)
+	}
+	coords, err := ParseCoordinates(placemark.Point.Coordinates)
+	if err != nil || len(coords) != 1 || coords[0].Lon != 13.3694 || coords[0].Lat != 52.5251 {
+		t.Errorf("Expected the point to round-trip, got %v (%v)", coords, err)
+	}
+	polyStyle := parsed.Document.Styles[0].PolyStyle
+	if polyStyle.Fill == nil || *polyStyle.Fill != 0 || polyStyle.Outline != nil {
+		t.Errorf("Expected fill 0 and no outline, got %+v", polyStyle)
+	}
+}
+
+func TestWriteFile_KMZ(t *testing.T) {
+	k := &KML{
+		Document: &Document{
+			Placemarks: []Placemark{{Name: "Route", StyleURL: "styles/shared.kml#route"}},
+		},
+		Resources: map[string]*KML{
+			"styles/shared.kml": {Document: &Document{Styles: []Style{{ID: "route", LineStyle: &LineStyle{Color: "ff3366cc"}}}}},
+		},
+	}
+
+	kmzPath := filepath.Join(t.TempDir(), "export.kmz")
+	if err := WriteFile(kmzPath, k); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+
+	parsed, err := ParseKMZ(kmzPath)
+	if err != nil {
+		t.Fatalf("ParseKMZ returned error: %v", err)
+	}
+	if len(parsed.Document.Placemarks) != 1 || parsed.Document.Placemarks[0].Name != "Route" {
+		t.Fatalf("Expected doc.kml to be the main document, got %+v", parsed.Document)
+	}
+	style := NewStyleSheet(parsed).PlacemarkStyle(&parsed.Document.Placemarks[0])
+	if style == nil || style.LineStyle == nil || style.LineStyle.Color != "ff3366cc" {
+		t.Errorf("Expected the shared style to resolve from the archive, got %+v", style)
+	}
+}
+
+func TestFormatCoordinates(t *testing.T) {
+	got := FormatCoordinates([]Coordinate{{Lon: 10, Lat: 53.5}, {Lon: -0.125, Lat: 51.5, Alt: 35}})
+	if expected := "10,53.5 -0.125,51.5,35"; got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}